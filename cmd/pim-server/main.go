@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/config"
 	"github.com/haconeco/project-information-manager/internal/mcp"
@@ -43,10 +44,15 @@ func main() {
 	defer repos.Close()
 
 	// サービス層初期化
-	services := service.NewServices(repos)
+	services := service.NewServices(repos, cfg)
 	if err := services.BootstrapVectorIndex(context.Background()); err != nil {
 		slog.Warn("failed to bootstrap vector index; continuing without blocking startup", "error", err)
 	}
+	if purged, err := services.Stock.PurgeDeleted(context.Background(), time.Now()); err != nil {
+		slog.Warn("failed to purge deleted stocks", "error", err)
+	} else if len(purged) > 0 {
+		slog.Info("purged deleted stocks", "count", len(purged))
+	}
 
 	// MCPサーバー初期化・起動
 	ctx, cancel := context.WithCancel(context.Background())
@@ -61,17 +67,34 @@ func main() {
 		cancel()
 	}()
 
+	// 放置State検出の定期実行
+	go services.Hygiene.Start(ctx)
+
 	server, err := mcp.NewServer(services, cfg)
 	if err != nil {
 		slog.Error("failed to create MCP server", "error", err)
 		os.Exit(1)
 	}
 
+	// Stockファイルの外部編集（手作業・git pull）の取り込み。変更はMCPサーバーがクライアントへ通知する
+	if services.StockWatcher != nil {
+		go services.StockWatcher.Start(ctx)
+	}
+
 	slog.Info("starting PIM MCP server", "version", cfg.Version)
 	if err := server.Run(ctx); err != nil {
 		slog.Error("server error", "error", err)
 		os.Exit(1)
 	}
+
+	if services.LLM != nil {
+		usage := services.LLM.Usage()
+		slog.Info("llm usage", "calls", usage.Calls, "errors", usage.Errors,
+			"input_tokens", usage.InputTokens, "output_tokens", usage.OutputTokens)
+	}
+	if usage := server.SamplingUsage(); usage.Calls > 0 {
+		slog.Info("mcp sampling usage", "calls", usage.Calls, "errors", usage.Errors)
+	}
 }
 
 func ensureDataDirs(cfg *config.Config) error {
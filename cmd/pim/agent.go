@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/haconeco/project-information-manager/internal/service"
+)
+
+func runAgent(args []string) error {
+	if len(args) == 0 || args[0] != "generate" {
+		return fmt.Errorf("usage: pim agent generate --project <id> [--out <dir>] [--data-dir <dir>] [--dry-run]")
+	}
+
+	fs := flag.NewFlagSet("agent generate", flag.ExitOnError)
+	projectID := fs.String("project", "", "対象プロジェクトID（必須）")
+	outDir := fs.String("out", ".", "出力先ディレクトリ（対象リポジトリのルート）")
+	dryRun := fs.Bool("dry-run", false, "ファイルを書き込まずに差分を表示する")
+	serverCommand := fs.String("server-command", "pim-server", ".mcp.json に記載する pim-server のコマンド")
+	dataDir := fs.String("data-dir", "", ".mcp.json に PIM_DATA_DIR として記載する pim-server のデータディレクトリ（省略時は記載しない）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *projectID == "" {
+		return fmt.Errorf("--project is required")
+	}
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// .mcp.json はリポジトリにコミットされるため、マシン固有のパスは明示された場合のみ記載する
+	input := service.GenerateAgentConfigInput{
+		ProjectID:     *projectID,
+		ServerCommand: *serverCommand,
+	}
+	if *dataDir != "" {
+		input.ServerEnv = map[string]string{"PIM_DATA_DIR": *dataDir}
+	}
+
+	files, err := a.services.AgentConfig.Generate(context.Background(), input)
+	if err != nil {
+		return err
+	}
+	changes, err := a.services.AgentConfig.Apply(input, files, *outDir, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%-9s %s\n", change.Action, change.Path)
+	}
+	if *dryRun {
+		for _, change := range changes {
+			if change.Diff != "" {
+				fmt.Printf("\n%s", change.Diff)
+			}
+		}
+	}
+	return nil
+}
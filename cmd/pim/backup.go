@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	list := fs.Bool("list", false, "スナップショットの一覧を表示する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		backups, err := repository.ListBackups(cfg.BackupsDir())
+		if err != nil {
+			return err
+		}
+		for _, backup := range backups {
+			printBackup("backup", backup)
+		}
+		return nil
+	}
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	result, err := a.services.Backup(context.Background())
+	if err != nil {
+		return err
+	}
+	printBackup("created", result.Backup)
+	for _, name := range result.Removed {
+		fmt.Printf("%-9s %s\n", "removed", name)
+	}
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	verifyOnly := fs.Bool("verify", false, "スナップショットの検証のみ行い、データを置き換えない")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pim restore [--verify] <backup name | latest>  (see pim backup --list)")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	name := fs.Arg(0)
+	if name == "latest" {
+		backups, err := repository.ListBackups(cfg.BackupsDir())
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			return fmt.Errorf("no backups in %s", cfg.BackupsDir())
+		}
+		name = backups[0].Name
+	}
+
+	ctx := context.Background()
+	if *verifyOnly {
+		migrations, err := repository.Migrations()
+		if err != nil {
+			return err
+		}
+		dir, err := repository.BackupPath(cfg, name)
+		if err != nil {
+			return err
+		}
+		info, err := repository.VerifyBackup(ctx, dir, len(migrations))
+		if err != nil {
+			return err
+		}
+		printBackup("verified", *info)
+		return nil
+	}
+
+	// states.db・Stockディレクトリを置き換えるため、pim-server が起動している場合は ErrDataDirInUse になる
+	result, err := repository.RestoreBackup(ctx, cfg, name)
+	if err != nil {
+		return err
+	}
+	printBackup("restored", result.Backup)
+	fmt.Printf("%-9s %s\n", "previous", result.Previous)
+	fmt.Println("the vector index will be rebuilt when pim-server starts")
+	return nil
+}
+
+func printBackup(label string, backup repository.BackupInfo) {
+	fmt.Printf("%-9s %s (%s, schema v%d, %d files, %d bytes)\n",
+		label, backup.Name, backup.CreatedAt.Local().Format(time.RFC3339), backup.SchemaVersion, backup.Files, backup.Size)
+}
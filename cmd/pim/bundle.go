@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/service"
+)
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	projectID := fs.String("project", "", "エクスポートするプロジェクトID（必須）")
+	out := fs.String("out", "", "出力先ファイル（デフォルト: <project>-<日付>.pim.tar.gz）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *projectID == "" {
+		return fmt.Errorf("--project is required")
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("%s-%s.pim.tar.gz", *projectID, time.Now().Format("20060102"))
+	}
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// 途中で失敗した場合に不完全なファイルを残さないよう、一時ファイルに書いてからリネームする
+	tmp, err := os.CreateTemp(filepath.Dir(*out), "."+filepath.Base(*out)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	manifest, err := a.services.ExportProject(context.Background(), *projectID, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), *out); err != nil {
+		return err
+	}
+
+	fmt.Printf("stocks    %d\n", manifest.Counts.Stocks)
+	fmt.Printf("states    %d\n", manifest.Counts.States)
+	fmt.Printf("releases  %d\n", manifest.Counts.Releases)
+	fmt.Printf("links     %d\n", manifest.Counts.Links)
+	fmt.Printf("exported  %s\n", *out)
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	projectID := fs.String("project", "", "取り込み先のプロジェクトID（デフォルト: エクスポート元のプロジェクトID）")
+	onConflict := fs.String("on-conflict", "fail", "取り込み先に同じ管理番号がある場合: fail | skip | renumber（新しい管理番号を採番して参照を書き換える）")
+	dryRun := fs.Bool("dry-run", false, "書き込まずに取り込み内容を表示する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pim import [--project <id>] [--on-conflict fail|skip|renumber] [--dry-run] <bundle.tar.gz>")
+	}
+	policy, err := service.ParseImportConflictPolicy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	report, err := a.services.ImportProject(context.Background(), f, service.ImportOptions{
+		ProjectID:  *projectID,
+		OnConflict: policy,
+		DryRun:     *dryRun,
+	})
+	if report != nil {
+		printImportReport(report)
+	}
+	if err != nil {
+		if report != nil && !report.DryRun {
+			w := report.Written
+			fmt.Printf("written   stocks %d, states %d, releases %d\n", w.Stocks, w.States, w.Releases)
+		}
+		return err
+	}
+	if report.DryRun {
+		fmt.Println("dry run: nothing was written")
+		return nil
+	}
+	fmt.Printf("imported  %s (created %d, renumbered %d, skipped %d)\n",
+		report.ProjectID, report.Count("create"), report.Count("renumber"), report.Count("skip"))
+	return nil
+}
+
+func printImportReport(report *service.ImportReport) {
+	m := report.Manifest
+	fmt.Printf("bundle    %s (format v%d, pim %s, exported %s)\n", m.Project.ID, m.Version, m.PIMVersion, m.ExportedAt.Format(time.RFC3339))
+	for _, item := range report.Items {
+		switch item.Action {
+		case "renumber":
+			fmt.Printf("%-9s %s %s -> %s\n", item.Action, item.Kind, item.OldID, item.NewID)
+		default:
+			fmt.Printf("%-9s %s %s\n", item.Action, item.Kind, item.OldID)
+		}
+	}
+	for _, conflict := range report.Conflicts {
+		fmt.Printf("conflict  %s\n", conflict)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("warning   %s\n", warning)
+	}
+}
@@ -0,0 +1,99 @@
+// pim はProject Information Managerの管理用コマンドラインツール。
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/repository"
+	"github.com/haconeco/project-information-manager/internal/service"
+)
+
+const usage = `Usage: pim <command> [options]
+
+Commands:
+  agent generate   プロジェクトのStockからエージェント設定ファイルを生成する
+  migrate status   states.db のスキーマバージョンとマイグレーションの適用状況を表示する
+  migrate up       未適用のマイグレーションをバックアップを取ってから適用する
+  stock sync       Stockディレクトリ（stock.git）の変更をコミットして git pull し、索引へ取り込む
+  export           プロジェクトのStock・State・Releaseをバンドル（tar.gz）に書き出す
+  import           バンドルを取り込む（管理番号の振り直し・dry-run 対応）
+  backup           データディレクトリ（states.db・Stock）のスナップショットを作成する（--list で一覧）
+  restore          スナップショットを検証してからデータを置き換える（pim-server を停止して実行）
+  site build       プロジェクトのStockを静的HTMLサイト（ナビゲーション・バックリンク・Stateダッシュボード・検索）に出力する
+
+Run "pim <command> -h" for command options.
+`
+
+func main() {
+	// CLIでは警告以上のみ標準エラーに出力する
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	})))
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "agent":
+		err = runAgent(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "stock":
+		err = runStock(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "site":
+		err = runSite(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// app はCLIコマンドが共有する設定・リポジトリ・サービス。
+type app struct {
+	cfg      *config.Config
+	repos    *repository.Repositories
+	services *service.Services
+}
+
+// openApp は設定を読み込み、pim-server と同じデータディレクトリのリポジトリ・サービスを初期化する。
+func openApp() (*app, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, dir := range []string{cfg.DataDir, cfg.StocksDir(), cfg.VectorsDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	repos, err := repository.NewRepositories(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize repositories: %w", err)
+	}
+	return &app{cfg: cfg, repos: repos, services: service.NewServices(repos, cfg)}, nil
+}
+
+func (a *app) Close() {
+	a.repos.Close()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func runMigrate(args []string) error {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "up") {
+		return fmt.Errorf("usage: pim migrate status|up [--no-backup]")
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	noBackup := fs.Bool("no-backup", false, "適用前のバックアップを取らない（up用）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", cfg.DataDir, err)
+	}
+	// openApp はリポジトリの初期化時にマイグレーションを適用するため、ここでは直接開く
+	db, err := repository.OpenStatesDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if args[0] == "status" {
+		return printMigrationStatus(ctx, migrator)
+	}
+
+	if !*noBackup {
+		migrator.SetBackupDir(cfg.BackupsDir())
+	}
+	result, err := migrator.Up(ctx)
+	if result != nil {
+		if result.Backup != "" {
+			fmt.Printf("backup    %s\n", result.Backup)
+		}
+		for _, version := range result.Applied {
+			fmt.Printf("applied   %04d\n", version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Applied) == 0 {
+		fmt.Printf("up to date (version %d)\n", result.From)
+		return nil
+	}
+	fmt.Printf("migrated to version %d\n", migrator.Latest())
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *repository.Migrator) error {
+	current, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("current version: %d (latest: %d)\n\n", current, migrator.Latest())
+	for _, s := range statuses {
+		state := "pending"
+		if s.AppliedAt != nil {
+			state = "applied"
+			if !s.AppliedAt.IsZero() {
+				state += "  " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+		}
+		fmt.Printf("%04d  %-28s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/haconeco/project-information-manager/internal/service"
+)
+
+func runSite(args []string) error {
+	if len(args) == 0 || args[0] != "build" {
+		return fmt.Errorf("usage: pim site build [--project <id>] [--out <dir>] [--title <title>]")
+	}
+
+	fs := flag.NewFlagSet("site build", flag.ExitOnError)
+	projectID := fs.String("project", "", "対象プロジェクトID（デフォルト: 全プロジェクト）")
+	outDir := fs.String("out", "site", "出力先ディレクトリ（前回の出力は置き換える）")
+	title := fs.String("title", "", "サイトのタイトル（デフォルト: Project Information Manager）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	files, err := a.services.Site.Build(context.Background(), service.SiteBuildInput{ProjectID: *projectID, Title: *title})
+	if err != nil {
+		return err
+	}
+	if err := service.WriteSite(*outDir, files); err != nil {
+		return err
+	}
+
+	index, err := filepath.Abs(filepath.Join(*outDir, "index.html"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-9s %d files\n", "generated", len(files))
+	fmt.Printf("%-9s %s\n", "open", index)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+func runStock(args []string) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return fmt.Errorf("usage: pim stock sync")
+	}
+
+	fs := flag.NewFlagSet("stock sync", flag.ExitOnError)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	a, err := openApp()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	report, err := a.services.SyncStocks(context.Background())
+	if errors.Is(err, domain.ErrHistoryDisabled) {
+		return fmt.Errorf("stock sync requires stock.git: true (or PIM_STOCK_GIT=true) and stock.store file or mirror")
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.Committed {
+		fmt.Println("committed local changes")
+	}
+	if report.Upstream == "" {
+		fmt.Println("upstream  (none; skipped pull)")
+	} else if report.Before != report.After {
+		fmt.Printf("pulled    %s %s..%s\n", report.Upstream, shortHash(report.Before), shortHash(report.After))
+	} else if len(report.Conflicts) == 0 {
+		fmt.Printf("upstream  %s (up to date)\n", report.Upstream)
+	}
+	for _, change := range report.Reconciled.Changes {
+		fmt.Printf("%-9s %s/%s\n", change.Op, change.ProjectID, change.ID)
+	}
+	for _, d := range report.Reconciled.Diagnostics {
+		fmt.Printf("invalid   %s: %s\n", d.Path, d.Message)
+	}
+	for _, path := range report.Conflicts {
+		fmt.Printf("conflict  %s\n", path)
+	}
+	if len(report.Conflicts) > 0 {
+		return fmt.Errorf("%d stock files conflict with %s; the merge was aborted. resolve them with git in the stocks directory and run sync again", len(report.Conflicts), report.Upstream)
+	}
+	return nil
+}
+
+// shortHash はコミットハッシュを表示用に短縮する。
+func shortHash(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -27,13 +27,27 @@ type Config struct {
 
 	// RAG設定
 	RAG RAGConfig `yaml:"rag"`
+
+	// SLA設定
+	SLA SLAConfig `yaml:"sla"`
+
+	// 放置State検出設定
+	Hygiene HygieneConfig `yaml:"hygiene"`
+
+	// Stock管理設定
+	Stock StockConfig `yaml:"stock"`
+
+	// バックアップ設定
+	Backup BackupConfig `yaml:"backup"`
 }
 
 // LLMConfig はLLMプロバイダーの設定を保持する。
+// APIキーが未設定の場合（stub を除く）はLLMを利用しない。
 type LLMConfig struct {
-	Provider string `yaml:"provider"` // "anthropic" | "openai"
+	Provider string `yaml:"provider"` // "anthropic" | "openai" | "stub"（オフライン・テスト用）
 	APIKey   string `yaml:"api_key"`
 	Model    string `yaml:"model"`
+	BaseURL  string `yaml:"base_url"` // OpenAI互換サーバー等のエンドポイント（省略時は公式API）
 }
 
 // MCPConfig はMCPサーバーの設定を保持する。
@@ -57,6 +71,49 @@ type RAGEmbeddingConfig struct {
 	OllamaBaseURL string `yaml:"ollama_base_url"` // ollama用
 }
 
+// SLAConfig はStateのSLAポリシー設定を保持する。
+type SLAConfig struct {
+	Policies []SLAPolicyConfig `yaml:"policies"`
+}
+
+// SLAPolicyConfig は種別・優先度ごとの解決期限を保持する。
+// type / priority を省略した場合はすべての種別・優先度に適用される。
+type SLAPolicyConfig struct {
+	Type          string `yaml:"type"`           // "task" | "issue" | "incident" | "change"
+	Priority      string `yaml:"priority"`       // "P0" | "P1" | "P2" | "P3"
+	ResolveWithin string `yaml:"resolve_within"` // 例: "4h", "72h"
+}
+
+// HygieneConfig は放置State検出ジョブの設定を保持する。
+type HygieneConfig struct {
+	Interval   string                 `yaml:"interval"`   // 定期実行間隔（例: "24h"）。空または "0" で定期実行しない
+	TagStale   bool                   `yaml:"tag_stale"`  // 放置Stateに stale タグを付与するか
+	Escalate   bool                   `yaml:"escalate"`   // 放置Stateの優先度を1段階引き上げるか
+	Thresholds []StaleThresholdConfig `yaml:"thresholds"` // 種別・優先度ごとのしきい値
+}
+
+// StaleThresholdConfig は種別・優先度ごとの放置判定しきい値を保持する。
+// type / priority を省略した場合はすべての種別・優先度に適用される。
+type StaleThresholdConfig struct {
+	Type     string `yaml:"type"`     // "task" | "issue" | "incident" | "change" | "problem"
+	Priority string `yaml:"priority"` // "P0" | "P1" | "P2" | "P3"
+	After    string `yaml:"after"`    // 例: "72h", "720h"
+}
+
+// StockConfig はStock管理の設定を保持する。
+type StockConfig struct {
+	DeletedRetention string `yaml:"deleted_retention"` // 削除済みStockを完全削除するまでの保持期間（例: "720h"）
+	Store            string `yaml:"store"`             // "file"（ファイルのみ） | "mirror"（ファイルを正としSQLiteに索引をミラー） | "sqlite"（SQLiteのみ）
+	Watch            string `yaml:"watch"`             // Stockファイルの外部編集の監視: "notify"（OSのファイル変更通知、使えない場合はポーリング） | "poll" | "off"
+	PollInterval     string `yaml:"poll_interval"`     // watch が poll の場合（または通知が使えない場合）の走査間隔（例: "10s"）
+	Git              bool   `yaml:"git"`               // Stockディレクトリをgitリポジトリとし、変更ごとにコミットする（store が file / mirror の場合）
+}
+
+// BackupConfig はデータディレクトリのバックアップ（pim backup / state_manage action=backup）の設定を保持する。
+type BackupConfig struct {
+	Retention int `yaml:"retention"` // 保持するスナップショットの数（0 で削除しない）
+}
+
 // Load は設定ファイルを読み込む。ファイルが存在しない場合はデフォルト値を使用する。
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -79,6 +136,31 @@ func Load() (*Config, error) {
 				OllamaBaseURL: "http://localhost:11434/api",
 			},
 		},
+		SLA: SLAConfig{
+			Policies: []SLAPolicyConfig{
+				{Type: "incident", Priority: "P0", ResolveWithin: "4h"},
+				{Type: "incident", Priority: "P1", ResolveWithin: "24h"},
+			},
+		},
+		Hygiene: HygieneConfig{
+			Interval: "24h",
+			TagStale: true,
+			Thresholds: []StaleThresholdConfig{
+				{Priority: "P0", After: "72h"},
+				{Priority: "P1", After: "168h"},
+				{Priority: "P2", After: "336h"},
+				{Priority: "P3", After: "720h"},
+			},
+		},
+		Stock: StockConfig{
+			DeletedRetention: "720h",
+			Store:            "file",
+			Watch:            "notify",
+			PollInterval:     "10s",
+		},
+		Backup: BackupConfig{
+			Retention: 7,
+		},
 	}
 
 	// 設定ファイルのパスを決定
@@ -110,6 +192,9 @@ func Load() (*Config, error) {
 	if v := os.Getenv("PIM_LLM_MODEL"); v != "" {
 		cfg.LLM.Model = v
 	}
+	if v := os.Getenv("PIM_LLM_BASE_URL"); v != "" {
+		cfg.LLM.BaseURL = v
+	}
 	if v := os.Getenv("PIM_RAG_ENABLED"); v != "" {
 		if enabled, err := strconv.ParseBool(v); err == nil {
 			cfg.RAG.Enabled = enabled
@@ -130,6 +215,35 @@ func Load() (*Config, error) {
 	if v := os.Getenv("PIM_RAG_EMBEDDING_OLLAMA_BASE_URL"); v != "" {
 		cfg.RAG.Embedding.OllamaBaseURL = v
 	}
+	if v := os.Getenv("PIM_HYGIENE_INTERVAL"); v != "" {
+		cfg.Hygiene.Interval = v
+	}
+	if v := os.Getenv("PIM_HYGIENE_ESCALATE"); v != "" {
+		if escalate, err := strconv.ParseBool(v); err == nil {
+			cfg.Hygiene.Escalate = escalate
+		}
+	}
+
+	if v := os.Getenv("PIM_STOCK_DELETED_RETENTION"); v != "" {
+		cfg.Stock.DeletedRetention = v
+	}
+	if v := os.Getenv("PIM_STOCK_STORE"); v != "" {
+		cfg.Stock.Store = v
+	}
+	if v := os.Getenv("PIM_STOCK_WATCH"); v != "" {
+		cfg.Stock.Watch = v
+	}
+	if v := os.Getenv("PIM_STOCK_GIT"); v != "" {
+		if git, err := strconv.ParseBool(v); err == nil {
+			cfg.Stock.Git = git
+		}
+	}
+
+	if v := os.Getenv("PIM_BACKUP_RETENTION"); v != "" {
+		if retention, err := strconv.Atoi(v); err == nil {
+			cfg.Backup.Retention = retention
+		}
+	}
 
 	return cfg, nil
 }
@@ -149,6 +263,11 @@ func (c *Config) StatesDBPath() string {
 	return filepath.Join(c.DataDir, "states.db")
 }
 
+// BackupsDir はバックアップの格納ディレクトリパスを返す。
+func (c *Config) BackupsDir() string {
+	return filepath.Join(c.DataDir, "backups")
+}
+
 // VectorsDir はベクトルインデックスの格納ディレクトリパスを返す。
 func (c *Config) VectorsDir() string {
 	return filepath.Join(c.DataDir, "vectors")
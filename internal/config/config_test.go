@@ -32,6 +32,18 @@ func TestLoadDefault(t *testing.T) {
 	if cfg.RAG.Collection != "pim-context" {
 		t.Errorf("expected rag collection pim-context, got %s", cfg.RAG.Collection)
 	}
+	if len(cfg.SLA.Policies) != 2 || cfg.SLA.Policies[0].ResolveWithin != "4h" {
+		t.Errorf("expected default incident SLA policies, got %+v", cfg.SLA.Policies)
+	}
+	if cfg.Hygiene.Interval != "24h" || !cfg.Hygiene.TagStale || cfg.Hygiene.Escalate || len(cfg.Hygiene.Thresholds) != 4 {
+		t.Errorf("unexpected default hygiene config: %+v", cfg.Hygiene)
+	}
+	if cfg.Stock.DeletedRetention != "720h" {
+		t.Errorf("expected stock deleted_retention 720h, got %s", cfg.Stock.DeletedRetention)
+	}
+	if cfg.Stock.Store != "file" {
+		t.Errorf("expected stock store file, got %s", cfg.Stock.Store)
+	}
 	if cfg.RAG.Embedding.Provider != "openai" {
 		t.Errorf("expected rag embedding provider openai, got %s", cfg.RAG.Embedding.Provider)
 	}
@@ -50,6 +62,7 @@ func TestLoadEnvOverride(t *testing.T) {
 	t.Setenv("PIM_RAG_EMBEDDING_MODEL", "nomic-embed-text")
 	t.Setenv("PIM_RAG_EMBEDDING_API_KEY", "rag-key")
 	t.Setenv("PIM_RAG_EMBEDDING_OLLAMA_BASE_URL", "http://localhost:11434/api")
+	t.Setenv("PIM_STOCK_STORE", "mirror")
 
 	cfg, err := Load()
 	if err != nil {
@@ -85,6 +98,9 @@ func TestLoadEnvOverride(t *testing.T) {
 	if cfg.RAG.Embedding.OllamaBaseURL != "http://localhost:11434/api" {
 		t.Errorf("expected rag ollama base url http://localhost:11434/api, got %s", cfg.RAG.Embedding.OllamaBaseURL)
 	}
+	if cfg.Stock.Store != "mirror" {
+		t.Errorf("expected stock store mirror, got %s", cfg.Stock.Store)
+	}
 }
 
 func TestConfigPaths(t *testing.T) {
@@ -0,0 +1,116 @@
+package domain
+
+import "time"
+
+// RiskLevel は変更のリスクレベルを表す。
+type RiskLevel string
+
+const (
+	RiskLow    RiskLevel = "low"
+	RiskMedium RiskLevel = "medium"
+	RiskHigh   RiskLevel = "high"
+)
+
+// ParseRiskLevel は文字列からRiskLevelを解析する。
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	switch r := RiskLevel(s); r {
+	case RiskLow, RiskMedium, RiskHigh:
+		return r, nil
+	default:
+		return "", ErrInvalidRiskLevel
+	}
+}
+
+// ApprovalStatus は変更の承認状況を表す。
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// ApprovalDecision は承認者の判断を表す。
+type ApprovalDecision string
+
+const (
+	DecisionApprove ApprovalDecision = "approve"
+	DecisionReject  ApprovalDecision = "reject"
+)
+
+// Approval は承認者1名分の判断記録。
+type Approval struct {
+	Approver  string           `json:"approver"`
+	Decision  ApprovalDecision `json:"decision"`
+	Comment   string           `json:"comment,omitempty"`
+	DecidedAt time.Time        `json:"decided_at"`
+}
+
+// ChangeDetails は変更（StateTypeChange）固有の構造化情報。
+type ChangeDetails struct {
+	RiskLevel    RiskLevel  `json:"risk_level,omitempty"`    // リスクレベル
+	RollbackPlan string     `json:"rollback_plan,omitempty"` // 切り戻し手順
+	Approvers    []string   `json:"approvers,omitempty"`     // 承認が必要な承認者
+	Approvals    []Approval `json:"approvals,omitempty"`     // 承認・却下の記録
+}
+
+// RecordApproval は承認者の判断を記録する。同じ承認者の過去の判断は置き換える。
+func (d *ChangeDetails) RecordApproval(a Approval) {
+	for i, existing := range d.Approvals {
+		if existing.Approver == a.Approver {
+			d.Approvals[i] = a
+			return
+		}
+	}
+	d.Approvals = append(d.Approvals, a)
+}
+
+// ApprovalStatus は記録された判断から承認状況を返す。
+// 承認者が指定されていれば、その承認者の判断のみを数え、全員の承認で approved となる。
+// 指定されていなければ1名以上の承認で approved となる。数えた判断に1件でも却下があれば rejected。
+func (d *ChangeDetails) ApprovalStatus() ApprovalStatus {
+	required := make(map[string]bool, len(d.Approvers))
+	for _, approver := range d.Approvers {
+		required[approver] = true
+	}
+	approved := make(map[string]bool)
+	for _, a := range d.Approvals {
+		if len(required) > 0 && !required[a.Approver] {
+			continue
+		}
+		if a.Decision == DecisionReject {
+			return ApprovalRejected
+		}
+		approved[a.Approver] = true
+	}
+
+	if len(d.Approvers) == 0 {
+		if len(approved) > 0 {
+			return ApprovalApproved
+		}
+		return ApprovalPending
+	}
+	for _, approver := range d.Approvers {
+		if !approved[approver] {
+			return ApprovalPending
+		}
+	}
+	return ApprovalApproved
+}
+
+// PendingApprovers はまだ承認していない承認者を返す。
+func (d *ChangeDetails) PendingApprovers() []string {
+	approved := make(map[string]bool)
+	for _, a := range d.Approvals {
+		if a.Decision == DecisionApprove {
+			approved[a.Approver] = true
+		}
+	}
+	var pending []string
+	for _, approver := range d.Approvers {
+		if !approved[approver] {
+			pending = append(pending, approver)
+		}
+	}
+	return pending
+}
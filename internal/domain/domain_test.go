@@ -95,10 +95,39 @@ func TestStateArchive(t *testing.T) {
 	}
 }
 
+func TestChangeApprovalStatus(t *testing.T) {
+	d := &ChangeDetails{Approvers: []string{"alice", "bob"}}
+	d.RecordApproval(Approval{Approver: "alice", Decision: DecisionApprove})
+	d.RecordApproval(Approval{Approver: "mallory", Decision: DecisionReject})
+	if got := d.ApprovalStatus(); got != ApprovalPending {
+		t.Fatalf("expected decisions of non-approvers to be ignored, got %s", got)
+	}
+	d.RecordApproval(Approval{Approver: "mallory", Decision: DecisionApprove})
+	if got := d.ApprovalStatus(); got != ApprovalPending {
+		t.Fatalf("expected non-approver's approval not to count, got %s", got)
+	}
+	d.RecordApproval(Approval{Approver: "bob", Decision: DecisionReject})
+	if got := d.ApprovalStatus(); got != ApprovalRejected {
+		t.Fatalf("expected rejected, got %s", got)
+	}
+	d.RecordApproval(Approval{Approver: "bob", Decision: DecisionApprove})
+	if got := d.ApprovalStatus(); got != ApprovalApproved {
+		t.Fatalf("expected approved, got %s", got)
+	}
+
+	// 承認者の指定がなければ、誰の判断でも数える
+	open := &ChangeDetails{}
+	open.RecordApproval(Approval{Approver: "carol", Decision: DecisionApprove})
+	open.RecordApproval(Approval{Approver: "dave", Decision: DecisionReject})
+	if got := open.ApprovalStatus(); got != ApprovalRejected {
+		t.Fatalf("expected rejected without approvers, got %s", got)
+	}
+}
+
 func TestValidStockCategories(t *testing.T) {
 	categories := ValidStockCategories()
-	if len(categories) != 6 {
-		t.Errorf("expected 6 categories, got %d", len(categories))
+	if len(categories) != 7 {
+		t.Errorf("expected 7 categories, got %d", len(categories))
 	}
 
 	expected := map[StockCategory]bool{
@@ -108,6 +137,7 @@ func TestValidStockCategories(t *testing.T) {
 		CategoryArchitecture: true,
 		CategoryRequirement:  true,
 		CategoryTest:         true,
+		CategoryPostmortem:   true,
 	}
 
 	for _, c := range categories {
@@ -116,3 +146,132 @@ func TestValidStockCategories(t *testing.T) {
 		}
 	}
 }
+
+func TestSLAPoliciesMatchPrefersMostSpecific(t *testing.T) {
+	p0 := PriorityP0
+	policies := SLAPolicies{
+		{ResolveWithin: 72 * time.Hour},
+		{Type: StateTypeIncident, ResolveWithin: 24 * time.Hour},
+		{Type: StateTypeIncident, Priority: &p0, ResolveWithin: 4 * time.Hour},
+	}
+
+	incident := &State{Type: StateTypeIncident, Priority: PriorityP0}
+	got, ok := policies.Match(incident)
+	if !ok || got.ResolveWithin != 4*time.Hour {
+		t.Fatalf("expected 4h policy for P0 incident, got %v (ok=%v)", got.ResolveWithin, ok)
+	}
+
+	task := &State{Type: StateTypeTask, Priority: PriorityP0}
+	got, ok = policies.Match(task)
+	if !ok || got.ResolveWithin != 72*time.Hour {
+		t.Fatalf("expected fallback 72h policy for task, got %v (ok=%v)", got.ResolveWithin, ok)
+	}
+
+	if _, ok := (SLAPolicies{}).Match(task); ok {
+		t.Fatal("expected no match for empty policies")
+	}
+}
+
+func TestSLAPolicyEvaluate(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := SLAPolicy{Type: StateTypeIncident, ResolveWithin: 4 * time.Hour}
+
+	open := &State{Type: StateTypeIncident, Status: StatusOpen, CreatedAt: created, UpdatedAt: created}
+	status := policy.Evaluate(open, created.Add(5*time.Hour))
+	if !status.Breached {
+		t.Fatal("expected open incident past deadline to be breached")
+	}
+	if !status.Deadline.Equal(created.Add(4 * time.Hour)) {
+		t.Fatalf("unexpected deadline: %v", status.Deadline)
+	}
+
+	resolved := &State{Type: StateTypeIncident, Status: StatusResolved, CreatedAt: created, UpdatedAt: created.Add(3 * time.Hour)}
+	if policy.Evaluate(resolved, created.Add(10*time.Hour)).Breached {
+		t.Fatal("expected incident resolved within deadline not to be breached")
+	}
+}
+
+func TestStateIsOverdue(t *testing.T) {
+	now := time.Now()
+	due := now.Add(-time.Hour)
+	state := &State{Status: StatusInProgress, DueAt: &due}
+	if !state.IsOverdue(now) {
+		t.Fatal("expected in-progress state past due to be overdue")
+	}
+	state.Status = StatusResolved
+	if state.IsOverdue(now) {
+		t.Fatal("expected resolved state not to be overdue")
+	}
+}
+
+func TestStalePolicies(t *testing.T) {
+	p1 := PriorityP1
+	policies := StalePolicies{
+		{After: 30 * 24 * time.Hour},
+		{Priority: &p1, After: 7 * 24 * time.Hour},
+		{Type: StateTypeIncident, After: 24 * time.Hour},
+	}
+
+	now := time.Now()
+	task := &State{Type: StateTypeTask, Status: StatusOpen, Priority: PriorityP1, UpdatedAt: now.Add(-8 * 24 * time.Hour)}
+	policy, ok := policies.Match(task)
+	if !ok || policy.After != 7*24*time.Hour {
+		t.Fatalf("expected P1 policy, got %+v (ok=%v)", policy, ok)
+	}
+	if !policy.IsStale(task, now) {
+		t.Fatal("expected task idle for 8 days to be stale")
+	}
+
+	incident := &State{Type: StateTypeIncident, Status: StatusOpen, Priority: PriorityP1, UpdatedAt: now.Add(-2 * time.Hour)}
+	if policy, _ := policies.Match(incident); policy.After != 24*time.Hour || policy.IsStale(incident, now) {
+		t.Fatalf("expected incident policy without staleness, got %+v", policy)
+	}
+
+	task.Status = StatusResolved
+	if policy.IsStale(task, now) {
+		t.Fatal("expected resolved state not to be stale")
+	}
+}
+
+func TestPriorityRaise(t *testing.T) {
+	if PriorityP3.Raise() != PriorityP2 || PriorityP1.Raise() != PriorityP0 || PriorityP0.Raise() != PriorityP0 {
+		t.Fatal("unexpected priority raise result")
+	}
+}
+
+func TestTagHelpers(t *testing.T) {
+	if got := NormalizeTags([]string{" api ", "", "api", "db"}); len(got) != 2 || got[0] != "api" || got[1] != "db" {
+		t.Fatalf("unexpected normalized tags: %v", got)
+	}
+
+	tags := []string{"api", "auth"}
+	if !MatchTags(tags, []string{"db", "auth"}, TagMatchAny) || MatchTags(tags, []string{"db"}, TagMatchAny) {
+		t.Fatal("unexpected any match result")
+	}
+	if !MatchTags(tags, []string{"api", "auth"}, TagMatchAll) || MatchTags(tags, []string{"api", "db"}, TagMatchAll) {
+		t.Fatal("unexpected all match result")
+	}
+	if !MatchTags(nil, nil, TagMatchAll) {
+		t.Fatal("expected empty filter to match")
+	}
+
+	if m, err := ParseTagMatch(""); err != nil || m != TagMatchAny {
+		t.Fatalf("expected default any, got %q (%v)", m, err)
+	}
+	if _, err := ParseTagMatch("some"); err != ErrInvalidTagMatch {
+		t.Fatalf("expected ErrInvalidTagMatch, got %v", err)
+	}
+
+	renamed, changed := RenameTags([]string{"be", "api", "backend"}, []string{"be", "backend"}, "server")
+	if !changed || len(renamed) != 2 || renamed[0] != "server" || renamed[1] != "api" {
+		t.Fatalf("unexpected renamed tags: %v (%t)", renamed, changed)
+	}
+	if _, changed := RenameTags([]string{"api"}, []string{"db"}, "server"); changed {
+		t.Fatal("expected no change without matching tags")
+	}
+
+	counts := CountTags([][]string{{"api", "db"}, {"api"}, {"auth"}})
+	if len(counts) != 3 || counts[0] != (TagCount{Tag: "api", Count: 2}) || counts[1].Tag != "auth" {
+		t.Fatalf("unexpected tag counts: %+v", counts)
+	}
+}
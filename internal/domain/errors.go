@@ -4,11 +4,30 @@ import "errors"
 
 // ドメインエラー定義
 var (
-	ErrNotFound        = errors.New("not found")
-	ErrAlreadyExists   = errors.New("already exists")
-	ErrInvalidPriority = errors.New("invalid priority: must be P0, P1, P2, or P3")
-	ErrInvalidCategory = errors.New("invalid stock category")
-	ErrInvalidStatus   = errors.New("invalid state status")
-	ErrInvalidType     = errors.New("invalid state type")
-	ErrArchived        = errors.New("state is already archived")
+	ErrNotFound               = errors.New("not found")
+	ErrAlreadyExists          = errors.New("already exists")
+	ErrInvalidPriority        = errors.New("invalid priority: must be P0, P1, P2, or P3")
+	ErrInvalidCategory        = errors.New("invalid stock category")
+	ErrInvalidStatus          = errors.New("invalid state status")
+	ErrInvalidType            = errors.New("invalid state type")
+	ErrArchived               = errors.New("state is already archived")
+	ErrInvalidSeverity        = errors.New("invalid incident severity: must be SEV1, SEV2, SEV3, or SEV4")
+	ErrNotIncident            = errors.New("state is not an incident")
+	ErrNotProblem             = errors.New("state is not a problem")
+	ErrNotChange              = errors.New("state is not a change")
+	ErrInvalidRiskLevel       = errors.New("invalid risk level: must be low, medium, or high")
+	ErrInvalidDecision        = errors.New("invalid approval decision: must be approve or reject")
+	ErrApprovalRequired       = errors.New("change requires approval before it can leave open")
+	ErrDuplicate              = errors.New("possible duplicate exists")
+	ErrInvalidDuplicatePolicy = errors.New("invalid on_duplicate: must be reject, merge, or allow")
+	ErrDeleted                = errors.New("stock is deleted")
+	ErrNotDeleted             = errors.New("stock is not deleted")
+	ErrRedirectLoop           = errors.New("stock redirect chain is too long")
+	ErrInvalidCursor          = errors.New("invalid cursor: it does not match this list or search")
+	ErrInvalidTagMatch        = errors.New("invalid tag match: must be any or all")
+	ErrAmbiguousID            = errors.New("id exists in multiple projects: specify the project id")
+	ErrInvalidProjectID       = errors.New("invalid project id: must not be empty or contain path separators")
+	ErrConflict               = errors.New("stock was modified by another update: read it again and retry")
+	ErrHistoryDisabled        = errors.New("stock history is not enabled: set stock.git to true (store must be file or mirror)")
+	ErrBackupDisabled         = errors.New("backup is not available: the data directory is not configured")
 )
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// IncidentSeverity はインシデントの深刻度を表す。
+type IncidentSeverity string
+
+const (
+	SeveritySEV1 IncidentSeverity = "SEV1" // 全面停止・データ損失
+	SeveritySEV2 IncidentSeverity = "SEV2" // 主要機能の停止
+	SeveritySEV3 IncidentSeverity = "SEV3" // 一部機能の劣化
+	SeveritySEV4 IncidentSeverity = "SEV4" // 軽微な影響
+)
+
+// ParseSeverity は文字列からIncidentSeverityを解析する。
+func ParseSeverity(s string) (IncidentSeverity, error) {
+	switch sev := IncidentSeverity(s); sev {
+	case SeveritySEV1, SeveritySEV2, SeveritySEV3, SeveritySEV4:
+		return sev, nil
+	default:
+		return "", ErrInvalidSeverity
+	}
+}
+
+// TimelineEntry はインシデントタイムラインの1エントリ。
+type TimelineEntry struct {
+	At   time.Time `json:"at"`   // 発生日時
+	Note string    `json:"note"` // 出来事・対応内容
+}
+
+// IncidentDetails はインシデント（StateTypeIncident）固有の構造化情報。
+type IncidentDetails struct {
+	Severity    IncidentSeverity `json:"severity,omitempty"`     // 深刻度
+	Impact      string           `json:"impact,omitempty"`       // 影響範囲
+	DetectedAt  *time.Time       `json:"detected_at,omitempty"`  // 検知日時
+	MitigatedAt *time.Time       `json:"mitigated_at,omitempty"` // 暫定対処日時
+	ResolvedAt  *time.Time       `json:"resolved_at,omitempty"`  // 解決日時
+	Timeline    []TimelineEntry  `json:"timeline,omitempty"`     // 時系列の対応記録
+}
+
+// AddTimelineEntry はタイムラインにエントリを追加し、時系列順に並べ替える。
+func (d *IncidentDetails) AddTimelineEntry(entry TimelineEntry) {
+	d.Timeline = append(d.Timeline, entry)
+	sort.SliceStable(d.Timeline, func(i, j int) bool {
+		return d.Timeline[i].At.Before(d.Timeline[j].At)
+	})
+}
+
+// TimeToMitigate は検知から暫定対処までの所要時間を返す。
+func (d *IncidentDetails) TimeToMitigate() (time.Duration, bool) {
+	if d.DetectedAt == nil || d.MitigatedAt == nil {
+		return 0, false
+	}
+	return d.MitigatedAt.Sub(*d.DetectedAt), true
+}
+
+// TimeToResolve は検知から解決までの所要時間を返す。
+func (d *IncidentDetails) TimeToResolve() (time.Duration, bool) {
+	if d.DetectedAt == nil || d.ResolvedAt == nil {
+		return 0, false
+	}
+	return d.ResolvedAt.Sub(*d.DetectedAt), true
+}
@@ -0,0 +1,40 @@
+package domain
+
+// ProblemDetails は問題（StateTypeProblem）固有の構造化情報。
+// ITILの問題管理に従い、再発するインシデントを根本原因単位で束ねる。
+type ProblemDetails struct {
+	RootCause   string   `json:"root_cause,omitempty"`   // 根本原因
+	Workaround  string   `json:"workaround,omitempty"`   // 既知のエラーに対する回避策
+	KnownError  bool     `json:"known_error,omitempty"`  // 既知のエラーとして登録済みか
+	IncidentIDs []string `json:"incident_ids,omitempty"` // 紐づくインシデントのID
+}
+
+// HasIncident はインシデントが紐づいているかを返す。
+func (d *ProblemDetails) HasIncident(id string) bool {
+	for _, linked := range d.IncidentIDs {
+		if linked == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkIncident はインシデントを紐づける。既に紐づいている場合は false を返す。
+func (d *ProblemDetails) LinkIncident(id string) bool {
+	if d.HasIncident(id) {
+		return false
+	}
+	d.IncidentIDs = append(d.IncidentIDs, id)
+	return true
+}
+
+// UnlinkIncident はインシデントの紐づけを解除する。紐づいていない場合は false を返す。
+func (d *ProblemDetails) UnlinkIncident(id string) bool {
+	for i, linked := range d.IncidentIDs {
+		if linked == id {
+			d.IncidentIDs = append(d.IncidentIDs[:i], d.IncidentIDs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Release は解決済みの変更・タスクを束ねたバージョン付きリリースを表す。
+// プロジェクトIDとバージョンの組で一意に識別する。
+type Release struct {
+	ProjectID string    `json:"project_id"` // 所属プロジェクトID
+	Version   string    `json:"version"`    // バージョン (例: "v1.2.0")
+	Title     string    `json:"title"`      // リリース名
+	ItemIDs   []string  `json:"item_ids"`   // 含まれる変更・タスクのState ID
+	Notes     string    `json:"notes"`      // Markdown形式のリリースノート
+	CreatedAt time.Time `json:"created_at"` // 作成日時
+}
+
+// ReleaseSummary はReleaseのサマリビュー。Notes を含まない。
+type ReleaseSummary struct {
+	ProjectID string    `json:"project_id"`
+	Version   string    `json:"version"`
+	Title     string    `json:"title"`
+	ItemCount int       `json:"item_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToSummary はReleaseからReleaseSummaryを生成する。
+func (r *Release) ToSummary() ReleaseSummary {
+	return ReleaseSummary{
+		ProjectID: r.ProjectID,
+		Version:   r.Version,
+		Title:     r.Title,
+		ItemCount: len(r.ItemIDs),
+		CreatedAt: r.CreatedAt,
+	}
+}
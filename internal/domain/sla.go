@@ -0,0 +1,88 @@
+package domain
+
+import "time"
+
+// SLAPolicy はStateの種別・優先度ごとの解決期限ポリシーを表す。
+// Type が空の場合は全種別、Priority が nil の場合は全優先度に適用される。
+type SLAPolicy struct {
+	Type          StateType     `json:"type,omitempty"`
+	Priority      *Priority     `json:"priority,omitempty"`
+	ResolveWithin time.Duration `json:"resolve_within"`
+}
+
+// Matches はポリシーがStateに適用可能かを返す。
+func (p SLAPolicy) Matches(s *State) bool {
+	return scopeMatches(p.Type, p.Priority, s)
+}
+
+// specificity はポリシーの限定度を返す。
+func (p SLAPolicy) specificity() int {
+	return scopeSpecificity(p.Type, p.Priority)
+}
+
+// scopeMatches は種別・優先度で限定されたポリシーの適用範囲にStateが含まれるかを返す。
+func scopeMatches(t StateType, p *Priority, s *State) bool {
+	if t != "" && t != s.Type {
+		return false
+	}
+	if p != nil && *p != s.Priority {
+		return false
+	}
+	return true
+}
+
+// scopeSpecificity はポリシーの限定度を返す。種別・優先度の両方を指定したものが最も強い。
+func scopeSpecificity(t StateType, p *Priority) int {
+	score := 0
+	if t != "" {
+		score += 2
+	}
+	if p != nil {
+		score++
+	}
+	return score
+}
+
+// SLAPolicies はSLAポリシーの集合。
+type SLAPolicies []SLAPolicy
+
+// Match はStateに適用されるポリシーのうち最も限定度の高いものを返す。
+// 同じ限定度のものが複数ある場合は先に定義されたものを優先する。
+func (ps SLAPolicies) Match(s *State) (SLAPolicy, bool) {
+	var (
+		best  SLAPolicy
+		found bool
+	)
+	for _, p := range ps {
+		if p.ResolveWithin <= 0 || !p.Matches(s) {
+			continue
+		}
+		if !found || p.specificity() > best.specificity() {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// SLAStatus はStateのSLA達成状況を表す。
+// 経過時間は CreatedAt を起点に、未解決なら現在時刻、解決済みなら UpdatedAt までで計算する。
+type SLAStatus struct {
+	ResolveWithin string    `json:"resolve_within"` // 解決期限（例: "4h0m0s"）
+	Deadline      time.Time `json:"deadline"`       // 解決期限日時
+	Breached      bool      `json:"breached"`       // 期限超過しているか
+}
+
+// Evaluate はポリシーに基づいてStateのSLA達成状況を計算する。
+func (p SLAPolicy) Evaluate(s *State, now time.Time) SLAStatus {
+	deadline := s.CreatedAt.Add(p.ResolveWithin)
+	end := now
+	if !s.IsOpen() {
+		end = s.UpdatedAt
+	}
+	return SLAStatus{
+		ResolveWithin: p.ResolveWithin.String(),
+		Deadline:      deadline,
+		Breached:      end.After(deadline),
+	}
+}
@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// StaleTag は一定期間更新のないStateに付与されるタグ。
+const StaleTag = "stale"
+
+// StalePolicy はStateの種別・優先度ごとの放置判定しきい値を表す。
+// Type が空の場合は全種別、Priority が nil の場合は全優先度に適用される。
+type StalePolicy struct {
+	Type     StateType     `json:"type,omitempty"`
+	Priority *Priority     `json:"priority,omitempty"`
+	After    time.Duration `json:"after"` // UpdatedAt からこの期間を超えて更新がなければ放置とみなす
+}
+
+// Matches はポリシーがStateに適用可能かを返す。
+func (p StalePolicy) Matches(s *State) bool {
+	return scopeMatches(p.Type, p.Priority, s)
+}
+
+func (p StalePolicy) specificity() int {
+	return scopeSpecificity(p.Type, p.Priority)
+}
+
+// StalePolicies は放置判定ポリシーの集合。
+type StalePolicies []StalePolicy
+
+// Match はStateに適用されるポリシーのうち最も限定度の高いものを返す。
+// 同じ限定度のものが複数ある場合は先に定義されたものを優先する。
+func (ps StalePolicies) Match(s *State) (StalePolicy, bool) {
+	var (
+		best  StalePolicy
+		found bool
+	)
+	for _, p := range ps {
+		if p.After <= 0 || !p.Matches(s) {
+			continue
+		}
+		if !found || p.specificity() > best.specificity() {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// IsStale は未解決のStateがしきい値を超えて更新されていないかを返す。
+func (p StalePolicy) IsStale(s *State, now time.Time) bool {
+	return s.IsOpen() && now.Sub(s.UpdatedAt) > p.After
+}
+
+// HasTag はStateに指定のタグが付与されているかを返す。
+func (s *State) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Touch はStateが更新されたことを記録する。更新日時を now にし、更新されたStateは放置状態ではなくなるため StaleTag を外す。
+func (s *State) Touch(now time.Time) {
+	s.UpdatedAt = now
+	tags := s.Tags[:0:0]
+	for _, t := range s.Tags {
+		if t != StaleTag {
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) != len(s.Tags) {
+		s.Tags = tags
+	}
+}
+
+// Raise は優先度を1段階引き上げた値を返す。P0 はそのまま。
+func (p Priority) Raise() Priority {
+	if p <= PriorityP0 {
+		return PriorityP0
+	}
+	return p - 1
+}
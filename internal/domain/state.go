@@ -10,6 +10,7 @@ const (
 	StateTypeIssue    StateType = "issue"
 	StateTypeIncident StateType = "incident"
 	StateTypeChange   StateType = "change"
+	StateTypeProblem  StateType = "problem"
 )
 
 // StateStatus はStateのライフサイクル上の状態を表す。
@@ -36,6 +37,11 @@ type State struct {
 	Resolution  string      `json:"resolution"`   // 解決内容（resolved/archived時）
 	Tags        []string    `json:"tags"`         // 検索用タグ
 	References  []string    `json:"references"`   // 関連Stock/StateのID
+	Assignee    string      `json:"assignee"`     // 担当者
+	DueAt       *time.Time  `json:"due_at"`       // 期日
+	Incident    *IncidentDetails `json:"incident,omitempty"` // インシデント固有情報（type=incidentのみ）
+	Problem     *ProblemDetails  `json:"problem,omitempty"`  // 問題固有情報（type=problemのみ）
+	Change      *ChangeDetails   `json:"change,omitempty"`   // 変更固有情報（type=changeのみ）
 	CreatedAt   time.Time   `json:"created_at"`   // 作成日時
 	UpdatedAt   time.Time   `json:"updated_at"`   // 更新日時
 	ArchivedAt  *time.Time  `json:"archived_at"`  // アーカイブ日時
@@ -46,6 +52,16 @@ func (s *State) IsActive() bool {
 	return s.Status != StatusArchived
 }
 
+// IsOpen はStateが未解決（open または in_progress）かを返す。
+func (s *State) IsOpen() bool {
+	return s.Status == StatusOpen || s.Status == StatusInProgress
+}
+
+// IsOverdue は未解決のStateが期日を過ぎているかを返す。
+func (s *State) IsOverdue(now time.Time) bool {
+	return s.IsOpen() && s.DueAt != nil && now.After(*s.DueAt)
+}
+
 // Archive はStateをアーカイブ状態にする。
 func (s *State) Archive(resolution string, now time.Time) {
 	s.Status = StatusArchived
@@ -64,6 +80,9 @@ type StateSummary struct {
 	Priority  Priority    `json:"priority"`
 	Title     string      `json:"title"`
 	Tags      []string    `json:"tags"`
+	Assignee  string      `json:"assignee,omitempty"`
+	DueAt     *time.Time  `json:"due_at,omitempty"`
+	SLA       *SLAStatus  `json:"sla,omitempty"` // SLAポリシー適用時のみ
 	UpdatedAt time.Time   `json:"updated_at"`
 }
 
@@ -77,6 +96,8 @@ func (s *State) ToSummary() StateSummary {
 		Priority:  s.Priority,
 		Title:     s.Title,
 		Tags:      s.Tags,
+		Assignee:  s.Assignee,
+		DueAt:     s.DueAt,
 		UpdatedAt: s.UpdatedAt,
 	}
 }
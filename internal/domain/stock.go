@@ -54,6 +54,7 @@ const (
 	CategoryArchitecture StockCategory = "architecture"
 	CategoryRequirement  StockCategory = "requirement"
 	CategoryTest         StockCategory = "test"
+	CategoryPostmortem   StockCategory = "postmortem"
 )
 
 // ValidStockCategories は有効なStockCategoryの一覧を返す。
@@ -65,6 +66,7 @@ func ValidStockCategories() []StockCategory {
 		CategoryArchitecture,
 		CategoryRequirement,
 		CategoryTest,
+		CategoryPostmortem,
 	}
 }
 
@@ -78,21 +80,37 @@ type Stock struct {
 	Title      string        `json:"title"`       // タイトル
 	Content    string        `json:"content"`     // Markdown形式の本文
 	Tags       []string      `json:"tags"`        // 検索用タグ
+	Summary    string        `json:"summary,omitempty"`        // 1行サマリ（作成・更新時に自動生成）
+	SuggestedTags []string   `json:"suggested_tags,omitempty"` // 本文から抽出したタグ候補（Tags に含まれないもの）
 	References []string      `json:"references"`  // 関連Stock/StateのID
 	CreatedAt  time.Time     `json:"created_at"`  // 作成日時
 	UpdatedAt  time.Time     `json:"updated_at"`  // 更新日時
+	DeletedAt  *time.Time    `json:"deleted_at,omitempty"`  // 削除日時（ソフトデリート。保持期間の経過後に完全削除）
+	RedirectTo string        `json:"redirect_to,omitempty"` // 再分類後の管理番号（旧管理番号を転送用のエイリアスとして残す）
+}
+
+// IsDeleted はStockが削除済み（復元可能）かどうかを返す。
+func (s *Stock) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
+
+// IsAlias はStockが再分類後の管理番号へ転送するエイリアスかどうかを返す。
+func (s *Stock) IsAlias() bool {
+	return s.RedirectTo != ""
 }
 
 // StockSummary はStockのサマリビュー。list/search時に使用し、
 // Content を含まないことでレスポンスのトークン消費を抑制する。
 type StockSummary struct {
-	ID        string        `json:"id"`
-	ProjectID string        `json:"project_id"`
-	Category  StockCategory `json:"category"`
-	Priority  Priority      `json:"priority"`
-	Title     string        `json:"title"`
-	Tags      []string      `json:"tags"`
-	UpdatedAt time.Time     `json:"updated_at"`
+	ID            string        `json:"id"`
+	ProjectID     string        `json:"project_id"`
+	Category      StockCategory `json:"category"`
+	Priority      Priority      `json:"priority"`
+	Title         string        `json:"title"`
+	Summary       string        `json:"summary,omitempty"`
+	Tags          []string      `json:"tags"`
+	SuggestedTags []string      `json:"suggested_tags,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 // ToSummary はStockからStockSummaryを生成する。
@@ -103,7 +121,9 @@ func (s *Stock) ToSummary() StockSummary {
 		Category:  s.Category,
 		Priority:  s.Priority,
 		Title:     s.Title,
+		Summary:   s.Summary,
 		Tags:      s.Tags,
+		SuggestedTags: s.SuggestedTags,
 		UpdatedAt: s.UpdatedAt,
 	}
 }
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TagMatch はタグによる絞り込みの一致条件を表す。
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any" // 指定タグのいずれかを含む（デフォルト）
+	TagMatchAll TagMatch = "all" // 指定タグをすべて含む
+)
+
+// ParseTagMatch は文字列からTagMatchを解析する。空文字は TagMatchAny とみなす。
+func ParseTagMatch(s string) (TagMatch, error) {
+	switch TagMatch(strings.ToLower(strings.TrimSpace(s))) {
+	case "", TagMatchAny:
+		return TagMatchAny, nil
+	case TagMatchAll:
+		return TagMatchAll, nil
+	default:
+		return TagMatchAny, ErrInvalidTagMatch
+	}
+}
+
+// MatchTags はタグ一覧が絞り込み条件に一致するかを返す。filter が空の場合は常に一致する。
+func MatchTags(tags []string, filter []string, match TagMatch) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		found := slices.Contains(tags, f)
+		if found && match != TagMatchAll {
+			return true
+		}
+		if !found && match == TagMatchAll {
+			return false
+		}
+	}
+	return match == TagMatchAll
+}
+
+// NormalizeTags は前後の空白を除去し、空のタグと重複を取り除いたタグ一覧を返す。順序は維持する。
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// TagCount はタグの使用件数を表す。
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// CountTags はタグ一覧の集合からタグごとの使用件数を件数の降順・タグ名の昇順で返す。
+func CountTags(tagLists [][]string) []TagCount {
+	counts := make(map[string]int)
+	for _, tags := range tagLists {
+		for _, tag := range NormalizeTags(tags) {
+			counts[tag]++
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
+
+// RenameTags はタグ一覧のうち from に含まれるタグを to に置き換える。
+// 置き換えの結果重複したタグは1つにまとめる。置き換えたタグがあった場合のみ true を返す。
+func RenameTags(tags []string, from []string, to string) ([]string, bool) {
+	changed := false
+	renamed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if slices.Contains(from, tag) && tag != to {
+			tag = to
+			changed = true
+		}
+		if slices.Contains(renamed, tag) {
+			continue
+		}
+		renamed = append(renamed, tag)
+	}
+	return renamed, changed
+}
@@ -0,0 +1,311 @@
+// Package llm はLLMプロバイダーへのゲートウェイを提供する。
+// サービス層は要約・分類のみに利用し、プロバイダーが未設定の場合は呼び出さない。
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+)
+
+// ErrNotConfigured はLLMプロバイダーが設定されていないことを示す。
+var ErrNotConfigured = errors.New("llm provider is not configured")
+
+// ErrUnavailable はプロバイダーがこのリクエストに応答できないことを示す（例: クライアントがサンプリング非対応）。
+// 呼び出し側はエラーとして扱わず、LLMを使わない処理で代替する。
+var ErrUnavailable = errors.New("llm provider is unavailable for this request")
+
+// Message は会話の1メッセージ。
+type Message struct {
+	Role    string `json:"role"` // "user" | "assistant"
+	Content string `json:"content"`
+}
+
+// Request はLLMへの補完リクエスト。
+type Request struct {
+	Purpose   string    // 利用目的（使用量の集計用。例: "summarize", "classify"）
+	System    string    // システムプロンプト
+	Messages  []Message // 会話
+	MaxTokens int       // 出力トークン数の上限
+	Options   []string  // 分類時の選択肢（スタブプロバイダーが選択に用いる）
+}
+
+// Usage はトークン使用量。
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Response はLLMからの応答。
+type Response struct {
+	Text  string
+	Model string
+	Usage Usage
+}
+
+// Provider はLLMプロバイダーのクライアントが実装するインターフェース。
+type Provider interface {
+	// Name はプロバイダー名を返す。
+	Name() string
+	// Complete はリクエストに対する応答を生成する。
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// CallRecord は1回の呼び出しの使用量記録。
+type CallRecord struct {
+	At       time.Time     `json:"at"`
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Purpose  string        `json:"purpose"`
+	Usage    Usage         `json:"usage"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// UsageSummary は累計の使用量。
+type UsageSummary struct {
+	Calls        int `json:"calls"`
+	Errors       int `json:"errors"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// maxCallRecords は保持する呼び出し記録の上限。古いものから破棄する。
+const maxCallRecords = 200
+
+// Client はプロバイダーをラップし、呼び出しごとのトークン使用量を記録する。
+type Client struct {
+	provider Provider
+
+	mu      sync.Mutex
+	calls   []CallRecord
+	summary UsageSummary
+}
+
+// NewClient はプロバイダーからClientを生成する。
+func NewClient(provider Provider) *Client {
+	return &Client{provider: provider}
+}
+
+// New は設定に基づいてClientを生成する。
+// プロバイダーが空、またはリモートプロバイダーでAPIキーが未設定の場合は ErrNotConfigured を返す。
+func New(cfg config.LLMConfig) (*Client, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	switch provider {
+	case "":
+		return nil, ErrNotConfigured
+	case "stub":
+		return NewClient(NewStubProvider()), nil
+	case "anthropic":
+		if strings.TrimSpace(cfg.APIKey) == "" {
+			return nil, ErrNotConfigured
+		}
+		return NewClient(NewAnthropicProvider(cfg.APIKey, cfg.Model, cfg.BaseURL)), nil
+	case "openai":
+		// ローカルのOpenAI互換サーバーはAPIキー不要のため、base_url 指定時はキーなしを許容する
+		if strings.TrimSpace(cfg.APIKey) == "" && strings.TrimSpace(cfg.BaseURL) == "" {
+			return nil, ErrNotConfigured
+		}
+		return NewClient(NewOpenAIProvider(cfg.APIKey, cfg.Model, cfg.BaseURL)), nil
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %s", cfg.Provider)
+	}
+}
+
+// Provider はラップしているプロバイダーを返す。
+func (c *Client) Provider() Provider {
+	return c.provider
+}
+
+// Complete はプロバイダーを呼び出し、使用量を記録する。
+func (c *Client) Complete(ctx context.Context, req Request) (*Response, error) {
+	start := time.Now()
+	resp, err := c.provider.Complete(ctx, req)
+	if errors.Is(err, ErrUnavailable) {
+		// 呼び出しが行われていないため使用量には記録しない
+		return nil, err
+	}
+
+	record := CallRecord{
+		At:       start,
+		Provider: c.provider.Name(),
+		Purpose:  req.Purpose,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Model = resp.Model
+		record.Usage = resp.Usage
+	}
+	c.record(record)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s completion failed: %w", c.provider.Name(), err)
+	}
+	return resp, nil
+}
+
+func (c *Client) record(record CallRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary.Calls++
+	if record.Error != "" {
+		c.summary.Errors++
+	}
+	c.summary.InputTokens += record.Usage.InputTokens
+	c.summary.OutputTokens += record.Usage.OutputTokens
+
+	c.calls = append(c.calls, record)
+	if len(c.calls) > maxCallRecords {
+		c.calls = c.calls[len(c.calls)-maxCallRecords:]
+	}
+}
+
+// Calls は直近の呼び出し記録を古い順に返す。
+func (c *Client) Calls() []CallRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CallRecord(nil), c.calls...)
+}
+
+// Usage は累計の使用量を返す。
+func (c *Client) Usage() UsageSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summary
+}
+
+// Summarize はテキストを 原文と同じ言語で maxChars 文字以内に要約する。
+func (c *Client) Summarize(ctx context.Context, text string, maxChars int) (string, error) {
+	resp, err := c.Complete(ctx, Request{
+		Purpose: "summarize",
+		System: fmt.Sprintf("You summarize project documents. Reply with a single plain-text summary of at most %d characters, "+
+			"in the same language as the document. Do not add any preface.", maxChars),
+		Messages:  []Message{{Role: "user", Content: text}},
+		MaxTokens: 256,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Text)
+	if summary == "" {
+		return "", errors.New("empty summary")
+	}
+	return TruncateRunes(summary, maxChars), nil
+}
+
+// Classify はテキストを labels のいずれかに分類する。応答が選択肢に含まれない場合はエラーを返す。
+func (c *Client) Classify(ctx context.Context, text string, labels []string) (string, error) {
+	if len(labels) == 0 {
+		return "", errors.New("labels are required")
+	}
+	resp, err := c.Complete(ctx, Request{
+		Purpose: "classify",
+		System: "You classify project work items. Reply with exactly one label from this list and nothing else: " +
+			strings.Join(labels, ", "),
+		Messages:  []Message{{Role: "user", Content: text}},
+		MaxTokens: 16,
+		Options:   labels,
+	})
+	if err != nil {
+		return "", err
+	}
+	return matchLabel(resp.Text, labels)
+}
+
+// Draft は instruction に従ってテキストから maxChars 文字以内の文書を下書きする。
+func (c *Client) Draft(ctx context.Context, instruction string, text string, maxChars int) (string, error) {
+	resp, err := c.Complete(ctx, Request{
+		Purpose: "draft",
+		System: fmt.Sprintf("%s Reply with the document only, at most %d characters, "+
+			"in the same language as the source. Do not add any preface.", instruction, maxChars),
+		Messages:  []Message{{Role: "user", Content: text}},
+		MaxTokens: 1024,
+	})
+	if err != nil {
+		return "", err
+	}
+	draft := strings.TrimSpace(resp.Text)
+	if draft == "" {
+		return "", errors.New("empty draft")
+	}
+	return TruncateRunes(draft, maxChars), nil
+}
+
+// ExtractKeywords はテキストから検索用のキーワードを最大 max 件抽出する。
+func (c *Client) ExtractKeywords(ctx context.Context, text string, max int) ([]string, error) {
+	resp, err := c.Complete(ctx, Request{
+		Purpose: "keywords",
+		System: fmt.Sprintf("You tag project documents. Reply with at most %d short lowercase keywords for search, "+
+			"comma-separated, in the same language as the document. Do not add any preface.", max),
+		Messages:  []Message{{Role: "user", Content: text}},
+		MaxTokens: 64,
+	})
+	if err != nil {
+		return nil, err
+	}
+	keywords := parseKeywords(resp.Text, max)
+	if len(keywords) == 0 {
+		return nil, errors.New("no keywords")
+	}
+	return keywords, nil
+}
+
+// parseKeywords はカンマ・改行区切りの応答をキーワードの一覧に正規化する。
+func parseKeywords(text string, max int) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '、' || r == '\n'
+	})
+	seen := make(map[string]bool, len(fields))
+	keywords := make([]string, 0, len(fields))
+	for _, field := range fields {
+		keyword := strings.ToLower(strings.TrimSpace(strings.Trim(strings.TrimSpace(field), "-*#`\"'.")))
+		if keyword == "" || seen[keyword] || len([]rune(keyword)) > maxKeywordRunes {
+			continue
+		}
+		seen[keyword] = true
+		keywords = append(keywords, keyword)
+		if max > 0 && len(keywords) == max {
+			break
+		}
+	}
+	return keywords
+}
+
+// maxKeywordRunes はキーワードとして受け付ける最大文字数。これより長いものは文とみなして捨てる。
+const maxKeywordRunes = 32
+
+// matchLabel は応答テキストから選択肢に一致するラベルを取り出す。
+func matchLabel(text string, labels []string) (string, error) {
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(text), "`\"'."))
+	for _, label := range labels {
+		if answer == strings.ToLower(label) {
+			return label, nil
+		}
+	}
+	for _, label := range labels {
+		if strings.Contains(answer, strings.ToLower(label)) {
+			return label, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected classification: %q", text)
+}
+
+// TruncateRunes は s を n 文字（rune）以内に切り詰める。切り詰めた場合は末尾を「…」にする。n が0以下の場合はそのまま返す。
+func TruncateRunes(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
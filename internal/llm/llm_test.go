@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.LLMConfig
+		provider string
+		err      error
+	}{
+		{name: "empty", cfg: config.LLMConfig{}, err: ErrNotConfigured},
+		{name: "anthropic without key", cfg: config.LLMConfig{Provider: "anthropic"}, err: ErrNotConfigured},
+		{name: "anthropic", cfg: config.LLMConfig{Provider: "anthropic", APIKey: "k"}, provider: "anthropic"},
+		{name: "openai without key", cfg: config.LLMConfig{Provider: "openai"}, err: ErrNotConfigured},
+		{name: "openai local", cfg: config.LLMConfig{Provider: "openai", BaseURL: "http://localhost:11434/v1"}, provider: "openai"},
+		{name: "stub", cfg: config.LLMConfig{Provider: "Stub"}, provider: "stub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.cfg)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+			if client.Provider().Name() != tt.provider {
+				t.Fatalf("expected %s, got %s", tt.provider, client.Provider().Name())
+			}
+		})
+	}
+
+	if _, err := New(config.LLMConfig{Provider: "unknown", APIKey: "k"}); err == nil || errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected unsupported provider error, got %v", err)
+	}
+}
+
+func TestStubProviderIsDeterministic(t *testing.T) {
+	client := NewClient(NewStubProvider())
+	ctx := context.Background()
+
+	text := "# 認証基盤\n\nOAuth2によるシングルサインオンを提供する。リフレッシュトークンは30日で失効する。"
+	first, err := client.Summarize(ctx, text, 100)
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	second, _ := client.Summarize(ctx, text, 100)
+	if first != second || first != "認証基盤" {
+		t.Fatalf("expected deterministic heading summary, got %q / %q", first, second)
+	}
+
+	label, err := client.Classify(ctx, "Rollout change for the database schema", []string{"task", "incident", "change"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if label != "change" {
+		t.Fatalf("expected change, got %s", label)
+	}
+}
+
+func TestClientTracksUsage(t *testing.T) {
+	client := NewClient(NewStubProvider())
+	ctx := context.Background()
+	if _, err := client.Summarize(ctx, "The quick brown fox jumps over the lazy dog.", 50); err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if _, err := client.Classify(ctx, "bug in login", []string{"issue", "task"}); err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+
+	calls := client.Calls()
+	if len(calls) != 2 || calls[0].Purpose != "summarize" || calls[1].Purpose != "classify" {
+		t.Fatalf("unexpected call records: %+v", calls)
+	}
+	usage := client.Usage()
+	if usage.Calls != 2 || usage.Errors != 0 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if usage.InputTokens != calls[0].Usage.InputTokens+calls[1].Usage.InputTokens || usage.OutputTokens == 0 {
+		t.Fatalf("expected usage totals to match call records: %+v", usage)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.Summarize(cancelled, "text", 10); err == nil {
+		t.Fatal("expected error on cancelled context")
+	}
+	if usage := client.Usage(); usage.Calls != 3 || usage.Errors != 1 {
+		t.Fatalf("expected failed call to be recorded, got %+v", usage)
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "secret" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing auth headers: %v", r.Header)
+		}
+		var body anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if body.Model != "claude-test" || body.System == "" || len(body.Messages) != 1 {
+			t.Errorf("unexpected request: %+v", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"claude-test","content":[{"type":"text","text":"incident"}],"usage":{"input_tokens":12,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(NewAnthropicProvider("secret", "claude-test", server.URL))
+	label, err := client.Classify(context.Background(), "site is down", []string{"task", "incident"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if label != "incident" {
+		t.Fatalf("expected incident, got %s", label)
+	}
+	if usage := client.Usage(); usage.InputTokens != 12 || usage.OutputTokens != 1 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no auth header for keyless local server")
+		}
+		var body openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if len(body.Messages) != 2 || body.Messages[0].Role != "system" {
+			t.Errorf("expected system message first: %+v", body.Messages)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"local","choices":[{"message":{"content":"要約です。"}}],"usage":{"prompt_tokens":20,"completion_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(NewOpenAIProvider("", "local", server.URL+"/v1"))
+	summary, err := client.Summarize(context.Background(), "長い文書", 100)
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if summary != "要約です。" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	if calls := client.Calls(); len(calls) != 1 || calls[0].Model != "local" || calls[0].Usage.InputTokens != 20 {
+		t.Fatalf("unexpected call record: %+v", calls)
+	}
+}
+
+func TestProviderHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"rate limited"}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(NewAnthropicProvider("secret", "claude-test", server.URL))
+	if _, err := client.Summarize(context.Background(), "text", 10); err == nil {
+		t.Fatal("expected error on non-2xx status")
+	}
+	if usage := client.Usage(); usage.Errors != 1 {
+		t.Fatalf("expected error to be tracked, got %+v", usage)
+	}
+}
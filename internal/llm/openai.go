@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIProvider はOpenAI互換のChat Completions APIのクライアント。
+// base_url を指定することで、OpenAI互換のローカルサーバー（Ollama, vLLM等）にも接続できる。
+type OpenAIProvider struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenAIProvider は新しいOpenAIProviderを生成する。baseURL が空の場合は公式APIを使用する。
+func NewOpenAIProvider(apiKey string, model string, baseURL string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &OpenAIProvider{
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+// Name はプロバイダー名を返す。
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+type openAIRequest struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Complete はChat Completions APIを呼び出す。システムプロンプトは先頭の system メッセージとして送る。
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var resp openAIResponse
+	err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", headers, openAIRequest{
+		Model:     p.model,
+		Messages:  messages,
+		MaxTokens: req.MaxTokens,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("response has no choices")
+	}
+	return &Response{
+		Text:  resp.Choices[0].Message.Content,
+		Model: resp.Model,
+		Usage: Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens},
+	}, nil
+}
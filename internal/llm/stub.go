@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// StubProvider はネットワークを使わない決定的なプロバイダー。テストやオフライン環境で使用する。
+//   - 分類（Request.Options あり）: 入力と最も多くの文字bigramを共有する選択肢を返す
+//   - キーワード抽出（Purpose が "keywords"）: 出現頻度の高い英数字の語をカンマ区切りで返す
+//   - それ以外: 最後のメッセージの最初の文を返す
+//
+// トークン数は4文字を1トークンとして概算する。
+type StubProvider struct{}
+
+// NewStubProvider は新しいStubProviderを生成する。
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+// Name はプロバイダー名を返す。
+func (p *StubProvider) Name() string {
+	return "stub"
+}
+
+// Complete は入力から決定的に応答を生成する。
+func (p *StubProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var input string
+	if len(req.Messages) > 0 {
+		input = req.Messages[len(req.Messages)-1].Content
+	}
+
+	var text string
+	switch {
+	case len(req.Options) > 0:
+		text = closestOption(input, req.Options)
+	case req.Purpose == "keywords":
+		text = strings.Join(frequentWords(input, 5), ", ")
+	default:
+		text = firstSentence(input)
+	}
+
+	return &Response{
+		Text:  text,
+		Model: "stub",
+		Usage: Usage{
+			InputTokens:  estimateTokens(req.System) + estimateTokens(input),
+			OutputTokens: estimateTokens(text),
+		},
+	}, nil
+}
+
+func closestOption(input string, options []string) string {
+	lower := strings.ToLower(input)
+	best, bestScore := options[0], -1
+	for _, option := range options {
+		score := strings.Count(lower, strings.ToLower(option)) * 10
+		for _, bigram := range bigrams(strings.ToLower(option)) {
+			if strings.Contains(lower, bigram) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = option, score
+		}
+	}
+	return best
+}
+
+// frequentWords は4文字以上の英数字の語を出現頻度順（同数は初出順）に最大 n 件返す。
+func frequentWords(text string, n int) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	counts := make(map[string]int)
+	var order []string
+	for _, word := range words {
+		if len(word) < 4 {
+			continue
+		}
+		if counts[word] == 0 {
+			order = append(order, word)
+		}
+		counts[word]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+	return order
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return []string{s}
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
+
+// firstSentence は見出し記号を除いた最初の空でない行の、最初の文を返す。
+func firstSentence(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#>-* "))
+		if line == "" {
+			continue
+		}
+		for i, r := range line {
+			if r == '。' || r == '.' || r == '!' || r == '?' || r == '！' || r == '？' {
+				return line[:i+utf8.RuneLen(r)]
+			}
+		}
+		return line
+	}
+	return ""
+}
+
+func estimateTokens(s string) int {
+	n := utf8.RuneCountInString(s)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
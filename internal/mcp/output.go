@@ -0,0 +1,364 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+	"github.com/haconeco/project-information-manager/internal/service"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ツール結果のテキスト表現。structuredContent は形式によらず同じ内容を返す。
+const (
+	formatJSON     = "json"     // structuredContent と同じ内容のコンパクトなJSON（デフォルト）
+	formatMarkdown = "markdown" // 人が読むためのMarkdown
+)
+
+// withFormat はツールにテキスト表現の指定パラメータを追加する。
+func withFormat() mcp.ToolOption {
+	return mcp.WithString("format", mcp.Description("テキスト表現: json（structuredContentと同じコンパクトなJSON、デフォルト）, markdown（人が読むための表示）"))
+}
+
+// checkFormat はテキスト表現の指定を検証する。
+func checkFormat(request mcp.CallToolRequest) *mcp.CallToolResult {
+	switch request.GetString("format", formatJSON) {
+	case formatJSON, formatMarkdown:
+		return nil
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("無効な format: %s（有効値: json, markdown）", request.GetString("format", "")))
+	}
+}
+
+// pageRequest はリクエストのページング指定（limit / cursor）を取り出す。
+func pageRequest(request mcp.CallToolRequest) service.PageRequest {
+	return service.PageRequest{
+		Cursor: request.GetString("cursor", ""),
+		Limit:  request.GetInt("limit", 0),
+	}
+}
+
+// markdownOutput はMarkdownで表示できる構造化出力。
+type markdownOutput interface {
+	markdown() string
+}
+
+// structuredResult は構造化出力を structuredContent とし、指定された形式のテキストを添えた結果を返す。
+func structuredResult(request mcp.CallToolRequest, out markdownOutput) *mcp.CallToolResult {
+	if request.GetString("format", formatJSON) == formatMarkdown {
+		return mcp.NewToolResultStructured(out, out.markdown())
+	}
+	return mcp.NewToolResultStructuredOnly(out)
+}
+
+// StockOutput は stock_manage の構造化出力。action に応じて該当するフィールドのみを設定する。
+type StockOutput struct {
+	Action     string                       `json:"action"`
+	Message    string                       `json:"message,omitempty"`     // 操作結果の説明
+	Stock      *domain.Stock                `json:"stock,omitempty"`       // read: フルビュー
+	Summary    *domain.StockSummary         `json:"summary,omitempty"`     // create/update/delete/restore: サマリビュー
+	Stocks     []domain.StockSummary        `json:"stocks,omitempty"`      // list/search: サマリビュー
+	Total      *int                         `json:"total,omitempty"`       // list/search: 条件に一致する全件数
+	NextCursor string                       `json:"next_cursor,omitempty"` // list/search: 次のページのカーソル（最後のページでは省略）
+	PreviousID string                       `json:"previous_id,omitempty"` // update: 再分類前の管理番号（管理番号が変わった場合のみ）
+	Merged     bool                         `json:"merged,omitempty"`      // create: 既存のStockに統合した場合 true
+	Duplicates []service.DuplicateCandidate `json:"duplicates,omitempty"`  // create: 重複の可能性がある既存のStock
+	Purged     []string                     `json:"purged,omitempty"`      // purge: 完全削除した管理番号
+	TagCounts  []domain.TagCount            `json:"tag_counts,omitempty"`  // tags: タグごとの件数
+	Renamed    *service.TagRenameResult     `json:"renamed,omitempty"`     // rename_tag/merge_tags: 書き換えた結果
+	Direction  *service.DirectionReport     `json:"direction,omitempty"`   // direction: 方向性チェックのレポート
+
+	Diagnostics []repository.StockFileDiagnostic `json:"diagnostics,omitempty"` // diagnostics: 取り込めなかったStockファイル
+	History     []repository.StockRevision       `json:"history,omitempty"`     // history: 変更履歴（新しい順）
+}
+
+func (o *StockOutput) markdown() string {
+	var b strings.Builder
+	writeMessage(&b, o.Message)
+	if o.Stock != nil {
+		writeStock(&b, o.Stock)
+	}
+	if o.Summary != nil {
+		writeStockTable(&b, []domain.StockSummary{*o.Summary})
+	}
+	if o.Stocks != nil {
+		writeStockTable(&b, o.Stocks)
+	}
+	writePage(&b, o.Total, o.NextCursor)
+	writeDuplicates(&b, "Stock", o.Duplicates)
+	if o.Purged != nil {
+		writeIDList(&b, o.Purged)
+	}
+	if o.TagCounts != nil {
+		writeTagCounts(&b, o.TagCounts)
+	}
+	if o.Renamed != nil {
+		writeIDList(&b, o.Renamed.Updated)
+	}
+	if o.Direction != nil {
+		writeJSONBlock(&b, o.Direction)
+	}
+	if o.Diagnostics != nil {
+		writeStockDiagnostics(&b, o.Diagnostics)
+	}
+	if o.History != nil {
+		writeStockHistory(&b, o.History)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// StateOutput は state_manage の構造化出力。action に応じて該当するフィールドのみを設定する。
+// インシデント・問題・変更・リリース等のaction固有の結果は result に格納する。
+type StateOutput struct {
+	Action     string                       `json:"action"`
+	Message    string                       `json:"message,omitempty"`     // 操作結果の説明
+	State      *domain.State                `json:"state,omitempty"`       // read: フルビュー
+	Summary    *domain.StateSummary         `json:"summary,omitempty"`     // create/update/archive: サマリビュー
+	States     []domain.StateSummary        `json:"states,omitempty"`      // list/search/overdue: サマリビュー
+	Total      *int                         `json:"total,omitempty"`       // list/search: 条件に一致する全件数
+	NextCursor string                       `json:"next_cursor,omitempty"` // list/search: 次のページのカーソル（最後のページでは省略）
+	Merged     bool                         `json:"merged,omitempty"`      // create: 既存のStateに統合した場合 true
+	Duplicates []service.DuplicateCandidate `json:"duplicates,omitempty"`  // create: 重複の可能性がある未解決のState
+	Learning   *domain.StockSummary         `json:"learning,omitempty"`    // archive: 学びとして保存したStock
+	Notes      string                       `json:"notes,omitempty"`       // postmortem/release_create/release_notes: Markdown本文
+	TagCounts  []domain.TagCount            `json:"tag_counts,omitempty"`  // tags: タグごとの件数
+	Renamed    *service.TagRenameResult     `json:"renamed,omitempty"`     // rename_tag/merge_tags: 書き換えた結果
+	Result     any                          `json:"result,omitempty"`      // その他のaction固有の結果
+}
+
+func (o *StateOutput) markdown() string {
+	var b strings.Builder
+	writeMessage(&b, o.Message)
+	if o.State != nil {
+		writeState(&b, o.State)
+	}
+	if o.Summary != nil {
+		writeStateTable(&b, []domain.StateSummary{*o.Summary})
+	}
+	if o.States != nil {
+		writeStateTable(&b, o.States)
+	}
+	writePage(&b, o.Total, o.NextCursor)
+	writeDuplicates(&b, "State", o.Duplicates)
+	if o.Learning != nil {
+		b.WriteString("学びをStockとして保存しました:\n\n")
+		writeStockTable(&b, []domain.StockSummary{*o.Learning})
+	}
+	if o.TagCounts != nil {
+		writeTagCounts(&b, o.TagCounts)
+	}
+	if o.Renamed != nil {
+		writeIDList(&b, o.Renamed.Updated)
+	}
+	if o.Result != nil {
+		writeJSONBlock(&b, o.Result)
+	}
+	if o.Notes != "" {
+		b.WriteString(strings.TrimRight(o.Notes, "\n"))
+		b.WriteString("\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ContextOutput は context_search の構造化出力。
+type ContextOutput service.ContextSearchResult
+
+func (o *ContextOutput) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d件\n\n", o.Total)
+	for _, section := range []struct {
+		title string
+		items []service.ContextSearchItem
+	}{{"Stock", o.Stocks}, {"State", o.States}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n\n", section.title)
+		b.WriteString("| ID | タイトル | 分類 | 優先度 | 状態 | スコア | サマリ |\n|---|---|---|---|---|---|---|\n")
+		for _, item := range section.items {
+			summary := item.Summary
+			if summary == "" {
+				summary = item.Snippet // Stateはサマリを持たないため全文検索の一致箇所を表示する
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %.2f | %s |\n",
+				item.ID, cell(item.Title), item.Category, item.Priority, item.Status, item.Score, cell(summary))
+		}
+		b.WriteString("\n")
+	}
+	writePage(&b, nil, o.NextCursor)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeMessage(b *strings.Builder, message string) {
+	if message != "" {
+		b.WriteString(message)
+		b.WriteString("\n\n")
+	}
+}
+
+func writeStock(b *strings.Builder, stock *domain.Stock) {
+	fmt.Fprintf(b, "## %s %s\n\n", stock.ID, stock.Title)
+	fmt.Fprintf(b, "- カテゴリ: %s\n- 優先度: %s\n", stock.Category, stock.Priority)
+	if len(stock.Tags) > 0 {
+		fmt.Fprintf(b, "- タグ: %s\n", strings.Join(stock.Tags, ", "))
+	}
+	if len(stock.References) > 0 {
+		fmt.Fprintf(b, "- 参照: %s\n", strings.Join(stock.References, ", "))
+	}
+	// expected_updated_at にそのまま指定できるよう、秒未満も出力する
+	fmt.Fprintf(b, "- 更新日時: %s\n\n", stock.UpdatedAt.Format(time.RFC3339Nano))
+	if stock.Content != "" {
+		b.WriteString(strings.TrimRight(stock.Content, "\n"))
+		b.WriteString("\n\n")
+	}
+}
+
+func writeTagCounts(b *strings.Builder, counts []domain.TagCount) {
+	if len(counts) == 0 {
+		b.WriteString("タグはありません\n\n")
+		return
+	}
+	b.WriteString("| タグ | 件数 |\n|---|---|\n")
+	for _, c := range counts {
+		fmt.Fprintf(b, "| %s | %d |\n", cell(c.Tag), c.Count)
+	}
+	b.WriteString("\n")
+}
+
+func writeStockDiagnostics(b *strings.Builder, diagnostics []repository.StockFileDiagnostic) {
+	if len(diagnostics) == 0 {
+		b.WriteString("不正なStockファイルはありません\n\n")
+		return
+	}
+	b.WriteString("| ファイル | 内容 |\n|---|---|\n")
+	for _, d := range diagnostics {
+		fmt.Fprintf(b, "| %s | %s |\n", cell(d.Path), cell(d.Message))
+	}
+	b.WriteString("\n")
+}
+
+func writeStockHistory(b *strings.Builder, history []repository.StockRevision) {
+	if len(history) == 0 {
+		b.WriteString("変更履歴はありません\n\n")
+		return
+	}
+	b.WriteString("| 日時 | 操作 | 作成者 | コミット | 内容 |\n|---|---|---|---|---|\n")
+	for _, r := range history {
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n",
+			formatTime(r.Date), r.Action, cell(r.Author), shortCommit(r.Commit), cell(r.Subject))
+	}
+	b.WriteString("\n")
+}
+
+// shortCommit はコミットハッシュを表示用に短縮する。
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func writeStockTable(b *strings.Builder, stocks []domain.StockSummary) {
+	if len(stocks) == 0 {
+		b.WriteString("該当するStockはありません\n\n")
+		return
+	}
+	b.WriteString("| ID | タイトル | カテゴリ | 優先度 | タグ | 更新日時 | サマリ |\n|---|---|---|---|---|---|---|\n")
+	for _, s := range stocks {
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			s.ID, cell(s.Title), s.Category, s.Priority, cell(strings.Join(s.Tags, ", ")), formatTime(s.UpdatedAt), cell(s.Summary))
+	}
+	b.WriteString("\n")
+}
+
+func writeState(b *strings.Builder, state *domain.State) {
+	fmt.Fprintf(b, "## %s %s\n\n", state.ID, state.Title)
+	fmt.Fprintf(b, "- 種別: %s\n- 状態: %s\n- 優先度: %s\n", state.Type, state.Status, state.Priority)
+	if state.Assignee != "" {
+		fmt.Fprintf(b, "- 担当: %s\n", state.Assignee)
+	}
+	if state.DueAt != nil {
+		fmt.Fprintf(b, "- 期日: %s\n", formatTime(*state.DueAt))
+	}
+	if len(state.Tags) > 0 {
+		fmt.Fprintf(b, "- タグ: %s\n", strings.Join(state.Tags, ", "))
+	}
+	fmt.Fprintf(b, "- 更新日時: %s\n\n", formatTime(state.UpdatedAt))
+	if state.Description != "" {
+		b.WriteString(strings.TrimRight(state.Description, "\n"))
+		b.WriteString("\n\n")
+	}
+	if state.Resolution != "" {
+		fmt.Fprintf(b, "### 解決内容\n\n%s\n\n", strings.TrimRight(state.Resolution, "\n"))
+	}
+}
+
+func writeStateTable(b *strings.Builder, states []domain.StateSummary) {
+	if len(states) == 0 {
+		b.WriteString("該当するStateはありません\n\n")
+		return
+	}
+	b.WriteString("| ID | タイトル | 種別 | 状態 | 優先度 | 担当 | 期日 | 更新日時 |\n|---|---|---|---|---|---|---|---|\n")
+	for _, s := range states {
+		due := ""
+		if s.DueAt != nil {
+			due = formatTime(*s.DueAt)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			s.ID, cell(s.Title), s.Type, s.Status, s.Priority, cell(s.Assignee), due, formatTime(s.UpdatedAt))
+	}
+	b.WriteString("\n")
+}
+
+// writePage は一覧・検索結果の全件数と次のページのカーソルを表示する。
+func writePage(b *strings.Builder, total *int, next string) {
+	if total != nil {
+		fmt.Fprintf(b, "全%d件\n", *total)
+	}
+	if next != "" {
+		fmt.Fprintf(b, "続きは cursor=%s を指定\n", next)
+	}
+	if total != nil || next != "" {
+		b.WriteString("\n")
+	}
+}
+
+func writeDuplicates(b *strings.Builder, kind string, duplicates []service.DuplicateCandidate) {
+	if len(duplicates) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "重複の可能性がある既存の%s（on_duplicate=merge で統合、reject で作成を拒否）:\n\n", kind)
+	b.WriteString("| ID | タイトル | 類似度 | 判定 |\n|---|---|---|---|\n")
+	for _, d := range duplicates {
+		fmt.Fprintf(b, "| %s | %s | %.2f | %s |\n", d.ID, cell(d.Title), d.Similarity, d.Method)
+	}
+	b.WriteString("\n")
+}
+
+func writeIDList(b *strings.Builder, ids []string) {
+	for _, id := range ids {
+		fmt.Fprintf(b, "- %s\n", id)
+	}
+	b.WriteString("\n")
+}
+
+func writeJSONBlock(b *strings.Builder, v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Fprintf(b, "```json\n%s\n```\n\n", data)
+}
+
+// cell はMarkdownの表のセルに埋め込めるよう改行と区切り文字をエスケープする。
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
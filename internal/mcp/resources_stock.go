@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/haconeco/project-information-manager/internal/repository"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// stockResourceTemplate はStockのフルビューを読み込むリソースのURIテンプレート。
+const stockResourceTemplate = "pim://stocks/{project_id}/{stock_id}"
+
+// stockResourceURI はStockのリソースURIを返す。
+func stockResourceURI(projectID, id string) string {
+	return fmt.Sprintf("pim://stocks/%s/%s", projectID, id)
+}
+
+// registerStockResources はStockをMCPリソースとして公開する。
+// Stockファイルが外部で編集された場合は notifications/resources/updated と list_changed で通知する。
+func (s *Server) registerStockResources() {
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(stockResourceTemplate, "stock",
+			mcp.WithTemplateDescription("Stockのフルビュー（stock_manage action=read と同じ内容のJSON）"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		s.handleStockResource,
+	)
+}
+
+func (s *Server) handleStockResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	projectID := resourceArgument(request, "project_id")
+	stockID := resourceArgument(request, "stock_id")
+	if projectID == "" || stockID == "" {
+		return nil, fmt.Errorf("invalid stock resource uri: %s", request.Params.URI)
+	}
+
+	stock, err := s.services.Stock.Get(ctx, projectID, stockID)
+	if err != nil {
+		return nil, fmt.Errorf("Stock取得エラー: %w", err)
+	}
+	data, err := json.MarshalIndent(stock, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stock: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
+
+// resourceArgument はURIテンプレートに一致した変数の値を返す。
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// notifyStockChanges は外部編集で変更されたStockのリソース更新を接続中のクライアントへ通知する。
+// サーバーは resources/subscribe に対応しておらず、購読のないクライアントは updated を無視するため、
+// 内容の変更を含むすべての変更でリソース一覧の変更（list_changed）も通知して再取得を促す。
+func (s *Server) notifyStockChanges(changes []repository.StockChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+			"uri": stockResourceURI(change.ProjectID, change.ID),
+		})
+	}
+	s.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+}
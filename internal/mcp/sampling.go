@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/haconeco/project-information-manager/internal/llm"
+	"github.com/mark3labs/mcp-go/mcp"
+	gomcp "github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// samplingMinSummaryRunes はサンプリングで要約するテキストの最小文字数。
+	// サンプリングはクライアント側で利用者の承認を求める場合があるため、抽出で十分な短いStockには使わない。
+	samplingMinSummaryRunes = 1000
+	// samplingTimeout はサンプリング応答の待ち時間の上限。超過した場合はLLMを使わない処理で代替する。
+	samplingTimeout = 60 * time.Second
+)
+
+// samplingProvider はMCPクライアントのモデルを sampling/createMessage で呼び出す llm.Provider。
+// クライアントがサンプリングに対応していない場合は llm.ErrUnavailable を返す。
+type samplingProvider struct {
+	server *gomcp.MCPServer
+}
+
+// Name はプロバイダー名を返す。
+func (p *samplingProvider) Name() string {
+	return "mcp-sampling"
+}
+
+// Complete はリクエスト元のセッションにサンプリングを要求する。
+func (p *samplingProvider) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	switch req.Purpose {
+	case "keywords":
+		// キーワードは抽出で十分なため、承認の手間をかけない
+		return nil, llm.ErrUnavailable
+	case "summarize":
+		if len(req.Messages) == 0 || utf8.RuneCountInString(req.Messages[len(req.Messages)-1].Content) < samplingMinSummaryRunes {
+			return nil, llm.ErrUnavailable
+		}
+	}
+	if !clientSupportsSampling(ctx) {
+		return nil, llm.ErrUnavailable
+	}
+
+	messages := make([]mcp.SamplingMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		role := mcp.RoleUser
+		if message.Role == "assistant" {
+			role = mcp.RoleAssistant
+		}
+		messages = append(messages, mcp.SamplingMessage{Role: role, Content: mcp.NewTextContent(message.Content)})
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 1024
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+	defer cancel()
+	result, err := p.server.RequestSampling(ctx, mcp.CreateMessageRequest{
+		CreateMessageParams: mcp.CreateMessageParams{
+			Messages:     messages,
+			SystemPrompt: req.System,
+			MaxTokens:    maxTokens,
+			// 要約・分類には高速・低コストなモデルで十分
+			ModelPreferences: &mcp.ModelPreferences{SpeedPriority: 0.8, CostPriority: 0.8, IntelligencePriority: 0.3},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := samplingText(result.Content)
+	if err != nil {
+		return nil, err
+	}
+	// クライアントはトークン使用量を返さないため Usage は記録しない
+	return &llm.Response{Text: text, Model: result.Model}, nil
+}
+
+// clientSupportsSampling はリクエスト元のクライアントが初期化時にサンプリング対応を宣言したかを返す。
+func clientSupportsSampling(ctx context.Context) bool {
+	session, ok := gomcp.ClientSessionFromContext(ctx).(gomcp.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	if _, ok := session.(gomcp.SessionWithSampling); !ok {
+		return false
+	}
+	return session.GetClientCapabilities().Sampling != nil
+}
+
+// samplingText はサンプリング結果のコンテンツからテキストを取り出す。
+// トランスポートによってはJSONをデコードした map で渡されるため、両方を扱う。
+func samplingText(content any) (string, error) {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text, nil
+	case *mcp.TextContent:
+		return c.Text, nil
+	case map[string]any:
+		if text, ok := c["text"].(string); ok && c["type"] == "text" {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("sampling returned non-text content: %T", content)
+}
@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	gomcp "github.com/mark3labs/mcp-go/server"
+)
+
+// fakeSampler はクライアントのモデルを模したサンプリングハンドラー。
+type fakeSampler struct {
+	requests []mcp.CreateMessageRequest
+}
+
+func (f *fakeSampler) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	f.requests = append(f.requests, request)
+	var text string
+	switch prompt := request.SystemPrompt; {
+	case strings.Contains(prompt, "classify"):
+		text = "incident"
+	case strings.Contains(prompt, "lessons learned"):
+		text = "## Learnings\n\n- Alert on queue depth"
+	default:
+		text = "Client summary"
+	}
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(text)},
+		Model:           "client-model",
+	}, nil
+}
+
+// samplingContext はサンプリング対応を宣言したクライアントのセッションを持つコンテキストを返す。
+func samplingContext(srv *Server, sampler *fakeSampler, supported bool) context.Context {
+	session := gomcp.NewInProcessSession("test-session", sampler)
+	if supported {
+		session.SetClientCapabilities(mcp.ClientCapabilities{Sampling: &struct{}{}})
+	}
+	return srv.mcpServer.WithContext(context.Background(), session)
+}
+
+func TestSamplingClassifiesAndDraftsLearnings(t *testing.T) {
+	srv, stockRepo, stateRepo := newTestServer(t)
+	sampler := &fakeSampler{}
+	ctx := samplingContext(srv, sampler, true)
+	handle := srv.withSampling(srv.handleStateManage)
+
+	result, _ := handle(ctx, newRequest(map[string]any{
+		"action":      "create",
+		"project_id":  "proj-1",
+		"priority":    "P1",
+		"title":       "Checkout is down",
+		"description": "Payments time out",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	states, _ := stateRepo.List(ctx, "proj-1", nil)
+	if len(states) != 1 || string(states[0].Type) != "incident" {
+		t.Fatalf("expected state classified by client model, got %+v", states)
+	}
+
+	result, _ = handle(ctx, newRequest(map[string]any{
+		"action":          "archive",
+		"state_id":        states[0].ID,
+		"resolution":      "Scaled the queue workers",
+		"draft_learnings": true,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on archive: %s", getText(t, result))
+	}
+	stocks, _ := stockRepo.List(ctx, "proj-1", nil)
+	if len(stocks) != 1 || stocks[0].Content != "## Learnings\n\n- Alert on queue depth" || string(stocks[0].Category) != "management" {
+		t.Fatalf("expected learnings drafted by client model, got %+v", stocks)
+	}
+	archived, _ := stateRepo.Get(ctx, states[0].ID)
+	if len(archived.References) != 1 || archived.References[0] != stocks[0].ID {
+		t.Fatalf("expected learnings to be linked, got %v", archived.References)
+	}
+
+	// 短いStockの要約・キーワード抽出ではサンプリングを要求しない
+	if len(sampler.requests) != 2 {
+		t.Fatalf("expected 2 sampling requests, got %d", len(sampler.requests))
+	}
+	if usage := srv.SamplingUsage(); usage.Calls != 2 || usage.Errors != 0 {
+		t.Fatalf("unexpected sampling usage: %+v", usage)
+	}
+}
+
+func TestSamplingSummarizesLongStocks(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	sampler := &fakeSampler{}
+	ctx := samplingContext(srv, sampler, true)
+	handle := srv.withSampling(srv.handleStockManage)
+
+	result, _ := handle(ctx, newRequest(map[string]any{
+		"action":     "create",
+		"project_id": "proj-1",
+		"category":   "design",
+		"priority":   "P2",
+		"title":      "Long design",
+		"content":    strings.Repeat("The cache layer stores sessions. ", 40),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, `"summary":"Client summary"`) {
+		t.Fatalf("expected summary from client model: %s", text)
+	}
+	if len(sampler.requests) != 1 || sampler.requests[0].MaxTokens == 0 {
+		t.Fatalf("expected one summarize request, got %+v", sampler.requests)
+	}
+}
+
+func TestSamplingUnavailableDegradesGracefully(t *testing.T) {
+	srv, stockRepo, stateRepo := newTestServer(t)
+	sampler := &fakeSampler{}
+	ctx := samplingContext(srv, sampler, false)
+	handle := srv.withSampling(srv.handleStateManage)
+
+	result, _ := handle(ctx, newRequest(map[string]any{
+		"action":     "create",
+		"project_id": "proj-1",
+		"priority":   "P1",
+		"title":      "Checkout is down",
+	}))
+	if !result.IsError {
+		t.Fatalf("expected type to be required without sampling")
+	}
+
+	result, _ = handle(ctx, newRequest(map[string]any{
+		"action":      "create",
+		"project_id":  "proj-1",
+		"type":        "task",
+		"priority":    "P2",
+		"title":       "Add retries",
+		"description": "Retry failed webhooks",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	states, _ := stateRepo.List(ctx, "proj-1", nil)
+	result, _ = handle(ctx, newRequest(map[string]any{
+		"action":          "archive",
+		"state_id":        states[0].ID,
+		"resolution":      "Added exponential backoff",
+		"draft_learnings": true,
+		"save_category":   "design",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on archive: %s", getText(t, result))
+	}
+	stocks, _ := stockRepo.List(ctx, "proj-1", nil)
+	if len(stocks) != 1 || !strings.Contains(stocks[0].Content, "Added exponential backoff") {
+		t.Fatalf("expected template learnings, got %+v", stocks)
+	}
+	if len(sampler.requests) != 0 || srv.SamplingUsage().Calls != 0 {
+		t.Fatalf("expected no sampling requests, got %d", len(sampler.requests))
+	}
+}
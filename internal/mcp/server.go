@@ -7,7 +7,9 @@ import (
 	"os"
 
 	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/llm"
 	"github.com/haconeco/project-information-manager/internal/service"
+	"github.com/mark3labs/mcp-go/mcp"
 	gomcp "github.com/mark3labs/mcp-go/server"
 )
 
@@ -16,29 +18,66 @@ type Server struct {
 	mcpServer *gomcp.MCPServer
 	services  *service.Services
 	cfg       *config.Config
+	// sampling はクライアントのモデルを用いるLLMクライアント。サーバー側でLLMが未設定の場合に使われる
+	sampling *llm.Client
 }
 
 // NewServer は新しいMCPサーバーを生成する。
 func NewServer(services *service.Services, cfg *config.Config) (*Server, error) {
-	mcpServer := gomcp.NewMCPServer(
+	s := &Server{
+		services: services,
+		cfg:      cfg,
+	}
+	s.mcpServer = gomcp.NewMCPServer(
 		cfg.MCP.Name,
 		cfg.Version,
+		gomcp.WithToolHandlerMiddleware(s.withSampling),
+		gomcp.WithToolHandlerMiddleware(withActor),
+		gomcp.WithResourceCapabilities(false, true),
 	)
-
-	s := &Server{
-		mcpServer: mcpServer,
-		services:  services,
-		cfg:       cfg,
-	}
+	s.mcpServer.EnableSampling()
+	s.sampling = llm.NewClient(&samplingProvider{server: s.mcpServer})
 
 	// MCPツールを登録（ファサードパターン: 3ツールのみ）
 	s.registerStockTools()
 	s.registerStateTools()
 	s.registerContextTools()
 
+	// Stockをリソースとして公開し、ファイルの外部編集を通知する
+	s.registerStockResources()
+	if services.StockWatcher != nil {
+		services.StockWatcher.OnChange(s.notifyStockChanges)
+	}
+
 	return s, nil
 }
 
+// withSampling はツール呼び出しのコンテキストにクライアントのサンプリングを設定する。
+// サービスはサーバー側でLLMが未設定の場合にのみ使用し、クライアントが非対応の場合はLLMなしで動作する。
+func (s *Server) withSampling(next gomcp.ToolHandlerFunc) gomcp.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(service.WithTextGenerator(ctx, s.sampling), request)
+	}
+}
+
+// withActor はツール呼び出しのコンテキストに、操作者としてMCPクライアントの名前を設定する。
+// Stockの変更履歴（stock.git）のコミットの作成者になる。
+func withActor(next gomcp.ToolHandlerFunc) gomcp.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if session, ok := gomcp.ClientSessionFromContext(ctx).(gomcp.SessionWithClientInfo); ok {
+			if info := session.GetClientInfo(); info.Name != "" {
+				ctx = service.WithActor(ctx, info.Name)
+			}
+		}
+		return next(ctx, request)
+	}
+}
+
+// SamplingUsage はクライアントのサンプリングの利用回数を返す。
+func (s *Server) SamplingUsage() llm.UsageSummary {
+	return s.sampling.Usage()
+}
+
 // Run はMCPサーバーを起動する。
 func (s *Server) Run(ctx context.Context) error {
 	slog.Info("starting MCP server",
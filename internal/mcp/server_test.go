@@ -3,9 +3,12 @@ package mcp
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/config"
 	"github.com/haconeco/project-information-manager/internal/domain"
@@ -33,9 +36,18 @@ func newTestServer(t *testing.T) (*Server, repository.StockRepository, repositor
 		_ = db.Close()
 	})
 
-	repos := &repository.Repositories{Stock: stockRepo, State: stateRepo}
-	services := service.NewServices(repos)
-	cfg := &config.Config{Version: "test", MCP: config.MCPConfig{Name: "pim", Transport: "stdio"}}
+	releaseRepo, err := repository.NewSQLiteReleaseRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create release repo: %v", err)
+	}
+
+	repos := &repository.Repositories{Stock: stockRepo, StockFiles: stockRepo, State: stateRepo, Release: releaseRepo}
+	cfg := &config.Config{
+		Version: "test",
+		MCP:     config.MCPConfig{Name: "pim", Transport: "stdio"},
+		Hygiene: config.HygieneConfig{TagStale: true, Thresholds: []config.StaleThresholdConfig{{After: "720h"}}},
+	}
+	services := service.NewServices(repos, cfg)
 
 	srv, err := NewServer(services, cfg)
 	if err != nil {
@@ -70,6 +82,48 @@ func TestServerRunUnsupportedTransport(t *testing.T) {
 	}
 }
 
+func TestStockDiagnosticsAndResource(t *testing.T) {
+	srv, stockRepo, _ := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "category": "design", "priority": "P1", "title": "Design", "content": "content",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	stocks, err := stockRepo.List(ctx, "proj-1", nil)
+	if err != nil || len(stocks) != 1 {
+		t.Fatalf("expected 1 stock, got %v (%v)", stocks, err)
+	}
+
+	// Stockはリソースとしても読み込める
+	contents, err := srv.handleStockResource(ctx, mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{
+		URI:       stockResourceURI("proj-1", stocks[0].ID),
+		Arguments: map[string]any{"project_id": []string{"proj-1"}, "stock_id": []string{stocks[0].ID}},
+	}})
+	if err != nil || len(contents) != 1 {
+		t.Fatalf("expected stock resource, got %v (%v)", contents, err)
+	}
+	if text, ok := contents[0].(mcp.TextResourceContents); !ok || !strings.Contains(text.Text, `"title": "Design"`) {
+		t.Fatalf("unexpected resource contents: %+v", contents[0])
+	}
+
+	// 手作業で置かれた壊れたファイルは diagnostics で報告する
+	broken := filepath.Join(stockRepo.(*repository.FileStockRepository).Dir(), "proj-1", "design", "STK-DESIGN-099.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken file: %v", err)
+	}
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "diagnostics", "project_id": "proj-1"}))
+	if result.IsError {
+		t.Fatalf("unexpected error on diagnostics: %s", getText(t, result))
+	}
+	out, ok := result.StructuredContent.(*StockOutput)
+	if !ok || len(out.Diagnostics) != 1 || out.Diagnostics[0].Path != broken {
+		t.Fatalf("expected diagnostic for broken file, got %+v", result.StructuredContent)
+	}
+}
+
 func TestStockManageHandlers(t *testing.T) {
 	srv, stockRepo, _ := newTestServer(t)
 	ctx := context.Background()
@@ -86,16 +140,23 @@ func TestStockManageHandlers(t *testing.T) {
 		"priority":   "P1",
 		"title":      "Design",
 		"content":    "content",
+		"tags":       []any{"api"},
 	}))
 	if result.IsError {
 		t.Fatalf("unexpected error on create: %s", getText(t, result))
 	}
+	if text := getText(t, result); !strings.Contains(text, `"summary":"content"`) {
+		t.Fatalf("expected generated summary in create result: %s", text)
+	}
 
 	stocks, err := stockRepo.List(ctx, "proj-1", nil)
 	if err != nil || len(stocks) != 1 {
 		t.Fatalf("expected 1 stock, got %d, err=%v", len(stocks), err)
 	}
 	stockID := stocks[0].ID
+	if len(stocks[0].Tags) != 1 || stocks[0].Tags[0] != "api" {
+		t.Fatalf("expected tags to be stored, got %v", stocks[0].Tags)
+	}
 
 	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{
 		"action":   "read",
@@ -118,10 +179,14 @@ func TestStockManageHandlers(t *testing.T) {
 		"stock_id": stockID,
 		"priority": "P0",
 		"content":  "updated",
+		"tags":     []any{"api", "design"},
 	}))
 	if result.IsError {
 		t.Fatalf("unexpected error on update: %s", getText(t, result))
 	}
+	if updated, _ := stockRepo.Get(ctx, "", stockID); len(updated.Tags) != 2 || updated.Summary != "updated" {
+		t.Fatalf("expected tags and summary to be updated, got %+v", updated)
+	}
 
 	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{
 		"action":     "list",
@@ -179,6 +244,24 @@ func TestStateManageHandlers(t *testing.T) {
 		t.Fatalf("unexpected error on update: %s", getText(t, result))
 	}
 
+	// 空文字を明示した担当者・期日は解除する。指定しなければそのまま
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "update", "state_id": stateID, "assignee": "alice", "due_at": "2026-04-01",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on update: %s", getText(t, result))
+	}
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{"action": "update", "state_id": stateID, "description": "kept"}))
+	if got, _ := stateRepo.Get(ctx, stateID); result.IsError || got.Assignee != "alice" || got.DueAt == nil {
+		t.Fatalf("expected assignee and due_at to be kept, got %+v", got)
+	}
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "update", "state_id": stateID, "assignee": "", "due_at": "",
+	}))
+	if got, _ := stateRepo.Get(ctx, stateID); result.IsError || got.Assignee != "" || got.DueAt != nil {
+		t.Fatalf("expected assignee and due_at to be cleared, got %+v", got)
+	}
+
 	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
 		"action":     "archive",
 		"state_id":   stateID,
@@ -261,6 +344,191 @@ func TestSearchHandlersFallbackWithoutVector(t *testing.T) {
 	}
 }
 
+func TestStateOverdueHandler(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":      "create",
+		"project_id":  "proj-1",
+		"type":        "task",
+		"priority":    "P1",
+		"title":       "Late task",
+		"description": "desc",
+		"assignee":    "alice",
+		"due_at":      "2000-01-01",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":     "overdue",
+		"project_id": "proj-1",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on overdue: %s", getText(t, result))
+	}
+	text := getText(t, result)
+	if !strings.Contains(text, "Late task") || !strings.Contains(text, "alice") {
+		t.Fatalf("expected overdue task in result, got: %s", text)
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":     "create",
+		"project_id": "proj-1",
+		"type":       "task",
+		"title":      "Bad due",
+		"due_at":     "tomorrow",
+	}))
+	if !result.IsError {
+		t.Fatalf("expected error for invalid due_at")
+	}
+}
+
+func TestStateIncidentPostmortemHandlers(t *testing.T) {
+	srv, stockRepo, stateRepo := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":      "create",
+		"project_id":  "proj-1",
+		"type":        "incident",
+		"priority":    "P0",
+		"title":       "DB outage",
+		"description": "primary DB unreachable",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	states, err := stateRepo.List(ctx, "proj-1", nil)
+	if err != nil || len(states) != 1 {
+		t.Fatalf("expected 1 state, got %d, err=%v", len(states), err)
+	}
+	stateID := states[0].ID
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":      "incident",
+		"state_id":    stateID,
+		"severity":    "SEV2",
+		"impact":      "writes failed",
+		"detected_at": "2025-03-01T10:00:00Z",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on incident: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":   "timeline",
+		"state_id": stateID,
+		"note":     "failover triggered",
+		"at":       "2025-03-01T10:15:00Z",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on timeline: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":        "postmortem",
+		"state_id":      stateID,
+		"save_category": "postmortem",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on postmortem: %s", getText(t, result))
+	}
+	if !strings.Contains(getText(t, result), "failover triggered") {
+		t.Fatalf("expected timeline in postmortem, got: %s", getText(t, result))
+	}
+
+	category := domain.CategoryPostmortem
+	stocks, err := stockRepo.List(ctx, "proj-1", &repository.StockListOptions{Category: &category})
+	if err != nil || len(stocks) != 1 {
+		t.Fatalf("expected 1 postmortem stock, got %d, err=%v", len(stocks), err)
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":   "timeline",
+		"state_id": stateID,
+		"note":     "x",
+		"at":       "yesterday",
+	}))
+	if !result.IsError {
+		t.Fatalf("expected error for invalid timeline timestamp")
+	}
+}
+
+func TestStateProblemHandlers(t *testing.T) {
+	srv, _, stateRepo := newTestServer(t)
+	ctx := context.Background()
+
+	for _, args := range []map[string]any{
+		{"type": "problem", "title": "Cache stampede", "description": "cache expiry causes load spikes"},
+		{"type": "incident", "title": "Latency spike", "description": "cache expiry load spike"},
+	} {
+		args["action"] = "create"
+		args["project_id"] = "proj-1"
+		args["priority"] = "P1"
+		if result, _ := srv.handleStateManage(ctx, newRequest(args)); result.IsError {
+			t.Fatalf("unexpected error on create: %s", getText(t, result))
+		}
+	}
+
+	var problemID, incidentID string
+	states, _ := stateRepo.List(ctx, "proj-1", nil)
+	for _, st := range states {
+		switch st.Type {
+		case domain.StateTypeProblem:
+			problemID = st.ID
+		case domain.StateTypeIncident:
+			incidentID = st.ID
+		}
+	}
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":   "suggest_incidents",
+		"state_id": problemID,
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), incidentID) {
+		t.Fatalf("expected incident suggestion, got: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":       "link_incidents",
+		"state_id":     problemID,
+		"incident_ids": []any{incidentID},
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on link_incidents: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":      "problem",
+		"state_id":    problemID,
+		"workaround":  "warm cache before expiry",
+		"known_error": true,
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "known_error") {
+		t.Fatalf("unexpected problem update result: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":     "problem_report",
+		"project_id": "proj-1",
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), `"total":1`) {
+		t.Fatalf("unexpected problem report: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":      "unlink_incident",
+		"state_id":    problemID,
+		"incident_id": incidentID,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on unlink_incident: %s", getText(t, result))
+	}
+}
+
 func TestStateListFilters(t *testing.T) {
 	srv, _, _ := newTestServer(t)
 	ctx := context.Background()
@@ -284,3 +552,364 @@ func TestStateListFilters(t *testing.T) {
 		t.Fatalf("unexpected error on filtered list: %s", getText(t, result))
 	}
 }
+
+func TestStateChangeAndReleaseHandlers(t *testing.T) {
+	srv, _, stateRepo := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "type": "change", "priority": "P1",
+		"title": "Rotate TLS certificates", "description": "replace expiring certs",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	states, _ := stateRepo.List(ctx, "proj-1", nil)
+	changeID := states[0].ID
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "update", "state_id": changeID, "status": "in_progress",
+	}))
+	if !result.IsError {
+		t.Fatalf("expected approval gate error, got: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "change", "state_id": changeID, "risk_level": "medium",
+		"rollback_plan": "reinstall previous certs", "approvers": []any{"alice"},
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "pending") {
+		t.Fatalf("unexpected change result: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "approve", "state_id": changeID, "approver": "alice", "decision": "approve",
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "approved") {
+		t.Fatalf("unexpected approve result: %s", getText(t, result))
+	}
+
+	for _, status := range []string{"in_progress", "resolved"} {
+		result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+			"action": "update", "state_id": changeID, "status": status, "resolution": "certs rotated",
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error on update to %s: %s", status, getText(t, result))
+		}
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "release_create", "project_id": "proj-1", "version": "2.0.0",
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "Rotate TLS certificates") {
+		t.Fatalf("unexpected release_create result: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "release_notes", "project_id": "proj-1", "version": "2.0.0",
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "risk: medium") {
+		t.Fatalf("unexpected release_notes result: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "release_list", "project_id": "proj-1",
+	}))
+	if result.IsError || !strings.Contains(getText(t, result), "2.0.0") {
+		t.Fatalf("unexpected release_list result: %s", getText(t, result))
+	}
+}
+
+func TestStateHygieneHandler(t *testing.T) {
+	srv, _, stateRepo := newTestServer(t)
+	ctx := context.Background()
+
+	idle := time.Now().Add(-60 * 24 * time.Hour)
+	if err := stateRepo.Create(ctx, &domain.State{
+		ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Priority: domain.PriorityP3, Title: "Neglected task", CreatedAt: idle, UpdatedAt: idle,
+	}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action":     "hygiene",
+		"project_id": "proj-1",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on hygiene: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, "Neglected task") || !strings.Contains(text, `"stale_count":1`) {
+		t.Fatalf("expected stale task in report, got: %s", text)
+	}
+	got, _ := stateRepo.Get(ctx, "STA-TASK-001")
+	if !got.HasTag(domain.StaleTag) {
+		t.Fatalf("expected stale tag, got %v", got.Tags)
+	}
+}
+
+func TestStockDirectionHandler(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "category": "requirement", "priority": "P0",
+		"title": "オフライン同期", "content": "現場作業を止めないオフライン同期",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "type": "task", "priority": "P2",
+		"title": "社内ブログのテーマ変更", "description": "デザインを刷新",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action":     "direction",
+		"project_id": "proj-1",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on direction: %s", getText(t, result))
+	}
+	text := getText(t, result)
+	if !strings.Contains(text, `"method":"lexical"`) || !strings.Contains(text, "社内ブログのテーマ変更") {
+		t.Fatalf("expected unaligned task in report, got: %s", text)
+	}
+}
+
+func TestStockCreateOnDuplicate(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	args := map[string]any{
+		"action": "create", "project_id": "proj-1", "category": "design", "priority": "P2",
+		"title": "キャッシュ設計", "content": "Redisを使う",
+	}
+	result, _ := srv.handleStockManage(ctx, newRequest(args))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+
+	args["title"] = "キャッシュ設計方針"
+	args["content"] = "TTLは5分"
+	result, _ = srv.handleStockManage(ctx, newRequest(args))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, `"duplicates":[`) || !strings.Contains(text, `"method":"title"`) {
+		t.Fatalf("expected duplicate warning, got: %s", text)
+	}
+
+	args["title"] = "キャッシュ設計の方針"
+	args["on_duplicate"] = "reject"
+	result, _ = srv.handleStockManage(ctx, newRequest(args))
+	if !result.IsError {
+		t.Fatalf("expected reject error, got: %s", getText(t, result))
+	}
+
+	args["on_duplicate"] = "merge"
+	result, _ = srv.handleStockManage(ctx, newRequest(args))
+	if result.IsError {
+		t.Fatalf("unexpected error on merge: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, "既存のStockに統合しました") {
+		t.Fatalf("expected merged result, got: %s", text)
+	}
+}
+
+func TestStockDeleteRestoreAndRecategorize(t *testing.T) {
+	srv, stockRepo, _ := newTestServer(t)
+	ctx := context.Background()
+
+	result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "category": "design", "priority": "P2",
+		"title": "監視方針", "content": "メトリクスを収集する", "references": []any{"STK-REQ-001"},
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	stocks, _ := stockRepo.List(ctx, "proj-1", nil)
+	if len(stocks) != 1 || len(stocks[0].References) != 1 {
+		t.Fatalf("expected created stock with references, got %+v", stocks)
+	}
+	oldID := stocks[0].ID
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "update", "stock_id": oldID, "category": "architecture", "title": "監視アーキテクチャ",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on update: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, "STK-ARCHITECTURE-") || !strings.Contains(text, "監視アーキテクチャ") {
+		t.Fatalf("expected recategorized stock, got: %s", text)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "delete", "stock_id": oldID}))
+	if result.IsError {
+		t.Fatalf("unexpected error on delete: %s", getText(t, result))
+	}
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "read", "stock_id": oldID}))
+	if !result.IsError {
+		t.Fatalf("expected read error for deleted stock, got: %s", getText(t, result))
+	}
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "list", "project_id": "proj-1", "include_deleted": true}))
+	if text := getText(t, result); !strings.Contains(text, "監視アーキテクチャ") {
+		t.Fatalf("expected deleted stock in list with include_deleted, got: %s", text)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "restore", "stock_id": oldID}))
+	if result.IsError {
+		t.Fatalf("unexpected error on restore: %s", getText(t, result))
+	}
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "read", "stock_id": oldID}))
+	if result.IsError || !strings.Contains(getText(t, result), "メトリクスを収集する") {
+		t.Fatalf("expected restored stock readable via old id, got: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "purge"}))
+	if result.IsError || !strings.Contains(getText(t, result), "0件") {
+		t.Fatalf("expected nothing purged, got: %s", getText(t, result))
+	}
+}
+
+func TestStructuredOutputs(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"stock_manage", "state_manage", "context_search"} {
+		tool := srv.mcpServer.GetTool(name)
+		if tool == nil || tool.Tool.OutputSchema.Type != "object" || len(tool.Tool.OutputSchema.Properties) == 0 {
+			t.Fatalf("expected output schema for %s", name)
+		}
+	}
+
+	result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "create", "project_id": "proj-1", "category": "design", "priority": "P1",
+		"title": "API設計", "content": "REST APIの設計方針",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error on create: %s", getText(t, result))
+	}
+	out, ok := result.StructuredContent.(*StockOutput)
+	if !ok || out.Summary == nil || out.Summary.Title != "API設計" {
+		t.Fatalf("expected structured stock output, got %#v", result.StructuredContent)
+	}
+	var decoded StockOutput
+	if err := json.Unmarshal([]byte(getText(t, result)), &decoded); err != nil || decoded.Summary.ID != out.Summary.ID {
+		t.Fatalf("expected JSON text matching structured content, got %q (%v)", getText(t, result), err)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "list", "project_id": "proj-1", "format": "markdown"}))
+	if result.IsError {
+		t.Fatalf("unexpected error on list: %s", getText(t, result))
+	}
+	if text := getText(t, result); !strings.Contains(text, "| ID | タイトル |") || !strings.Contains(text, "| API設計 | design | P1 |") {
+		t.Fatalf("expected markdown table, got: %s", text)
+	}
+	if out, ok := result.StructuredContent.(*StockOutput); !ok || len(out.Stocks) != 1 {
+		t.Fatalf("expected structured content with markdown format, got %#v", result.StructuredContent)
+	}
+
+	result, _ = srv.handleContextSearch(ctx, newRequest(map[string]any{"query": "API", "project_id": "proj-1"}))
+	if out, ok := result.StructuredContent.(*ContextOutput); !ok || out.Total != 1 {
+		t.Fatalf("expected structured context output, got %#v", result.StructuredContent)
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{"action": "list", "project_id": "proj-1", "format": "yaml"}))
+	if !result.IsError {
+		t.Fatalf("expected error for invalid format, got: %s", getText(t, result))
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	for _, title := range []string{"設計A", "設計B", "設計C"} {
+		result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{
+			"action": "create", "project_id": "proj-1", "category": "design", "priority": "P2",
+			"title": title, "content": "本文",
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error on create: %s", getText(t, result))
+		}
+	}
+
+	result, _ := srv.handleStockManage(ctx, newRequest(map[string]any{"action": "list", "project_id": "proj-1", "limit": 2}))
+	out, ok := result.StructuredContent.(*StockOutput)
+	if !ok || len(out.Stocks) != 2 || out.Total == nil || *out.Total != 3 || out.NextCursor == "" {
+		t.Fatalf("expected first page with cursor, got %#v", result.StructuredContent)
+	}
+	if text := getText(t, result); !strings.Contains(text, `"total":3`) || !strings.Contains(text, `"next_cursor":"`) {
+		t.Fatalf("expected total and next_cursor in JSON, got: %s", text)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{
+		"action": "list", "project_id": "proj-1", "limit": 2, "cursor": out.NextCursor,
+	}))
+	last, ok := result.StructuredContent.(*StockOutput)
+	if !ok || len(last.Stocks) != 1 || last.NextCursor != "" || last.Stocks[0].ID == out.Stocks[0].ID || last.Stocks[0].ID == out.Stocks[1].ID {
+		t.Fatalf("expected last page, got %#v", result.StructuredContent)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "search", "query": "設計", "cursor": out.NextCursor}))
+	if !result.IsError {
+		t.Fatalf("expected error for cursor from another query, got: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleContextSearch(ctx, newRequest(map[string]any{"query": "設計", "project_id": "proj-1", "limit": 2}))
+	ctxOut, ok := result.StructuredContent.(*ContextOutput)
+	if !ok || ctxOut.Total != 3 || len(ctxOut.Stocks) != 2 || ctxOut.NextCursor == "" {
+		t.Fatalf("expected paged context search, got %#v", result.StructuredContent)
+	}
+}
+
+func TestTagActions(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	ctx := context.Background()
+
+	for _, in := range []struct {
+		title string
+		tags  []any
+	}{
+		{"ログイン不具合", []any{"auth", "bug"}},
+		{"決済エラー", []any{"payment", "bug"}},
+	} {
+		result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+			"action": "create", "project_id": "proj-1", "type": "issue", "priority": "P2",
+			"title": in.title, "description": "調査中", "tags": in.tags,
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error on create: %s", getText(t, result))
+		}
+	}
+
+	result, _ := srv.handleStateManage(ctx, newRequest(map[string]any{
+		"action": "list", "project_id": "proj-1", "tags": []any{"auth", "bug"}, "tag_match": "all",
+	}))
+	if out, ok := result.StructuredContent.(*StateOutput); !ok || len(out.States) != 1 || out.States[0].Title != "ログイン不具合" {
+		t.Fatalf("expected one state matching all tags, got %#v", result.StructuredContent)
+	}
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{"action": "list", "project_id": "proj-1", "tags": []any{"bug"}, "tag_match": "some"}))
+	if !result.IsError {
+		t.Fatalf("expected error for invalid tag_match, got: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{"action": "rename_tag", "project_id": "proj-1", "tag": "bug", "new_tag": "defect"}))
+	if result.IsError || !strings.Contains(getText(t, result), `"updated":["`) {
+		t.Fatalf("unexpected rename result: %s", getText(t, result))
+	}
+
+	result, _ = srv.handleStateManage(ctx, newRequest(map[string]any{"action": "tags", "project_id": "proj-1", "format": "markdown"}))
+	if text := getText(t, result); !strings.Contains(text, "| defect | 2 |") || strings.Contains(text, "| bug |") {
+		t.Fatalf("expected renamed tag counts, got: %s", text)
+	}
+
+	result, _ = srv.handleStockManage(ctx, newRequest(map[string]any{"action": "merge_tags", "project_id": "proj-1", "new_tag": "x"}))
+	if !result.IsError {
+		t.Fatalf("expected error without tags, got: %s", getText(t, result))
+	}
+}
@@ -2,8 +2,8 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
 	"github.com/haconeco/project-information-manager/internal/repository"
@@ -15,19 +15,54 @@ import (
 func (s *Server) registerStateTools() {
 	s.mcpServer.AddTool(
 		mcp.NewTool("state_manage",
-			mcp.WithDescription("プロダクト開発の動的な状態情報（タスク、課題、インシデント等）を管理するState操作ツール。actionで操作を指定。list/searchはサマリ（タイトル・ステータス等のみ）を返却、readで全文取得。"),
-			mcp.WithString("action", mcp.Required(), mcp.Description("操作種別: create, read, update, archive, list, search")),
-			mcp.WithString("project_id", mcp.Description("プロジェクトID（create/list/searchで必須）")),
+			mcp.WithDescription("プロダクト開発の動的な状態情報（タスク、課題、インシデント等）を管理するState操作ツール。actionで操作を指定。list/searchはサマリ（タイトル・ステータス等のみ）を返却、readで全文取得。tagsでタグごとの件数、rename_tag/merge_tagsでタグの名前変更・統合。backupでデータディレクトリ（State・Stock）のスナップショットを作成（管理用）。"),
+			mcp.WithString("action", mcp.Required(), mcp.Description("操作種別: create, read, update, archive, list, search, tags, rename_tag, merge_tags, overdue, incident, timeline, postmortem, problem, link_incidents, unlink_incident, suggest_incidents, problem_report, change, approve, release_create, release_notes, release_list, hygiene, backup")),
+			mcp.WithString("project_id", mcp.Description("プロジェクトID（create/list/search/overdue/tags/rename_tag/merge_tagsで必須）")),
 			mcp.WithString("state_id", mcp.Description("State管理番号（read/update/archiveで必須）")),
-			mcp.WithString("type", mcp.Description("種別: task, issue, incident, change（createで必須、listでフィルタ）")),
-			mcp.WithString("priority", mcp.Description("優先度: P0, P1, P2, P3（createで必須）")),
+			mcp.WithString("type", mcp.Description("種別: task, issue, incident, change, problem（createで必須。LLM設定時は省略すると自動分類。listでフィルタ）")),
+			mcp.WithString("priority", mcp.Description("優先度: P0, P1, P2, P3（createで必須。postmortem/archiveではStock保存時の優先度、デフォルト: P2）")),
 			mcp.WithString("title", mcp.Description("タイトル（createで必須）")),
 			mcp.WithString("description", mcp.Description("詳細説明（createで必須、updateでオプション）")),
+			mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("タグ（create/updateでオプション。updateでは指定したタグで置き換え。list/searchでは絞り込み、merge_tagsでは統合元のタグ）")),
+			mcp.WithString("on_duplicate", mcp.Description("重複の可能性がある未解決のStateがある場合の扱い: allow（作成して警告、デフォルト）, reject（作成しない）, merge（既存Stateにタグ・参照・説明を統合）（create用）")),
 			mcp.WithString("status", mcp.Description("ステータス: open, in_progress, resolved（updateでオプション、listでフィルタ）")),
 			mcp.WithString("resolution", mcp.Description("解決内容（update/archiveでオプション）")),
+			mcp.WithString("assignee", mcp.Description("担当者（create/updateでオプション、listでフィルタ。updateで空文字を指定すると担当者を外す）")),
+			mcp.WithString("due_at", mcp.Description("期日: RFC3339 または YYYY-MM-DD（create/updateでオプション。updateで空文字を指定すると期日を削除）")),
+			mcp.WithString("severity", mcp.Description("インシデント深刻度: SEV1, SEV2, SEV3, SEV4（incident用）")),
+			mcp.WithString("impact", mcp.Description("インシデントの影響範囲（incident用）")),
+			mcp.WithString("detected_at", mcp.Description("検知日時 RFC3339（incident用）")),
+			mcp.WithString("mitigated_at", mcp.Description("暫定対処日時 RFC3339（incident用）")),
+			mcp.WithString("resolved_at", mcp.Description("解決日時 RFC3339（incident用）")),
+			mcp.WithString("note", mcp.Description("タイムラインに追記する内容（timelineで必須）")),
+			mcp.WithString("at", mcp.Description("タイムラインの発生日時 RFC3339（timeline用、省略時は現在時刻）")),
+			mcp.WithString("save_category", mcp.Description("Stockとして保存する場合のカテゴリ（postmortem用: postmortem, test, management。archive用: 学びの転記先、デフォルト: management）")),
+			mcp.WithString("stock_summary", mcp.Description("アーカイブ時にStockとして転記する学び（archive用）")),
+			mcp.WithBoolean("draft_learnings", mcp.Description("アーカイブ時に学びを下書きしてStockとして転記する（archive用。LLMまたはクライアントのサンプリングが利用できない場合はテンプレート）")),
+			mcp.WithString("root_cause", mcp.Description("根本原因（problem用）")),
+			mcp.WithString("workaround", mcp.Description("既知のエラーに対する回避策（problem用）")),
+			mcp.WithBoolean("known_error", mcp.Description("既知のエラーとして登録するか（problem用）")),
+			mcp.WithArray("incident_ids", mcp.WithStringItems(), mcp.Description("問題に紐づけるインシデントID（link_incidentsで必須）")),
+			mcp.WithString("incident_id", mcp.Description("紐づけを解除するインシデントID（unlink_incidentで必須）")),
+			mcp.WithString("period", mcp.Description("集計期間: month, week（problem_report用、デフォルト: month）")),
+			mcp.WithString("risk_level", mcp.Description("変更のリスクレベル: low, medium, high（change用）")),
+			mcp.WithString("rollback_plan", mcp.Description("切り戻し手順（change用）")),
+			mcp.WithArray("approvers", mcp.WithStringItems(), mcp.Description("承認が必要な承認者（change用。全員の承認でopenから遷移可能）")),
+			mcp.WithString("approver", mcp.Description("承認者名（approveで必須）")),
+			mcp.WithString("decision", mcp.Description("判断: approve, reject（approveで必須）")),
+			mcp.WithString("comment", mcp.Description("承認・却下のコメント（approve用）")),
+			mcp.WithString("version", mcp.Description("リリースバージョン（release_create/release_notesで必須）")),
+			mcp.WithArray("item_ids", mcp.WithStringItems(), mcp.Description("リリースに含める解決済みの変更・タスクID（release_create用、省略時は未リリースの解決済み（アーカイブ済みを含む）をすべて含める）")),
+			mcp.WithBoolean("dry_run", mcp.Description("検出のみ行いタグ付け・優先度引き上げをしない（hygiene用、デフォルト: false）")),
 			mcp.WithString("query", mcp.Description("検索クエリ（searchで必須）")),
-			mcp.WithNumber("limit", mcp.Description("検索結果の上限数（search用、デフォルト: 10）")),
-			mcp.WithBoolean("include_archived", mcp.Description("アーカイブ済みを含むか（list用、デフォルト: false）")),
+			mcp.WithNumber("limit", mcp.Description("1ページあたりの件数（list用、デフォルト: 50。search/suggest_incidents用、デフォルト: 10。最大: 200）")),
+			mcp.WithString("cursor", mcp.Description("前のページの next_cursor（list/search用。同じ条件で指定する）")),
+			mcp.WithBoolean("include_archived", mcp.Description("アーカイブ済みを含むか（list/tags用、デフォルト: false）")),
+			mcp.WithString("tag_match", mcp.Description("tags による絞り込みの一致条件: any（いずれかを含む、デフォルト）, all（すべてを含む）（list/search用）")),
+			mcp.WithString("tag", mcp.Description("名前を変更するタグ（rename_tagで必須）")),
+			mcp.WithString("new_tag", mcp.Description("変更後・統合先のタグ（rename_tag/merge_tagsで必須）")),
+			withFormat(),
+			mcp.WithOutputSchema[StateOutput](),
 		),
 		s.handleStateManage,
 	)
@@ -35,6 +70,9 @@ func (s *Server) registerStateTools() {
 
 func (s *Server) handleStateManage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	action := request.GetString("action", "")
+	if result := checkFormat(request); result != nil {
+		return result, nil
+	}
 
 	switch action {
 	case "create":
@@ -49,9 +87,79 @@ func (s *Server) handleStateManage(ctx context.Context, request mcp.CallToolRequ
 		return s.handleStateList(ctx, request)
 	case "search":
 		return s.handleStateSearch(ctx, request)
+	case "tags":
+		return s.handleStateTags(ctx, request)
+	case "rename_tag", "merge_tags":
+		return s.handleStateRenameTags(ctx, action, request)
+	case "overdue":
+		return s.handleStateOverdue(ctx, request)
+	case "incident":
+		return s.handleStateIncident(ctx, request)
+	case "timeline":
+		return s.handleStateTimeline(ctx, request)
+	case "postmortem":
+		return s.handleStatePostmortem(ctx, request)
+	case "problem":
+		return s.handleStateProblem(ctx, request)
+	case "link_incidents":
+		return s.handleStateLinkIncidents(ctx, request)
+	case "unlink_incident":
+		return s.handleStateUnlinkIncident(ctx, request)
+	case "suggest_incidents":
+		return s.handleStateSuggestIncidents(ctx, request)
+	case "problem_report":
+		return s.handleStateProblemReport(ctx, request)
+	case "change":
+		return s.handleStateChange(ctx, request)
+	case "approve":
+		return s.handleStateApprove(ctx, request)
+	case "release_create":
+		return s.handleReleaseCreate(ctx, request)
+	case "release_notes":
+		return s.handleReleaseNotes(ctx, request)
+	case "release_list":
+		return s.handleReleaseList(ctx, request)
+	case "hygiene":
+		return s.handleStateHygiene(ctx, request)
+	case "backup":
+		return s.handleStateBackup(ctx, request)
 	default:
-		return mcp.NewToolResultError(fmt.Sprintf("不明なaction: %s（有効値: create, read, update, archive, list, search）", action)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("不明なaction: %s（有効値: create, read, update, archive, list, search, overdue, incident, timeline, postmortem, problem, link_incidents, unlink_incident, suggest_incidents, problem_report, change, approve, release_create, release_notes, release_list, hygiene, backup）", action)), nil
+	}
+}
+
+// parseDueAt は期日文字列を解析する。RFC3339 と日付のみ（YYYY-MM-DD, 当日終わりまで）を受け付ける。
+func parseDueAt(v string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("due_at は RFC3339 または YYYY-MM-DD で指定してください: %s", v)
+	}
+	end := d.Add(24*time.Hour - time.Second)
+	return &end, nil
+}
+
+// explicitString は引数が指定されていればその値を、空文字も含めて返す。指定されていない場合は nil を返す。
+func explicitString(request mcp.CallToolRequest, name string) *string {
+	v, ok := request.GetArguments()[name].(string)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// parseTimestamp はRFC3339形式の日時パラメータを解析する。空文字列の場合は nil を返す。
+func parseTimestamp(name, v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
 	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s は RFC3339 で指定してください: %s", name, v)
+	}
+	return &t, nil
 }
 
 func (s *Server) handleStateCreate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -64,17 +172,29 @@ func (s *Server) handleStateCreate(ctx context.Context, request mcp.CallToolRequ
 		Title:       request.GetString("title", ""),
 		Description: request.GetString("description", ""),
 		Tags:        tags,
+		Assignee:    request.GetString("assignee", ""),
+		OnDuplicate: request.GetString("on_duplicate", ""),
+	}
+	if v := request.GetString("due_at", ""); v != "" {
+		dueAt, err := parseDueAt(v)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		input.DueAt = dueAt
 	}
 
-	state, err := s.services.State.Create(ctx, input)
+	result, err := s.services.State.CreateWithDuplicateCheck(ctx, input)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("State作成エラー: %v", err)), nil
 	}
 
 	// 作成結果はサマリビューで返却
-	summary := state.ToSummary()
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(fmt.Sprintf("Stateを作成しました:\n%s", string(data))), nil
+	summary := result.State.ToSummary()
+	out := &StateOutput{Action: "create", Message: "Stateを作成しました", Summary: &summary, Merged: result.Merged, Duplicates: result.Duplicates}
+	if result.Merged {
+		out.Message = "既存のStateに統合しました"
+	}
+	return structuredResult(request, out), nil
 }
 
 func (s *Server) handleStateRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -89,8 +209,7 @@ func (s *Server) handleStateRead(ctx context.Context, request mcp.CallToolReques
 	}
 
 	// readはフルビューで返却
-	data, _ := json.MarshalIndent(state, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StateOutput{Action: "read", State: state}), nil
 }
 
 func (s *Server) handleStateUpdate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -109,6 +228,20 @@ func (s *Server) handleStateUpdate(ctx context.Context, request mcp.CallToolRequ
 	if v := request.GetString("resolution", ""); v != "" {
 		input.Resolution = &v
 	}
+	// 担当者・期日は空文字を明示した場合に解除する
+	input.Assignee = explicitString(request, "assignee")
+	if v := explicitString(request, "due_at"); v != nil {
+		if *v == "" {
+			input.ClearDueAt = true
+		} else {
+			dueAt, err := parseDueAt(*v)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			input.DueAt = dueAt
+		}
+	}
+	input.Tags = request.GetStringSlice("tags", nil)
 
 	state, err := s.services.State.Update(ctx, stateID, input)
 	if err != nil {
@@ -117,8 +250,7 @@ func (s *Server) handleStateUpdate(ctx context.Context, request mcp.CallToolRequ
 
 	// 更新結果はサマリビューで返却
 	summary := state.ToSummary()
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(fmt.Sprintf("Stateを更新しました:\n%s", string(data))), nil
+	return structuredResult(request, &StateOutput{Action: "update", Message: "Stateを更新しました", Summary: &summary}), nil
 }
 
 func (s *Server) handleStateArchive(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -128,19 +260,26 @@ func (s *Server) handleStateArchive(ctx context.Context, request mcp.CallToolReq
 	}
 
 	input := service.ArchiveInput{
-		Resolution:   request.GetString("resolution", ""),
-		StockSummary: request.GetString("stock_summary", ""),
+		Resolution:     request.GetString("resolution", ""),
+		StockSummary:   request.GetString("stock_summary", ""),
+		DraftLearnings: request.GetBool("draft_learnings", false),
+		SaveCategory:   request.GetString("save_category", ""),
+		Priority:       request.GetString("priority", ""),
 	}
 
-	state, err := s.services.State.Archive(ctx, stateID, input)
+	result, err := s.services.State.Archive(ctx, stateID, input)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stateアーカイブエラー: %v", err)), nil
 	}
 
 	// アーカイブ結果はサマリビューで返却
-	summary := state.ToSummary()
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(fmt.Sprintf("Stateをアーカイブしました:\n%s", string(data))), nil
+	summary := result.State.ToSummary()
+	return structuredResult(request, &StateOutput{
+		Action:   "archive",
+		Message:  "Stateをアーカイブしました",
+		Summary:  &summary,
+		Learning: result.Learning,
+	}), nil
 }
 
 func (s *Server) handleStateList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -159,16 +298,23 @@ func (s *Server) handleStateList(ctx context.Context, request mcp.CallToolReques
 		st := domain.StateStatus(v)
 		opts.Status = &st
 	}
+	if v := request.GetString("assignee", ""); v != "" {
+		opts.Assignee = &v
+	}
 	opts.IncludeArchived = request.GetBool("include_archived", false)
+	filter, err := tagFilterFromRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("無効なタグ条件: %v", err)), nil
+	}
+	opts.Tags, opts.TagMatch = filter.Tags, filter.Match
 
 	// サマリビューで返却（Description を含まない）
-	summaries, err := s.services.State.ListSummary(ctx, projectID, opts)
+	page, err := s.services.State.ListSummaryPage(ctx, projectID, opts, pageRequest(request))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("State一覧取得エラー: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(summaries, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StateOutput{Action: "list", States: page.States, Total: &page.Total, NextCursor: page.NextCursor}), nil
 }
 
 func (s *Server) handleStateSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -180,14 +326,333 @@ func (s *Server) handleStateSearch(ctx context.Context, request mcp.CallToolRequ
 	if projectID == "" {
 		return mcp.NewToolResultError("project_id は必須です"), nil
 	}
-	limit := request.GetInt("limit", 10)
+
+	filter, err := tagFilterFromRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("無効なタグ条件: %v", err)), nil
+	}
 
 	// サマリビューで返却
-	summaries, err := s.services.State.SearchSummary(ctx, query, limit, projectID)
+	page, err := s.services.State.SearchSummaryPage(ctx, query, projectID, filter, pageRequest(request))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("State検索エラー: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(summaries, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StateOutput{Action: "search", States: page.States, Total: &page.Total, NextCursor: page.NextCursor}), nil
+}
+
+func (s *Server) handleStateOverdue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	// 期日超過・SLA違反のStateをサマリビューで返却
+	summaries, err := s.services.State.Overdue(ctx, projectID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("期限超過State取得エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "overdue", States: summaries}), nil
+}
+
+func (s *Server) handleStateIncident(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+
+	input := service.UpdateIncidentInput{}
+	if v := request.GetString("severity", ""); v != "" {
+		input.Severity = &v
+	}
+	if v := request.GetString("impact", ""); v != "" {
+		input.Impact = &v
+	}
+	var err error
+	if input.DetectedAt, err = parseTimestamp("detected_at", request.GetString("detected_at", "")); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if input.MitigatedAt, err = parseTimestamp("mitigated_at", request.GetString("mitigated_at", "")); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if input.ResolvedAt, err = parseTimestamp("resolved_at", request.GetString("resolved_at", "")); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	state, err := s.services.Incident.Update(ctx, stateID, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("インシデント更新エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "incident", Message: "インシデント情報を更新しました", Result: state.Incident}), nil
+}
+
+func (s *Server) handleStateTimeline(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+	note := request.GetString("note", "")
+	if note == "" {
+		return mcp.NewToolResultError("note は必須です"), nil
+	}
+	at, err := parseTimestamp("at", request.GetString("at", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var entryAt time.Time
+	if at != nil {
+		entryAt = *at
+	}
+
+	state, err := s.services.Incident.AddTimelineEntry(ctx, stateID, entryAt, note)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("タイムライン追記エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "timeline", Message: "タイムラインに追記しました", Result: state.Incident.Timeline}), nil
+}
+
+func (s *Server) handleStatePostmortem(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+
+	input := service.PostmortemInput{
+		SaveCategory: request.GetString("save_category", ""),
+		Priority:     request.GetString("priority", ""),
+	}
+
+	result, err := s.services.Incident.Postmortem(ctx, stateID, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("ポストモーテム生成エラー: %v", err)), nil
+	}
+
+	out := &StateOutput{Action: "postmortem", Notes: result.Markdown}
+	if result.Stock != nil {
+		out.Message = "ポストモーテムをStockとして保存しました"
+		out.Learning = result.Stock
+	}
+	return structuredResult(request, out), nil
+}
+
+func (s *Server) handleStateProblem(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+
+	input := service.UpdateProblemInput{}
+	if v := request.GetString("root_cause", ""); v != "" {
+		input.RootCause = &v
+	}
+	if v := request.GetString("workaround", ""); v != "" {
+		input.Workaround = &v
+	}
+	if args := request.GetArguments(); args != nil {
+		if _, ok := args["known_error"]; ok {
+			v := request.GetBool("known_error", false)
+			input.KnownError = &v
+		}
+	}
+
+	state, err := s.services.Problem.Update(ctx, stateID, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("問題更新エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "problem", Message: "問題情報を更新しました", Result: state.Problem}), nil
+}
+
+func (s *Server) handleStateLinkIncidents(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+	incidentIDs := request.GetStringSlice("incident_ids", nil)
+	if len(incidentIDs) == 0 {
+		return mcp.NewToolResultError("incident_ids は必須です"), nil
+	}
+
+	state, err := s.services.Problem.LinkIncidents(ctx, stateID, incidentIDs)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("インシデント紐づけエラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "link_incidents", Message: "インシデントを紐づけました", Result: state.Problem}), nil
+}
+
+func (s *Server) handleStateUnlinkIncident(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+	incidentID := request.GetString("incident_id", "")
+	if incidentID == "" {
+		return mcp.NewToolResultError("incident_id は必須です"), nil
+	}
+
+	state, err := s.services.Problem.UnlinkIncident(ctx, stateID, incidentID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("インシデント紐づけ解除エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "unlink_incident", Message: "インシデントの紐づけを解除しました", Result: state.Problem}), nil
+}
+
+func (s *Server) handleStateSuggestIncidents(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+	limit := request.GetInt("limit", 10)
+
+	suggestions, err := s.services.Problem.SuggestIncidents(ctx, stateID, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("インシデント候補取得エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "suggest_incidents", Result: suggestions}), nil
+}
+
+func (s *Server) handleStateProblemReport(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	reports, err := s.services.Problem.Report(ctx, projectID, request.GetString("period", ""))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("問題レポート取得エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "problem_report", Result: reports}), nil
+}
+
+func (s *Server) handleStateChange(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+
+	input := service.UpdateChangeInput{
+		Approvers: request.GetStringSlice("approvers", nil),
+	}
+	if v := request.GetString("risk_level", ""); v != "" {
+		input.RiskLevel = &v
+	}
+	if v := request.GetString("rollback_plan", ""); v != "" {
+		input.RollbackPlan = &v
+	}
+
+	state, err := s.services.Change.Update(ctx, stateID, input)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("変更更新エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{
+		Action:  "change",
+		Message: fmt.Sprintf("変更情報を更新しました（承認状況: %s）", state.Change.ApprovalStatus()),
+		Result:  state.Change,
+	}), nil
+}
+
+func (s *Server) handleStateApprove(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID := request.GetString("state_id", "")
+	if stateID == "" {
+		return mcp.NewToolResultError("state_id は必須です"), nil
+	}
+
+	state, err := s.services.Change.Approve(ctx, stateID, service.ApproveInput{
+		Approver: request.GetString("approver", ""),
+		Decision: request.GetString("decision", ""),
+		Comment:  request.GetString("comment", ""),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("承認記録エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{
+		Action:  "approve",
+		Message: fmt.Sprintf("承認を記録しました（承認状況: %s）", state.Change.ApprovalStatus()),
+		Result:  state.Change,
+	}), nil
+}
+
+func (s *Server) handleReleaseCreate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	release, err := s.services.Release.Create(ctx, service.CreateReleaseInput{
+		ProjectID: request.GetString("project_id", ""),
+		Version:   request.GetString("version", ""),
+		Title:     request.GetString("title", ""),
+		ItemIDs:   request.GetStringSlice("item_ids", nil),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("リリース作成エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{
+		Action:  "release_create",
+		Message: "リリースを作成しました",
+		Notes:   release.Notes,
+		Result:  release.ToSummary(),
+	}), nil
+}
+
+func (s *Server) handleReleaseNotes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	version := request.GetString("version", "")
+	if projectID == "" || version == "" {
+		return mcp.NewToolResultError("project_id と version は必須です"), nil
+	}
+
+	release, err := s.services.Release.Get(ctx, projectID, version)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("リリース取得エラー: %v", err)), nil
+	}
+	return structuredResult(request, &StateOutput{Action: "release_notes", Notes: release.Notes}), nil
+}
+
+func (s *Server) handleReleaseList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	summaries, err := s.services.Release.ListSummary(ctx, projectID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("リリース一覧取得エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{
+		Action:  "release_list",
+		Message: fmt.Sprintf("リリース一覧 (%d件)", len(summaries)),
+		Result:  summaries,
+	}), nil
+}
+
+func (s *Server) handleStateHygiene(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	report, err := s.services.Hygiene.Run(ctx, service.HygieneRunInput{
+		ProjectID: request.GetString("project_id", ""),
+		DryRun:    request.GetBool("dry_run", false),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("放置State検出エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "hygiene", Result: report}), nil
+}
+
+func (s *Server) handleStateBackup(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result, err := s.services.Backup(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("バックアップエラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{
+		Action:  "backup",
+		Message: fmt.Sprintf("バックアップを作成しました: %s（削除: %d件）", result.Backup.Name, len(result.Removed)),
+		Result:  result,
+	}), nil
 }
@@ -2,8 +2,9 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
 	"github.com/haconeco/project-information-manager/internal/repository"
@@ -15,16 +16,29 @@ import (
 func (s *Server) registerStockTools() {
 	s.mcpServer.AddTool(
 		mcp.NewTool("stock_manage",
-			mcp.WithDescription("プロダクトの静的情報（設計、ルール、方針等）を管理するStock操作ツール。actionで操作を指定。list/searchはサマリ（タイトル・優先度等のみ）を返却、readで全文取得。"),
-			mcp.WithString("action", mcp.Required(), mcp.Description("操作種別: create, read, list, update, search")),
-			mcp.WithString("project_id", mcp.Description("プロジェクトID（create/list/searchで必須）")),
-			mcp.WithString("stock_id", mcp.Description("Stock管理番号（read/updateで必須）")),
-			mcp.WithString("category", mcp.Description("カテゴリ: design, rules, management, architecture, requirement, test（createで必須、listでフィルタ）")),
+			mcp.WithDescription("プロダクトの静的情報（設計、ルール、方針等）を管理するStock操作ツール。actionで操作を指定。list/searchはサマリ（タイトル・優先度等のみ）を返却、readで全文取得。tagsでタグごとの件数、rename_tag/merge_tagsでタグの名前変更・統合。diagnosticsでStockファイルの外部編集を取り込み直し、取り込めなかったファイルを確認。historyで変更履歴（stock.git 有効時、gitのコミット）を取得。deleteは復元可能な削除（保持期間の経過後に完全削除）。"),
+			mcp.WithString("action", mcp.Required(), mcp.Description("操作種別: create, read, list, update, delete, restore, purge, search, tags, rename_tag, merge_tags, direction, diagnostics, history")),
+			mcp.WithString("project_id", mcp.Description("プロジェクトID（create/list/search/tags/rename_tag/merge_tagsで必須。管理番号はプロジェクト内で一意のため、read/update/delete/restore/historyでは複数のプロジェクトに同じ管理番号がある場合に必須（完全削除済みStockのhistoryでも必須）。diagnosticsで省略すると全プロジェクト）")),
+			mcp.WithString("stock_id", mcp.Description("Stock管理番号（read/update/delete/restore/historyで必須。再分類前の管理番号も利用可）")),
+			mcp.WithString("category", mcp.Description("カテゴリ: design, rules, management, architecture, requirement, test, postmortem（createで必須、listでフィルタ。updateで変更すると新しい管理番号を採番し、旧管理番号は転送用に残す）")),
 			mcp.WithString("priority", mcp.Description("優先度: P0, P1, P2, P3（createで必須、update/listでオプション）")),
-			mcp.WithString("title", mcp.Description("タイトル（createで必須）")),
+			mcp.WithString("title", mcp.Description("タイトル（createで必須、updateでオプション）")),
 			mcp.WithString("content", mcp.Description("Markdown形式の本文（createで必須、updateでオプション）")),
+			mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("検索用タグ（create/updateでオプション。updateでは指定したタグで置き換え。結果の suggested_tags は本文から抽出したタグ候補。list/searchでは絞り込み、merge_tagsでは統合元のタグ）")),
+			mcp.WithArray("references", mcp.WithStringItems(), mcp.Description("関連Stock/StateのID（create/updateでオプション。updateでは指定した参照で置き換え）")),
+			mcp.WithString("expected_updated_at", mcp.Description("読み込んだ時点のStockの updated_at（RFC3339、updateでオプション）。指定すると、その後に他のエージェントが更新していた場合は上書きせずにエラーを返す")),
+			mcp.WithString("on_duplicate", mcp.Description("重複の可能性がある既存Stockがある場合の扱い: allow（作成して警告、デフォルト）, reject（作成しない）, merge（既存Stockにタグ・参照・本文を統合）（create用）")),
 			mcp.WithString("query", mcp.Description("検索クエリ（searchで必須）")),
-			mcp.WithNumber("limit", mcp.Description("検索結果の上限数（search用、デフォルト: 10）")),
+			mcp.WithNumber("limit", mcp.Description("1ページあたりの件数（list用、デフォルト: 50。search用、デフォルト: 10。最大: 200。history用、デフォルト: 20）")),
+			mcp.WithString("cursor", mcp.Description("前のページの next_cursor（list/search用。同じ条件で指定する）")),
+			mcp.WithBoolean("include_deleted", mcp.Description("削除済み（復元可能）のStockを含むか（list用、デフォルト: false）")),
+			mcp.WithNumber("since_days", mcp.Description("分析対象とする最近の作業の日数（direction用、デフォルト: 90）")),
+			mcp.WithNumber("threshold", mcp.Description("ゴールに沿っているとみなす類似度の下限（direction用、省略時は手法ごとの既定値）")),
+			mcp.WithString("tag_match", mcp.Description("tags による絞り込みの一致条件: any（いずれかを含む、デフォルト）, all（すべてを含む）（list/search用）")),
+			mcp.WithString("tag", mcp.Description("名前を変更するタグ（rename_tagで必須）")),
+			mcp.WithString("new_tag", mcp.Description("変更後・統合先のタグ（rename_tag/merge_tagsで必須）")),
+			withFormat(),
+			mcp.WithOutputSchema[StockOutput](),
 		),
 		s.handleStockManage,
 	)
@@ -32,6 +46,9 @@ func (s *Server) registerStockTools() {
 
 func (s *Server) handleStockManage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	action := request.GetString("action", "")
+	if result := checkFormat(request); result != nil {
+		return result, nil
+	}
 
 	switch action {
 	case "create":
@@ -42,10 +59,26 @@ func (s *Server) handleStockManage(ctx context.Context, request mcp.CallToolRequ
 		return s.handleStockList(ctx, request)
 	case "update":
 		return s.handleStockUpdate(ctx, request)
+	case "delete":
+		return s.handleStockDelete(ctx, request)
+	case "restore":
+		return s.handleStockRestore(ctx, request)
+	case "purge":
+		return s.handleStockPurge(ctx, request)
 	case "search":
 		return s.handleStockSearch(ctx, request)
+	case "tags":
+		return s.handleStockTags(ctx, request)
+	case "rename_tag", "merge_tags":
+		return s.handleStockRenameTags(ctx, action, request)
+	case "direction":
+		return s.handleStockDirection(ctx, request)
+	case "diagnostics":
+		return s.handleStockDiagnostics(ctx, request)
+	case "history":
+		return s.handleStockHistory(ctx, request)
 	default:
-		return mcp.NewToolResultError(fmt.Sprintf("不明なaction: %s（有効値: create, read, list, update, search）", action)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("不明なaction: %s（有効値: create, read, list, update, delete, restore, purge, search, tags, rename_tag, merge_tags, direction, diagnostics, history）", action)), nil
 	}
 }
 
@@ -53,23 +86,28 @@ func (s *Server) handleStockCreate(ctx context.Context, request mcp.CallToolRequ
 	tags := request.GetStringSlice("tags", nil)
 
 	input := service.CreateStockInput{
-		ProjectID: request.GetString("project_id", ""),
-		Category:  request.GetString("category", ""),
-		Priority:  request.GetString("priority", "P3"),
-		Title:     request.GetString("title", ""),
-		Content:   request.GetString("content", ""),
-		Tags:      tags,
+		ProjectID:   request.GetString("project_id", ""),
+		Category:    request.GetString("category", ""),
+		Priority:    request.GetString("priority", "P3"),
+		Title:       request.GetString("title", ""),
+		Content:     request.GetString("content", ""),
+		Tags:        tags,
+		References:  request.GetStringSlice("references", nil),
+		OnDuplicate: request.GetString("on_duplicate", ""),
 	}
 
-	stock, err := s.services.Stock.Create(ctx, input)
+	result, err := s.services.Stock.CreateWithDuplicateCheck(ctx, input)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stock作成エラー: %v", err)), nil
 	}
 
 	// 作成結果はサマリビューで返却
-	summary := stock.ToSummary()
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(fmt.Sprintf("Stockを作成しました:\n%s", string(data))), nil
+	summary := result.Stock.ToSummary()
+	out := &StockOutput{Action: "create", Message: "Stockを作成しました", Summary: &summary, Merged: result.Merged, Duplicates: result.Duplicates}
+	if result.Merged {
+		out.Message = "既存のStockに統合しました"
+	}
+	return structuredResult(request, out), nil
 }
 
 func (s *Server) handleStockRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -78,14 +116,13 @@ func (s *Server) handleStockRead(ctx context.Context, request mcp.CallToolReques
 		return mcp.NewToolResultError("stock_id は必須です"), nil
 	}
 
-	stock, err := s.services.Stock.Get(ctx, stockID)
+	stock, err := s.services.Stock.Get(ctx, request.GetString("project_id", ""), stockID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stock取得エラー: %v", err)), nil
 	}
 
 	// readはフルビューで返却
-	data, _ := json.MarshalIndent(stock, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StockOutput{Action: "read", Stock: stock}), nil
 }
 
 func (s *Server) handleStockList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -107,15 +144,20 @@ func (s *Server) handleStockList(ctx context.Context, request mcp.CallToolReques
 		}
 		opts.Priority = &p
 	}
+	opts.IncludeDeleted = request.GetBool("include_deleted", false)
+	filter, err := tagFilterFromRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("無効なタグ条件: %v", err)), nil
+	}
+	opts.Tags, opts.TagMatch = filter.Tags, filter.Match
 
 	// サマリビューで返却（Content を含まない）
-	summaries, err := s.services.Stock.ListSummary(ctx, projectID, opts)
+	page, err := s.services.Stock.ListSummaryPage(ctx, projectID, opts, pageRequest(request))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stock一覧取得エラー: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(summaries, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StockOutput{Action: "list", Stocks: page.Stocks, Total: &page.Total, NextCursor: page.NextCursor}), nil
 }
 
 func (s *Server) handleStockUpdate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -126,22 +168,130 @@ func (s *Server) handleStockUpdate(ctx context.Context, request mcp.CallToolRequ
 
 	input := service.UpdateStockInput{}
 
+	if v := request.GetString("title", ""); v != "" {
+		input.Title = &v
+	}
+	if v := request.GetString("category", ""); v != "" {
+		input.Category = &v
+	}
 	if v := request.GetString("content", ""); v != "" {
 		input.Content = &v
 	}
 	if v := request.GetString("priority", ""); v != "" {
 		input.Priority = &v
 	}
+	input.Tags = request.GetStringSlice("tags", nil)
+	input.References = request.GetStringSlice("references", nil)
+	if v := request.GetString("expected_updated_at", ""); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("expected_updated_at は RFC3339 で指定してください: %s", v)), nil
+		}
+		input.ExpectedUpdatedAt = &t
+	}
 
-	stock, err := s.services.Stock.Update(ctx, stockID, input)
+	stock, err := s.services.Stock.Update(ctx, request.GetString("project_id", ""), stockID, input)
+	if errors.Is(err, domain.ErrConflict) {
+		return mcp.NewToolResultError(fmt.Sprintf("Stock更新エラー: %v（%s を read で読み直し、変更を反映してから再度 update してください）", err, stockID)), nil
+	}
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stock更新エラー: %v", err)), nil
 	}
 
 	// 更新結果はサマリビューで返却
 	summary := stock.ToSummary()
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(fmt.Sprintf("Stockを更新しました:\n%s", string(data))), nil
+	out := &StockOutput{Action: "update", Message: "Stockを更新しました", Summary: &summary}
+	if stock.ID != stockID {
+		// 再分類（または再分類前の管理番号での指定）
+		out.PreviousID = stockID
+		out.Message = fmt.Sprintf("Stockを更新しました（管理番号 %s → %s。旧管理番号は新しい管理番号へ転送されます）", stockID, stock.ID)
+	}
+	return structuredResult(request, out), nil
+}
+
+func (s *Server) handleStockDelete(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stockID := request.GetString("stock_id", "")
+	if stockID == "" {
+		return mcp.NewToolResultError("stock_id は必須です"), nil
+	}
+
+	stock, err := s.services.Stock.Delete(ctx, request.GetString("project_id", ""), stockID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Stock削除エラー: %v", err)), nil
+	}
+
+	summary := stock.ToSummary()
+	return structuredResult(request, &StockOutput{
+		Action:  "delete",
+		Message: "Stockを削除しました（restore で復元可能。保持期間の経過後に完全削除されます）",
+		Summary: &summary,
+	}), nil
+}
+
+func (s *Server) handleStockRestore(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stockID := request.GetString("stock_id", "")
+	if stockID == "" {
+		return mcp.NewToolResultError("stock_id は必須です"), nil
+	}
+
+	stock, err := s.services.Stock.Restore(ctx, request.GetString("project_id", ""), stockID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Stock復元エラー: %v", err)), nil
+	}
+
+	summary := stock.ToSummary()
+	return structuredResult(request, &StockOutput{Action: "restore", Message: "Stockを復元しました", Summary: &summary}), nil
+}
+
+func (s *Server) handleStockPurge(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	purged, err := s.services.Stock.PurgeDeleted(ctx, time.Now())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Stock完全削除エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StockOutput{
+		Action:  "purge",
+		Message: fmt.Sprintf("保持期間を過ぎた削除済みStockを完全削除しました（%d件）", len(purged)),
+		Purged:  purged,
+	}), nil
+}
+
+func (s *Server) handleStockDiagnostics(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	watcher := s.services.StockWatcher
+	if watcher == nil {
+		return mcp.NewToolResultError("diagnostics は stock.store が file または mirror の場合のみ利用できます"), nil
+	}
+	projectID := request.GetString("project_id", "")
+
+	result, err := watcher.Reconcile(ctx, projectID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Stockファイル取り込みエラー: %v", err)), nil
+	}
+	diagnostics := watcher.Diagnostics(projectID)
+
+	return structuredResult(request, &StockOutput{
+		Action:      "diagnostics",
+		Message:     fmt.Sprintf("Stockファイルを取り込みました（変更 %d件、不正なファイル %d件）", len(result.Changes), len(diagnostics)),
+		Diagnostics: diagnostics,
+	}), nil
+}
+
+func (s *Server) handleStockHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stockID := request.GetString("stock_id", "")
+	if stockID == "" {
+		return mcp.NewToolResultError("stock_id は必須です"), nil
+	}
+
+	history, err := s.services.Stock.History(ctx, request.GetString("project_id", ""), stockID, request.GetInt("limit", 20))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Stock履歴取得エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StockOutput{
+		Action:  "history",
+		Message: fmt.Sprintf("%s の変更履歴（%d件）", stockID, len(history)),
+		History: history,
+	}), nil
 }
 
 func (s *Server) handleStockSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -153,14 +303,35 @@ func (s *Server) handleStockSearch(ctx context.Context, request mcp.CallToolRequ
 	if projectID == "" {
 		return mcp.NewToolResultError("project_id は必須です"), nil
 	}
-	limit := request.GetInt("limit", 10)
+
+	filter, err := tagFilterFromRequest(request)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("無効なタグ条件: %v", err)), nil
+	}
 
 	// サマリビューで返却
-	summaries, err := s.services.Stock.SearchSummary(ctx, query, limit, projectID)
+	page, err := s.services.Stock.SearchSummaryPage(ctx, query, projectID, filter, pageRequest(request))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Stock検索エラー: %v", err)), nil
 	}
 
-	data, _ := json.MarshalIndent(summaries, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(request, &StockOutput{Action: "search", Stocks: page.Stocks, Total: &page.Total, NextCursor: page.NextCursor}), nil
+}
+
+func (s *Server) handleStockDirection(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	report, err := s.services.Direction.Check(ctx, service.DirectionInput{
+		ProjectID: projectID,
+		Since:     time.Duration(request.GetInt("since_days", 0)) * 24 * time.Hour,
+		Threshold: float32(request.GetFloat("threshold", 0)),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("方向性チェックエラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StockOutput{Action: "direction", Direction: report}), nil
 }
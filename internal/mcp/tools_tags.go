@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+	"github.com/haconeco/project-information-manager/internal/service"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// tagFilterFromRequest はリクエストの tags / tag_match から絞り込み条件を取り出す。
+func tagFilterFromRequest(request mcp.CallToolRequest) (service.TagFilter, error) {
+	match, err := domain.ParseTagMatch(request.GetString("tag_match", ""))
+	if err != nil {
+		return service.TagFilter{}, err
+	}
+	return service.TagFilter{Tags: request.GetStringSlice("tags", nil), Match: match}, nil
+}
+
+// renameTagsFromRequest は rename_tag（tag → new_tag）と merge_tags（tags → new_tag）の変更元を取り出す。
+func renameTagsFromRequest(action string, request mcp.CallToolRequest) ([]string, string, *mcp.CallToolResult) {
+	var from []string
+	if action == "rename_tag" {
+		tag := request.GetString("tag", "")
+		if tag == "" {
+			return nil, "", mcp.NewToolResultError("tag は必須です")
+		}
+		from = []string{tag}
+	} else {
+		from = request.GetStringSlice("tags", nil)
+		if len(from) == 0 {
+			return nil, "", mcp.NewToolResultError("tags は必須です")
+		}
+	}
+	to := request.GetString("new_tag", "")
+	if to == "" {
+		return nil, "", mcp.NewToolResultError("new_tag は必須です")
+	}
+	return from, to, nil
+}
+
+func (s *Server) handleStockTags(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	opts := &repository.StockListOptions{IncludeDeleted: request.GetBool("include_deleted", false)}
+	counts, err := s.services.Stock.TagCounts(ctx, projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("タグ集計エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StockOutput{Action: "tags", TagCounts: counts}), nil
+}
+
+func (s *Server) handleStockRenameTags(ctx context.Context, action string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+	from, to, errResult := renameTagsFromRequest(action, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	result, err := s.services.Stock.RenameTags(ctx, projectID, from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("タグ変更エラー: %v", err)), nil
+	}
+
+	message := fmt.Sprintf("%d件のStockのタグを %s に変更しました", len(result.Updated), to)
+	return structuredResult(request, &StockOutput{Action: action, Message: message, Renamed: result}), nil
+}
+
+func (s *Server) handleStateTags(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+
+	opts := &repository.StateListOptions{IncludeArchived: request.GetBool("include_archived", false)}
+	counts, err := s.services.State.TagCounts(ctx, projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("タグ集計エラー: %v", err)), nil
+	}
+
+	return structuredResult(request, &StateOutput{Action: "tags", TagCounts: counts}), nil
+}
+
+func (s *Server) handleStateRenameTags(ctx context.Context, action string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectID := request.GetString("project_id", "")
+	if projectID == "" {
+		return mcp.NewToolResultError("project_id は必須です"), nil
+	}
+	from, to, errResult := renameTagsFromRequest(action, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	result, err := s.services.State.RenameTags(ctx, projectID, from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("タグ変更エラー: %v", err)), nil
+	}
+
+	message := fmt.Sprintf("%d件のStateのタグを %s に変更しました", len(result.Updated), to)
+	return structuredResult(request, &StateOutput{Action: action, Message: message, Renamed: result}), nil
+}
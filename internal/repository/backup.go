@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+)
+
+// バックアップ（スナップショット）の形式
+const (
+	backupVersion  = 1
+	backupPrefix   = "pim-"          // ローテーションの対象とするスナップショットのディレクトリ名の接頭辞
+	backupManifest = "manifest.json" // 最後に書き込み、スナップショットが完成していることを示す
+	backupStatesDB = "states.db"
+	backupStocks   = "stocks"
+)
+
+// ErrBackupNotFound は指定したバックアップが存在しないことを表す。
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupManifest はスナップショットの内容を記述するマニフェスト。
+type BackupManifest struct {
+	Version       int               `json:"version"`
+	PIMVersion    string            `json:"pim_version"`
+	CreatedAt     time.Time         `json:"created_at"`
+	SchemaVersion int               `json:"schema_version"` // states.db のスキーマバージョン
+	Files         map[string]string `json:"files"`          // スナップショット内のパス → SHA-256
+}
+
+// BackupInfo はスナップショット1件の情報。
+type BackupInfo struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	Files         int       `json:"files"`
+	Size          int64     `json:"size"` // バイト数
+}
+
+// BackupResult はバックアップの結果。
+type BackupResult struct {
+	Backup  BackupInfo `json:"backup"`
+	Removed []string   `json:"removed,omitempty"` // 保持数を超えて削除したスナップショット
+}
+
+// BackupManager はデータディレクトリのオンラインバックアップとローテーションを行う。
+// states.db は VACUUM INTO で複製し、Stockファイルはロックファイル（stocks/.lock）で書き込みを止めた状態で複製するため、
+// pim-server の実行中でも一貫したスナップショットを取得できる。ベクトルインデックスはStock・Stateから再構築できるため含めない。
+type BackupManager struct {
+	db         *sql.DB
+	stocksDir  string
+	backupsDir string
+	retention  int // 保持するスナップショットの数（0以下の場合は削除しない）
+	now        func() time.Time
+}
+
+// NewBackupManager は新しいBackupManagerを生成する。
+func NewBackupManager(db *sql.DB, cfg *config.Config) *BackupManager {
+	return &BackupManager{
+		db:         db,
+		stocksDir:  cfg.StocksDir(),
+		backupsDir: cfg.BackupsDir(),
+		retention:  cfg.Backup.Retention,
+		now:        time.Now,
+	}
+}
+
+// Create はスナップショットを作成し、保持数を超えた古いスナップショットを削除する。
+// スナップショットは一時ディレクトリに作成してからリネームするため、途中で失敗しても不完全なものは残らない。
+func (m *BackupManager) Create(ctx context.Context) (*BackupResult, error) {
+	if err := os.MkdirAll(m.backupsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	now := m.now()
+	name := backupPrefix + now.Format("20060102-150405")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(m.backupsDir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s%s-%d", backupPrefix, now.Format("20060102-150405"), i)
+	}
+	tmp, err := os.MkdirTemp(m.backupsDir, "."+name+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	migrator, err := NewMigrator(m.db)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := migrator.Version(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	// Stockの書き込みを止めている間に states.db と Stockファイルを複製し、両者の時点を揃える
+	lock, err := acquireFileLock(filepath.Join(m.stocksDir, ".lock"))
+	if err != nil {
+		return nil, err
+	}
+	err = m.snapshot(ctx, tmp)
+	lock.release()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{Version: backupVersion, PIMVersion: config.Version, CreatedAt: now.UTC(), SchemaVersion: schema}
+	if manifest.Files, err = checksumTree(tmp); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(tmp, backupManifest), data, 0o644); err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(m.backupsDir, name)
+	if err := os.Rename(tmp, dest); err != nil {
+		return nil, fmt.Errorf("failed to finalize backup: %w", err)
+	}
+	syncDir(m.backupsDir)
+
+	info, err := readBackupInfo(dest)
+	if err != nil {
+		return nil, err
+	}
+	result := &BackupResult{Backup: *info}
+	if result.Removed, err = m.rotate(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// snapshot は states.db と Stockファイルを dir に複製する。Stockのロックを保持した状態で呼び出す。
+func (m *BackupManager) snapshot(ctx context.Context, dir string) error {
+	if _, err := m.db.ExecContext(ctx, "VACUUM INTO ?", filepath.Join(dir, backupStatesDB)); err != nil {
+		return fmt.Errorf("failed to back up states database: %w", err)
+	}
+	return copyStockTree(m.stocksDir, filepath.Join(dir, backupStocks))
+}
+
+// List はスナップショットを新しい順に返す。作成途中・マニフェストの壊れたものは含めない。
+func (m *BackupManager) List() ([]BackupInfo, error) {
+	return ListBackups(m.backupsDir)
+}
+
+// rotate は保持数を超えた古いスナップショットを削除し、削除した名前を返す。
+func (m *BackupManager) rotate() ([]string, error) {
+	if m.retention <= 0 {
+		return nil, nil
+	}
+	backups, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, backup := range backups[min(m.retention, len(backups)):] {
+		if err := os.RemoveAll(backup.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove old backup %s: %w", backup.Name, err)
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// ListBackups は backupsDir のスナップショットを新しい順に返す。
+func ListBackups(backupsDir string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(backupsDir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), backupPrefix) {
+			continue
+		}
+		info, err := readBackupInfo(filepath.Join(backupsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *info)
+	}
+	slices.SortFunc(backups, func(a, b BackupInfo) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Name, a.Name)
+	})
+	return backups, nil
+}
+
+// VerifyBackup はスナップショットのファイルがマニフェストのチェックサムと一致し、
+// states.db が SQLite の整合性チェック（PRAGMA integrity_check）を通ることを確認する。
+// latestSchema より新しいスキーマのスナップショットは、このバイナリでは扱えないためエラーとする。
+func VerifyBackup(ctx context.Context, dir string, latestSchema int) (*BackupInfo, error) {
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Version < 1 || manifest.Version > backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	if manifest.SchemaVersion > latestSchema {
+		return nil, fmt.Errorf("backup schema version %d is newer than this pim supports (%d)", manifest.SchemaVersion, latestSchema)
+	}
+	if _, ok := manifest.Files[backupStatesDB]; !ok {
+		return nil, fmt.Errorf("backup is missing %s", backupStatesDB)
+	}
+	files, err := checksumTree(dir)
+	if err != nil {
+		return nil, err
+	}
+	delete(files, backupManifest)
+	for name, sum := range manifest.Files {
+		got, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("backup file is missing: %s", name)
+		}
+		if got != sum {
+			return nil, fmt.Errorf("backup file checksum mismatch: %s", name)
+		}
+	}
+	for name := range files {
+		if _, ok := manifest.Files[name]; !ok {
+			return nil, fmt.Errorf("unexpected file in backup: %s", name)
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(filepath.Join(dir, backupStatesDB))+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup database: %w", err)
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return nil, fmt.Errorf("failed to check backup database: %w", err)
+	}
+	if result != "ok" {
+		return nil, fmt.Errorf("backup database failed integrity check: %s", result)
+	}
+	return readBackupInfo(dir)
+}
+
+// BackupPath はスナップショット名をディレクトリパスに解決する。存在しない場合は ErrBackupNotFound を返す。
+func BackupPath(cfg *config.Config, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	dir := filepath.Join(cfg.BackupsDir(), name)
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	return dir, nil
+}
+
+// RestoreResult はリストアの結果。
+type RestoreResult struct {
+	Backup   BackupInfo
+	Previous string // リストア前のデータを退避したディレクトリ
+}
+
+// RestoreBackup はスナップショットを検証してから、データディレクトリの states.db と Stockファイルを置き換える。
+// 置き換え前のデータは backups/pre-restore-{日時} に退避する（ローテーションの対象外）。
+// ベクトルインデックスも退避し、次回起動時にStock・Stateから再構築させる。
+// Stockディレクトリのgitリポジトリ（.git）は引き継ぎ、リストアによる変更は pim stock sync で記録する。
+// データディレクトリの排他ロックを取得し、pim-server などがリポジトリを開いている場合は ErrDataDirInUse を返す。
+// 入れ替えの途中で失敗した場合は、退避したデータを元の場所に戻してからエラーを返す。
+func RestoreBackup(ctx context.Context, cfg *config.Config, name string) (*RestoreResult, error) {
+	src, err := BackupPath(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	dataLock, err := tryAcquireFileLock(filepath.Join(cfg.DataDir, dataDirLockFile), false)
+	if err != nil {
+		if errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("%w: stop pim-server and other pim commands using %s before restoring", ErrDataDirInUse, cfg.DataDir)
+		}
+		return nil, err
+	}
+	defer dataLock.release()
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	info, err := VerifyBackup(ctx, src, len(migrations))
+	if err != nil {
+		return nil, err
+	}
+
+	// 検証済みのスナップショットをデータディレクトリ内に複製してから入れ替える（同じファイルシステム内のリネームにする）
+	staging, err := os.MkdirTemp(cfg.DataDir, ".restore-tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	keepStaging := false
+	defer func() {
+		if !keepStaging {
+			os.RemoveAll(staging)
+		}
+	}()
+	if err := copyTree(src, staging, func(rel string) bool { return rel != backupManifest }); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(staging, backupStocks), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lock, err := acquireFileLock(filepath.Join(cfg.StocksDir(), ".lock"))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	previous := filepath.Join(cfg.BackupsDir(), "pre-restore-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(previous, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	dbPath := cfg.StatesDBPath()
+	previousStocks := filepath.Join(previous, backupStocks)
+	stagingStocks := filepath.Join(staging, backupStocks)
+	// WAL・共有メモリのファイルも退避し、リストアしたデータベースに古いWALが適用されないようにする。
+	// Stockのロックファイルは開いたまま退避する（入れ替え後のディレクトリに新しいロックファイルが作られる）
+	moves := []fileMove{
+		{dbPath, filepath.Join(previous, filepath.Base(dbPath))},
+		{dbPath + "-wal", filepath.Join(previous, filepath.Base(dbPath)+"-wal")},
+		{dbPath + "-shm", filepath.Join(previous, filepath.Base(dbPath)+"-shm")},
+		{cfg.VectorsDir(), filepath.Join(previous, filepath.Base(cfg.VectorsDir()))},
+		{cfg.StocksDir(), previousStocks},
+		{filepath.Join(previousStocks, ".git"), filepath.Join(stagingStocks, ".git")},
+		{filepath.Join(previousStocks, ".gitignore"), filepath.Join(stagingStocks, ".gitignore")},
+		{filepath.Join(staging, backupStatesDB), dbPath},
+		{stagingStocks, cfg.StocksDir()},
+	}
+	if err := applyMoves(moves); err != nil {
+		syncDir(cfg.DataDir)
+		if errors.Is(err, errIncompleteRollback) {
+			// 戻せなかったデータが残っている可能性があるため、退避先・作業ディレクトリを削除しない
+			keepStaging = true
+			return nil, fmt.Errorf("failed to restore %s; recover the data manually from %s and %s: %w", name, previous, staging, err)
+		}
+		_ = os.Remove(previous) // 戻しきれた場合は空になっている
+		return nil, fmt.Errorf("failed to restore %s (the previous data was put back): %w", name, err)
+	}
+	syncDir(cfg.DataDir)
+	return &RestoreResult{Backup: *info, Previous: previous}, nil
+}
+
+// copyStockTree はStockディレクトリを複製する。ロックファイル・書き込み途中の一時ファイル・.git 等の隠しファイルは除く。
+func copyStockTree(src, dst string) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return copyTree(src, dst, func(rel string) bool {
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			if strings.HasPrefix(part, ".") {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// copyTree は src 以下のファイルのうち include が true を返すものを dst に複製する。
+func copyTree(src, dst string, include func(rel string) bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !include(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to sync %s: %w", dst, err)
+	}
+	return out.Close()
+}
+
+// checksumTree は dir 以下の全ファイルの SHA-256 を、スラッシュ区切りの相対パスをキーとして返す。
+func checksumTree(dir string) (map[string]string, error) {
+	sums := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(rel)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum backup files: %w", err)
+	}
+	return sums, nil
+}
+
+func readBackupManifest(dir string) (*BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func readBackupInfo(dir string) (*BackupInfo, error) {
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	info := &BackupInfo{
+		Name:          filepath.Base(dir),
+		Path:          dir,
+		CreatedAt:     manifest.CreatedAt,
+		SchemaVersion: manifest.SchemaVersion,
+		Files:         len(manifest.Files),
+	}
+	for name := range manifest.Files {
+		if stat, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+			info.Size += stat.Size()
+		}
+	}
+	return info, nil
+}
+
+// errIncompleteRollback はリストアの失敗後に、退避したデータを元の場所に戻しきれなかったことを表す。
+var errIncompleteRollback = errors.New("rollback is incomplete")
+
+// fileMove はリストアで行うファイル・ディレクトリのリネーム。
+type fileMove struct {
+	from, to string
+}
+
+// applyMoves は moves を順にリネームする。移動元が存在しないものは飛ばす。
+// 途中で失敗した場合は、それまでのリネームを逆順に戻してからエラーを返す。戻せなかったものがある場合は
+// errIncompleteRollback とともにエラーに含める。
+func applyMoves(moves []fileMove) error {
+	var done []fileMove
+	for _, move := range moves {
+		if _, err := os.Lstat(move.from); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(move.from, move.to); err != nil {
+			err = fmt.Errorf("failed to move %s: %w", move.from, err)
+			for i := len(done) - 1; i >= 0; i-- {
+				if rbErr := os.Rename(done[i].to, done[i].from); rbErr != nil {
+					err = errors.Join(err, fmt.Errorf("%w: failed to move %s back to %s: %w", errIncompleteRollback, done[i].to, done[i].from, rbErr))
+				}
+			}
+			return err
+		}
+		done = append(done, move)
+	}
+	return nil
+}
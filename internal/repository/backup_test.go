@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+func TestBackupCreateRotateAndRestore(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{DataDir: t.TempDir(), Backup: config.BackupConfig{Retention: 2}}
+	repos, err := NewRepositories(cfg)
+	if err != nil {
+		t.Fatalf("NewRepositories: %v", err)
+	}
+	defer func() { repos.Close() }()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	repos.Backups.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	stock := &domain.Stock{ID: "STK-REQ-001", ProjectID: "proj-1", Category: domain.CategoryRequirement, Title: "Before backup", Content: "original"}
+	if err := repos.Stock.Create(ctx, stock); err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	state := &domain.State{ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen, Priority: domain.PriorityP2, Title: "Before backup"}
+	if err := repos.State.Create(ctx, state); err != nil {
+		t.Fatalf("create state: %v", err)
+	}
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		result, err := repos.Backups.Create(ctx)
+		if err != nil {
+			t.Fatalf("backup %d: %v", i, err)
+		}
+		names = append(names, result.Backup.Name)
+		if i == 2 && (len(result.Removed) != 1 || result.Removed[0] != names[0]) {
+			t.Fatalf("expected oldest backup to be rotated out, got %v", result.Removed)
+		}
+	}
+	backups, err := repos.Backups.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Name != names[2] || backups[1].Name != names[1] {
+		t.Fatalf("unexpected backups after rotation: %+v", backups)
+	}
+	latest := backups[0]
+	if _, err := os.Stat(filepath.Join(latest.Path, "stocks", "proj-1", "requirement", "STK-REQ-001.json")); err != nil {
+		t.Fatalf("expected stock file in backup: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(latest.Path, "stocks", ".lock")); !os.IsNotExist(err) {
+		t.Fatalf("lock file should not be backed up: %v", err)
+	}
+	if _, err := VerifyBackup(ctx, latest.Path, 1<<10); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// バックアップ後の変更はリストアで巻き戻る
+	stock.Title = "After backup"
+	if err := repos.Stock.Update(ctx, stock); err != nil {
+		t.Fatalf("update stock: %v", err)
+	}
+	state.Status = domain.StatusResolved
+	if err := repos.State.Update(ctx, state); err != nil {
+		t.Fatalf("update state: %v", err)
+	}
+	// リポジトリを開いている（pim-server が起動している）間はリストアしない
+	if _, err := RestoreBackup(ctx, cfg, latest.Name); !errors.Is(err, ErrDataDirInUse) {
+		t.Fatalf("expected ErrDataDirInUse while repositories are open, got %v", err)
+	}
+	repos.Close()
+
+	// リストア中（排他ロックを保持している間）はリポジトリを開かない
+	restoring, err := tryAcquireFileLock(filepath.Join(cfg.DataDir, dataDirLockFile), false)
+	if err != nil {
+		t.Fatalf("lock data dir: %v", err)
+	}
+	if _, err := NewRepositories(cfg); !errors.Is(err, ErrDataDirInUse) {
+		t.Fatalf("expected ErrDataDirInUse while restoring, got %v", err)
+	}
+	restoring.release()
+
+	// 改ざんされたスナップショットはリストアしない
+	tampered := filepath.Join(backups[1].Path, "stocks", "proj-1", "requirement", "STK-REQ-001.json")
+	if err := os.WriteFile(tampered, []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if _, err := RestoreBackup(ctx, cfg, backups[1].Name); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	if _, err := RestoreBackup(ctx, cfg, "../etc"); err == nil {
+		t.Fatalf("expected error for invalid backup name")
+	}
+
+	result, err := RestoreBackup(ctx, cfg, latest.Name)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.Previous, "states.db")); err != nil {
+		t.Fatalf("expected previous states.db to be kept: %v", err)
+	}
+
+	repos, err = NewRepositories(cfg)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, err := repos.Stock.Get(ctx, "proj-1", "STK-REQ-001")
+	if err != nil || got.Title != "Before backup" {
+		t.Fatalf("expected restored stock, got %+v (%v)", got, err)
+	}
+	if restored, err := repos.State.Get(ctx, "STA-TASK-001"); err != nil || restored.Status != domain.StatusOpen {
+		t.Fatalf("expected restored state, got %+v (%v)", restored, err)
+	}
+}
+
+func TestApplyMovesRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := applyMoves([]fileMove{
+		{filepath.Join(dir, "a"), filepath.Join(dir, "a.moved")},
+		{filepath.Join(dir, "missing"), filepath.Join(dir, "missing.moved")},
+		{filepath.Join(dir, "b"), filepath.Join(dir, "no-such-dir", "b")},
+	})
+	if err == nil || errors.Is(err, errIncompleteRollback) {
+		t.Fatalf("expected a rolled back failure, got %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected %s to be put back: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.moved")); !os.IsNotExist(err) {
+		t.Fatalf("expected a.moved to be gone, got %v", err)
+	}
+}
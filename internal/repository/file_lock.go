@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errLockHeld は別のプロセス（またはファイル）が競合するロックを保持していることを表す。
+var errLockHeld = errors.New("lock is held by another process")
+
+// fileLock はロックファイルによるプロセス間のアドバイザリロック。
+// 同じデータディレクトリを複数の pim-server プロセスで共有する場合に、書き込みを直列化する。
+// ロックはプロセス内の排他を兼ねないため、呼び出し側で sync.Mutex と併用する。
+type fileLock struct {
+	f *os.File
+}
+
+// acquireFileLock は path のロックファイルを作成（既存なら再利用）し、排他ロックを取得するまで待つ。
+func acquireFileLock(path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &fileLock{f: f}, nil
+}
+
+// tryAcquireFileLock は path のロックファイルを作成（既存なら再利用）し、待たずにロックを取得する。
+// shared の場合は共有ロックとし、共有ロック同士は同時に取得できる。競合するロックがある場合は errLockHeld を返す。
+func tryAcquireFileLock(path string, shared bool) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := tryLockFile(f, shared); err != nil {
+		f.Close()
+		if errors.Is(err, errLockHeld) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &fileLock{f: f}, nil
+}
+
+// release はロックを解放してロックファイルを閉じる。ロックファイル自体は削除しない
+// （削除すると、待機中の別プロセスが削除前のファイルをロックしてしまうため）。
+func (l *fileLock) release() {
+	_ = unlockFile(l.f)
+	_ = l.f.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package repository
+
+import "os"
+
+// このプラットフォームではプロセス間のロックをサポートしない。プロセス内の排他のみとなるため、
+// データディレクトリを複数プロセスで共有しないこと。
+func lockFile(f *os.File) error { return nil }
+
+func tryLockFile(f *os.File, shared bool) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
 )
@@ -9,29 +10,73 @@ import (
 // StockRepository はStockの永続化を担うインターフェース。
 // ファイルシステムベースの実装を想定する。
 type StockRepository interface {
-	// Create は新しいStockを保存する。
+	// Create は新しいStockを保存する。同じプロジェクトに同じ管理番号がある場合は domain.ErrAlreadyExists を返す。
 	Create(ctx context.Context, stock *domain.Stock) error
 
-	// Get は管理番号でStockを取得する。
-	Get(ctx context.Context, id string) (*domain.Stock, error)
+	// Get はプロジェクトと管理番号でStockを取得する。管理番号はプロジェクト内で一意。
+	// projectID が空の場合は全プロジェクトから探し、複数のプロジェクトに存在する場合は domain.ErrAmbiguousID を返す。
+	Get(ctx context.Context, projectID string, id string) (*domain.Stock, error)
 
-	// Update はStockを更新する。
+	// Update はStockを更新する。対象は stock.ProjectID と stock.ID で特定する。
 	Update(ctx context.Context, stock *domain.Stock) error
 
-	// Delete はStockを削除する。
-	Delete(ctx context.Context, id string) error
+	// UpdateIfUnchanged は保存されているStockの更新日時が expectedUpdatedAt と一致する場合のみ更新する（楽観的排他制御）。
+	// 一致しない場合は domain.ErrConflict を返す。確認と更新は他の書き込みと競合しないよう不可分に行う。
+	UpdateIfUnchanged(ctx context.Context, stock *domain.Stock, expectedUpdatedAt time.Time) error
+
+	// Delete はStockを完全に削除する。ソフトデリートはサービス層で DeletedAt を設定して行う。
+	// projectID の扱いは Get と同じ。
+	Delete(ctx context.Context, projectID string, id string) error
 
-	// List はプロジェクト内のStockを一覧取得する。
+	// List はプロジェクト内のStockを優先度の昇順・更新日時の降順・管理番号の昇順で一覧取得する。
 	List(ctx context.Context, projectID string, opts *StockListOptions) ([]*domain.Stock, error)
+
+	// Count は List の条件に一致するStockの件数を返す（Limit/Offset は無視する）。
+	Count(ctx context.Context, projectID string, opts *StockListOptions) (int, error)
+
+	// TagCounts は List の条件に一致するStockのタグごとの件数を、件数の降順・タグ名の昇順で返す。
+	TagCounts(ctx context.Context, projectID string, opts *StockListOptions) ([]domain.TagCount, error)
+}
+
+// StockFileSource はファイルを正とするStockリポジトリが実装する、ファイルの外部編集を取り込むためのインターフェース。
+// stock.store が file / mirror の場合に利用できる。
+type StockFileSource interface {
+	// Dir はStockファイルを保存するディレクトリを返す。
+	Dir() string
+
+	// Reconcile はプロジェクトのStockファイルを索引と突き合わせ、追加・変更・削除されたStockと
+	// 取り込めなかったファイルの診断情報を返す。projectID が空の場合は全プロジェクトを対象にする。
+	Reconcile(ctx context.Context, projectID string) (*StockReconcileResult, error)
+
+	// WithLock は書き込みのロックを保持したまま fn を実行する。fn に渡すコンテキストで呼び出した操作はロックを取得し直さない。
+	WithLock(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// StockHistory はStockファイルの変更をバージョン管理し、変更履歴を提供するインターフェース。
+// stock.git が有効な場合に利用できる。
+type StockHistory interface {
+	// Record は指定したStockファイルの変更のみを、管理番号・操作・アクターとともに記録する。
+	Record(ctx context.Context, commit StockCommit) error
+
+	// History はStockの変更履歴を新しい順に返す。limit が0以下の場合はすべて返す。
+	History(ctx context.Context, projectID, stockID string, limit int) ([]StockRevision, error)
+
+	// Sync はローカルの未記録の変更を記録し、上流の変更を取り込む。
+	Sync(ctx context.Context) (*StockSyncResult, error)
 }
 
 // StockListOptions はStock一覧取得時のフィルタリングオプション。
+// 削除済みのStockは IncludeDeleted を、再分類によるエイリアスは IncludeAliases を指定した場合のみ含まれる。
+// Tags を指定した場合は TagMatch に従い、いずれか（any）またはすべて（all）のタグを含むものに絞り込む。
 type StockListOptions struct {
-	Category *domain.StockCategory
-	Priority *domain.Priority
-	Tags     []string
-	Limit    int
-	Offset   int
+	Category       *domain.StockCategory
+	Priority       *domain.Priority
+	Tags           []string
+	TagMatch       domain.TagMatch
+	IncludeDeleted bool
+	IncludeAliases bool // エクスポート等、転送用のエイリアスも含める場合に指定する
+	Limit          int
+	Offset         int
 }
 
 // StateRepository はStateの永続化を担うインターフェース。
@@ -46,20 +91,60 @@ type StateRepository interface {
 	// Update はStateを更新する。
 	Update(ctx context.Context, state *domain.State) error
 
-	// List はプロジェクト内のStateを一覧取得する。
+	// List はプロジェクト内のStateを優先度の昇順・更新日時の降順・管理番号の昇順で一覧取得する。
 	List(ctx context.Context, projectID string, opts *StateListOptions) ([]*domain.State, error)
+
+	// Count は List の条件に一致するStateの件数を返す（Limit/Offset は無視する）。
+	Count(ctx context.Context, projectID string, opts *StateListOptions) (int, error)
+
+	// TagCounts は List の条件に一致するStateのタグごとの件数を、件数の降順・タグ名の昇順で返す。
+	TagCounts(ctx context.Context, projectID string, opts *StateListOptions) ([]domain.TagCount, error)
+
+	// Search はタイトル・説明・解決内容・タグを全文検索し、関連度の高い順に返す。opts の絞り込み条件とページングを併用できる。
+	Search(ctx context.Context, projectID string, query string, opts *StateListOptions) ([]StateSearchHit, error)
+}
+
+// StateSearchHit はStateの全文検索結果の1件を表す。
+type StateSearchHit struct {
+	State   *domain.State
+	Snippet string  // 一致箇所を [ ] で囲んだ抜粋
+	Score   float64 // bm25 スコア（小さいほど関連度が高い）
 }
 
 // StateListOptions はState一覧取得時のフィルタリングオプション。
+// Tags を指定した場合は TagMatch に従い、いずれか（any）またはすべて（all）のタグを含むものに絞り込む。
 type StateListOptions struct {
 	Type            *domain.StateType
 	Status          *domain.StateStatus
 	Priority        *domain.Priority
+	Assignee        *string
+	Tags            []string
+	TagMatch        domain.TagMatch
 	IncludeArchived bool
 	Limit           int
 	Offset          int
 }
 
+// StateBatchCreator はStateとReleaseを1つのトランザクションで作成できるStateリポジトリ（states.db を共有するSQLite実装）。
+// プロジェクトの取り込みで、途中で失敗した場合に一部だけが書き込まれないようにするために使う。
+type StateBatchCreator interface {
+	// CreateBatch は states と releases を作成する。いずれかの作成に失敗した場合は何も作成しない。
+	CreateBatch(ctx context.Context, states []*domain.State, releases []*domain.Release) error
+}
+
+// ReleaseRepository はReleaseの永続化を担うインターフェース。
+// SQLiteベースの実装を想定する。
+type ReleaseRepository interface {
+	// Create は新しいReleaseを保存する。
+	Create(ctx context.Context, release *domain.Release) error
+
+	// Get はプロジェクトIDとバージョンでReleaseを取得する。
+	Get(ctx context.Context, projectID string, version string) (*domain.Release, error)
+
+	// List はプロジェクト内のReleaseを作成日時の新しい順に一覧取得する。
+	List(ctx context.Context, projectID string) ([]*domain.Release, error)
+}
+
 // VectorRepository はベクトルインデックスの管理を担うインターフェース。
 // chromem-goベースの実装を想定する。
 type VectorRepository interface {
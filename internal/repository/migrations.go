@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration は states.db のスキーマを1段階進める前方マイグレーション。
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+
+	// post はSQLの実行後に同じトランザクションで行うデータ移行（SQLだけでは書けないもの）。
+	post func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrationHooks はバージョンごとのGoによるデータ移行。
+var migrationHooks = map[int]func(ctx context.Context, tx *sql.Tx) error{
+	5: normalizeStringArrays,
+}
+
+// Migrations はバイナリに埋め込まれたマイグレーションをバージョン順に返す。
+// ファイル名は "{4桁のバージョン}_{名前}.sql" とする。
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, label, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: label, SQL: string(data), post: migrationHooks[version]})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be sequential: expected %d, got %d", i+1, m.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// MigrationStatus はマイグレーションの適用状況。
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // 未適用の場合は nil
+}
+
+// Migrator は schema_version テーブルでスキーマのバージョンを管理し、未適用のマイグレーションを順に適用する。
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	backupDir  string // 空の場合は適用前のバックアップを取らない
+	now        func() time.Time
+}
+
+// NewMigrator は埋め込みマイグレーションを用いるMigratorを生成する。
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, now: time.Now}, nil
+}
+
+// SetBackupDir は既存データへのマイグレーション適用前に、データベースのバックアップを保存するディレクトリを設定する。
+func (m *Migrator) SetBackupDir(dir string) {
+	m.backupDir = dir
+}
+
+// Latest はバイナリが対応する最新のスキーマバージョンを返す。
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version は現在のスキーマバージョンを返す。
+// schema_version テーブルのない既存データベースは、列の有無から適用済みのバージョンを推定する。
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if applied != nil {
+		version := 0
+		for v := range applied {
+			version = max(version, v)
+		}
+		return version, nil
+	}
+	return m.detectLegacyVersion(ctx)
+}
+
+// Status は全マイグレーションの適用状況を返す。
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	legacy := 0
+	if applied == nil {
+		if legacy, err = m.detectLegacyVersion(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &at
+		} else if migration.Version <= legacy {
+			// バージョン管理導入前に適用済み（適用日時は不明）
+			status.AppliedAt = &time.Time{}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// MigrationResult は Up の結果。
+type MigrationResult struct {
+	From    int    // 適用前のバージョン
+	Applied []int  // 適用したバージョン
+	Backup  string // 適用前のバックアップのパス（取得しなかった場合は空）
+}
+
+// Up は未適用のマイグレーションを順に適用する。
+// 既存データに適用する場合は、バックアップディレクトリが設定されていれば事前にバックアップを取る。
+func (m *Migrator) Up(ctx context.Context) (*MigrationResult, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current > m.Latest() {
+		return nil, fmt.Errorf("database schema version %d is newer than supported version %d", current, m.Latest())
+	}
+	result := &MigrationResult{From: current}
+	if current == m.Latest() {
+		return result, nil
+	}
+
+	if current > 0 && m.backupDir != "" {
+		if result.Backup, err = m.backup(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.ensureVersionTable(ctx, current); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range m.migrations[current:] {
+		if err := m.apply(ctx, migration); err != nil {
+			return result, fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		result.Applied = append(result.Applied, migration.Version)
+	}
+	return result, nil
+}
+
+// backup はデータベースを VACUUM INTO でバックアップディレクトリに複製し、そのパスを返す。
+func (m *Migrator) backup(ctx context.Context, version int) (string, error) {
+	if err := os.MkdirAll(m.backupDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	dest := filepath.Join(m.backupDir, fmt.Sprintf("states-v%d-%s.db", version, m.now().Format("20060102-150405")))
+	if _, err := m.db.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return "", fmt.Errorf("failed to back up database before migration: %w", err)
+	}
+	return dest, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return err
+	}
+	if migration.post != nil {
+		if err := migration.post(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		migration.Version, migration.Name, m.now().UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applied は適用済みのバージョンと適用日時を返す。schema_version テーブルがない場合は nil を返す。
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	exists, err := tableExists(ctx, m.db, "schema_version")
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// ensureVersionTable は schema_version テーブルを作成し、バージョン管理導入前に適用済みのマイグレーションを記録する。
+func (m *Migrator) ensureVersionTable(ctx context.Context, current int) error {
+	exists, err := tableExists(ctx, m.db, "schema_version")
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+	CREATE TABLE schema_version (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_version: %w", err)
+	}
+	for _, migration := range m.migrations[:current] {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+			migration.Version, migration.Name, m.now().UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to record legacy migration: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// detectLegacyVersion はバージョン管理導入前のデータベースについて、列の有無から適用済みのバージョンを推定する。
+// 0004 以降は冪等なため、列の追加を伴う 0003 までを判定すれば足りる。
+func (m *Migrator) detectLegacyVersion(ctx context.Context) (int, error) {
+	exists, err := tableExists(ctx, m.db, "states")
+	if err != nil || !exists {
+		return 0, err
+	}
+	columns, err := tableColumns(ctx, m.db, "states")
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case columns["change"]:
+		return 3, nil
+	case columns["assignee"]:
+		return 2, nil
+	default:
+		return 1, nil
+	}
+}
+
+// migrateStatesDB は未適用のマイグレーションを適用する（リポジトリの初期化用。バックアップは取らない）。
+func migrateStatesDB(db *sql.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
+
+func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var name string
+	err := db.QueryRowContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return true, nil
+}
+
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// normalizeStringArrays は旧実装が文字列連結で保存した tags / ref_ids のうち、
+// JSONとして不正なもの（引用符を含むタグ等）を正しいJSON配列に書き換える。
+func normalizeStringArrays(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, tags, ref_ids FROM states WHERE json_valid(tags) = 0 OR json_valid(ref_ids) = 0`)
+	if err != nil {
+		return fmt.Errorf("failed to inspect tags: %w", err)
+	}
+	type fix struct{ id, tags, refs string }
+	var fixes []fix
+	for rows.Next() {
+		var f fix
+		if err := rows.Scan(&f.id, &f.tags, &f.refs); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan tags: %w", err)
+		}
+		fixes = append(fixes, f)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, f := range fixes {
+		tagsJSON, err := marshalStringArray(parseJSONStringArray(f.tags))
+		if err != nil {
+			return err
+		}
+		refsJSON, err := marshalStringArray(parseJSONStringArray(f.refs))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE states SET tags = ?, ref_ids = ? WHERE id = ?`, tagsJSON, refsJSON, f.id); err != nil {
+			return fmt.Errorf("failed to normalize tags of %s: %w", f.id, err)
+		}
+	}
+	return nil
+}
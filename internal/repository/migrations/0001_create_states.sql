@@ -0,0 +1,21 @@
+-- States（タスク・課題・インシデント等）の初期スキーマ
+CREATE TABLE IF NOT EXISTS states (
+	id          TEXT PRIMARY KEY,
+	project_id  TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	status      TEXT NOT NULL DEFAULT 'open',
+	priority    INTEGER NOT NULL DEFAULT 3,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	resolution  TEXT NOT NULL DEFAULT '',
+	tags        TEXT NOT NULL DEFAULT '[]',
+	ref_ids     TEXT NOT NULL DEFAULT '[]',
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL,
+	archived_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_states_project_id ON states(project_id);
+CREATE INDEX IF NOT EXISTS idx_states_status ON states(status);
+CREATE INDEX IF NOT EXISTS idx_states_type ON states(type);
+CREATE INDEX IF NOT EXISTS idx_states_priority ON states(priority);
@@ -0,0 +1,5 @@
+-- 担当者と期日
+ALTER TABLE states ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+ALTER TABLE states ADD COLUMN due_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_states_assignee ON states(assignee);
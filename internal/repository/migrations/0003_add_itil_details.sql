@@ -0,0 +1,4 @@
+-- インシデント・問題・変更の種別固有情報（JSON）
+ALTER TABLE states ADD COLUMN incident TEXT NOT NULL DEFAULT '';
+ALTER TABLE states ADD COLUMN problem TEXT NOT NULL DEFAULT '';
+ALTER TABLE states ADD COLUMN change TEXT NOT NULL DEFAULT '';
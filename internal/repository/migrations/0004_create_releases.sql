@@ -0,0 +1,10 @@
+-- リリース（解決済みの変更・タスクの束とリリースノート）
+CREATE TABLE IF NOT EXISTS releases (
+	project_id TEXT NOT NULL,
+	version    TEXT NOT NULL,
+	title      TEXT NOT NULL DEFAULT '',
+	item_ids   TEXT NOT NULL DEFAULT '[]',
+	notes      TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, version)
+);
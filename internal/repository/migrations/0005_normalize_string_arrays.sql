@@ -0,0 +1,2 @@
+-- 旧実装が文字列連結で保存した tags / ref_ids を正しいJSON配列に書き換える。
+-- JSONとして不正な行の書き換えは normalizeStringArrays（Go）で行う。
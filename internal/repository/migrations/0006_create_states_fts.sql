@@ -0,0 +1,30 @@
+-- States の全文検索インデックス（FTS5）。分かち書きのない日本語でも部分一致できるよう trigram で分割する。
+-- タグはJSON配列の記号に一致しないよう空白区切りの文字列にして登録する。
+-- states には INTEGER PRIMARY KEY がなく VACUUM で rowid が変わりうるため、管理番号（id）で対応付ける。
+CREATE VIRTUAL TABLE IF NOT EXISTS states_fts USING fts5(
+	id UNINDEXED,
+	title,
+	description,
+	resolution,
+	tags,
+	tokenize = 'trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS states_fts_insert AFTER INSERT ON states BEGIN
+	INSERT INTO states_fts (id, title, description, resolution, tags)
+	VALUES (new.id, new.title, new.description, new.resolution, (SELECT group_concat(value, ' ') FROM json_each(CASE WHEN json_valid(new.tags) THEN new.tags END)));
+END;
+
+CREATE TRIGGER IF NOT EXISTS states_fts_delete AFTER DELETE ON states BEGIN
+	DELETE FROM states_fts WHERE id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS states_fts_update AFTER UPDATE OF id, title, description, resolution, tags ON states BEGIN
+	DELETE FROM states_fts WHERE id = old.id;
+	INSERT INTO states_fts (id, title, description, resolution, tags)
+	VALUES (new.id, new.title, new.description, new.resolution, (SELECT group_concat(value, ' ') FROM json_each(CASE WHEN json_valid(new.tags) THEN new.tags END)));
+END;
+
+-- 既存のStateを索引に登録する
+INSERT INTO states_fts (id, title, description, resolution, tags)
+SELECT id, title, description, resolution, (SELECT group_concat(value, ' ') FROM json_each(CASE WHEN json_valid(states.tags) THEN states.tags END)) FROM states;
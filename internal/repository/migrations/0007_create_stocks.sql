@@ -0,0 +1,20 @@
+-- Stock の SQLite ストア。ファイルのStockを正とするミラー（読み取り用インデックス）としても使う。
+-- 絞り込み・並び替えに使う列を持ち、Stock全体は data に JSON で保存する。
+CREATE TABLE IF NOT EXISTS stocks (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	priority INTEGER NOT NULL DEFAULT 2,
+	title TEXT NOT NULL,
+	tags TEXT NOT NULL DEFAULT '[]',
+	redirect_to TEXT NOT NULL DEFAULT '',
+	deleted_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	data TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stocks_project_category ON stocks(project_id, category);
+CREATE INDEX IF NOT EXISTS idx_stocks_project_priority ON stocks(project_id, priority, updated_at DESC, id);
+CREATE INDEX IF NOT EXISTS idx_stocks_category ON stocks(category);
+CREATE INDEX IF NOT EXISTS idx_stocks_priority ON stocks(priority, updated_at DESC, id);
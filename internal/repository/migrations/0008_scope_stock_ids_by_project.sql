@@ -0,0 +1,27 @@
+-- Stockの管理番号をプロジェクト内で一意にする（主キーを (project_id, id) に変更）。
+CREATE TABLE stocks_new (
+	id TEXT NOT NULL,
+	project_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	priority INTEGER NOT NULL DEFAULT 2,
+	title TEXT NOT NULL,
+	tags TEXT NOT NULL DEFAULT '[]',
+	redirect_to TEXT NOT NULL DEFAULT '',
+	deleted_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (project_id, id)
+);
+
+INSERT INTO stocks_new (id, project_id, category, priority, title, tags, redirect_to, deleted_at, created_at, updated_at, data)
+SELECT id, project_id, category, priority, title, tags, redirect_to, deleted_at, created_at, updated_at, data FROM stocks;
+
+DROP TABLE stocks;
+ALTER TABLE stocks_new RENAME TO stocks;
+
+CREATE INDEX idx_stocks_id ON stocks(id);
+CREATE INDEX idx_stocks_project_category ON stocks(project_id, category);
+CREATE INDEX idx_stocks_project_priority ON stocks(project_id, priority, updated_at DESC, id);
+CREATE INDEX idx_stocks_category ON stocks(category);
+CREATE INDEX idx_stocks_priority ON stocks(priority, updated_at DESC, id);
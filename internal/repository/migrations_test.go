@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// openFixtureDB はテスト用フィクスチャのSQLで旧バージョンのデータベースを作成する。
+func openFixtureDB(t *testing.T, fixture string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "states.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if fixture != "" {
+		data, err := os.ReadFile(filepath.Join("testdata", "migrations", fixture))
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		if _, err := db.Exec(string(data)); err != nil {
+			t.Fatalf("load fixture %s: %v", fixture, err)
+		}
+	}
+	return db
+}
+
+func TestMigrationsAreSequential(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 || migrations[0].Name != "create_states" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+}
+
+func TestMigratorUpgradesLegacyFixtures(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		fixture string
+		version int
+		stateID string
+	}{
+		{"legacy_v1.sql", 1, "STA-TASK-001"},
+		{"legacy_v2.sql", 2, "STA-TASK-001"},
+		{"legacy_v3.sql", 3, "STA-INCIDENT-001"},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			db := openFixtureDB(t, tc.fixture)
+			migrator, err := NewMigrator(db)
+			if err != nil {
+				t.Fatalf("new migrator: %v", err)
+			}
+			backupDir := t.TempDir()
+			migrator.SetBackupDir(backupDir)
+
+			if v, err := migrator.Version(ctx); err != nil || v != tc.version {
+				t.Fatalf("expected detected version %d, got %d (%v)", tc.version, v, err)
+			}
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			if statuses[tc.version-1].AppliedAt == nil || statuses[tc.version].AppliedAt != nil {
+				t.Fatalf("unexpected status before upgrade: %+v", statuses)
+			}
+
+			result, err := migrator.Up(ctx)
+			if err != nil {
+				t.Fatalf("up: %v", err)
+			}
+			if len(result.Applied) != migrator.Latest()-tc.version || result.Applied[0] != tc.version+1 || result.From != tc.version {
+				t.Fatalf("unexpected migration result: %+v", result)
+			}
+			if v, err := migrator.Version(ctx); err != nil || v != migrator.Latest() {
+				t.Fatalf("expected latest version after upgrade, got %d (%v)", v, err)
+			}
+
+			// 適用前の状態がバックアップされている
+			backups, _ := filepath.Glob(filepath.Join(backupDir, "*.db"))
+			if len(backups) != 1 || backups[0] != result.Backup {
+				t.Fatalf("expected one backup at %s, got %v", result.Backup, backups)
+			}
+			backup, err := sql.Open("sqlite", backups[0])
+			if err != nil {
+				t.Fatalf("open backup: %v", err)
+			}
+			defer backup.Close()
+			if exists, err := tableExists(ctx, backup, "schema_version"); err != nil || exists {
+				t.Fatalf("expected backup taken before migration, schema_version exists=%t (%v)", exists, err)
+			}
+
+			repo, err := NewSQLiteStateRepository(db)
+			if err != nil {
+				t.Fatalf("repo: %v", err)
+			}
+			state, err := repo.Get(ctx, tc.stateID)
+			if err != nil {
+				t.Fatalf("get migrated state: %v", err)
+			}
+			if tc.version == 1 && (len(state.Tags) != 2 || state.Tags[0] != `say "hi"` || state.References[0] != "STK-DESIGN-001") {
+				t.Fatalf("expected normalized legacy tags, got %q / %q", state.Tags, state.References)
+			}
+			if tc.version == 2 && state.Assignee != "alice" {
+				t.Fatalf("expected assignee preserved, got %q", state.Assignee)
+			}
+			if tc.version == 3 && (state.Incident == nil || state.Incident.Severity != domain.SeveritySEV2) {
+				t.Fatalf("expected incident details preserved, got %+v", state.Incident)
+			}
+
+			releases, err := NewSQLiteReleaseRepository(db)
+			if err != nil {
+				t.Fatalf("release repo: %v", err)
+			}
+			if _, err := releases.List(ctx, "proj-1"); err != nil {
+				t.Fatalf("expected releases table after upgrade: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigratorFreshDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := openFixtureDB(t, "")
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	migrator.SetBackupDir(backupDir)
+	migrator.now = func() time.Time { return time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC) }
+
+	if v, err := migrator.Version(ctx); err != nil || v != 0 {
+		t.Fatalf("expected version 0, got %d (%v)", v, err)
+	}
+	result, err := migrator.Up(ctx)
+	if err != nil || len(result.Applied) != migrator.Latest() || result.Backup != "" {
+		t.Fatalf("expected all migrations applied without backup, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Fatalf("expected no backup for empty database, got %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil || !s.AppliedAt.Equal(time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected status: %+v", s)
+		}
+	}
+
+	if result, err := migrator.Up(ctx); err != nil || len(result.Applied) != 0 {
+		t.Fatalf("expected no-op, got %+v (%v)", result, err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (999, 'future', CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err == nil {
+		t.Fatal("expected error for database newer than binary")
+	}
+}
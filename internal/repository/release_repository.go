@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// SQLiteReleaseRepository はSQLiteベースのReleaseリポジトリ実装。
+type SQLiteReleaseRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteReleaseRepository は新しいSQLiteReleaseRepositoryを生成する。
+func NewSQLiteReleaseRepository(db *sql.DB) (*SQLiteReleaseRepository, error) {
+	if err := migrateStatesDB(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate releases table: %w", err)
+	}
+	return &SQLiteReleaseRepository{db: db}, nil
+}
+
+// Create は新しいReleaseをSQLiteに保存する。
+func (r *SQLiteReleaseRepository) Create(ctx context.Context, release *domain.Release) error {
+	return insertReleaseRow(ctx, r.db, release)
+}
+
+func insertReleaseRow(ctx context.Context, db sqlExecer, release *domain.Release) error {
+	itemsJSON, err := json.Marshal(release.ItemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal release items: %w", err)
+	}
+
+	query := `
+	INSERT INTO releases (project_id, version, title, item_ids, notes, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err = db.ExecContext(ctx, query,
+		release.ProjectID,
+		release.Version,
+		release.Title,
+		string(itemsJSON),
+		release.Notes,
+		release.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return domain.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert release: %w", err)
+	}
+	return nil
+}
+
+// Get はプロジェクトIDとバージョンでReleaseを取得する。
+func (r *SQLiteReleaseRepository) Get(ctx context.Context, projectID string, version string) (*domain.Release, error) {
+	query := `
+	SELECT project_id, version, title, item_ids, notes, created_at
+	FROM releases WHERE project_id = ? AND version = ?
+	`
+	release, err := scanRelease(r.db.QueryRowContext(ctx, query, projectID, version))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return release, err
+}
+
+// List はプロジェクト内のReleaseを作成日時の新しい順に一覧取得する。
+func (r *SQLiteReleaseRepository) List(ctx context.Context, projectID string) ([]*domain.Release, error) {
+	query := `
+	SELECT project_id, version, title, item_ids, notes, created_at
+	FROM releases WHERE project_id = ?
+	ORDER BY created_at DESC, version DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+	defer rows.Close()
+
+	var releases []*domain.Release
+	for rows.Next() {
+		release, err := scanRelease(rows)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	return releases, rows.Err()
+}
+
+func scanRelease(row scanner) (*domain.Release, error) {
+	var (
+		release   domain.Release
+		itemsJSON string
+	)
+	if err := row.Scan(
+		&release.ProjectID,
+		&release.Version,
+		&release.Title,
+		&itemsJSON,
+		&release.Notes,
+		&release.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan release: %w", err)
+	}
+	if err := json.Unmarshal([]byte(itemsJSON), &release.ItemIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal release items: %w", err)
+	}
+	return &release, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+func TestSQLiteReleaseRepository(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "states.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	repo, err := NewSQLiteReleaseRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+
+	base := time.Now().Truncate(time.Second)
+	for i, version := range []string{"1.0.0", "1.1.0"} {
+		release := &domain.Release{
+			ProjectID: "proj-1",
+			Version:   version,
+			Title:     "Release " + version,
+			ItemIDs:   []string{"STA-CHANGE-001", "STA-TASK-002"},
+			Notes:     "# Release " + version,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}
+		if err := repo.Create(ctx, release); err != nil {
+			t.Fatalf("create %s: %v", version, err)
+		}
+	}
+
+	dup := &domain.Release{ProjectID: "proj-1", Version: "1.0.0", CreatedAt: base}
+	if err := repo.Create(ctx, dup); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	got, err := repo.Get(ctx, "proj-1", "1.0.0")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(got.ItemIDs) != 2 || got.Notes != "# Release 1.0.0" {
+		t.Fatalf("unexpected release: %+v", got)
+	}
+	if _, err := repo.Get(ctx, "proj-2", "1.0.0"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	list, err := repo.List(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 || list[0].Version != "1.1.0" {
+		t.Fatalf("expected newest first, got %+v", list)
+	}
+}
+
+func TestSQLiteStateRepositoryCreateBatch(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "states.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	states, err := NewSQLiteStateRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create state repo: %v", err)
+	}
+	releases, err := NewSQLiteReleaseRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create release repo: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	if err := releases.Create(ctx, &domain.Release{ProjectID: "proj-1", Version: "1.0.0", CreatedAt: now}); err != nil {
+		t.Fatalf("create release: %v", err)
+	}
+	newState := func(id string) *domain.State {
+		return &domain.State{ID: id, ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen, Title: id, CreatedAt: now, UpdatedAt: now}
+	}
+
+	// Releaseの作成に失敗した場合は、先に作成したStateも残さない
+	err = states.CreateBatch(ctx, []*domain.State{newState("STA-task-001")}, []*domain.Release{{ProjectID: "proj-1", Version: "1.0.0", CreatedAt: now}})
+	if !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+	if _, err := states.Get(ctx, "STA-task-001"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected state to be rolled back, got %v", err)
+	}
+
+	if err := states.CreateBatch(ctx, []*domain.State{newState("STA-task-001")}, []*domain.Release{{ProjectID: "proj-1", Version: "1.1.0", CreatedAt: now}}); err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if _, err := states.Get(ctx, "STA-task-001"); err != nil {
+		t.Fatalf("get state: %v", err)
+	}
+	if _, err := releases.Get(ctx, "proj-1", "1.1.0"); err != nil {
+		t.Fatalf("get release: %v", err)
+	}
+}
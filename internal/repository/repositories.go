@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 
 	"github.com/haconeco/project-information-manager/internal/config"
 
@@ -12,16 +15,31 @@ import (
 
 // Repositories は全リポジトリを束ねる構造体。
 type Repositories struct {
-	Stock  StockRepository
-	State  StateRepository
-	Vector VectorRepository
+	Stock   StockRepository
+	State   StateRepository
+	Release ReleaseRepository
+	Vector  VectorRepository
 
-	db *sql.DB // closeのために保持
+	// StockFiles はStockファイルの外部編集を取り込むためのソース。stock.store が sqlite の場合は nil。
+	StockFiles StockFileSource
+	// StockHistory はStockファイルの変更履歴（git）。stock.git が無効、または StockFiles が nil の場合は nil。
+	StockHistory StockHistory
+	// Backups はデータディレクトリのスナップショットを作成する。
+	Backups *BackupManager
+
+	db       *sql.DB   // closeのために保持
+	dataLock *fileLock // データディレクトリの共有ロック（closeで解放）
 }
 
-// NewRepositories は設定に基づいて全リポジトリを初期化する。
-func NewRepositories(cfg *config.Config) (*Repositories, error) {
-	// SQLite接続
+// dataDirLockFile はデータディレクトリを使用中であることを示すロックファイル名（DataDir 直下）。
+// pim-server・pim はリポジトリを開いている間は共有ロックを保持し、リストアは排他ロックを取得する。
+const dataDirLockFile = ".pim.lock"
+
+// ErrDataDirInUse はリストア中のデータディレクトリを開こうとした、または使用中のデータディレクトリをリストアしようとしたことを表す。
+var ErrDataDirInUse = errors.New("data directory is in use")
+
+// OpenStatesDB は states.db を開く。スキーマのマイグレーションは行わない。
+func OpenStatesDB(cfg *config.Config) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", cfg.StatesDBPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
@@ -32,6 +50,51 @@ func NewRepositories(cfg *config.Config) (*Repositories, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
 	}
+	return db, nil
+}
+
+// NewRepositories は設定に基づいて全リポジトリを初期化する。
+// states.db に未適用のマイグレーションがあれば、バックアップを取ってから適用する。
+// 開いている間はデータディレクトリの共有ロックを保持し、リストア中の場合は ErrDataDirInUse を返す。
+func NewRepositories(cfg *config.Config) (*Repositories, error) {
+	dataLock, err := tryAcquireFileLock(filepath.Join(cfg.DataDir, dataDirLockFile), true)
+	if err != nil {
+		if errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("%w: %s is being restored", ErrDataDirInUse, cfg.DataDir)
+		}
+		return nil, err
+	}
+	repos, err := openRepositories(cfg)
+	if err != nil {
+		dataLock.release()
+		return nil, err
+	}
+	repos.dataLock = dataLock
+	return repos, nil
+}
+
+// openRepositories は NewRepositories の本体。データディレクトリのロックを保持した状態で呼び出す。
+func openRepositories(cfg *config.Config) (*Repositories, error) {
+	// SQLite接続
+	db, err := OpenStatesDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	migrator.SetBackupDir(cfg.BackupsDir())
+	result, err := migrator.Up(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate states database: %w", err)
+	}
+	if len(result.Applied) > 0 {
+		slog.Info("migrated states database", "from", result.From, "to", migrator.Latest(), "backup", result.Backup)
+	}
 
 	// State リポジトリ
 	stateRepo, err := NewSQLiteStateRepository(db)
@@ -40,10 +103,37 @@ func NewRepositories(cfg *config.Config) (*Repositories, error) {
 		return nil, err
 	}
 
+	// Release リポジトリ（states.db を共有）
+	releaseRepo, err := NewSQLiteReleaseRepository(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Stock リポジトリ（ファイル、またはstates.db を共有するSQLite）
+	stockRepo, err := newStockRepository(context.Background(), cfg, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	repos := &Repositories{
-		Stock: NewFileStockRepository(cfg.StocksDir()),
-		State: stateRepo,
-		db:    db,
+		Stock:   stockRepo,
+		State:   stateRepo,
+		Release: releaseRepo,
+		Backups: NewBackupManager(db, cfg),
+		db:      db,
+	}
+	if files, ok := stockRepo.(StockFileSource); ok {
+		repos.StockFiles = files
+		if cfg.Stock.Git {
+			history, err := NewGitStockHistory(context.Background(), files.Dir())
+			if err != nil {
+				db.Close()
+				return nil, err
+			}
+			repos.StockHistory = history
+		}
 	}
 
 	if cfg.RAG.Enabled {
@@ -58,8 +148,69 @@ func NewRepositories(cfg *config.Config) (*Repositories, error) {
 	return repos, nil
 }
 
+// newStockRepository は stock.store の設定に応じたStockリポジトリを生成する。
+//   - file: ファイルのみ（デフォルト）
+//   - mirror: ファイルを正とし、起動時にSQLiteの索引を再構築して一覧・検索に使う
+//   - sqlite: SQLiteのみ。初回（stocks テーブルが空の場合）はファイルのStockを取り込む
+func newStockRepository(ctx context.Context, cfg *config.Config, db *sql.DB) (StockRepository, error) {
+	files := NewFileStockRepository(cfg.StocksDir())
+	// 旧形式（stocks/{id}.json）のファイルを stocks/{project}/{category}/ に移動する
+	moved, err := files.MigrateLayout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate stock layout: %w", err)
+	}
+	if moved > 0 {
+		slog.Info("moved legacy stock files into per-project layout", "count", moved, "dir", cfg.StocksDir())
+	}
+
+	switch cfg.Stock.Store {
+	case "", "file":
+		return files, nil
+	case "mirror":
+		index, err := NewSQLiteStockRepository(db)
+		if err != nil {
+			return nil, err
+		}
+		mirrored := NewMirroredStockRepository(files, index)
+		count, err := mirrored.Sync(ctx)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("synced stock index from files", "count", count)
+		return mirrored, nil
+	case "sqlite":
+		stocks, err := NewSQLiteStockRepository(db)
+		if err != nil {
+			return nil, err
+		}
+		empty, err := stocks.IsEmpty(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			imported, err := files.loadAll()
+			if err != nil {
+				return nil, err
+			}
+			if len(imported) > 0 {
+				if err := stocks.Replace(ctx, imported); err != nil {
+					return nil, fmt.Errorf("failed to import stocks from files: %w", err)
+				}
+				slog.Info("imported stocks from files into sqlite", "count", len(imported), "dir", cfg.StocksDir())
+			}
+		}
+		return stocks, nil
+	default:
+		return nil, fmt.Errorf("unknown stock store %q (expected file, mirror or sqlite)", cfg.Stock.Store)
+	}
+}
+
 // Close はリポジトリのリソースを解放する。
 func (r *Repositories) Close() error {
+	if r.dataLock != nil {
+		defer r.dataLock.release()
+		r.dataLock = nil
+	}
 	if r.db != nil {
 		return r.db.Close()
 	}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -17,56 +18,66 @@ type SQLiteStateRepository struct {
 
 // NewSQLiteStateRepository は新しいSQLiteStateRepositoryを生成する。
 func NewSQLiteStateRepository(db *sql.DB) (*SQLiteStateRepository, error) {
-	repo := &SQLiteStateRepository{db: db}
-	if err := repo.migrate(); err != nil {
+	if err := migrateStatesDB(db); err != nil {
 		return nil, fmt.Errorf("failed to migrate states table: %w", err)
 	}
-	return repo, nil
-}
-
-func (r *SQLiteStateRepository) migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS states (
-		id          TEXT PRIMARY KEY,
-		project_id  TEXT NOT NULL,
-		type        TEXT NOT NULL,
-		status      TEXT NOT NULL DEFAULT 'open',
-		priority    INTEGER NOT NULL DEFAULT 3,
-		title       TEXT NOT NULL,
-		description TEXT NOT NULL DEFAULT '',
-		resolution  TEXT NOT NULL DEFAULT '',
-		tags        TEXT NOT NULL DEFAULT '[]',
-		ref_ids     TEXT NOT NULL DEFAULT '[]',
-		created_at  DATETIME NOT NULL,
-		updated_at  DATETIME NOT NULL,
-		archived_at DATETIME
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_states_project_id ON states(project_id);
-	CREATE INDEX IF NOT EXISTS idx_states_status ON states(status);
-	CREATE INDEX IF NOT EXISTS idx_states_type ON states(type);
-	CREATE INDEX IF NOT EXISTS idx_states_priority ON states(priority);
-	`
-	_, err := r.db.Exec(query)
-	return err
+	return &SQLiteStateRepository{db: db}, nil
 }
 
 // Create は新しいStateをSQLiteに保存する。
 func (r *SQLiteStateRepository) Create(ctx context.Context, state *domain.State) error {
-	tagsJSON := "[]"
-	if len(state.Tags) > 0 {
-		tagsJSON = `["` + strings.Join(state.Tags, `","`) + `"]`
+	return insertStateRow(ctx, r.db, state)
+}
+
+// CreateBatch は states と releases を1つのトランザクションで作成する。いずれかの作成に失敗した場合は何も作成しない。
+// Releaseも states.db に保存するため、プロジェクトの取り込みでStateとReleaseをまとめて書き込むために使う。
+func (r *SQLiteStateRepository) CreateBatch(ctx context.Context, states []*domain.State, releases []*domain.Release) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	refsJSON := "[]"
-	if len(state.References) > 0 {
-		refsJSON = `["` + strings.Join(state.References, `","`) + `"]`
+	defer tx.Rollback()
+
+	for _, state := range states {
+		if err := insertStateRow(ctx, tx, state); err != nil {
+			return fmt.Errorf("state %s: %w", state.ID, err)
+		}
+	}
+	for _, release := range releases {
+		if err := insertReleaseRow(ctx, tx, release); err != nil {
+			return fmt.Errorf("release %s: %w", release.Version, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func insertStateRow(ctx context.Context, db sqlExecer, state *domain.State) error {
+	tagsJSON, err := marshalStringArray(state.Tags)
+	if err != nil {
+		return err
+	}
+	refsJSON, err := marshalStringArray(state.References)
+	if err != nil {
+		return err
 	}
 
 	query := `
-	INSERT INTO states (id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, created_at, updated_at, archived_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO states (id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, assignee, due_at, incident, problem, change, created_at, updated_at, archived_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	incidentJSON, err := marshalIncident(state.Incident)
+	if err != nil {
+		return err
+	}
+	problemJSON, err := marshalProblem(state.Problem)
+	if err != nil {
+		return err
+	}
+	changeJSON, err := marshalChange(state.Change)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, query,
 		state.ID,
 		state.ProjectID,
 		string(state.Type),
@@ -77,6 +88,11 @@ func (r *SQLiteStateRepository) Create(ctx context.Context, state *domain.State)
 		state.Resolution,
 		tagsJSON,
 		refsJSON,
+		state.Assignee,
+		state.DueAt,
+		incidentJSON,
+		problemJSON,
+		changeJSON,
 		state.CreatedAt,
 		state.UpdatedAt,
 		state.ArchivedAt,
@@ -90,7 +106,7 @@ func (r *SQLiteStateRepository) Create(ctx context.Context, state *domain.State)
 // Get は管理番号でStateを取得する。
 func (r *SQLiteStateRepository) Get(ctx context.Context, id string) (*domain.State, error) {
 	query := `
-	SELECT id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, created_at, updated_at, archived_at
+	SELECT id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, assignee, due_at, incident, problem, change, created_at, updated_at, archived_at
 	FROM states WHERE id = ?
 	`
 	row := r.db.QueryRowContext(ctx, query, id)
@@ -99,21 +115,33 @@ func (r *SQLiteStateRepository) Get(ctx context.Context, id string) (*domain.Sta
 
 // Update はStateを更新する。
 func (r *SQLiteStateRepository) Update(ctx context.Context, state *domain.State) error {
-	tagsJSON := "[]"
-	if len(state.Tags) > 0 {
-		tagsJSON = `["` + strings.Join(state.Tags, `","`) + `"]`
+	tagsJSON, err := marshalStringArray(state.Tags)
+	if err != nil {
+		return err
 	}
-	refsJSON := "[]"
-	if len(state.References) > 0 {
-		refsJSON = `["` + strings.Join(state.References, `","`) + `"]`
+	refsJSON, err := marshalStringArray(state.References)
+	if err != nil {
+		return err
 	}
 
 	query := `
 	UPDATE states
 	SET project_id = ?, type = ?, status = ?, priority = ?, title = ?, description = ?,
-	    resolution = ?, tags = ?, ref_ids = ?, updated_at = ?, archived_at = ?
+	    resolution = ?, tags = ?, ref_ids = ?, assignee = ?, due_at = ?, incident = ?, problem = ?, change = ?, updated_at = ?, archived_at = ?
 	WHERE id = ?
 	`
+	incidentJSON, err := marshalIncident(state.Incident)
+	if err != nil {
+		return err
+	}
+	problemJSON, err := marshalProblem(state.Problem)
+	if err != nil {
+		return err
+	}
+	changeJSON, err := marshalChange(state.Change)
+	if err != nil {
+		return err
+	}
 	result, err := r.db.ExecContext(ctx, query,
 		state.ProjectID,
 		string(state.Type),
@@ -124,6 +152,11 @@ func (r *SQLiteStateRepository) Update(ctx context.Context, state *domain.State)
 		state.Resolution,
 		tagsJSON,
 		refsJSON,
+		state.Assignee,
+		state.DueAt,
+		incidentJSON,
+		problemJSON,
+		changeJSON,
 		state.UpdatedAt,
 		state.ArchivedAt,
 		state.ID,
@@ -144,6 +177,79 @@ func (r *SQLiteStateRepository) Update(ctx context.Context, state *domain.State)
 
 // List はプロジェクト内のStateを一覧取得する。
 func (r *SQLiteStateRepository) List(ctx context.Context, projectID string, opts *StateListOptions) ([]*domain.State, error) {
+	where, args := stateListConditions(projectID, opts)
+
+	query := `
+	SELECT id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, assignee, due_at, incident, problem, change, created_at, updated_at, archived_at
+	FROM states` + where + `
+	ORDER BY priority ASC, updated_at DESC, id ASC
+	`
+
+	if opts != nil && (opts.Limit > 0 || opts.Offset > 0) {
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = -1 // SQLiteでは負のLIMITは無制限
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, max(opts.Offset, 0))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []*domain.State
+	for rows.Next() {
+		state, err := r.scanStateRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// Count は条件に一致するStateの件数を返す。
+func (r *SQLiteStateRepository) Count(ctx context.Context, projectID string, opts *StateListOptions) (int, error) {
+	where, args := stateListConditions(projectID, opts)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM states`+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count states: %w", err)
+	}
+	return count, nil
+}
+
+// TagCounts は条件に一致するStateのタグごとの件数を件数の降順・タグ名の昇順で返す。
+func (r *SQLiteStateRepository) TagCounts(ctx context.Context, projectID string, opts *StateListOptions) ([]domain.TagCount, error) {
+	where, args := stateListConditions(projectID, opts)
+
+	query := `
+	SELECT t.value, COUNT(*) AS cnt
+	FROM (SELECT tags FROM states` + where + `) s, json_each(s.tags) t
+	GROUP BY t.value
+	ORDER BY cnt DESC, t.value ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []domain.TagCount{}
+	for rows.Next() {
+		var c domain.TagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// stateListConditions は一覧取得条件のWHERE句と引数を組み立てる。
+func stateListConditions(projectID string, opts *StateListOptions) (string, []any) {
 	var conditions []string
 	var args []any
 
@@ -165,6 +271,14 @@ func (r *SQLiteStateRepository) List(ctx context.Context, projectID string, opts
 			conditions = append(conditions, "priority = ?")
 			args = append(args, int(*opts.Priority))
 		}
+		if opts.Assignee != nil {
+			conditions = append(conditions, "assignee = ?")
+			args = append(args, *opts.Assignee)
+		}
+		if condition, tagArgs := tagCondition("states.tags", opts.Tags, opts.TagMatch); condition != "" {
+			conditions = append(conditions, condition)
+			args = append(args, tagArgs...)
+		}
 		if !opts.IncludeArchived {
 			conditions = append(conditions, "status != 'archived'")
 		}
@@ -172,39 +286,28 @@ func (r *SQLiteStateRepository) List(ctx context.Context, projectID string, opts
 		conditions = append(conditions, "status != 'archived'")
 	}
 
-	query := `
-	SELECT id, project_id, type, status, priority, title, description, resolution, tags, ref_ids, created_at, updated_at, archived_at
-	FROM states`
-	if len(conditions) > 0 {
-		query += `
-	WHERE ` + strings.Join(conditions, " AND ")
-	}
-	query += `
-	ORDER BY priority ASC, updated_at DESC
-	`
-
-	if opts != nil && opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-		if opts.Offset > 0 {
-			query += fmt.Sprintf(" OFFSET %d", opts.Offset)
-		}
+	if len(conditions) == 0 {
+		return "", args
 	}
+	return `
+	WHERE ` + strings.Join(conditions, " AND "), args
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query states: %w", err)
+// tagCondition はJSON配列のタグ列に対する絞り込み条件と引数を組み立てる。タグ未指定の場合は空文字を返す。
+func tagCondition(column string, tags []string, match domain.TagMatch) (string, []any) {
+	tags = domain.NormalizeTags(tags)
+	if len(tags) == 0 {
+		return "", nil
 	}
-	defer rows.Close()
-
-	var states []*domain.State
-	for rows.Next() {
-		state, err := r.scanStateRows(rows)
-		if err != nil {
-			return nil, err
-		}
-		states = append(states, state)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+	args := make([]any, 0, len(tags))
+	for _, tag := range tags {
+		args = append(args, tag)
 	}
-	return states, rows.Err()
+	if match == domain.TagMatchAll {
+		return fmt.Sprintf("(SELECT COUNT(DISTINCT value) FROM json_each(%s) WHERE value IN (%s)) = %d", column, placeholders, len(tags)), args
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE value IN (%s))", column, placeholders), args
 }
 
 type scanner interface {
@@ -219,6 +322,10 @@ func (r *SQLiteStateRepository) scanState(row *sql.Row) (*domain.State, error) {
 		priority   int
 		tagsJSON   string
 		refsJSON   string
+		dueAt      sql.NullTime
+		incident   string
+		problem    string
+		change     string
 		archivedAt sql.NullTime
 	)
 
@@ -233,6 +340,11 @@ func (r *SQLiteStateRepository) scanState(row *sql.Row) (*domain.State, error) {
 		&state.Resolution,
 		&tagsJSON,
 		&refsJSON,
+		&state.Assignee,
+		&dueAt,
+		&incident,
+		&problem,
+		&change,
 		&state.CreatedAt,
 		&state.UpdatedAt,
 		&archivedAt,
@@ -249,6 +361,18 @@ func (r *SQLiteStateRepository) scanState(row *sql.Row) (*domain.State, error) {
 	state.Priority = domain.Priority(priority)
 	state.Tags = parseJSONStringArray(tagsJSON)
 	state.References = parseJSONStringArray(refsJSON)
+	if dueAt.Valid {
+		state.DueAt = &dueAt.Time
+	}
+	if state.Incident, err = unmarshalIncident(incident); err != nil {
+		return nil, err
+	}
+	if state.Problem, err = unmarshalProblem(problem); err != nil {
+		return nil, err
+	}
+	if state.Change, err = unmarshalChange(change); err != nil {
+		return nil, err
+	}
 	if archivedAt.Valid {
 		state.ArchivedAt = &archivedAt.Time
 	}
@@ -256,7 +380,8 @@ func (r *SQLiteStateRepository) scanState(row *sql.Row) (*domain.State, error) {
 	return &state, nil
 }
 
-func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows) (*domain.State, error) {
+// scanStateRows は一覧取得の行をStateに変換する。extra には State の列の後に続く列の格納先を指定する。
+func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows, extra ...any) (*domain.State, error) {
 	var (
 		state      domain.State
 		typeStr    string
@@ -264,10 +389,14 @@ func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows) (*domain.State, er
 		priority   int
 		tagsJSON   string
 		refsJSON   string
+		dueAt      sql.NullTime
+		incident   string
+		problem    string
+		change     string
 		archivedAt sql.NullTime
 	)
 
-	err := rows.Scan(
+	err := rows.Scan(append([]any{
 		&state.ID,
 		&state.ProjectID,
 		&typeStr,
@@ -278,10 +407,15 @@ func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows) (*domain.State, er
 		&state.Resolution,
 		&tagsJSON,
 		&refsJSON,
+		&state.Assignee,
+		&dueAt,
+		&incident,
+		&problem,
+		&change,
 		&state.CreatedAt,
 		&state.UpdatedAt,
 		&archivedAt,
-	)
+	}, extra...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan state row: %w", err)
 	}
@@ -291,6 +425,18 @@ func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows) (*domain.State, er
 	state.Priority = domain.Priority(priority)
 	state.Tags = parseJSONStringArray(tagsJSON)
 	state.References = parseJSONStringArray(refsJSON)
+	if dueAt.Valid {
+		state.DueAt = &dueAt.Time
+	}
+	if state.Incident, err = unmarshalIncident(incident); err != nil {
+		return nil, err
+	}
+	if state.Problem, err = unmarshalProblem(problem); err != nil {
+		return nil, err
+	}
+	if state.Change, err = unmarshalChange(change); err != nil {
+		return nil, err
+	}
 	if archivedAt.Valid {
 		state.ArchivedAt = &archivedAt.Time
 	}
@@ -298,12 +444,102 @@ func (r *SQLiteStateRepository) scanStateRows(rows *sql.Rows) (*domain.State, er
 	return &state, nil
 }
 
+// marshalIncident はインシデント固有情報をJSON文字列に変換する。nil の場合は空文字列。
+func marshalIncident(d *domain.IncidentDetails) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal incident details: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalIncident はJSON文字列からインシデント固有情報を復元する。
+func unmarshalIncident(s string) (*domain.IncidentDetails, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var d domain.IncidentDetails
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal incident details: %w", err)
+	}
+	return &d, nil
+}
+
+// marshalProblem は問題固有情報をJSON文字列に変換する。nil の場合は空文字列。
+func marshalProblem(d *domain.ProblemDetails) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal problem details: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalProblem はJSON文字列から問題固有情報を復元する。
+func unmarshalProblem(s string) (*domain.ProblemDetails, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var d domain.ProblemDetails
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal problem details: %w", err)
+	}
+	return &d, nil
+}
+
+// marshalChange は変更固有情報をJSON文字列に変換する。nil の場合は空文字列。
+func marshalChange(d *domain.ChangeDetails) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change details: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalChange はJSON文字列から変更固有情報を復元する。
+func unmarshalChange(s string) (*domain.ChangeDetails, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var d domain.ChangeDetails
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal change details: %w", err)
+	}
+	return &d, nil
+}
+
 // parseJSONStringArray は JSON 配列文字列を []string にパースする。
+// marshalStringArray は文字列の配列をJSON配列として保存用に変換する。
+func marshalStringArray(values []string) (string, error) {
+	if len(values) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal string array: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseJSONStringArray は保存されたJSON配列を文字列の配列に変換する。
+// 旧実装が文字列連結で保存した不正なJSONは簡易パースで読み取る。
 func parseJSONStringArray(s string) []string {
 	s = strings.TrimSpace(s)
 	if s == "" || s == "[]" {
 		return nil
 	}
+	var values []string
+	if err := json.Unmarshal([]byte(s), &values); err == nil {
+		return values
+	}
 	// 簡易パース: ["a","b","c"] → [a, b, c]
 	s = strings.TrimPrefix(s, "[")
 	s = strings.TrimSuffix(s, "]")
@@ -311,7 +547,7 @@ func parseJSONStringArray(s string) []string {
 	var result []string
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
-		p = strings.Trim(p, `"`)
+		p = strings.TrimSuffix(strings.TrimPrefix(p, `"`), `"`)
 		if p != "" {
 			result = append(result, p)
 		}
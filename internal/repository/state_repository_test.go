@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,6 +119,19 @@ func TestSQLiteStateRepositoryCRUDAndList(t *testing.T) {
 		t.Fatalf("expected 1 issue, got %d", len(listType))
 	}
 
+	// 並び順は priority ASC, updated_at DESC, id ASC
+	if listAll[0].ID != state2.ID || listAll[1].ID != state1.ID {
+		t.Fatalf("unexpected order: %s, %s", listAll[0].ID, listAll[1].ID)
+	}
+	count, err := repo.Count(ctx, "proj-1", &StateListOptions{IncludeArchived: true})
+	if err != nil || count != 2 {
+		t.Fatalf("expected count 2, got %d (%v)", count, err)
+	}
+	page, err := repo.List(ctx, "proj-1", &StateListOptions{IncludeArchived: true, Offset: 1})
+	if err != nil || len(page) != 1 || page[0].ID != state1.ID {
+		t.Fatalf("expected second state with offset, got %v (%v)", page, err)
+	}
+
 	missing := &domain.State{ID: "STA-MISSING-999"}
 	if err := repo.Update(ctx, missing); err != domain.ErrNotFound {
 		t.Fatalf("expected ErrNotFound on update missing, got %v", err)
@@ -127,6 +141,264 @@ func TestSQLiteStateRepositoryCRUDAndList(t *testing.T) {
 	}
 }
 
+func TestSQLiteStateRepositoryAssigneeAndDueAt(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteRepo(t)
+
+	now := time.Now()
+	due := now.Add(24 * time.Hour).Truncate(time.Second)
+	state := &domain.State{
+		ID:        "STA-TASK-001",
+		ProjectID: "proj-1",
+		Type:      domain.StateTypeTask,
+		Status:    domain.StatusOpen,
+		Priority:  domain.PriorityP1,
+		Title:     "Task",
+		Assignee:  "alice",
+		DueAt:     &due,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := repo.Create(ctx, state); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := repo.Create(ctx, &domain.State{
+		ID: "STA-TASK-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Title: "Unassigned", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create unassigned: %v", err)
+	}
+
+	got, err := repo.Get(ctx, state.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Assignee != "alice" {
+		t.Fatalf("expected assignee alice, got %q", got.Assignee)
+	}
+	if got.DueAt == nil || !got.DueAt.Equal(due) {
+		t.Fatalf("expected due_at %v, got %v", due, got.DueAt)
+	}
+
+	assignee := "alice"
+	list, err := repo.List(ctx, "proj-1", &StateListOptions{Assignee: &assignee})
+	if err != nil {
+		t.Fatalf("list by assignee: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != state.ID {
+		t.Fatalf("expected only alice's state, got %d", len(list))
+	}
+}
+
+func TestSQLiteStateRepositoryIncidentDetails(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteRepo(t)
+
+	now := time.Now()
+	detected := now.Add(-time.Hour).Truncate(time.Second).UTC()
+	state := &domain.State{
+		ID:        "STA-INCIDENT-001",
+		ProjectID: "proj-1",
+		Type:      domain.StateTypeIncident,
+		Status:    domain.StatusOpen,
+		Priority:  domain.PriorityP0,
+		Title:     "Outage",
+		Incident: &domain.IncidentDetails{
+			Severity:   domain.SeveritySEV1,
+			Impact:     "all users",
+			DetectedAt: &detected,
+			Timeline:   []domain.TimelineEntry{{At: detected, Note: "alert fired, \"quoted\""}},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := repo.Create(ctx, state); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, err := repo.Get(ctx, state.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Incident == nil || got.Incident.Severity != domain.SeveritySEV1 || got.Incident.Impact != "all users" {
+		t.Fatalf("unexpected incident details: %+v", got.Incident)
+	}
+	if len(got.Incident.Timeline) != 1 || got.Incident.Timeline[0].Note != `alert fired, "quoted"` {
+		t.Fatalf("unexpected timeline: %+v", got.Incident.Timeline)
+	}
+	if got.Incident.DetectedAt == nil || !got.Incident.DetectedAt.Equal(detected) {
+		t.Fatalf("unexpected detected_at: %v", got.Incident.DetectedAt)
+	}
+}
+
+func TestSQLiteStateRepositoryMigratesLegacyTable(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "states.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	// assignee / due_at 追加前のスキーマ
+	if _, err := db.Exec(`
+	CREATE TABLE states (
+		id          TEXT PRIMARY KEY,
+		project_id  TEXT NOT NULL,
+		type        TEXT NOT NULL,
+		status      TEXT NOT NULL DEFAULT 'open',
+		priority    INTEGER NOT NULL DEFAULT 3,
+		title       TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		resolution  TEXT NOT NULL DEFAULT '',
+		tags        TEXT NOT NULL DEFAULT '[]',
+		ref_ids     TEXT NOT NULL DEFAULT '[]',
+		created_at  DATETIME NOT NULL,
+		updated_at  DATETIME NOT NULL,
+		archived_at DATETIME
+	);
+	INSERT INTO states (id, project_id, type, title, created_at, updated_at)
+	VALUES ('STA-TASK-001', 'proj-1', 'task', 'legacy', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+	`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	repo, err := NewSQLiteStateRepository(db)
+	if err != nil {
+		t.Fatalf("migrate legacy table: %v", err)
+	}
+	got, err := repo.Get(context.Background(), "STA-TASK-001")
+	if err != nil {
+		t.Fatalf("get legacy state: %v", err)
+	}
+	if got.Assignee != "" || got.DueAt != nil {
+		t.Fatalf("expected empty assignee/due_at, got %q / %v", got.Assignee, got.DueAt)
+	}
+}
+
+func TestSQLiteStateRepositoryTags(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteRepo(t)
+
+	now := time.Now()
+	for _, state := range []*domain.State{
+		{ID: "STA-TASK-001", Tags: []string{"api", `say "hi"`, "a,b"}},
+		{ID: "STA-TASK-002", Tags: []string{"api"}},
+		{ID: "STA-TASK-003", Tags: []string{"db"}},
+	} {
+		state.ProjectID = "proj-1"
+		state.Type = domain.StateTypeTask
+		state.Status = domain.StatusOpen
+		state.Priority = domain.PriorityP2
+		state.Title = state.ID
+		state.CreatedAt = now
+		state.UpdatedAt = now
+		if err := repo.Create(ctx, state); err != nil {
+			t.Fatalf("create %s: %v", state.ID, err)
+		}
+	}
+
+	got, err := repo.Get(ctx, "STA-TASK-001")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(got.Tags) != 3 || got.Tags[1] != `say "hi"` || got.Tags[2] != "a,b" {
+		t.Fatalf("expected tags with quotes and commas preserved, got %q", got.Tags)
+	}
+
+	any, err := repo.List(ctx, "proj-1", &StateListOptions{Tags: []string{"a,b", "db"}})
+	if err != nil || len(any) != 2 || any[0].ID != "STA-TASK-001" || any[1].ID != "STA-TASK-003" {
+		t.Fatalf("unexpected any-tag list: %v (%v)", any, err)
+	}
+	all, err := repo.List(ctx, "proj-1", &StateListOptions{Tags: []string{"api", `say "hi"`}, TagMatch: domain.TagMatchAll})
+	if err != nil || len(all) != 1 || all[0].ID != "STA-TASK-001" {
+		t.Fatalf("unexpected all-tag list: %v (%v)", all, err)
+	}
+	if count, err := repo.Count(ctx, "proj-1", &StateListOptions{Tags: []string{"api"}}); err != nil || count != 2 {
+		t.Fatalf("expected 2 states tagged api, got %d (%v)", count, err)
+	}
+
+	counts, err := repo.TagCounts(ctx, "proj-1", nil)
+	if err != nil {
+		t.Fatalf("tag counts: %v", err)
+	}
+	if len(counts) != 4 || counts[0] != (domain.TagCount{Tag: "api", Count: 2}) {
+		t.Fatalf("unexpected tag counts: %+v", counts)
+	}
+}
+
+func TestSQLiteStateRepositorySearch(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteRepo(t)
+
+	now := time.Now()
+	for _, state := range []*domain.State{
+		{ID: "STA-INCIDENT-001", Priority: domain.PriorityP1, Title: "決済APIのタイムアウト", Description: "ピーク時に決済処理が遅延する"},
+		{ID: "STA-TASK-002", Priority: domain.PriorityP0, Title: "ログ整理", Description: "古いログを削除する。決済APIのログは残す"},
+		{ID: "STA-TASK-003", Priority: domain.PriorityP2, Title: "DB移行", Resolution: "100% 完了", Tags: []string{"db"}},
+		{ID: "STA-TASK-004", Priority: domain.PriorityP2, Title: "決済APIの旧版", Status: domain.StatusArchived},
+	} {
+		state.ProjectID = "proj-1"
+		state.Type = domain.StateTypeTask
+		if state.Status == "" {
+			state.Status = domain.StatusOpen
+		}
+		state.CreatedAt = now
+		state.UpdatedAt = now
+		if err := repo.Create(ctx, state); err != nil {
+			t.Fatalf("create %s: %v", state.ID, err)
+		}
+	}
+
+	// 日本語の部分一致。タイトルに一致するものが説明のみに一致するものより上位になる
+	hits, err := repo.Search(ctx, "proj-1", "決済API", nil)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 || hits[0].State.ID != "STA-INCIDENT-001" || hits[1].State.ID != "STA-TASK-002" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Score >= hits[1].Score {
+		t.Fatalf("expected title match ranked higher, got scores %v / %v", hits[0].Score, hits[1].Score)
+	}
+	if !strings.Contains(hits[1].Snippet, "[決済API]") {
+		t.Fatalf("expected highlighted snippet, got %q", hits[1].Snippet)
+	}
+	all, err := repo.Search(ctx, "proj-1", "決済API", &StateListOptions{IncludeArchived: true})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("expected archived state included, got %+v (%v)", all, err)
+	}
+	if page, err := repo.Search(ctx, "proj-1", "決済API", &StateListOptions{IncludeArchived: true, Limit: 1, Offset: 2}); err != nil || len(page) != 1 || page[0].State.ID != all[2].State.ID {
+		t.Fatalf("unexpected last page: %+v (%v)", page, err)
+	}
+
+	// 3文字未満のクエリは部分一致で検索する（ワイルドカードはエスケープする）
+	short, err := repo.Search(ctx, "proj-1", "0%", nil)
+	if err != nil || len(short) != 1 || short[0].State.ID != "STA-TASK-003" || short[0].Snippet != "10[0%] 完了" {
+		t.Fatalf("unexpected short query hits: %+v (%v)", short, err)
+	}
+	if none, err := repo.Search(ctx, "proj-1", `"`, nil); err != nil || len(none) != 0 {
+		t.Fatalf("expected no hits for quote, got %+v (%v)", none, err)
+	}
+
+	// 更新・削除がトリガーで索引に反映される
+	updated, err := repo.Get(ctx, "STA-TASK-002")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	updated.Description = "古いログを削除する"
+	if err := repo.Update(ctx, updated); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := repo.db.ExecContext(ctx, `DELETE FROM states WHERE id = ?`, "STA-INCIDENT-001"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if hits, err := repo.Search(ctx, "proj-1", "決済API", nil); err != nil || len(hits) != 0 {
+		t.Fatalf("expected index synced after update and delete, got %+v (%v)", hits, err)
+	}
+}
+
 func TestParseJSONStringArray(t *testing.T) {
 	if got := parseJSONStringArray(""); got != nil {
 		t.Fatalf("expected nil for empty string, got %v", got)
@@ -138,6 +410,9 @@ func TestParseJSONStringArray(t *testing.T) {
 	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
 		t.Fatalf("unexpected parse result: %v", got)
 	}
+	if got := parseJSONStringArray(`["a,b","c"]`); len(got) != 2 || got[0] != "a,b" {
+		t.Fatalf("expected comma inside tag preserved, got %v", got)
+	}
 }
 
 func ptrStateType(v domain.StateType) *domain.StateType {
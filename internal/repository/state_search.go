@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ftsMinQueryRunes は trigram の全文索引で検索できるクエリの最小文字数。これより短いクエリは LIKE で検索する。
+	ftsMinQueryRunes = 3
+	// snippetRunes はスニペットに含める一致箇所の前後の文字数。
+	snippetRunes = 24
+)
+
+// stateSearchColumns は states_fts と結合した際に曖昧にならないよう修飾したStateの列。
+const stateSearchColumns = `states.id, states.project_id, states.type, states.status, states.priority, states.title, states.description, states.resolution, states.tags, states.ref_ids, states.assignee, states.due_at, states.incident, states.problem, states.change, states.created_at, states.updated_at, states.archived_at`
+
+// Search はタイトル・説明・解決内容・タグを全文検索し、関連度（bm25）の高い順に返す。
+// 3文字以上のクエリは FTS5 の全文索引を用い、それより短いクエリは部分一致で検索する（スコアは 0）。
+func (r *SQLiteStateRepository) Search(ctx context.Context, projectID string, query string, opts *StateListOptions) ([]StateSearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	where, args := stateListConditions(projectID, opts)
+
+	useIndex := utf8.RuneCountInString(query) >= ftsMinQueryRunes
+	var match, score, snippet string
+	if useIndex {
+		match = "states_fts MATCH ?"
+		args = append(args, ftsPhrase(query))
+		// 列ごとの重み: id（索引対象外）, title, description, resolution, tags
+		score = "bm25(states_fts, 0.0, 10.0, 4.0, 2.0, 6.0)"
+		snippet = "snippet(states_fts, -1, '[', ']', '…', 16)"
+	} else {
+		pattern := "%" + escapeLike(query) + "%"
+		match = `(states_fts.title LIKE ? ESCAPE '\' OR states_fts.description LIKE ? ESCAPE '\' OR states_fts.resolution LIKE ? ESCAPE '\' OR states_fts.tags LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern, pattern, pattern)
+		score = "0.0"
+		snippet = "''"
+	}
+	if where == "" {
+		where = `
+	WHERE ` + match
+	} else {
+		where += " AND " + match
+	}
+
+	sqlQuery := `
+	SELECT ` + stateSearchColumns + `, ` + snippet + `, ` + score + ` AS score
+	FROM states_fts JOIN states ON states.id = states_fts.id` + where + `
+	ORDER BY score ASC, states.priority ASC, states.updated_at DESC, states.id ASC
+	`
+	if opts != nil && (opts.Limit > 0 || opts.Offset > 0) {
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = -1 // SQLiteでは負のLIMITは無制限
+		}
+		sqlQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, max(opts.Offset, 0))
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search states: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []StateSearchHit
+	for rows.Next() {
+		var hit StateSearchHit
+		state, err := r.scanStateRows(rows, &hit.Snippet, &hit.Score)
+		if err != nil {
+			return nil, err
+		}
+		hit.State = state
+		if !useIndex {
+			hit.Snippet = likeSnippet(query, state.Title, state.Description, state.Resolution, strings.Join(state.Tags, " "))
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// ftsPhrase はクエリを FTS5 のフレーズとして扱えるよう引用符で囲む（演算子として解釈させない）。
+func ftsPhrase(query string) string {
+	return `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+}
+
+// escapeLike は LIKE のワイルドカードをエスケープする。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// likeSnippet は最初に一致したフィールドから一致箇所を [ ] で囲んだ抜粋を作る（FTS5 の snippet と同じ形式）。
+func likeSnippet(query string, fields ...string) string {
+	q := []rune(strings.ToLower(query))
+	for _, field := range fields {
+		runes := []rune(field)
+		lower := []rune(strings.ToLower(field))
+		if len(lower) != len(runes) {
+			continue // 小文字化で文字数が変わる場合は位置を対応付けられない
+		}
+		idx := runeIndex(lower, q)
+		if idx < 0 {
+			continue
+		}
+		start := max(idx-snippetRunes, 0)
+		end := min(idx+len(q)+snippetRunes, len(runes))
+		var b strings.Builder
+		if start > 0 {
+			b.WriteString("…")
+		}
+		b.WriteString(string(runes[start:idx]))
+		b.WriteString("[" + string(runes[idx:idx+len(q)]) + "]")
+		b.WriteString(string(runes[idx+len(q) : end]))
+		if end < len(runes) {
+			b.WriteString("…")
+		}
+		return b.String()
+	}
+	return ""
+}
+
+func runeIndex(s []rune, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
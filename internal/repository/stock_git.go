@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stockGitIgnore はStockディレクトリのgitで管理しないファイル。索引は各環境でファイルから再構築する。
+const stockGitIgnore = `# pim が管理する作業ファイル（Stockファイルから再構築できるため管理しない）
+.lock
+index.json
+.*.tmp-*
+`
+
+// gitCommitterName は pim が作成するコミットのコミッター名。作成者（Author）は操作したアクター。
+const gitCommitterName = "pim"
+
+// StockCommit はStockの変更を記録するコミットの内容。
+type StockCommit struct {
+	ProjectID string
+	StockID   string // プロジェクト全体の変更（import）では空
+	Action    string // create, update, delete, restore, purge, import 等
+	Actor     string // 操作したエージェント・ユーザー（空の場合は pim）
+	// Paths は記録するStockファイル（Stockディレクトリからの相対パス、StockFilePath）。
+	// 再分類では旧・新の両方を指定する。それ以外のファイルの未記録の変更はコミットに含めない。
+	Paths []string
+}
+
+// StockRevision はStockの変更履歴の1件（gitのコミット）。
+type StockRevision struct {
+	Commit  string    `json:"commit"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Action  string    `json:"action,omitempty"` // pim 以外のコミット（手作業・git pull）では空
+	Subject string    `json:"subject"`
+}
+
+// StockSyncResult は pim stock sync の結果。
+type StockSyncResult struct {
+	Upstream  string   // 取り込み元のブランチ（未設定の場合は空で、pull は行わない）
+	Committed bool     // 未コミットの変更をコミットしたか
+	Before    string   // pull 前のコミット
+	After     string   // pull 後のコミット
+	Conflicts []string // 競合したファイル（競合した場合は pull を取り消す）
+}
+
+// GitStockHistory はStockディレクトリをgitリポジトリとして扱い、変更ごとにコミットして履歴を記録する。
+// gitコマンドを実行し、リモートは必須としない。
+type GitStockHistory struct {
+	dir string
+
+	mu sync.Mutex // gitコマンドの実行を直列化する
+}
+
+// NewGitStockHistory はStockディレクトリをgitリポジトリとして初期化し（既にリポジトリの場合はそのまま使う）、
+// 新しいGitStockHistoryを生成する。
+func NewGitStockHistory(ctx context.Context, dir string) (*GitStockHistory, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required for the git-backed stock store: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	h := &GitStockHistory{dir: dir}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if _, err := h.git(ctx, nil, "init", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+	ignorePath := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
+		if err := writeFileAtomic(ignorePath, []byte(stockGitIgnore), 0o644); err != nil {
+			return nil, err
+		}
+		if err := h.commitAll(ctx, ".", "stock: initialize stock repository", "", ""); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// Record は commit.Paths のStockファイルの変更のみをコミットする。変更がない場合は何もしない。
+// 手作業の編集など他のファイルの未記録の変更は含めない（pim stock sync で記録する）。
+// コミットメッセージには管理番号・操作・アクターをトレーラーとして記録する。
+// 書き込みとコミットの間に他の書き込みが入らないよう、Stockリポジトリのロック（WithLock）の中で呼び出す。
+func (h *GitStockHistory) Record(ctx context.Context, commit StockCommit) error {
+	if !ValidPathElement(commit.ProjectID) {
+		return fmt.Errorf("invalid project id for stock commit: %q", commit.ProjectID)
+	}
+	for _, path := range commit.Paths {
+		if !filepath.IsLocal(path) || strings.Split(filepath.ToSlash(path), "/")[0] != commit.ProjectID {
+			return fmt.Errorf("invalid path for stock commit: %q", path)
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// 取り込み等、プロジェクト全体の変更では管理番号を省略する
+	subject, trailers := commit.ProjectID, ""
+	if commit.StockID != "" {
+		subject += "/" + commit.StockID
+		trailers = "Stock-ID: " + commit.StockID + "\n"
+	}
+	message := fmt.Sprintf("stock: %s %s\n\n%sProject: %s\nAction: %s\nActor: %s\n",
+		commit.Action, subject, trailers, commit.ProjectID, commit.Action, actorOrDefault(commit.Actor))
+	return h.commitPaths(ctx, commit.Paths, message, commit.Actor, commit.Action)
+}
+
+// History はStockの変更履歴を新しい順に返す。手作業や git pull で取り込んだコミットも含む。
+// limit が0以下の場合はすべて返す。
+func (h *GitStockHistory) History(ctx context.Context, projectID, stockID string, limit int) ([]StockRevision, error) {
+	if !ValidPathElement(projectID) || !ValidPathElement(stockID) {
+		return nil, fmt.Errorf("invalid project id or stock id: %q / %q", projectID, stockID)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// フィールドは \x1f、コミットは \x1e で区切る
+	args := []string{"log", "--format=%H%x1f%an%x1f%aI%x1f%s%x1f%(trailers:key=Action,valueonly,separator=%x2C)%x1e"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	// カテゴリの変更でファイルが移動しても追えるよう、カテゴリのディレクトリはワイルドカードにする
+	args = append(args, "--", fmt.Sprintf(":(glob)%s/*/%s.json", projectID, stockID))
+	out, err := h.git(ctx, nil, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return []StockRevision{}, nil
+		}
+		return nil, err
+	}
+
+	revisions := []StockRevision{}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) < 5 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		revisions = append(revisions, StockRevision{
+			Commit:  fields[0],
+			Author:  fields[1],
+			Date:    date,
+			Subject: fields[3],
+			Action:  strings.TrimSpace(fields[4]),
+		})
+	}
+	return revisions, nil
+}
+
+// Sync は未コミットの変更（手作業の編集）をコミットしてから、上流ブランチの変更を取り込む。
+// 上流ブランチが設定されていない場合は pull を行わない。競合した場合は取り込みを取り消し、競合したファイルを返す。
+func (h *GitStockHistory) Sync(ctx context.Context) (*StockSyncResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := &StockSyncResult{}
+	before, _ := h.git(ctx, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err := h.commitAll(ctx, ".", "stock: record local changes\n\nAction: sync\n", "", "sync"); err != nil {
+		return nil, err
+	}
+	result.Before, _ = h.git(ctx, nil, "rev-parse", "HEAD")
+	result.Committed = before != result.Before
+
+	upstream, err := h.git(ctx, nil, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		result.After = result.Before
+		return result, nil
+	}
+	result.Upstream = upstream
+
+	if _, err := h.git(ctx, gitIdentityEnv(""), "pull", "--no-rebase", "--no-edit", "--quiet"); err != nil {
+		conflicts, _ := h.git(ctx, nil, "diff", "--name-only", "--diff-filter=U")
+		if conflicts == "" {
+			return nil, err
+		}
+		result.Conflicts = strings.Split(conflicts, "\n")
+		if _, abortErr := h.git(ctx, nil, "merge", "--abort"); abortErr != nil {
+			return result, fmt.Errorf("failed to abort conflicting merge: %w", abortErr)
+		}
+	}
+	result.After, _ = h.git(ctx, nil, "rev-parse", "HEAD")
+	return result, nil
+}
+
+// commitAll は pathspec 以下の変更をすべてステージしてコミットする。ステージした変更がない場合は何もしない。
+func (h *GitStockHistory) commitAll(ctx context.Context, pathspec, message, actor, action string) error {
+	if _, err := h.git(ctx, nil, "add", "--all", "--", pathspec); err != nil {
+		return err
+	}
+	if _, err := h.git(ctx, nil, "diff", "--cached", "--quiet"); err == nil {
+		return nil // 変更なし
+	}
+	if _, err := h.git(ctx, gitIdentityEnv(actor), "commit", "--quiet", "--no-verify", "-m", message); err != nil {
+		return fmt.Errorf("failed to commit stock %s: %w", action, err)
+	}
+	return nil
+}
+
+// commitPaths は paths の変更のみをステージしてコミットする（git commit --only）。
+// 存在しないファイルは削除として扱い、追跡されていない場合は対象から外す。対象の変更がない場合は何もしない。
+func (h *GitStockHistory) commitPaths(ctx context.Context, paths []string, message, actor, action string) error {
+	paths = slashPaths(paths)
+	if len(paths) == 0 {
+		return nil
+	}
+	out, err := h.git(ctx, nil, append([]string{"ls-files", "-z", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	tracked := map[string]bool{}
+	for _, path := range strings.Split(out, "\x00") {
+		tracked[path] = true
+	}
+	var targets []string
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(h.dir, path)); err == nil || tracked[path] {
+			targets = append(targets, path)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if _, err := h.git(ctx, nil, append([]string{"add", "--all", "--"}, targets...)...); err != nil {
+		return err
+	}
+	if _, err := h.git(ctx, nil, append([]string{"diff", "--cached", "--quiet", "--"}, targets...)...); err == nil {
+		return nil // 変更なし
+	}
+	args := append([]string{"commit", "--quiet", "--no-verify", "--only", "-m", message, "--"}, targets...)
+	if _, err := h.git(ctx, gitIdentityEnv(actor), args...); err != nil {
+		return fmt.Errorf("failed to commit stock %s: %w", action, err)
+	}
+	return nil
+}
+
+// slashPaths は重複を除いたgitのパス（/ 区切り）を返す。
+func slashPaths(paths []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, path := range paths {
+		path = filepath.ToSlash(path)
+		if path != "" && !seen[path] {
+			seen[path] = true
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// git はStockディレクトリでgitコマンドを実行し、標準出力を返す（前後の空白は除く）。
+func (h *GitStockHistory) git(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = h.dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return strings.TrimSpace(stdout.String()), fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(stdout.String()), fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitIdentityEnv はコミットの作成者を actor、コミッターを pim とする環境変数を返す。
+// gitのユーザー設定がない環境でもコミットできるようにする。
+func gitIdentityEnv(actor string) []string {
+	name := actorOrDefault(actor)
+	email := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '<' || r == '>' || r == '@' {
+			return '-'
+		}
+		return r
+	}, strings.ToLower(name))
+	return []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email + "@pim.local",
+		"GIT_COMMITTER_NAME=" + gitCommitterName,
+		"GIT_COMMITTER_EMAIL=" + gitCommitterName + "@pim.local",
+	}
+}
+
+func actorOrDefault(actor string) string {
+	if actor == "" {
+		return gitCommitterName
+	}
+	return actor
+}
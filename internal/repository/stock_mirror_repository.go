@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// MirroredStockRepository はファイルのStockを正とし、SQLiteのインデックスをミラーとして読み取りに使うStockリポジトリ。
+// 書き込みはファイルに行ってからインデックスへ反映し、一覧・件数・タグ集計はインデックスから取得する。
+// 管理番号による取得はファイルから直接読む。
+type MirroredStockRepository struct {
+	source *FileStockRepository
+	index  *SQLiteStockRepository
+}
+
+// NewMirroredStockRepository は新しいMirroredStockRepositoryを生成する。
+// インデックスの内容は Sync を呼ぶまでファイルと一致しない場合がある。
+func NewMirroredStockRepository(source *FileStockRepository, index *SQLiteStockRepository) *MirroredStockRepository {
+	return &MirroredStockRepository{source: source, index: index}
+}
+
+// Sync はファイルのStockをすべて読み込み、インデックスを再構築する。反映した件数を返す。
+// ファイルを直接編集した場合や、インデックスへの反映に失敗した場合に呼び出す。
+func (r *MirroredStockRepository) Sync(ctx context.Context) (int, error) {
+	stocks, err := r.source.loadAll()
+	if err != nil {
+		return 0, err
+	}
+	if err := r.index.Replace(ctx, stocks); err != nil {
+		return 0, fmt.Errorf("failed to rebuild stock index: %w", err)
+	}
+	return len(stocks), nil
+}
+
+// WithLock はファイルへの書き込みのロックを保持したまま fn を実行する。
+func (r *MirroredStockRepository) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.source.WithLock(ctx, fn)
+}
+
+// Dir はStockファイルを保存するディレクトリを返す。
+func (r *MirroredStockRepository) Dir() string {
+	return r.source.Dir()
+}
+
+// Reconcile はファイルの外部編集を index.json に取り込み、変更されたStockをSQLiteのインデックスへ反映する。
+func (r *MirroredStockRepository) Reconcile(ctx context.Context, projectID string) (*StockReconcileResult, error) {
+	result, err := r.source.Reconcile(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range result.Changes {
+		if change.Op == StockDeleted {
+			r.mirror(change.ID, func() error {
+				if err := r.index.Delete(ctx, change.ProjectID, change.ID); err != domain.ErrNotFound {
+					return err
+				}
+				return nil
+			})
+			continue
+		}
+		r.mirrorUpdate(ctx, change.Stock)
+	}
+	return result, nil
+}
+
+// Create はStockをファイルに保存し、インデックスへ反映する。
+func (r *MirroredStockRepository) Create(ctx context.Context, stock *domain.Stock) error {
+	if err := r.source.Create(ctx, stock); err != nil {
+		return err
+	}
+	r.mirror(stock.ID, func() error {
+		err := r.index.Create(ctx, stock)
+		if err == domain.ErrAlreadyExists {
+			return r.index.Update(ctx, stock)
+		}
+		return err
+	})
+	return nil
+}
+
+// Get は管理番号でStockをファイルから取得する。
+func (r *MirroredStockRepository) Get(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	return r.source.Get(ctx, projectID, id)
+}
+
+// Update はStockをファイルに保存し、インデックスへ反映する。
+func (r *MirroredStockRepository) Update(ctx context.Context, stock *domain.Stock) error {
+	if err := r.source.Update(ctx, stock); err != nil {
+		return err
+	}
+	r.mirrorUpdate(ctx, stock)
+	return nil
+}
+
+// UpdateIfUnchanged はファイルのStockの更新日時が expectedUpdatedAt と一致する場合のみ更新し、インデックスへ反映する。
+// 更新日時の確認は正となるファイルに対して行う。
+func (r *MirroredStockRepository) UpdateIfUnchanged(ctx context.Context, stock *domain.Stock, expectedUpdatedAt time.Time) error {
+	if err := r.source.UpdateIfUnchanged(ctx, stock, expectedUpdatedAt); err != nil {
+		return err
+	}
+	r.mirrorUpdate(ctx, stock)
+	return nil
+}
+
+// Delete はStockのファイルを削除し、インデックスからも削除する。
+func (r *MirroredStockRepository) Delete(ctx context.Context, projectID string, id string) error {
+	stock, err := r.source.Get(ctx, projectID, id)
+	if err != nil {
+		return err
+	}
+	if err := r.source.Delete(ctx, stock.ProjectID, id); err != nil {
+		return err
+	}
+	r.mirror(id, func() error {
+		if err := r.index.Delete(ctx, stock.ProjectID, id); err != domain.ErrNotFound {
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+// List はインデックスからStockを一覧取得する。
+func (r *MirroredStockRepository) List(ctx context.Context, projectID string, opts *StockListOptions) ([]*domain.Stock, error) {
+	return r.index.List(ctx, projectID, opts)
+}
+
+// Count はインデックスから条件に一致するStockの件数を返す。
+func (r *MirroredStockRepository) Count(ctx context.Context, projectID string, opts *StockListOptions) (int, error) {
+	return r.index.Count(ctx, projectID, opts)
+}
+
+// TagCounts はインデックスから条件に一致するStockのタグごとの件数を返す。
+func (r *MirroredStockRepository) TagCounts(ctx context.Context, projectID string, opts *StockListOptions) ([]domain.TagCount, error) {
+	return r.index.TagCounts(ctx, projectID, opts)
+}
+
+// mirrorUpdate は更新したStockをインデックスへ反映する。インデックスにない場合は追加する。
+func (r *MirroredStockRepository) mirrorUpdate(ctx context.Context, stock *domain.Stock) {
+	r.mirror(stock.ID, func() error {
+		err := r.index.Update(ctx, stock)
+		if err == domain.ErrNotFound {
+			return r.index.Create(ctx, stock)
+		}
+		return err
+	})
+}
+
+// mirror はインデックスへの反映を行う。ファイルへの保存は完了しているため、失敗しても警告のみとし、
+// 次回の Sync（pim-server の起動時）で再構築する。
+func (r *MirroredStockRepository) mirror(id string, apply func() error) {
+	if err := apply(); err != nil {
+		slog.Warn("failed to mirror stock to sqlite index; it will be rebuilt on next sync", "id", id, "error", err)
+	}
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// StockChangeOp はファイルの外部編集によるStockの変更の種類。
+type StockChangeOp string
+
+const (
+	StockCreated StockChangeOp = "created"
+	StockChanged StockChangeOp = "changed"
+	StockDeleted StockChangeOp = "deleted"
+)
+
+// StockChange は外部編集で追加・変更・削除されたStock。
+type StockChange struct {
+	Op        StockChangeOp
+	ProjectID string
+	ID        string
+	Stock     *domain.Stock // 削除の場合は nil
+}
+
+// StockFileDiagnostic は取り込めなかったStockファイルの診断情報。
+type StockFileDiagnostic struct {
+	Path      string `json:"path"`
+	ProjectID string `json:"project_id"`
+	Message   string `json:"message"`
+}
+
+// StockReconcileResult はStockファイルと索引の突き合わせの結果。
+type StockReconcileResult struct {
+	Changes     []StockChange
+	Diagnostics []StockFileDiagnostic
+}
+
+// Dir はStockファイルを保存するディレクトリを返す。
+func (r *FileStockRepository) Dir() string {
+	return r.baseDir
+}
+
+// Reconcile はプロジェクトのStockファイルを走査して index.json と突き合わせ、索引を更新する。
+// 手作業や git pull でファイルが追加・変更・削除された場合に呼び出し、変更されたStockを返す。
+// 内容の変更はファイルのチェックサムで検出するため、このリポジトリ自身の書き込みは変更として扱わない。
+// 不正なファイル（JSONが壊れている、管理番号・プロジェクト・カテゴリがパスと一致しない等）は診断情報として返し、
+// 既存の索引の項目はそのまま残す。projectID が空の場合は全プロジェクトを対象にする。
+func (r *FileStockRepository) Reconcile(ctx context.Context, projectID string) (*StockReconcileResult, error) {
+	projects := []string{projectID}
+	if projectID == "" {
+		var err error
+		if projects, err = r.projects(); err != nil {
+			return nil, err
+		}
+	} else if !ValidPathElement(projectID) {
+		return nil, domain.ErrInvalidProjectID
+	}
+
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := &StockReconcileResult{}
+	for _, project := range projects {
+		if err := r.reconcileProject(project, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// reconcileProject は Reconcile の1プロジェクト分の処理。ロックを保持した状態で呼び出す。
+func (r *FileStockRepository) reconcileProject(projectID string, result *StockReconcileResult) error {
+	var previous stockIndex
+	if data, err := os.ReadFile(r.indexPath(projectID)); err == nil {
+		// 壊れた索引は空として扱い、ファイルから作り直す
+		_ = json.Unmarshal(data, &previous)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read stock index: %w", err)
+	}
+	old := make(map[string]stockIndexEntry, len(previous.Stocks))
+	for _, entry := range previous.Stocks {
+		old[entry.ID] = entry
+	}
+
+	paths, err := filepath.Glob(filepath.Join(r.projectDir(projectID), "*", "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list stock files: %w", err)
+	}
+	slices.Sort(paths)
+
+	index := &stockIndex{Stocks: []stockIndexEntry{}}
+	seen := map[string]string{}    // 管理番号 → 最初に見つかったファイル
+	malformed := map[string]bool{} // 不正なファイルの管理番号（ファイル名から判断）
+	var changes []StockChange
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		stock, checksum, err := readStockData(path)
+		if err == nil {
+			err = validateStockFile(stock, projectID, filepath.Base(filepath.Dir(path)), id)
+		}
+		if err == nil && seen[id] != "" {
+			err = fmt.Errorf("duplicate stock id %s (also in %s)", id, seen[id])
+		}
+		if err != nil {
+			result.Diagnostics = append(result.Diagnostics, StockFileDiagnostic{Path: path, ProjectID: projectID, Message: err.Error()})
+			malformed[id] = true
+			continue
+		}
+		seen[id] = path
+
+		entry := newStockIndexEntry(stock, checksum)
+		index.put(entry)
+		prev, existed := old[id]
+		switch {
+		case !existed:
+			changes = append(changes, StockChange{Op: StockCreated, ProjectID: projectID, ID: id, Stock: stock})
+		case stockEntryChanged(prev, entry):
+			changes = append(changes, StockChange{Op: StockChanged, ProjectID: projectID, ID: id, Stock: stock})
+		}
+	}
+
+	for id, prev := range old {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		if malformed[id] {
+			// 直前の正しい内容を索引に残し、診断で知らせる
+			index.put(prev)
+			continue
+		}
+		changes = append(changes, StockChange{Op: StockDeleted, ProjectID: projectID, ID: id})
+	}
+	slices.SortFunc(changes, func(a, b StockChange) int { return strings.Compare(a.ID, b.ID) })
+	result.Changes = append(result.Changes, changes...)
+
+	if _, err := os.Stat(r.projectDir(projectID)); os.IsNotExist(err) {
+		return nil
+	}
+	if slices.EqualFunc(previous.Stocks, index.Stocks, stockIndexEntryEqual) {
+		return nil
+	}
+	return r.writeIndex(projectID, index)
+}
+
+// stockEntryChanged は索引の項目から、ファイルの内容が変わったかどうかを返す。
+// チェックサムのない古い索引の項目は、更新日時とカテゴリで判断する。
+func stockEntryChanged(prev, current stockIndexEntry) bool {
+	if prev.Category != current.Category {
+		return true
+	}
+	if prev.Checksum == "" {
+		return !prev.UpdatedAt.Equal(current.UpdatedAt)
+	}
+	return prev.Checksum != current.Checksum
+}
+
+func stockIndexEntryEqual(a, b stockIndexEntry) bool {
+	return a.ID == b.ID && a.Category == b.Category && a.Checksum == b.Checksum && a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+// validateStockFile はファイルから読み込んだStockが保存場所と一致し、有効な値を持つかを検証する。
+func validateStockFile(stock *domain.Stock, projectID, category, id string) error {
+	switch {
+	case stock.ID != id:
+		return fmt.Errorf("id %q does not match file name %s.json", stock.ID, id)
+	case stock.ProjectID != projectID:
+		return fmt.Errorf("project_id %q does not match directory %s", stock.ProjectID, projectID)
+	case string(stock.Category) != category:
+		return fmt.Errorf("category %q does not match directory %s", stock.Category, category)
+	case !slices.Contains(domain.ValidStockCategories(), stock.Category):
+		return fmt.Errorf("%w: %s", domain.ErrInvalidCategory, stock.Category)
+	case stock.Priority < domain.PriorityP0 || stock.Priority > domain.PriorityP3:
+		return fmt.Errorf("%w: %d", domain.ErrInvalidPriority, stock.Priority)
+	}
+	return nil
+}
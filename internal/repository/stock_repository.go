@@ -2,19 +2,39 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
 )
 
+// stockIndexFile はプロジェクトごとのStock索引のファイル名。
+const stockIndexFile = "index.json"
+
+// stockLockFile は複数プロセス間で書き込みを直列化するためのロックファイル名（baseDir 直下）。
+const stockLockFile = ".lock"
+
 // FileStockRepository はファイルシステムベースのStockリポジトリ実装。
-// 各StockはJSON形式で個別ファイルに保存される。
+// 各StockはJSON形式で stocks/{projectID}/{category}/{id}.json に保存し、
+// プロジェクトごとの stocks/{projectID}/index.json に一覧用の索引を保持する。
+// 管理番号はプロジェクト内で一意で、異なるプロジェクトは同じ管理番号を使える。
+//
+// ファイルは一時ファイルに書き込んでからリネームするため、書き込み途中で落ちても壊れたJSONは残らない。
+// 書き込みと索引の更新は baseDir/.lock のアドバイザリロックで直列化し、
+// 同じデータディレクトリを複数の pim-server プロセスで共有できる。
 type FileStockRepository struct {
 	baseDir string
+
+	mu sync.Mutex // プロセス内での書き込み・index.json の読み込みを直列化する
 }
 
 // NewFileStockRepository は新しいFileStockRepositoryを生成する。
@@ -22,147 +42,617 @@ func NewFileStockRepository(baseDir string) *FileStockRepository {
 	return &FileStockRepository{baseDir: baseDir}
 }
 
-func (r *FileStockRepository) stockPath(id string) string {
-	// IDからプロジェクトIDとカテゴリを推定してパスを構築
-	// 例: "STK-DESIGN-001" → stocks/{projectID}/design/STK-DESIGN-001.json
-	// 簡易実装: フラットにstocks/{id}.json
-	return filepath.Join(r.baseDir, id+".json")
+// stockIndex は index.json の内容。一覧取得時はStockのファイルを読まずに絞り込み・並び替えを行う。
+type stockIndex struct {
+	Stocks []stockIndexEntry `json:"stocks"` // 管理番号の昇順
+}
+
+// stockIndexEntry は索引に保持するStockの項目。
+type stockIndexEntry struct {
+	ID         string               `json:"id"`
+	Category   domain.StockCategory `json:"category"`
+	Priority   domain.Priority      `json:"priority"`
+	Title      string               `json:"title"`
+	Tags       []string             `json:"tags,omitempty"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	DeletedAt  *time.Time           `json:"deleted_at,omitempty"`
+	RedirectTo string               `json:"redirect_to,omitempty"`
+	Checksum   string               `json:"checksum,omitempty"` // ファイル内容のSHA-256。外部からの編集の検出に使う
+}
+
+// newStockIndexEntry は索引の項目を生成する。checksum は保存したファイル内容のチェックサム。
+func newStockIndexEntry(stock *domain.Stock, checksum string) stockIndexEntry {
+	return stockIndexEntry{
+		ID:         stock.ID,
+		Category:   stock.Category,
+		Priority:   stock.Priority,
+		Title:      stock.Title,
+		Tags:       stock.Tags,
+		UpdatedAt:  stock.UpdatedAt,
+		DeletedAt:  stock.DeletedAt,
+		RedirectTo: stock.RedirectTo,
+		Checksum:   checksum,
+	}
+}
+
+// ValidPathElement はパスの1要素として安全に使える名前かどうかを返す（区切り文字・glob の特殊文字を含まない）。
+// プロジェクトIDや管理番号はファイルパスに使われるため、書き込み前にこれで検証する。
+func ValidPathElement(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\*?[]`)
 }
 
 func (r *FileStockRepository) projectDir(projectID string) string {
 	return filepath.Join(r.baseDir, projectID)
 }
 
-// Create は新しいStockをファイルとして保存する。
-func (r *FileStockRepository) Create(ctx context.Context, stock *domain.Stock) error {
-	path := r.stockPath(stock.ID)
+func (r *FileStockRepository) stockPath(stock *domain.Stock) string {
+	return filepath.Join(r.baseDir, StockFilePath(stock))
+}
 
-	// 既存チェック
-	if _, err := os.Stat(path); err == nil {
-		return domain.ErrAlreadyExists
+// StockFilePath はStockファイルのStockディレクトリからの相対パスを返す。
+// 例: "STK-DESIGN-001" → {projectID}/design/STK-DESIGN-001.json
+func StockFilePath(stock *domain.Stock) string {
+	return filepath.Join(stock.ProjectID, string(stock.Category), stock.ID+".json")
+}
+
+func (r *FileStockRepository) indexPath(projectID string) string {
+	return filepath.Join(r.projectDir(projectID), stockIndexFile)
+}
+
+// locate は管理番号のStockファイルを探す。projectID が空の場合は全プロジェクトから探す。
+func (r *FileStockRepository) locate(projectID string, id string) (string, error) {
+	if !ValidPathElement(id) || (projectID != "" && !ValidPathElement(projectID)) {
+		return "", domain.ErrNotFound
+	}
+	project := projectID
+	if project == "" {
+		project = "*"
+	}
+	paths, err := filepath.Glob(filepath.Join(r.baseDir, project, "*", id+".json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to locate stock file: %w", err)
 	}
+	switch len(paths) {
+	case 0:
+		return "", domain.ErrNotFound
+	case 1:
+		return paths[0], nil
+	default:
+		return "", domain.ErrAmbiguousID
+	}
+}
+
+// projectOf はStockファイルのパスからプロジェクトIDを返す。
+func (r *FileStockRepository) projectOf(path string) string {
+	return filepath.Base(filepath.Dir(filepath.Dir(path)))
+}
+
+// stockLockKey は WithLock でロックを保持しているリポジトリを示すコンテキストのキー。
+type stockLockKey struct{}
 
-	// ディレクトリ作成
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// WithLock は書き込みのロックを保持したまま fn を実行する。fn に渡すコンテキストで呼び出した操作はロックを取得し直さない。
+// Stockの書き込みと変更履歴の記録の間に他の書き込みが入らないようにするために使う。
+func (r *FileStockRepository) WithLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+	return fn(context.WithValue(ctx, stockLockKey{}, r))
+}
 
-	return r.writeStock(path, stock)
+// lock はプロセス内の r.mu とプロセス間のロックファイルの両方を取得し、解放する関数を返す。
+// ctx が WithLock でこのリポジトリのロックを保持している場合は何もしない。
+func (r *FileStockRepository) lock(ctx context.Context) (func(), error) {
+	if held, _ := ctx.Value(stockLockKey{}).(*FileStockRepository); held == r {
+		return func() {}, nil
+	}
+	r.mu.Lock()
+	l, err := acquireFileLock(filepath.Join(r.baseDir, stockLockFile))
+	if err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		l.release()
+		r.mu.Unlock()
+	}, nil
 }
 
-// Get は管理番号でStockを取得する。
-func (r *FileStockRepository) Get(ctx context.Context, id string) (*domain.Stock, error) {
-	path := r.stockPath(id)
+// Create は新しいStockをファイルとして保存する。
+func (r *FileStockRepository) Create(ctx context.Context, stock *domain.Stock) error {
+	if !ValidPathElement(stock.ProjectID) {
+		return domain.ErrInvalidProjectID
+	}
+	if !ValidPathElement(stock.ID) || !ValidPathElement(string(stock.Category)) {
+		return fmt.Errorf("invalid stock id or category: %q / %q", stock.ID, stock.Category)
+	}
 
-	data, err := os.ReadFile(path)
+	unlock, err := r.lock(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, domain.ErrNotFound
-		}
-		return nil, fmt.Errorf("failed to read stock file: %w", err)
+		return err
 	}
+	defer unlock()
 
-	var stock domain.Stock
-	if err := json.Unmarshal(data, &stock); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal stock: %w", err)
+	// 既存チェック（同じプロジェクト内で管理番号が一意）
+	if _, err := r.locate(stock.ProjectID, stock.ID); err == nil || err == domain.ErrAmbiguousID {
+		return domain.ErrAlreadyExists
+	} else if err != domain.ErrNotFound {
+		return err
 	}
 
-	return &stock, nil
+	checksum, err := r.writeStock(r.stockPath(stock), stock)
+	if err != nil {
+		return err
+	}
+	return r.updateIndex(stock.ProjectID, func(index *stockIndex) {
+		index.put(newStockIndexEntry(stock, checksum))
+	})
 }
 
-// Update はStockを更新する。
+// Get は管理番号でStockを取得する。
+func (r *FileStockRepository) Get(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	path, err := r.locate(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	return readStockFile(path)
+}
+
+// Update はStockを更新する。カテゴリが変わった場合はファイルを移動する。
 func (r *FileStockRepository) Update(ctx context.Context, stock *domain.Stock) error {
-	path := r.stockPath(stock.ID)
+	return r.update(ctx, stock, nil)
+}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+// UpdateIfUnchanged は保存されているStockの更新日時が expectedUpdatedAt と一致する場合のみ更新する。
+// 一致しない場合（読み込んだ後に別の書き込みがあった場合）は domain.ErrConflict を返す。
+func (r *FileStockRepository) UpdateIfUnchanged(ctx context.Context, stock *domain.Stock, expectedUpdatedAt time.Time) error {
+	return r.update(ctx, stock, &expectedUpdatedAt)
+}
+
+// update は Update と UpdateIfUnchanged の本体。確認から書き込みまでをロックを保持したまま行う。
+func (r *FileStockRepository) update(ctx context.Context, stock *domain.Stock, expectedUpdatedAt *time.Time) error {
+	if !ValidPathElement(stock.ProjectID) {
 		return domain.ErrNotFound
 	}
+	if !ValidPathElement(string(stock.Category)) {
+		return fmt.Errorf("invalid stock category: %q", stock.Category)
+	}
 
-	return r.writeStock(path, stock)
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	oldPath, err := r.locate(stock.ProjectID, stock.ID)
+	if err != nil {
+		return err
+	}
+	if expectedUpdatedAt != nil {
+		current, err := readStockFile(oldPath)
+		if err != nil {
+			return err
+		}
+		if !current.UpdatedAt.Equal(*expectedUpdatedAt) {
+			return domain.ErrConflict
+		}
+	}
+
+	path := r.stockPath(stock)
+	checksum, err := r.writeStock(path, stock)
+	if err != nil {
+		return err
+	}
+	if oldPath != path {
+		if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old stock file: %w", err)
+		}
+		syncDir(filepath.Dir(oldPath))
+	}
+	return r.updateIndex(stock.ProjectID, func(index *stockIndex) {
+		index.put(newStockIndexEntry(stock, checksum))
+	})
 }
 
 // Delete はStockを削除する。
-func (r *FileStockRepository) Delete(ctx context.Context, id string) error {
-	path := r.stockPath(id)
+func (r *FileStockRepository) Delete(ctx context.Context, projectID string, id string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	path, err := r.locate(projectID, id)
+	if err != nil {
+		return err
+	}
 	if err := os.Remove(path); err != nil {
 		if os.IsNotExist(err) {
 			return domain.ErrNotFound
 		}
 		return fmt.Errorf("failed to delete stock file: %w", err)
 	}
-
-	return nil
+	syncDir(filepath.Dir(path))
+	return r.updateIndex(r.projectOf(path), func(index *stockIndex) {
+		index.remove(id)
+	})
 }
 
 // List はプロジェクト内のStockを一覧取得する。
+// 並び順は優先度の昇順・更新日時の降順・管理番号の昇順で、ページングしても安定する。
+// 絞り込み・並び替えは索引で行い、返却するページのStockのみファイルから読み込む。
 func (r *FileStockRepository) List(ctx context.Context, projectID string, opts *StockListOptions) ([]*domain.Stock, error) {
-	// baseDir以下のすべてのJSONファイルをスキャン
-	var stocks []*domain.Stock
+	entries, err := r.scan(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	err := filepath.Walk(r.baseDir, func(path string, info os.FileInfo, err error) error {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].stockIndexEntry, entries[j].stockIndexEntry
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return entries[i].projectID < entries[j].projectID
+	})
+
+	// Limit/Offset
+	if opts != nil {
+		if opts.Offset > 0 {
+			entries = entries[min(opts.Offset, len(entries)):]
+		}
+		if opts.Limit > 0 && opts.Limit < len(entries) {
+			entries = entries[:opts.Limit]
+		}
+	}
+
+	stocks := make([]*domain.Stock, 0, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(r.projectDir(entry.projectID), string(entry.Category), entry.ID+".json")
+		stock, err := readStockFile(path)
 		if err != nil {
-			return err
+			// 索引にあるが読み込めないファイルは読み飛ばし、気付けるよう警告を残す
+			slog.Warn("skipping unreadable stock file", "path", path, "error", err)
+			continue
 		}
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
-			return nil
+		stocks = append(stocks, stock)
+	}
+	return stocks, nil
+}
+
+// Count は条件に一致するStockの件数を返す。
+func (r *FileStockRepository) Count(ctx context.Context, projectID string, opts *StockListOptions) (int, error) {
+	entries, err := r.scan(ctx, projectID, opts)
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// TagCounts は条件に一致するStockのタグごとの件数を返す。
+func (r *FileStockRepository) TagCounts(ctx context.Context, projectID string, opts *StockListOptions) ([]domain.TagCount, error) {
+	entries, err := r.scan(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
+	tagLists := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		tagLists = append(tagLists, entry.Tags)
+	}
+	return domain.CountTags(tagLists), nil
+}
+
+// projectStockEntry はプロジェクトIDを付けた索引の項目。
+type projectStockEntry struct {
+	projectID string
+	stockIndexEntry
+}
+
+// scan はプロジェクトの索引から条件に一致するStockの項目を返す。projectID が空の場合は全プロジェクトを対象にする。
+func (r *FileStockRepository) scan(ctx context.Context, projectID string, opts *StockListOptions) ([]projectStockEntry, error) {
+	projects := []string{projectID}
+	if projectID == "" {
+		var err error
+		if projects, err = r.projects(); err != nil {
+			return nil, err
 		}
+	} else if !ValidPathElement(projectID) {
+		return nil, nil
+	}
 
-		data, err := os.ReadFile(path)
+	var tags []string
+	if opts != nil {
+		tags = domain.NormalizeTags(opts.Tags)
+	}
+
+	var entries []projectStockEntry
+	for _, project := range projects {
+		index, err := r.loadIndex(ctx, project)
 		if err != nil {
-			return nil // skip unreadable files
+			return nil, err
+		}
+		for _, entry := range index.Stocks {
+			if entry.RedirectTo != "" && (opts == nil || !opts.IncludeAliases) {
+				continue
+			}
+			if entry.DeletedAt != nil && (opts == nil || !opts.IncludeDeleted) {
+				continue
+			}
+
+			// オプションによるフィルタ
+			if opts != nil {
+				if opts.Category != nil && entry.Category != *opts.Category {
+					continue
+				}
+				if opts.Priority != nil && entry.Priority != *opts.Priority {
+					continue
+				}
+				if !domain.MatchTags(entry.Tags, tags, opts.TagMatch) {
+					continue
+				}
+			}
+
+			entries = append(entries, projectStockEntry{projectID: project, stockIndexEntry: entry})
+		}
+	}
+	return entries, nil
+}
+
+// projects はStockを保存しているプロジェクトの一覧を返す。
+func (r *FileStockRepository) projects() ([]string, error) {
+	dirEntries, err := os.ReadDir(r.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list stock projects: %w", err)
+	}
+	var projects []string
+	for _, entry := range dirEntries {
+		// .git などの隠しディレクトリはプロジェクトとして扱わない
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			projects = append(projects, entry.Name())
+		}
+	}
+	return projects, nil
+}
+
+// loadIndex はプロジェクトの索引を読み込む。索引がない・壊れている場合はファイルから再構築する。
+// 再構築で index.json を書き込むことがあるため、ロックを取得して読み込む。
+func (r *FileStockRepository) loadIndex(ctx context.Context, projectID string) (*stockIndex, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.readIndex(projectID)
+}
+
+// readIndex は loadIndex の本体。ロックを保持した状態で呼び出す。
+func (r *FileStockRepository) readIndex(projectID string) (*stockIndex, error) {
+	data, err := os.ReadFile(r.indexPath(projectID))
+	if err == nil {
+		var index stockIndex
+		if err := json.Unmarshal(data, &index); err == nil {
+			return &index, nil
 		}
+		slog.Warn("stock index is corrupted; rebuilding from files", "project_id", projectID)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read stock index: %w", err)
+	}
+	return r.rebuildIndex(projectID)
+}
+
+// RebuildIndex はプロジェクトのStockファイルを走査して index.json を作り直す。
+// Stockのファイルを直接編集した場合に呼び出す。
+func (r *FileStockRepository) RebuildIndex(ctx context.Context, projectID string) error {
+	if !ValidPathElement(projectID) {
+		return domain.ErrInvalidProjectID
+	}
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	_, err = r.rebuildIndex(projectID)
+	return err
+}
 
-		var stock domain.Stock
-		if err := json.Unmarshal(data, &stock); err != nil {
-			return nil // skip invalid files
+// rebuildIndex は RebuildIndex の本体。ロックを保持した状態で呼び出す。
+func (r *FileStockRepository) rebuildIndex(projectID string) (*stockIndex, error) {
+	paths, err := filepath.Glob(filepath.Join(r.projectDir(projectID), "*", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock files: %w", err)
+	}
+	index := &stockIndex{Stocks: []stockIndexEntry{}}
+	for _, path := range paths {
+		stock, checksum, err := readStockData(path)
+		if err != nil {
+			slog.Warn("skipping unreadable stock file", "path", path, "error", err)
+			continue
 		}
+		index.put(newStockIndexEntry(stock, checksum))
+	}
+	if _, err := os.Stat(r.projectDir(projectID)); os.IsNotExist(err) {
+		return index, nil
+	}
+	if err := r.writeIndex(projectID, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// updateIndex はプロジェクトの索引を読み込んで更新し、書き戻す。ロックを保持した状態で呼び出す。
+func (r *FileStockRepository) updateIndex(projectID string, update func(index *stockIndex)) error {
+	index, err := r.readIndex(projectID)
+	if err != nil {
+		return err
+	}
+	update(index)
+	return r.writeIndex(projectID, index)
+}
+
+func (r *FileStockRepository) writeIndex(projectID string, index *stockIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stock index: %w", err)
+	}
+	if err := writeFileAtomic(r.indexPath(projectID), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write stock index: %w", err)
+	}
+	return nil
+}
+
+// put は索引の項目を追加・置換する。
+func (idx *stockIndex) put(entry stockIndexEntry) {
+	i := sort.Search(len(idx.Stocks), func(i int) bool { return idx.Stocks[i].ID >= entry.ID })
+	if i < len(idx.Stocks) && idx.Stocks[i].ID == entry.ID {
+		idx.Stocks[i] = entry
+		return
+	}
+	idx.Stocks = append(idx.Stocks, stockIndexEntry{})
+	copy(idx.Stocks[i+1:], idx.Stocks[i:])
+	idx.Stocks[i] = entry
+}
 
-		// projectID が指定されている場合のみフィルタ
-		if projectID != "" && stock.ProjectID != projectID {
+// remove は索引から項目を取り除く。
+func (idx *stockIndex) remove(id string) {
+	i := sort.Search(len(idx.Stocks), func(i int) bool { return idx.Stocks[i].ID >= id })
+	if i < len(idx.Stocks) && idx.Stocks[i].ID == id {
+		idx.Stocks = append(idx.Stocks[:i], idx.Stocks[i+1:]...)
+	}
+}
+
+// MigrateLayout は旧形式（stocks/{id}.json のフラット配置）のStockファイルを
+// stocks/{projectID}/{category}/{id}.json に移動し、移動したプロジェクトの索引を作り直す。移動した件数を返す。
+func (r *FileStockRepository) MigrateLayout(ctx context.Context) (int, error) {
+	dirEntries, err := os.ReadDir(r.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read stock directory: %w", err)
+	}
+
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	moved := 0
+	projects := map[string]bool{}
+	for _, entry := range dirEntries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		legacy := filepath.Join(r.baseDir, entry.Name())
+		stock, err := readStockFile(legacy)
+		if err != nil {
+			slog.Warn("skipping unreadable legacy stock file", "path", legacy, "error", err)
+			continue
+		}
+		if !ValidPathElement(stock.ProjectID) || !ValidPathElement(stock.ID) || !ValidPathElement(string(stock.Category)) {
+			slog.Warn("skipping legacy stock file with invalid project, id or category", "path", legacy)
+			continue
+		}
+		path := r.stockPath(stock)
+		if _, err := os.Stat(path); err == nil {
+			slog.Warn("skipping legacy stock file; destination already exists", "path", legacy, "destination", path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return moved, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Rename(legacy, path); err != nil {
+			return moved, fmt.Errorf("failed to move legacy stock file: %w", err)
+		}
+		moved++
+		projects[stock.ProjectID] = true
+	}
+
+	for projectID := range projects {
+		if _, err := r.rebuildIndex(projectID); err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// loadAll はbaseDir以下のすべてのStockファイルを読み込む（エイリアス・削除済みを含む）。
+// 読み込めないファイルや不正なファイルは読み飛ばす。
+func (r *FileStockRepository) loadAll() ([]*domain.Stock, error) {
+	var stocks []*domain.Stock
+
+	err := filepath.Walk(r.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// 書き込み途中の一時ファイル（.{id}.json.tmp-*）は拡張子が .json にならないため対象外になる
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") || info.Name() == stockIndexFile {
 			return nil
 		}
 
-		// オプションによるフィルタ
-		if opts != nil {
-			if opts.Category != nil && stock.Category != *opts.Category {
-				return nil
-			}
-			if opts.Priority != nil && stock.Priority != *opts.Priority {
-				return nil
-			}
+		stock, err := readStockFile(path)
+		if err != nil {
+			slog.Warn("skipping unreadable stock file", "path", path, "error", err)
+			return nil
 		}
 
-		stocks = append(stocks, &stock)
+		stocks = append(stocks, stock)
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list stocks: %w", err)
 	}
 
-	// Limit/Offset
-	if opts != nil {
-		if opts.Offset > 0 && opts.Offset < len(stocks) {
-			stocks = stocks[opts.Offset:]
-		}
-		if opts.Limit > 0 && opts.Limit < len(stocks) {
-			stocks = stocks[:opts.Limit]
+	return stocks, nil
+}
+
+func readStockFile(path string) (*domain.Stock, error) {
+	stock, _, err := readStockData(path)
+	return stock, err
+}
+
+// readStockData はStockファイルを読み込み、Stockとファイル内容のチェックサムを返す。
+func readStockData(path string) (*domain.Stock, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", domain.ErrNotFound
 		}
+		return nil, "", fmt.Errorf("failed to read stock file: %w", err)
 	}
 
-	return stocks, nil
+	var stock domain.Stock
+	if err := json.Unmarshal(data, &stock); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal stock: %w", err)
+	}
+
+	return &stock, stockChecksum(data), nil
 }
 
-func (r *FileStockRepository) writeStock(path string, stock *domain.Stock) error {
+// writeStock はStockをファイルに保存し、書き込んだ内容のチェックサムを返す。
+func (r *FileStockRepository) writeStock(path string, stock *domain.Stock) (string, error) {
 	data, err := json.MarshalIndent(stock, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal stock: %w", err)
+		return "", fmt.Errorf("failed to marshal stock: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write stock file: %w", err)
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write stock file: %w", err)
 	}
 
-	return nil
+	return stockChecksum(data), nil
+}
+
+func stockChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
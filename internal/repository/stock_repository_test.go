@@ -2,15 +2,56 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
 )
 
-func TestFileStockRepositoryCRUDAndList(t *testing.T) {
+func TestStockRepositoryCRUDAndList(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		repo func(t *testing.T) StockRepository
+	}{
+		{"file", func(t *testing.T) StockRepository { return NewFileStockRepository(t.TempDir()) }},
+		{"sqlite", func(t *testing.T) StockRepository { return newTestSQLiteStockRepo(t) }},
+		{"mirror", func(t *testing.T) StockRepository {
+			return NewMirroredStockRepository(NewFileStockRepository(t.TempDir()), newTestSQLiteStockRepo(t))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testStockRepositoryCRUDAndList(t, tc.repo(t))
+		})
+	}
+}
+
+func newTestSQLiteStockRepo(t *testing.T) *SQLiteStockRepository {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "states.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	repo, err := NewSQLiteStockRepository(db)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	return repo
+}
+
+func testStockRepositoryCRUDAndList(t *testing.T, repo StockRepository) {
 	ctx := context.Background()
-	repo := NewFileStockRepository(t.TempDir())
 
 	stock1 := &domain.Stock{
 		ID:        "STK-DESIGN-001",
@@ -56,7 +97,7 @@ func TestFileStockRepositoryCRUDAndList(t *testing.T) {
 		t.Fatalf("expected ErrAlreadyExists, got %v", err)
 	}
 
-	got, err := repo.Get(ctx, stock1.ID)
+	got, err := repo.Get(ctx, "proj-1", stock1.ID)
 	if err != nil {
 		t.Fatalf("get stock1: %v", err)
 	}
@@ -69,7 +110,7 @@ func TestFileStockRepositoryCRUDAndList(t *testing.T) {
 	if err := repo.Update(ctx, stock1); err != nil {
 		t.Fatalf("update stock1: %v", err)
 	}
-	updated, err := repo.Get(ctx, stock1.ID)
+	updated, err := repo.Get(ctx, "", stock1.ID)
 	if err != nil {
 		t.Fatalf("get updated stock1: %v", err)
 	}
@@ -109,17 +150,543 @@ func TestFileStockRepositoryCRUDAndList(t *testing.T) {
 		t.Fatalf("expected 1 stock with limit, got %d", len(limited))
 	}
 
-	if err := repo.Delete(ctx, stock2.ID); err != nil {
+	if err := repo.Delete(ctx, "proj-1", stock2.ID); err != nil {
 		t.Fatalf("delete stock2: %v", err)
 	}
-	if _, err := repo.Get(ctx, stock2.ID); err != domain.ErrNotFound {
+	if _, err := repo.Get(ctx, "proj-1", stock2.ID); err != domain.ErrNotFound {
 		t.Fatalf("expected ErrNotFound after delete, got %v", err)
 	}
 
-	if _, err := repo.Get(ctx, "missing"); err != domain.ErrNotFound {
+	if _, err := repo.Get(ctx, "proj-1", "missing"); err != domain.ErrNotFound {
 		t.Fatalf("expected ErrNotFound for missing stock, got %v", err)
 	}
-	if err := repo.Delete(ctx, "missing"); err != domain.ErrNotFound {
+	if err := repo.Delete(ctx, "", "missing"); err != domain.ErrNotFound {
 		t.Fatalf("expected ErrNotFound for delete missing, got %v", err)
 	}
+
+	// 管理番号はプロジェクト内で一意。別のプロジェクトでは同じ管理番号を使える
+	other := *stock1
+	other.ProjectID = "proj-2"
+	other.Title = "Other Design Doc"
+	if err := repo.Create(ctx, &other); err != nil {
+		t.Fatalf("create same id in another project: %v", err)
+	}
+	if got, err := repo.Get(ctx, "proj-2", stock1.ID); err != nil || got.Title != other.Title {
+		t.Fatalf("expected project-scoped stock, got %+v (%v)", got, err)
+	}
+	if _, err := repo.Get(ctx, "", stock1.ID); err != domain.ErrAmbiguousID {
+		t.Fatalf("expected ErrAmbiguousID without project, got %v", err)
+	}
+	if err := repo.Delete(ctx, "proj-2", stock1.ID); err != nil {
+		t.Fatalf("delete project-scoped stock: %v", err)
+	}
+	if got, err := repo.Get(ctx, "", stock1.ID); err != nil || got.ProjectID != "proj-1" {
+		t.Fatalf("expected original stock kept, got %+v (%v)", got, err)
+	}
+
+	// 楽観的排他制御: 読み込んだ時点の更新日時と一致する場合のみ更新する
+	current, err := repo.Get(ctx, "proj-1", stock1.ID)
+	if err != nil {
+		t.Fatalf("get before conditional update: %v", err)
+	}
+	first, second := *current, *current
+	first.Content, first.UpdatedAt = "first writer", current.UpdatedAt.Add(time.Second)
+	second.Content, second.UpdatedAt = "second writer", current.UpdatedAt.Add(2*time.Second)
+	if err := repo.UpdateIfUnchanged(ctx, &first, current.UpdatedAt); err != nil {
+		t.Fatalf("conditional update: %v", err)
+	}
+	if err := repo.UpdateIfUnchanged(ctx, &second, current.UpdatedAt); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for stale update, got %v", err)
+	}
+	if got, err := repo.Get(ctx, "proj-1", stock1.ID); err != nil || got.Content != "first writer" {
+		t.Fatalf("expected first writer kept, got %+v (%v)", got, err)
+	}
+	if err := repo.UpdateIfUnchanged(ctx, &domain.Stock{ID: "missing", ProjectID: "proj-1", Category: domain.CategoryDesign}, time.Now()); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for conditional update of missing stock, got %v", err)
+	}
+}
+
+func TestFileStockRepositoryLayoutAndIndex(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := NewFileStockRepository(dir)
+
+	// 旧形式のフラット配置のファイル
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, stock := range []*domain.Stock{
+		{ID: "STK-DESIGN-001", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Design", Tags: []string{"api"}, CreatedAt: now, UpdatedAt: now},
+		{ID: "STK-RULES-002", ProjectID: "proj-2", Category: domain.CategoryRules, Title: "Rules", CreatedAt: now, UpdatedAt: now},
+	} {
+		if _, err := repo.writeStock(filepath.Join(dir, stock.ID+".json"), stock); err != nil {
+			t.Fatalf("write legacy file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken file: %v", err)
+	}
+
+	moved, err := repo.MigrateLayout(ctx)
+	if err != nil || moved != 2 {
+		t.Fatalf("expected 2 stocks moved, got %d (%v)", moved, err)
+	}
+	for _, path := range []string{
+		filepath.Join(dir, "proj-1", "design", "STK-DESIGN-001.json"),
+		filepath.Join(dir, "proj-2", "rules", "STK-RULES-002.json"),
+		filepath.Join(dir, "proj-1", "index.json"),
+		filepath.Join(dir, "proj-2", "index.json"),
+		filepath.Join(dir, "broken.json"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s: %v", path, err)
+		}
+	}
+	if moved, err := repo.MigrateLayout(ctx); err != nil || moved != 0 {
+		t.Fatalf("expected migration to be idempotent, got %d (%v)", moved, err)
+	}
+
+	// 一覧・タグ集計は索引から行う
+	if counts, err := repo.TagCounts(ctx, "proj-1", nil); err != nil || len(counts) != 1 || counts[0].Tag != "api" {
+		t.Fatalf("unexpected tag counts: %+v (%v)", counts, err)
+	}
+
+	// カテゴリを変えて更新するとファイルを移動する
+	stock, err := repo.Get(ctx, "proj-1", "STK-DESIGN-001")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	stock.Category = domain.CategoryArchitecture
+	if err := repo.Update(ctx, stock); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "proj-1", "design", "STK-DESIGN-001.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected old file removed, got %v", err)
+	}
+	architecture := domain.CategoryArchitecture
+	if count, err := repo.Count(ctx, "proj-1", &StockListOptions{Category: &architecture}); err != nil || count != 1 {
+		t.Fatalf("expected index updated with new category, got %d (%v)", count, err)
+	}
+
+	// 索引が失われても一覧時にファイルから再構築する
+	if err := os.Remove(filepath.Join(dir, "proj-1", "index.json")); err != nil {
+		t.Fatalf("remove index: %v", err)
+	}
+	if list, err := repo.List(ctx, "", nil); err != nil || len(list) != 2 {
+		t.Fatalf("expected index rebuilt, got %v (%v)", list, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "proj-1", "index.json")); err != nil {
+		t.Fatalf("expected index rewritten: %v", err)
+	}
+
+	if err := repo.Create(ctx, &domain.Stock{ID: "STK-DESIGN-003", ProjectID: "../proj", Category: domain.CategoryDesign}); err != domain.ErrInvalidProjectID {
+		t.Fatalf("expected ErrInvalidProjectID, got %v", err)
+	}
+}
+
+func TestFileStockRepositoryConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 同じデータディレクトリを共有する2つのプロセスを想定し、別々のリポジトリから書き込む
+	repos := []*FileStockRepository{NewFileStockRepository(dir), NewFileStockRepository(dir)}
+
+	const n = 20
+	var wg sync.WaitGroup
+	created := make([]int, n)
+	var mu sync.Mutex
+	for i := range n {
+		for _, repo := range repos {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				now := time.Now()
+				stock := &domain.Stock{ID: fmt.Sprintf("STK-DESIGN-%03d", i+1), ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "t", CreatedAt: now, UpdatedAt: now}
+				err := repo.Create(ctx, stock)
+				if err != nil && err != domain.ErrAlreadyExists {
+					t.Errorf("create %s: %v", stock.ID, err)
+					return
+				}
+				if err == nil {
+					mu.Lock()
+					created[i]++
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	// 同じ管理番号の作成はどちらか一方のみ成功し、索引の更新も失われない
+	for i, c := range created {
+		if c != 1 {
+			t.Fatalf("expected exactly one create to succeed for #%d, got %d", i+1, c)
+		}
+	}
+	if count, err := repos[1].Count(ctx, "proj-1", nil); err != nil || count != n {
+		t.Fatalf("expected %d stocks in index, got %d (%v)", n, count, err)
+	}
+
+	// 書き込み途中の一時ファイルは残らない
+	temps, err := filepath.Glob(filepath.Join(dir, "proj-1", "*", ".*.tmp-*"))
+	indexTemps, _ := filepath.Glob(filepath.Join(dir, "proj-1", ".*.tmp-*"))
+	temps = append(temps, indexTemps...)
+	if err != nil || len(temps) != 0 {
+		t.Fatalf("expected no temp files left, got %v (%v)", temps, err)
+	}
+}
+
+func TestSQLiteStockRepositoryFilters(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLiteStockRepo(t)
+
+	base := time.Date(2025, 7, 1, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	deletedAt := base
+	for i, stock := range []*domain.Stock{
+		{ID: "STK-DESIGN-001", Priority: domain.PriorityP1, Tags: []string{"api", "db"}},
+		{ID: "STK-DESIGN-002", Priority: domain.PriorityP1, Tags: []string{"api"}},
+		{ID: "STK-RULES-003", Category: domain.CategoryRules, Priority: domain.PriorityP0},
+		{ID: "STK-DESIGN-004", Priority: domain.PriorityP2, Tags: []string{"api"}, DeletedAt: &deletedAt},
+		{ID: "STK-DESIGN-005", Priority: domain.PriorityP2, RedirectTo: "STK-RULES-003"},
+	} {
+		stock.ProjectID = "proj-1"
+		if stock.Category == "" {
+			stock.Category = domain.CategoryDesign
+		}
+		stock.Title = stock.ID
+		stock.CreatedAt = base
+		// タイムゾーンが異なっても更新日時の順に並ぶ
+		stock.UpdatedAt = base.Add(time.Duration(i) * time.Hour).In(time.UTC)
+		if i%2 == 0 {
+			stock.UpdatedAt = stock.UpdatedAt.In(base.Location())
+		}
+		if err := repo.Create(ctx, stock); err != nil {
+			t.Fatalf("create %s: %v", stock.ID, err)
+		}
+	}
+
+	list, err := repo.List(ctx, "proj-1", nil)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var ids []string
+	for _, stock := range list {
+		ids = append(ids, stock.ID)
+	}
+	if len(ids) != 3 || ids[0] != "STK-RULES-003" || ids[1] != "STK-DESIGN-002" || ids[2] != "STK-DESIGN-001" {
+		t.Fatalf("expected aliases and deleted excluded in stable order, got %v", ids)
+	}
+	if !list[2].UpdatedAt.Equal(base) || len(list[2].Tags) != 2 {
+		t.Fatalf("expected stock round-tripped, got %+v", list[2])
+	}
+
+	design := domain.CategoryDesign
+	if count, err := repo.Count(ctx, "proj-1", &StockListOptions{Category: &design, IncludeDeleted: true}); err != nil || count != 3 {
+		t.Fatalf("expected 3 design stocks including deleted, got %d (%v)", count, err)
+	}
+	if count, err := repo.Count(ctx, "", &StockListOptions{IncludeDeleted: true, IncludeAliases: true}); err != nil || count != 5 {
+		t.Fatalf("expected 5 stocks including deleted and aliases, got %d (%v)", count, err)
+	}
+	all, err := repo.List(ctx, "proj-1", &StockListOptions{Tags: []string{"api", "db"}, TagMatch: domain.TagMatchAll})
+	if err != nil || len(all) != 1 || all[0].ID != "STK-DESIGN-001" {
+		t.Fatalf("unexpected all-tag list: %v (%v)", all, err)
+	}
+	counts, err := repo.TagCounts(ctx, "proj-1", nil)
+	if err != nil || len(counts) != 2 || counts[0] != (domain.TagCount{Tag: "api", Count: 2}) {
+		t.Fatalf("unexpected tag counts: %+v (%v)", counts, err)
+	}
+
+	alias, err := repo.Get(ctx, "proj-1", "STK-DESIGN-005")
+	if err != nil || alias.RedirectTo != "STK-RULES-003" {
+		t.Fatalf("expected alias retrievable by id, got %+v (%v)", alias, err)
+	}
+	if err := repo.Update(ctx, &domain.Stock{ID: "missing"}); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for update missing, got %v", err)
+	}
+}
+
+func TestMirroredStockRepositorySync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := NewFileStockRepository(dir)
+	index := newTestSQLiteStockRepo(t)
+	repo := NewMirroredStockRepository(files, index)
+
+	now := time.Now()
+	stock := &domain.Stock{ID: "STK-DESIGN-001", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Design", CreatedAt: now, UpdatedAt: now}
+	if err := repo.Create(ctx, stock); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := index.Get(ctx, "proj-1", stock.ID); err != nil {
+		t.Fatalf("expected stock mirrored to index: %v", err)
+	}
+
+	// ファイルを直接追加・削除した変更は Sync で索引に反映される
+	external := &domain.Stock{ID: "STK-RULES-002", ProjectID: "proj-1", Category: domain.CategoryRules, Title: "Rules", CreatedAt: now, UpdatedAt: now}
+	if err := files.Create(ctx, external); err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "proj-1", "design", "STK-DESIGN-001.json")); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	if list, err := repo.List(ctx, "proj-1", nil); err != nil || len(list) != 1 || list[0].ID != "STK-DESIGN-001" {
+		t.Fatalf("expected stale index before sync, got %v (%v)", list, err)
+	}
+	if _, err := repo.Get(ctx, "", "STK-RULES-002"); err != nil {
+		t.Fatalf("expected get to read files: %v", err)
+	}
+
+	count, err := repo.Sync(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 stock synced, got %d (%v)", count, err)
+	}
+	if list, err := repo.List(ctx, "proj-1", nil); err != nil || len(list) != 1 || list[0].ID != "STK-RULES-002" {
+		t.Fatalf("expected index rebuilt from files, got %v (%v)", list, err)
+	}
+
+	if err := repo.Delete(ctx, "proj-1", "STK-RULES-002"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if empty, err := index.IsEmpty(ctx); err != nil || !empty {
+		t.Fatalf("expected delete mirrored to index, empty=%t (%v)", empty, err)
+	}
+}
+
+func TestStockRepositoryReconcile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := NewFileStockRepository(dir)
+	index := newTestSQLiteStockRepo(t)
+	repo := NewMirroredStockRepository(files, index)
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, stock := range []*domain.Stock{
+		{ID: "STK-DESIGN-001", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Design", Content: "v1", CreatedAt: now, UpdatedAt: now},
+		{ID: "STK-DESIGN-002", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Deleted", CreatedAt: now, UpdatedAt: now},
+		{ID: "STK-DESIGN-003", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Broken", CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := repo.Create(ctx, stock); err != nil {
+			t.Fatalf("create %s: %v", stock.ID, err)
+		}
+	}
+
+	// リポジトリ自身の書き込みは変更として扱わない
+	if result, err := repo.Reconcile(ctx, ""); err != nil || len(result.Changes) != 0 || len(result.Diagnostics) != 0 {
+		t.Fatalf("expected no changes after own writes, got %+v (%v)", result, err)
+	}
+
+	writeJSON := func(path string, v any) {
+		t.Helper()
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	projectDir := filepath.Join(dir, "proj-1")
+
+	// 手作業での変更（更新日時は変えない）・追加・削除・壊れたJSON・パスと一致しない管理番号
+	writeJSON(filepath.Join(projectDir, "design", "STK-DESIGN-001.json"), &domain.Stock{ID: "STK-DESIGN-001", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Design", Content: "v2 (hand edit)", CreatedAt: now, UpdatedAt: now})
+	writeJSON(filepath.Join(projectDir, "rules", "STK-RULES-001.json"), &domain.Stock{ID: "STK-RULES-001", ProjectID: "proj-1", Category: domain.CategoryRules, Title: "Rules", CreatedAt: now, UpdatedAt: now})
+	if err := os.Remove(filepath.Join(projectDir, "design", "STK-DESIGN-002.json")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "design", "STK-DESIGN-003.json"), []byte(`{"id": "STK-DESIGN-003",`), 0o644); err != nil {
+		t.Fatalf("write broken: %v", err)
+	}
+	writeJSON(filepath.Join(projectDir, "design", "STK-DESIGN-009.json"), &domain.Stock{ID: "STK-DESIGN-010", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Mismatch", CreatedAt: now, UpdatedAt: now})
+
+	result, err := repo.Reconcile(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	got := map[string]StockChangeOp{}
+	for _, change := range result.Changes {
+		got[change.ID] = change.Op
+	}
+	want := map[string]StockChangeOp{"STK-DESIGN-001": StockChanged, "STK-RULES-001": StockCreated, "STK-DESIGN-002": StockDeleted}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected changes: %+v", result.Changes)
+	}
+	for id, op := range want {
+		if got[id] != op {
+			t.Fatalf("expected %s %s, got %+v", id, op, result.Changes)
+		}
+	}
+	if len(result.Diagnostics) != 2 {
+		t.Fatalf("expected diagnostics for broken and mismatched files, got %+v", result.Diagnostics)
+	}
+
+	// 変更はSQLiteのインデックスにも反映され、壊れたファイルの直前の内容は索引に残る
+	if stock, err := index.Get(ctx, "proj-1", "STK-DESIGN-001"); err != nil || stock.Content != "v2 (hand edit)" {
+		t.Fatalf("expected hand edit mirrored, got %+v (%v)", stock, err)
+	}
+	if _, err := index.Get(ctx, "proj-1", "STK-DESIGN-002"); err != domain.ErrNotFound {
+		t.Fatalf("expected deleted stock removed from index, got %v", err)
+	}
+	if _, err := index.Get(ctx, "proj-1", "STK-RULES-001"); err != nil {
+		t.Fatalf("expected created stock in index: %v", err)
+	}
+	if count, err := files.Count(ctx, "proj-1", nil); err != nil || count != 3 {
+		t.Fatalf("expected broken stock kept in file index, got %d (%v)", count, err)
+	}
+
+	// 取り込み済みの変更は再度報告しない
+	if result, err := repo.Reconcile(ctx, "proj-1"); err != nil || len(result.Changes) != 0 || len(result.Diagnostics) != 2 {
+		t.Fatalf("expected only diagnostics on second reconcile, got %+v (%v)", result, err)
+	}
+}
+
+func TestGitStockHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), gitIdentityEnv("tester")...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// ローカルのリポジトリと、共有用のベアリポジトリ（リモートの代わり）
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	git(root, "init", "--quiet", "--bare", remote)
+
+	dirA := filepath.Join(root, "a")
+	repoA := NewFileStockRepository(dirA)
+	historyA, err := NewGitStockHistory(ctx, dirA)
+	if err != nil {
+		t.Fatalf("init history: %v", err)
+	}
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	stock := &domain.Stock{ID: "STK-DESIGN-001", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Design", Content: "v1", CreatedAt: now, UpdatedAt: now}
+	if err := repoA.Create(ctx, stock); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	paths := []string{StockFilePath(stock)}
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: stock.ID, Action: "create", Actor: "Claude Code", Paths: paths}); err != nil {
+		t.Fatalf("record create: %v", err)
+	}
+
+	// 記録対象以外のファイルの未記録の変更（手作業の編集）はコミットに含めない
+	other := &domain.Stock{ID: "STK-DESIGN-002", ProjectID: "proj-1", Category: domain.CategoryDesign, Title: "Other", CreatedAt: now, UpdatedAt: now}
+	if err := repoA.Create(ctx, other); err != nil {
+		t.Fatalf("create other: %v", err)
+	}
+	stock.Content = "v2"
+	stock.UpdatedAt = now.Add(time.Hour)
+	if err := repoA.Update(ctx, stock); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: stock.ID, Action: "update", Paths: paths}); err != nil {
+		t.Fatalf("record update: %v", err)
+	}
+	if tracked := git(dirA, "ls-files"); strings.Contains(tracked, other.ID) {
+		t.Fatalf("expected unrelated change not to be committed, got tracked files:\n%s", tracked)
+	}
+	// 変更がなければコミットしない
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: stock.ID, Action: "update", Paths: paths}); err != nil {
+		t.Fatalf("record without changes: %v", err)
+	}
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: other.ID, Action: "create", Paths: []string{StockFilePath(other)}}); err != nil {
+		t.Fatalf("record other: %v", err)
+	}
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: stock.ID, Action: "update", Paths: []string{"proj-2/design/x.json"}}); err == nil {
+		t.Fatal("expected paths outside the project to be rejected")
+	}
+
+	revisions, err := historyA.History(ctx, "proj-1", stock.ID, 0)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Action != "update" || revisions[1].Action != "create" {
+		t.Fatalf("expected update and create revisions, got %+v", revisions)
+	}
+	if revisions[1].Author != "Claude Code" || revisions[0].Author != "pim" {
+		t.Fatalf("expected actors recorded as authors, got %+v", revisions)
+	}
+	if got, err := historyA.History(ctx, "proj-1", stock.ID, 1); err != nil || len(got) != 1 {
+		t.Fatalf("expected limit to apply, got %+v (%v)", got, err)
+	}
+	if tracked := git(dirA, "ls-files"); strings.Contains(tracked, "index.json") || strings.Contains(tracked, ".lock") {
+		t.Fatalf("expected index and lock files to be ignored, got tracked files:\n%s", tracked)
+	}
+
+	// 上流がない場合は pull しない
+	if result, err := historyA.Sync(ctx); err != nil || result.Upstream != "" || result.Committed {
+		t.Fatalf("expected sync without upstream to do nothing, got %+v (%v)", result, err)
+	}
+
+	git(dirA, "remote", "add", "origin", remote)
+	git(dirA, "push", "--quiet", "-u", "origin", "HEAD")
+	dirB := filepath.Join(root, "b")
+	git(root, "clone", "--quiet", remote, dirB)
+	historyB, err := NewGitStockHistory(ctx, dirB)
+	if err != nil {
+		t.Fatalf("open cloned history: %v", err)
+	}
+
+	// B での手作業の編集を sync でコミットして共有し、A の sync で取り込む
+	pathB := filepath.Join(dirB, "proj-1", "design", stock.ID+".json")
+	edited := *stock
+	edited.Content = "v3 (edited on b)"
+	data, _ := json.MarshalIndent(&edited, "", "  ")
+	if err := os.WriteFile(pathB, data, 0o644); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if result, err := historyB.Sync(ctx); err != nil || !result.Committed || len(result.Conflicts) != 0 {
+		t.Fatalf("expected hand edit to be committed, got %+v (%v)", result, err)
+	}
+	git(dirB, "push", "--quiet")
+
+	result, err := historyA.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if result.Upstream == "" || result.Before == result.After || len(result.Conflicts) != 0 {
+		t.Fatalf("expected pulled changes, got %+v", result)
+	}
+	got, err := repoA.Get(ctx, "proj-1", stock.ID)
+	if err != nil || got.Content != edited.Content {
+		t.Fatalf("expected pulled content, got %+v (%v)", got, err)
+	}
+
+	// 同じStockを両方で変更すると競合し、取り込みを取り消す
+	got.Content = "v4 (a)"
+	if err := repoA.Update(ctx, got); err != nil {
+		t.Fatalf("update a: %v", err)
+	}
+	if err := historyA.Record(ctx, StockCommit{ProjectID: "proj-1", StockID: stock.ID, Action: "update", Paths: paths}); err != nil {
+		t.Fatalf("record a: %v", err)
+	}
+	edited.Content = "v4 (b)"
+	data, _ = json.MarshalIndent(&edited, "", "  ")
+	if err := os.WriteFile(pathB, data, 0o644); err != nil {
+		t.Fatalf("edit b: %v", err)
+	}
+	if _, err := historyB.Sync(ctx); err != nil {
+		t.Fatalf("sync b: %v", err)
+	}
+	git(dirB, "push", "--quiet")
+
+	result, err = historyA.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync with conflict: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "proj-1/design/"+stock.ID+".json" || result.Before != result.After {
+		t.Fatalf("expected one aborted conflict, got %+v", result)
+	}
+	if got, err := repoA.Get(ctx, "proj-1", stock.ID); err != nil || got.Content != "v4 (a)" {
+		t.Fatalf("expected local content kept after aborted merge, got %+v (%v)", got, err)
+	}
 }
@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// SQLiteStockRepository はSQLiteベースのStockリポジトリ実装。
+// states.db の接続を共有し、プロジェクト・カテゴリ・優先度のインデックスで一覧を取得する。
+// Stock全体は data 列にJSONで保存し、絞り込みに使う項目のみ列として持つ。
+type SQLiteStockRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteStockRepository は新しいSQLiteStockRepositoryを生成する。
+func NewSQLiteStockRepository(db *sql.DB) (*SQLiteStockRepository, error) {
+	if err := migrateStatesDB(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate stocks table: %w", err)
+	}
+	return &SQLiteStockRepository{db: db}, nil
+}
+
+const stockUpsertColumns = `id, project_id, category, priority, title, tags, redirect_to, deleted_at, created_at, updated_at, data`
+
+// Create は新しいStockをSQLiteに保存する。
+func (r *SQLiteStockRepository) Create(ctx context.Context, stock *domain.Stock) error {
+	args, err := stockRowArgs(stock)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO stocks (`+stockUpsertColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return domain.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert stock: %w", err)
+	}
+	return nil
+}
+
+// Get は管理番号でStockを取得する。projectID が空の場合は全プロジェクトから探す。
+func (r *SQLiteStockRepository) Get(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	query := `SELECT data FROM stocks WHERE id = ?`
+	args := []any{id}
+	if projectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, projectID)
+	}
+	rows, err := r.db.QueryContext(ctx, query+` LIMIT 2`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	defer rows.Close()
+
+	var found []string
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		found = append(found, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	switch len(found) {
+	case 0:
+		return nil, domain.ErrNotFound
+	case 1:
+		return unmarshalStock(found[0])
+	default:
+		return nil, domain.ErrAmbiguousID
+	}
+}
+
+// Update はStockを更新する。
+func (r *SQLiteStockRepository) Update(ctx context.Context, stock *domain.Stock) error {
+	return updateStockRow(ctx, r.db, stock)
+}
+
+// UpdateIfUnchanged は保存されているStockの更新日時が expectedUpdatedAt と一致する場合のみ更新する。
+// 確認と更新を1トランザクションで行い、一致しない場合は domain.ErrConflict を返す。
+func (r *SQLiteStockRepository) UpdateIfUnchanged(ctx context.Context, stock *domain.Stock, expectedUpdatedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRowContext(ctx, `SELECT data FROM stocks WHERE id = ? AND project_id = ?`, stock.ID, stock.ProjectID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stock: %w", err)
+	}
+	current, err := unmarshalStock(data)
+	if err != nil {
+		return err
+	}
+	if !current.UpdatedAt.Equal(expectedUpdatedAt) {
+		return domain.ErrConflict
+	}
+
+	if err := updateStockRow(ctx, tx, stock); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlExecer は *sql.DB と *sql.Tx の共通部分。
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func updateStockRow(ctx context.Context, db sqlExecer, stock *domain.Stock) error {
+	args, err := stockRowArgs(stock)
+	if err != nil {
+		return err
+	}
+	// stockUpsertColumns の順の id, project_id を WHERE 句に回す
+	result, err := db.ExecContext(ctx, `
+	UPDATE stocks
+	SET category = ?, priority = ?, title = ?, tags = ?, redirect_to = ?, deleted_at = ?, created_at = ?, updated_at = ?, data = ?
+	WHERE id = ? AND project_id = ?
+	`, append(args[2:], args[0], args[1])...)
+	if err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Delete はStockを削除する。projectID が空の場合は全プロジェクトから探す。
+func (r *SQLiteStockRepository) Delete(ctx context.Context, projectID string, id string) error {
+	if projectID == "" {
+		stock, err := r.Get(ctx, "", id)
+		if err != nil {
+			return err
+		}
+		projectID = stock.ProjectID
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM stocks WHERE project_id = ? AND id = ?`, projectID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete stock: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// List はプロジェクト内のStockを優先度の昇順・更新日時の降順・管理番号の昇順で一覧取得する。
+func (r *SQLiteStockRepository) List(ctx context.Context, projectID string, opts *StockListOptions) ([]*domain.Stock, error) {
+	where, args := stockListConditions(projectID, opts)
+
+	query := `
+	SELECT data FROM stocks` + where + `
+	ORDER BY priority ASC, updated_at DESC, id ASC
+	`
+	if opts != nil && (opts.Limit > 0 || opts.Offset > 0) {
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = -1 // SQLiteでは負のLIMITは無制限
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, max(opts.Offset, 0))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
+	}
+	defer rows.Close()
+
+	var stocks []*domain.Stock
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		stock, err := unmarshalStock(data)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	return stocks, rows.Err()
+}
+
+// Count は条件に一致するStockの件数を返す。
+func (r *SQLiteStockRepository) Count(ctx context.Context, projectID string, opts *StockListOptions) (int, error) {
+	where, args := stockListConditions(projectID, opts)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM stocks`+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count stocks: %w", err)
+	}
+	return count, nil
+}
+
+// TagCounts は条件に一致するStockのタグごとの件数を件数の降順・タグ名の昇順で返す。
+func (r *SQLiteStockRepository) TagCounts(ctx context.Context, projectID string, opts *StockListOptions) ([]domain.TagCount, error) {
+	where, args := stockListConditions(projectID, opts)
+
+	query := `
+	SELECT t.value, COUNT(*) AS cnt
+	FROM (SELECT tags FROM stocks` + where + `) s, json_each(s.tags) t
+	GROUP BY t.value
+	ORDER BY cnt DESC, t.value ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []domain.TagCount{}
+	for rows.Next() {
+		var c domain.TagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// IsEmpty はStockが1件も保存されていない場合に true を返す。
+func (r *SQLiteStockRepository) IsEmpty(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM stocks)`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check stocks: %w", err)
+	}
+	return !exists, nil
+}
+
+// Replace は保存されているStockをすべて stocks で置き換える（エイリアス・削除済みを含む）。
+// ミラーの再構築やファイルからの取り込みに使い、1トランザクションで反映する。
+func (r *SQLiteStockRepository) Replace(ctx context.Context, stocks []*domain.Stock) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stocks`); err != nil {
+		return fmt.Errorf("failed to clear stocks: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO stocks (`+stockUpsertColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare stock insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, stock := range stocks {
+		args, err := stockRowArgs(stock)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert stock %s: %w", stock.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// stockListConditions は一覧取得条件のWHERE句と引数を組み立てる。
+// 再分類によるエイリアスは IncludeAliases を、削除済みは IncludeDeleted を指定しない限り除外する。
+func stockListConditions(projectID string, opts *StockListOptions) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if opts == nil || !opts.IncludeAliases {
+		conditions = append(conditions, "redirect_to = ''")
+	}
+
+	if projectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, projectID)
+	}
+	if opts == nil || !opts.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if opts != nil {
+		if opts.Category != nil {
+			conditions = append(conditions, "category = ?")
+			args = append(args, string(*opts.Category))
+		}
+		if opts.Priority != nil {
+			conditions = append(conditions, "priority = ?")
+			args = append(args, int(*opts.Priority))
+		}
+		if condition, tagArgs := tagCondition("stocks.tags", opts.Tags, opts.TagMatch); condition != "" {
+			conditions = append(conditions, condition)
+			args = append(args, tagArgs...)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return `
+	WHERE ` + strings.Join(conditions, " AND "), args
+}
+
+// stockRowArgs は stockUpsertColumns の順に列の値を返す。
+// 日時はUTCで保存し、文字列としての比較で並び順が崩れないようにする。
+func stockRowArgs(stock *domain.Stock) ([]any, error) {
+	data, err := json.Marshal(stock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stock: %w", err)
+	}
+	tagsJSON, err := marshalStringArray(domain.NormalizeTags(stock.Tags))
+	if err != nil {
+		return nil, err
+	}
+	var deletedAt any
+	if stock.DeletedAt != nil {
+		deletedAt = stock.DeletedAt.UTC()
+	}
+	return []any{
+		stock.ID,
+		stock.ProjectID,
+		string(stock.Category),
+		int(stock.Priority),
+		stock.Title,
+		tagsJSON,
+		stock.RedirectTo,
+		deletedAt,
+		stock.CreatedAt.UTC(),
+		stock.UpdatedAt.UTC(),
+		string(data),
+	}, nil
+}
+
+func unmarshalStock(data string) (*domain.Stock, error) {
+	var stock domain.Stock
+	if err := json.Unmarshal([]byte(data), &stock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stock: %w", err)
+	}
+	return &stock, nil
+}
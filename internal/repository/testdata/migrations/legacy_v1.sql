@@ -0,0 +1,20 @@
+-- 初期スキーマ（assignee / due_at 追加前）。タグは文字列連結で保存されている
+CREATE TABLE states (
+	id          TEXT PRIMARY KEY,
+	project_id  TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	status      TEXT NOT NULL DEFAULT 'open',
+	priority    INTEGER NOT NULL DEFAULT 3,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	resolution  TEXT NOT NULL DEFAULT '',
+	tags        TEXT NOT NULL DEFAULT '[]',
+	ref_ids     TEXT NOT NULL DEFAULT '[]',
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL,
+	archived_at DATETIME
+);
+CREATE INDEX idx_states_project_id ON states(project_id);
+
+INSERT INTO states (id, project_id, type, title, tags, ref_ids, created_at, updated_at)
+VALUES ('STA-TASK-001', 'proj-1', 'task', 'legacy task', '["say "hi"","api"]', '["STK-DESIGN-001"]', '2025-01-10 09:00:00', '2025-01-10 09:00:00');
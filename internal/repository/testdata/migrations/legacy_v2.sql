@@ -0,0 +1,22 @@
+-- assignee / due_at 追加後、インシデント等の種別固有情報の追加前
+CREATE TABLE states (
+	id          TEXT PRIMARY KEY,
+	project_id  TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	status      TEXT NOT NULL DEFAULT 'open',
+	priority    INTEGER NOT NULL DEFAULT 3,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	resolution  TEXT NOT NULL DEFAULT '',
+	tags        TEXT NOT NULL DEFAULT '[]',
+	ref_ids     TEXT NOT NULL DEFAULT '[]',
+	assignee    TEXT NOT NULL DEFAULT '',
+	due_at      DATETIME,
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL,
+	archived_at DATETIME
+);
+CREATE INDEX idx_states_assignee ON states(assignee);
+
+INSERT INTO states (id, project_id, type, title, tags, assignee, created_at, updated_at)
+VALUES ('STA-TASK-001', 'proj-1', 'task', 'legacy task', '["api"]', 'alice', '2025-03-01 09:00:00', '2025-03-01 09:00:00');
@@ -0,0 +1,24 @@
+-- 種別固有情報の追加後、releases テーブルの追加前
+CREATE TABLE states (
+	id          TEXT PRIMARY KEY,
+	project_id  TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	status      TEXT NOT NULL DEFAULT 'open',
+	priority    INTEGER NOT NULL DEFAULT 3,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	resolution  TEXT NOT NULL DEFAULT '',
+	tags        TEXT NOT NULL DEFAULT '[]',
+	ref_ids     TEXT NOT NULL DEFAULT '[]',
+	assignee    TEXT NOT NULL DEFAULT '',
+	due_at      DATETIME,
+	incident    TEXT NOT NULL DEFAULT '',
+	problem     TEXT NOT NULL DEFAULT '',
+	change      TEXT NOT NULL DEFAULT '',
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL,
+	archived_at DATETIME
+);
+
+INSERT INTO states (id, project_id, type, title, tags, incident, created_at, updated_at)
+VALUES ('STA-INCIDENT-001', 'proj-1', 'incident', 'legacy incident', '["api"]', '{"severity":"SEV2"}', '2025-06-01 09:00:00', '2025-06-01 09:00:00');
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// mcpServerName は生成する .mcp.json に登録するサーバー名。
+const mcpServerName = "project-information-manager"
+
+// CLAUDE.md / AGENTS.md のうち pim が管理する範囲を示すマーカー。範囲外の記述はそのまま残す。
+const (
+	guideBeginMarker = "<!-- BEGIN pim agent generate -->"
+	guideEndMarker   = "<!-- END pim agent generate -->"
+)
+
+// AgentConfigService はプロジェクトの rules / management および P0/P1 のStockから、
+// AIエージェント向けの設定ファイル（CLAUDE.md, AGENTS.md, Cursor rules, Skill, .mcp.json）を生成する。
+// 出力は入力Stockのみから決まり、何度実行しても同じ内容になる。
+type AgentConfigService struct {
+	stockRepo repository.StockRepository
+}
+
+// NewAgentConfigService は新しいAgentConfigServiceを生成する。
+func NewAgentConfigService(stockRepo repository.StockRepository) *AgentConfigService {
+	return &AgentConfigService{stockRepo: stockRepo}
+}
+
+// GenerateAgentConfigInput は設定ファイル生成の入力パラメータ。
+type GenerateAgentConfigInput struct {
+	ProjectID     string
+	ServerCommand string            // .mcp.json に記載する pim-server のコマンド（デフォルト: pim-server）
+	ServerArgs    []string          // pim-server の引数
+	ServerEnv     map[string]string // pim-server に渡す環境変数（例: PIM_DATA_DIR）
+}
+
+// GeneratedFile は生成された1ファイル。Path は出力先ディレクトリからの相対パス。
+type GeneratedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// agentConfigStocks は生成に用いるStockを用途別に分類したもの。
+type agentConfigStocks struct {
+	rules      []*domain.Stock // 開発ルール
+	management []*domain.Stock // 管理方針
+	context    []*domain.Stock // その他カテゴリの P0/P1（プロダクトゴール・上位設計）
+}
+
+// Generate はプロジェクトのStockから設定ファイル一式を生成する。結果はパス順に並ぶ。
+func (s *AgentConfigService) Generate(ctx context.Context, input GenerateAgentConfigInput) ([]GeneratedFile, error) {
+	if strings.TrimSpace(input.ProjectID) == "" {
+		return nil, fmt.Errorf("project_id is required")
+	}
+	stocks, err := s.loadStocks(ctx, input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	guide := renderAgentGuide(input.ProjectID, stocks)
+	files := []GeneratedFile{
+		{Path: "CLAUDE.md", Content: guide},
+		{Path: "AGENTS.md", Content: guide},
+		{Path: filepath.Join(".claude", "skills", "pim-state-management", "SKILL.md"), Content: renderStateSkill(input.ProjectID)},
+		{Path: filepath.Join(".cursor", "rules", "pim-workflow.mdc"), Content: renderCursorRule(input.ProjectID,
+			"Project Information Manager (PIM) によるStock/State管理ルール", true, renderWorkflowRules(input.ProjectID))},
+	}
+	if len(stocks.context) > 0 {
+		files = append(files, GeneratedFile{
+			Path: filepath.Join(".cursor", "rules", "pim-product-context.mdc"),
+			Content: renderCursorRule(input.ProjectID, "プロダクトゴール・上位設計（P0/P1）", true,
+				renderStockSection("プロダクトゴール・上位設計", stocks.context)),
+		})
+	}
+	for _, stock := range append(append([]*domain.Stock{}, stocks.rules...), stocks.management...) {
+		files = append(files, GeneratedFile{
+			Path:    filepath.Join(".cursor", "rules", "pim-"+strings.ToLower(stock.ID)+".mdc"),
+			Content: renderCursorRule(input.ProjectID, stock.Title, stock.Priority <= domain.PriorityP1, renderStockBody(stock, 0)),
+		})
+	}
+
+	mcpJSON, err := renderMCPConfig(input, nil)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, GeneratedFile{Path: ".mcp.json", Content: mcpJSON})
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (s *AgentConfigService) loadStocks(ctx context.Context, projectID string) (*agentConfigStocks, error) {
+	all, err := s.stockRepo.List(ctx, projectID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Priority != all[j].Priority {
+			return all[i].Priority < all[j].Priority
+		}
+		return all[i].ID < all[j].ID
+	})
+
+	stocks := &agentConfigStocks{}
+	for _, stock := range all {
+		switch {
+		case stock.Category == domain.CategoryRules:
+			stocks.rules = append(stocks.rules, stock)
+		case stock.Category == domain.CategoryManagement:
+			stocks.management = append(stocks.management, stock)
+		case stock.Priority <= domain.PriorityP1:
+			stocks.context = append(stocks.context, stock)
+		}
+	}
+	return stocks, nil
+}
+
+// FileChange は出力先への反映結果（dry-run時は反映予定）。
+type FileChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`         // "create" | "update" | "unchanged" | "delete"
+	Diff   string `json:"diff,omitempty"` // unified diff（dry-run時のみ）
+}
+
+// Apply は生成したファイルを outDir に書き込む。dryRun の場合は書き込まずに差分のみを返す。
+// 既存の .mcp.json は他のサーバー設定を残したまま pim-server のエントリのみを更新する。
+// 既存の CLAUDE.md / AGENTS.md はマーカーで囲んだ範囲のみを置き換え、無ければ末尾に追記する。
+// 今回生成されなかった .cursor/rules/pim-*.mdc（削除・カテゴリ変更されたStockのルール）は、pim が生成したもののみ削除する。
+func (s *AgentConfigService) Apply(input GenerateAgentConfigInput, files []GeneratedFile, outDir string, dryRun bool) ([]FileChange, error) {
+	changes := make([]FileChange, 0, len(files))
+	for _, file := range files {
+		path := filepath.Join(outDir, file.Path)
+		existing, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		content := file.Content
+		switch {
+		case existing == nil:
+		case file.Path == ".mcp.json":
+			if content, err = renderMCPConfig(input, existing); err != nil {
+				return nil, fmt.Errorf("failed to merge %s: %w", path, err)
+			}
+		case file.Path == "CLAUDE.md" || file.Path == "AGENTS.md":
+			if content, err = mergeGuideSection(string(existing), file.Content); err != nil {
+				return nil, fmt.Errorf("failed to merge %s: %w", path, err)
+			}
+		}
+
+		change := FileChange{Path: file.Path, Action: "update"}
+		switch {
+		case existing == nil:
+			change.Action = "create"
+		case string(existing) == content:
+			change.Action = "unchanged"
+		}
+		if dryRun {
+			change.Diff = unifiedDiff(file.Path, string(existing), content)
+		} else if change.Action != "unchanged" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	stale, err := staleCursorRules(input.ProjectID, files, outDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range stale {
+		path := filepath.Join(outDir, rel)
+		change := FileChange{Path: rel, Action: "delete"}
+		if dryRun {
+			existing, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			change.Diff = unifiedDiff(rel, string(existing), "")
+		} else if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// staleCursorRules は outDir にある pim-*.mdc のうち、files に含まれないもののパスを返す。
+// 利用者が書いたルールを消さないよう、projectID の生成ファイルの注記があるものに限る。
+func staleCursorRules(projectID string, files []GeneratedFile, outDir string) ([]string, error) {
+	notice := generatedNotice(projectID, "file")
+	generated := make(map[string]bool, len(files))
+	for _, file := range files {
+		generated[file.Path] = true
+	}
+	matches, err := filepath.Glob(filepath.Join(outDir, ".cursor", "rules", "pim-*.mdc"))
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, match := range matches {
+		rel, err := filepath.Rel(outDir, match)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() || generated[rel] {
+			continue
+		}
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", match, err)
+		}
+		if !strings.Contains(string(data), notice) {
+			continue
+		}
+		stale = append(stale, rel)
+	}
+	sort.Strings(stale)
+	return stale, nil
+}
+
+// mergeGuideSection は既存のガイド existing のうち、マーカーで囲まれた範囲を section に置き換える。
+// マーカーが無い場合は末尾に追記する。ただしマーカー導入前に pim が生成したファイルは全体を置き換える。
+// 終了マーカーだけが失われている場合は、どこまでが生成範囲か分からないためエラーを返す。
+func mergeGuideSection(existing, section string) (string, error) {
+	begin := strings.Index(existing, guideBeginMarker)
+	end := strings.Index(existing, guideEndMarker)
+	switch {
+	case begin >= 0 && end > begin:
+		return existing[:begin] + strings.TrimSuffix(section, "\n") + existing[end+len(guideEndMarker):], nil
+	case begin >= 0:
+		return "", fmt.Errorf("%q has no matching %q", guideBeginMarker, guideEndMarker)
+	case strings.Contains(existing, generatedNoticePrefix), strings.TrimSpace(existing) == "":
+		return section, nil
+	}
+	return strings.TrimRight(existing, "\n") + "\n\n" + section, nil
+}
+
+// renderMCPConfig は pim-server を登録した .mcp.json を生成する。existing が与えられた場合はマージする。
+func renderMCPConfig(input GenerateAgentConfigInput, existing []byte) (string, error) {
+	config := map[string]any{}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(existing, &config); err != nil {
+			return "", err
+		}
+	}
+	servers, _ := config["mcpServers"].(map[string]any)
+	if servers == nil {
+		servers = map[string]any{}
+	}
+
+	command := input.ServerCommand
+	if command == "" {
+		command = "pim-server"
+	}
+	server := map[string]any{
+		"command": command,
+		"args":    append([]string{}, input.ServerArgs...),
+	}
+	if len(input.ServerEnv) > 0 {
+		server["env"] = input.ServerEnv
+	}
+	servers[mcpServerName] = server
+	config["mcpServers"] = servers
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// generatedNoticePrefix は生成ファイル・生成範囲の注記の書き出し。
+const generatedNoticePrefix = "<!-- Generated by `pim agent generate"
+
+// generatedNotice は生成ファイルであることを示す注記。target は手で編集しないでほしい範囲（file / section）。
+func generatedNotice(projectID, target string) string {
+	return fmt.Sprintf("%s --project %s`. Edit the source Stocks and re-run instead of editing this %s. -->\n", generatedNoticePrefix, projectID, target)
+}
+
+// renderAgentGuide は CLAUDE.md / AGENTS.md 共通のエージェント向けガイドを、pim が管理する範囲のマーカーで囲んで組み立てる。
+func renderAgentGuide(projectID string, stocks *agentConfigStocks) string {
+	var b strings.Builder
+	b.WriteString(guideBeginMarker + "\n")
+	fmt.Fprintf(&b, "# %s Agent Guide\n\n", projectID)
+	b.WriteString(generatedNotice(projectID, "section"))
+	b.WriteString("\n")
+	b.WriteString(renderWorkflowRules(projectID))
+	if len(stocks.context) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderStockSection("プロダクトゴール・上位設計", stocks.context))
+	}
+	if len(stocks.rules) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderStockSection("開発ルール", stocks.rules))
+	}
+	if len(stocks.management) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderStockSection("管理方針", stocks.management))
+	}
+	b.WriteString(guideEndMarker + "\n")
+	return b.String()
+}
+
+// renderWorkflowRules はPIMを用いたセッション内での情報管理ルールを組み立てる。
+func renderWorkflowRules(projectID string) string {
+	var b strings.Builder
+	b.WriteString("## セッション内での設計・タスク状態管理ルール\n\n")
+	fmt.Fprintf(&b, "- MCP Server の %s を必ず利用して管理する（project_id: `%s`）。\n", mcpServerName, projectID)
+	b.WriteString("- すべての設計・ルール・方針の追加/変更は、必ず stock_manage で登録/更新する。\n")
+	b.WriteString("- すべての進行中のタスク・課題・変更は、必ず state_manage で登録/更新/アーカイブする。\n")
+	b.WriteString("- 情報参照は context_search を優先し、詳細が必要な場合のみ stock_manage read / state_manage read を使う。\n")
+	b.WriteString("- MCPに未登録の設計や状態を会話内で発見した場合、必ずMCPへ追記/更新を要求する。\n")
+	return b.String()
+}
+
+func renderStockSection(heading string, stocks []*domain.Stock) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", heading)
+	for _, stock := range stocks {
+		fmt.Fprintf(&b, "\n%s %s\n\n", strings.Repeat("#", stockHeadingLevel), stock.Title)
+		b.WriteString(renderStockBody(stock, stockHeadingLevel))
+	}
+	return b.String()
+}
+
+// stockHeadingLevel は renderStockSection が各Stockのタイトルに使う見出しレベル。
+const stockHeadingLevel = 3
+
+// renderStockBody はStockの本文を出典付きで組み立てる。
+// 本文中の見出しは埋め込み先の見出しレベル parentLevel より下になるよう下げる（0 の場合はそのまま）。
+func renderStockBody(stock *domain.Stock, parentLevel int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "_Source: %s (%s, %s)_\n\n", stock.ID, stock.Category, stock.Priority)
+	content := strings.TrimSpace(stock.Content)
+	if content != "" {
+		b.WriteString(demoteHeadings(content, parentLevel))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// demoteHeadings は content 中の最上位の見出しが parentLevel+1 になるよう、すべての見出しを同じ段数だけ下げる。
+// フェンスコードブロック内の行は見出しとして扱わない。見出しレベルは6で頭打ちにする。
+func demoteHeadings(content string, parentLevel int) string {
+	lines := strings.Split(content, "\n")
+	levels := make([]int, len(lines))
+	minLevel := 0
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" && len(line)-len(trimmed) <= 3 {
+			fence = marker
+			continue
+		}
+		if level := atxHeadingLevel(line); level > 0 {
+			levels[i] = level
+			if minLevel == 0 || level < minLevel {
+				minLevel = level
+			}
+		}
+	}
+	shift := parentLevel + 1 - minLevel
+	if minLevel == 0 || shift <= 0 {
+		return content
+	}
+	for i, level := range levels {
+		if level == 0 {
+			continue
+		}
+		rest := strings.TrimLeft(strings.TrimLeft(lines[i], " "), "#")
+		lines[i] = strings.Repeat("#", min(level+shift, 6)) + rest
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fenceMarker はフェンスコードブロックの開始行であればそのフェンス（``` / ~~~ 以上の連続）を返す。
+func fenceMarker(trimmed string) string {
+	for _, ch := range []string{"`", "~"} {
+		if strings.HasPrefix(trimmed, strings.Repeat(ch, 3)) {
+			n := len(trimmed) - len(strings.TrimLeft(trimmed, ch))
+			return strings.Repeat(ch, n)
+		}
+	}
+	return ""
+}
+
+// atxHeadingLevel は line がATX見出し（# 〜 ######）であればそのレベルを、そうでなければ0を返す。
+func atxHeadingLevel(line string) int {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0
+	}
+	level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if rest := trimmed[level:]; rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0
+	}
+	return level
+}
+
+// renderCursorRule はCursorのルールファイル（.mdc）を組み立てる。
+// alwaysApply=false のルールはdescriptionに基づいてエージェントが必要時に参照する。
+// 本文の先頭には生成ファイルの注記を入れ、不要になったときに削除してよいファイルと判別できるようにする。
+func renderCursorRule(projectID, description string, alwaysApply bool, body string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "description: %s\n", strings.ReplaceAll(description, "\n", " "))
+	b.WriteString("globs:\n")
+	fmt.Fprintf(&b, "alwaysApply: %t\n", alwaysApply)
+	b.WriteString("---\n\n")
+	b.WriteString(generatedNotice(projectID, "file"))
+	b.WriteString("\n")
+	b.WriteString(body)
+	return b.String()
+}
+
+// renderStateSkill はStateの作成・更新・アーカイブを行うSkill（SKILL.md形式）を組み立てる。
+func renderStateSkill(projectID string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("name: pim-state-management\n")
+	fmt.Fprintf(&b, "description: %s のタスク・課題・インシデント・変更（State）を state_manage ツールで作成・更新・アーカイブする。作業の開始・進捗・完了時に使用する。\n", projectID)
+	b.WriteString("---\n\n")
+	b.WriteString("# State Management\n\n")
+	fmt.Fprintf(&b, "このSkillはMCP Server %s の `state_manage` ツールを用いて、プロジェクト `%s` の動的な状態情報を管理する。\n", mcpServerName, projectID)
+	b.WriteString("\n## create: 追加のStateの作成\n\n")
+	b.WriteString("新しい作業・課題・インシデント・変更が発生したら作成する。\n\n")
+	fmt.Fprintf(&b, "- `state_manage action=create project_id=%s type=<task|issue|incident|change|problem> priority=<P0-P3> title=... description=...`\n", projectID)
+	b.WriteString("- 作成前に `state_manage action=search` で重複がないか確認する。\n")
+	b.WriteString("\n## update: 既存Stateの状態管理・情報追加\n\n")
+	b.WriteString("作業の開始・進捗・解決のたびに更新する。\n\n")
+	b.WriteString("- `state_manage action=update state_id=... status=<open|in_progress|resolved> description=... resolution=...`\n")
+	b.WriteString("- 変更（type=change）は承認が揃うまで open から進められない（in_progress・resolved とも）。`action=change` / `action=approve` で承認を記録する。\n")
+	b.WriteString("\n## archive: 不要なStateのアーカイブ\n\n")
+	b.WriteString("解決済みで日常的に参照しないStateはアーカイブする。後から参照すべき知見はStockに転記する。\n\n")
+	b.WriteString("- `state_manage action=archive state_id=... resolution=... stock_summary=...`\n")
+	b.WriteString("- 学びを書き起こす代わりに `draft_learnings=true` で下書きをStockに保存できる（転記先は `save_category`、デフォルト: management）。\n")
+	return b.String()
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func seedAgentConfigStocks(t *testing.T, repo repository.StockRepository) {
+	t.Helper()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, stock := range []*domain.Stock{
+		{ID: "STK-RULES-001", Category: domain.CategoryRules, Priority: domain.PriorityP1, Title: "TDD", Content: "# TDD\n\nテストを先に書く。\n\n## 手順\n\n```sh\n# コメント\ngo test ./...\n```"},
+		{ID: "STK-RULES-002", Category: domain.CategoryRules, Priority: domain.PriorityP3, Title: "命名規則", Content: "snake_case を使う。"},
+		{ID: "STK-MANAGEMENT-003", Category: domain.CategoryManagement, Priority: domain.PriorityP1, Title: "スクラム運用", Content: "2週間スプリント。"},
+		{ID: "STK-ARCHITECTURE-004", Category: domain.CategoryArchitecture, Priority: domain.PriorityP0, Title: "レイヤ構成", Content: "domain → repository → service。"},
+		{ID: "STK-DESIGN-005", Category: domain.CategoryDesign, Priority: domain.PriorityP2, Title: "詳細設計", Content: "含まれない"},
+	} {
+		stock.ProjectID = "proj-1"
+		stock.CreatedAt = now
+		stock.UpdatedAt = now
+		if err := repo.Create(context.Background(), stock); err != nil {
+			t.Fatalf("create stock: %v", err)
+		}
+	}
+}
+
+func TestAgentConfigServiceGenerate(t *testing.T) {
+	repo := repository.NewFileStockRepository(t.TempDir())
+	seedAgentConfigStocks(t, repo)
+	svc := NewAgentConfigService(repo)
+	input := GenerateAgentConfigInput{ProjectID: "proj-1"}
+
+	files, err := svc.Generate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	byPath := make(map[string]string)
+	for _, f := range files {
+		byPath[filepath.ToSlash(f.Path)] = f.Content
+	}
+
+	for _, path := range []string{
+		"CLAUDE.md", "AGENTS.md", ".mcp.json",
+		".claude/skills/pim-state-management/SKILL.md",
+		".cursor/rules/pim-workflow.mdc",
+		".cursor/rules/pim-product-context.mdc",
+		".cursor/rules/pim-stk-rules-001.mdc",
+		".cursor/rules/pim-stk-rules-002.mdc",
+		".cursor/rules/pim-stk-management-003.mdc",
+	} {
+		if _, ok := byPath[path]; !ok {
+			t.Fatalf("expected %s to be generated, got %v", path, files)
+		}
+	}
+
+	guide := byPath["CLAUDE.md"]
+	for _, want := range []string{"レイヤ構成", "### TDD", "### TDD\n\n_Source: STK-RULES-001 (rules, P1)_\n\n#### TDD", "\n##### 手順\n", "```sh\n# コメント\n", "スクラム運用", "project_id: `proj-1`"} {
+		if !strings.Contains(guide, want) {
+			t.Fatalf("CLAUDE.md missing %q:\n%s", want, guide)
+		}
+	}
+	if strings.Contains(guide, "詳細設計") {
+		t.Fatalf("P2 design stock should not be included:\n%s", guide)
+	}
+	if !strings.Contains(byPath[".cursor/rules/pim-stk-rules-001.mdc"], "\n# TDD\n") {
+		t.Fatalf("standalone rule should keep its headings:\n%s", byPath[".cursor/rules/pim-stk-rules-001.mdc"])
+	}
+	if !strings.Contains(byPath[".cursor/rules/pim-stk-rules-002.mdc"], "alwaysApply: false") {
+		t.Fatalf("expected P3 rule not to be always applied")
+	}
+	if !strings.Contains(byPath[".claude/skills/pim-state-management/SKILL.md"], "name: pim-state-management") {
+		t.Fatalf("unexpected skill file")
+	}
+
+	again, err := svc.Generate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("generate again: %v", err)
+	}
+	if len(again) != len(files) {
+		t.Fatalf("expected deterministic output")
+	}
+	for i := range files {
+		if again[i] != files[i] {
+			t.Fatalf("expected deterministic output for %s", files[i].Path)
+		}
+	}
+}
+
+func TestAgentConfigServiceApply(t *testing.T) {
+	repo := repository.NewFileStockRepository(t.TempDir())
+	seedAgentConfigStocks(t, repo)
+	svc := NewAgentConfigService(repo)
+	input := GenerateAgentConfigInput{ProjectID: "proj-1", ServerEnv: map[string]string{"PIM_DATA_DIR": "/srv/pim"}}
+	outDir := t.TempDir()
+
+	existing := `{"mcpServers": {"other": {"command": "other-server"}}}`
+	if err := os.WriteFile(filepath.Join(outDir, ".mcp.json"), []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 既存の AGENTS.md の手書きの記述は残し、pim の範囲を追記する
+	agents := filepath.Join(outDir, "AGENTS.md")
+	if err := os.WriteFile(agents, []byte("# Team notes\n\nkeep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := filepath.Join(outDir, ".cursor", "rules", "pim-stk-rules-099.mdc")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("---\ndescription: 削除済み\n---\n\n"+generatedNotice("proj-1", "file")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 利用者が書いた pim-*.mdc は生成ファイルの注記が無いため削除しない
+	handWritten := filepath.Join(outDir, ".cursor", "rules", "pim-team.mdc")
+	if err := os.WriteFile(handWritten, []byte("---\ndescription: チームのルール\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := svc.Generate(context.Background(), input)
+	changes, err := svc.Apply(input, files, outDir, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	deleted := false
+	for _, change := range changes {
+		if change.Action == "delete" {
+			if filepath.ToSlash(change.Path) != ".cursor/rules/pim-stk-rules-099.mdc" || !strings.Contains(change.Diff, "+++ /dev/null") {
+				t.Fatalf("unexpected delete: %+v", change)
+			}
+			deleted = true
+		}
+		if change.Path == ".mcp.json" && (change.Action != "update" || !strings.Contains(change.Diff, `+      "command": "pim-server"`)) {
+			t.Fatalf("unexpected .mcp.json change: %+v", change)
+		}
+		if change.Path == "CLAUDE.md" && (change.Action != "create" || !strings.HasPrefix(change.Diff, "--- /dev/null\n+++ b/CLAUDE.md\n@@ -0,0 +1,")) {
+			t.Fatalf("unexpected CLAUDE.md change: %+v", change)
+		}
+	}
+	if !deleted {
+		t.Fatalf("expected stale rule to be listed for deletion: %+v", changes)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "CLAUDE.md")); !os.IsNotExist(err) {
+		t.Fatal("dry run should not write files")
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatal("dry run should not remove files")
+	}
+
+	if _, err := svc.Apply(input, files, outDir, false); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatal("expected stale rule to be removed")
+	}
+	if _, err := os.Stat(handWritten); err != nil {
+		t.Fatalf("expected hand-written rule to be kept: %v", err)
+	}
+	guide, err := os.ReadFile(agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(guide), "# Team notes\n\nkeep me\n\n"+guideBeginMarker+"\n") || !strings.HasSuffix(string(guide), guideEndMarker+"\n") {
+		t.Fatalf("expected hand-written notes kept before the generated section:\n%s", guide)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, ".mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged struct {
+		MCPServers map[string]struct {
+			Command string            `json:"command"`
+			Env     map[string]string `json:"env"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		t.Fatalf("invalid .mcp.json: %v", err)
+	}
+	if merged.MCPServers["other"].Command != "other-server" || merged.MCPServers[mcpServerName].Env["PIM_DATA_DIR"] != "/srv/pim" {
+		t.Fatalf("unexpected merged .mcp.json: %s", data)
+	}
+
+	changes, err = svc.Apply(input, files, outDir, false)
+	if err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != "unchanged" {
+			t.Fatalf("expected re-run to be a no-op, got %+v", change)
+		}
+	}
+
+	// 再生成で置き換えるのはマーカーの範囲のみ
+	edited := strings.Replace(string(guide), "project_id: `proj-1`", "project_id: `edited`", 1) + "\n## Local\n"
+	if err := os.WriteFile(agents, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Apply(input, files, outDir, false); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	if data, _ := os.ReadFile(agents); string(data) != string(guide)+"\n## Local\n" {
+		t.Fatalf("expected only the generated section to be replaced:\n%s", data)
+	}
+}
+
+func TestMergeGuideSection(t *testing.T) {
+	section := guideBeginMarker + "\nnew\n" + guideEndMarker + "\n"
+	for _, tc := range []struct {
+		name, existing, want string
+	}{
+		{"empty", "", section},
+		{"append", "notes\n", "notes\n\n" + section},
+		{"replace", "a\n" + guideBeginMarker + "\nold\n" + guideEndMarker + "\nb\n", "a\n" + section + "b\n"},
+		{"legacy", "# proj-1 Agent Guide\n\n" + generatedNotice("proj-1", "file") + "old\n", section},
+	} {
+		got, err := mergeGuideSection(tc.existing, section)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: got %q (%v), want %q", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := mergeGuideSection("notes\n"+guideBeginMarker+"\nold\n", section); err == nil {
+		t.Fatal("expected error for a missing end marker")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if unifiedDiff("a.txt", "same\n", "same\n") != "" {
+		t.Fatal("expected empty diff for identical text")
+	}
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	newText := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+	want := "--- a/a.txt\n+++ b/a.txt\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"
+	if got := unifiedDiff("a.txt", oldText, newText); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
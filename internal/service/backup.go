@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// Backup はデータディレクトリ（states.db とStockファイル）のスナップショットを作成し、保持数を超えた古いものを削除する。
+// 実行中のサーバーからも呼び出せる。リストアはサーバーを停止して pim restore で行う。
+func (s *Services) Backup(ctx context.Context) (*repository.BackupResult, error) {
+	if s.backups == nil {
+		return nil, domain.ErrBackupDisabled
+	}
+	result, err := s.backups.Create(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to back up data directory: %w", err)
+	}
+	slog.Info("backed up data directory", "backup", result.Backup.Path, "removed", len(result.Removed))
+	return result, nil
+}
+
+// ListBackups はスナップショットを新しい順に返す。
+func (s *Services) ListBackups() ([]repository.BackupInfo, error) {
+	if s.backups == nil {
+		return nil, domain.ErrBackupDisabled
+	}
+	return s.backups.List()
+}
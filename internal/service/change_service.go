@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// ChangeService はITILの変更管理（リスク評価・切り戻し計画・承認ゲート）を提供する。
+type ChangeService struct {
+	stateRepo repository.StateRepository
+}
+
+// NewChangeService は新しいChangeServiceを生成する。
+func NewChangeService(stateRepo repository.StateRepository) *ChangeService {
+	return &ChangeService{stateRepo: stateRepo}
+}
+
+// UpdateChangeInput は変更固有情報の更新パラメータ。nil の項目は変更しない。
+type UpdateChangeInput struct {
+	RiskLevel    *string
+	RollbackPlan *string
+	Approvers    []string
+}
+
+// Update はリスクレベル・切り戻し手順・承認者を更新する。
+func (s *ChangeService) Update(ctx context.Context, id string, input UpdateChangeInput) (*domain.State, error) {
+	change, err := s.getChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.RiskLevel != nil {
+		risk, err := domain.ParseRiskLevel(*input.RiskLevel)
+		if err != nil {
+			return nil, err
+		}
+		change.Change.RiskLevel = risk
+	}
+	if input.RollbackPlan != nil {
+		change.Change.RollbackPlan = *input.RollbackPlan
+	}
+	if input.Approvers != nil {
+		change.Change.Approvers = input.Approvers
+	}
+
+	change.Touch(time.Now())
+	if err := s.stateRepo.Update(ctx, change); err != nil {
+		return nil, fmt.Errorf("failed to update change: %w", err)
+	}
+	return change, nil
+}
+
+// ApproveInput は承認・却下の記録パラメータ。
+type ApproveInput struct {
+	Approver string
+	Decision string // "approve" | "reject"
+	Comment  string
+}
+
+// Approve は承認者の判断を記録する。開始済み・解決済みの変更には記録できない。
+func (s *ChangeService) Approve(ctx context.Context, id string, input ApproveInput) (*domain.State, error) {
+	if strings.TrimSpace(input.Approver) == "" {
+		return nil, fmt.Errorf("approver is required")
+	}
+	decision := domain.ApprovalDecision(input.Decision)
+	if decision != domain.DecisionApprove && decision != domain.DecisionReject {
+		return nil, domain.ErrInvalidDecision
+	}
+
+	change, err := s.getChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != domain.StatusOpen {
+		return nil, fmt.Errorf("cannot record approval for change in status %s", change.Status)
+	}
+
+	now := time.Now()
+	change.Change.RecordApproval(domain.Approval{
+		Approver:  input.Approver,
+		Decision:  decision,
+		Comment:   input.Comment,
+		DecidedAt: now,
+	})
+
+	change.Touch(now)
+	if err := s.stateRepo.Update(ctx, change); err != nil {
+		return nil, fmt.Errorf("failed to record approval: %w", err)
+	}
+	return change, nil
+}
+
+func (s *ChangeService) getChange(ctx context.Context, id string) (*domain.State, error) {
+	state, err := s.stateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if state.Type != domain.StateTypeChange {
+		return nil, domain.ErrNotChange
+	}
+	if state.Status == domain.StatusArchived {
+		return nil, domain.ErrArchived
+	}
+	if state.Change == nil {
+		state.Change = &domain.ChangeDetails{}
+	}
+	return state, nil
+}
+
+// checkChangeApproval は変更を in_progress に遷移させてよいかを検証する。
+func checkChangeApproval(state *domain.State) error {
+	if state.Type != domain.StateTypeChange {
+		return nil
+	}
+	if state.Change == nil || state.Change.ApprovalStatus() != domain.ApprovalApproved {
+		return domain.ErrApprovalRequired
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func TestChangeServiceApprovalGate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeStateRepo()
+	stateSvc := NewStateService(repo, nil)
+	changeSvc := NewChangeService(repo)
+
+	change, err := stateSvc.Create(ctx, CreateStateInput{
+		ProjectID: "proj-1", Type: "change", Priority: "P1", Title: "Upgrade DB", Description: "major version upgrade",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	inProgress := string(domain.StatusInProgress)
+	if _, err := stateSvc.Update(ctx, change.ID, UpdateStateInput{Status: &inProgress}); !errors.Is(err, domain.ErrApprovalRequired) {
+		t.Fatalf("expected ErrApprovalRequired before approval, got %v", err)
+	}
+
+	risk := "high"
+	plan := "restore from snapshot"
+	if _, err := changeSvc.Update(ctx, change.ID, UpdateChangeInput{RiskLevel: &risk, RollbackPlan: &plan, Approvers: []string{"alice", "bob"}}); err != nil {
+		t.Fatalf("update change: %v", err)
+	}
+	if _, err := changeSvc.Approve(ctx, change.ID, ApproveInput{Approver: "alice", Decision: "approve"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if _, err := stateSvc.Update(ctx, change.ID, UpdateStateInput{Status: &inProgress}); !errors.Is(err, domain.ErrApprovalRequired) {
+		t.Fatalf("expected ErrApprovalRequired with pending approver, got %v", err)
+	}
+	// 作業開始を経ずに解決することもできない
+	resolved := string(domain.StatusResolved)
+	if _, err := stateSvc.Update(ctx, change.ID, UpdateStateInput{Status: &resolved}); !errors.Is(err, domain.ErrApprovalRequired) {
+		t.Fatalf("expected ErrApprovalRequired when resolving directly, got %v", err)
+	}
+	// 承認者以外の却下は数えない
+	if _, err := changeSvc.Approve(ctx, change.ID, ApproveInput{Approver: "mallory", Decision: "reject"}); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+
+	if _, err := changeSvc.Approve(ctx, change.ID, ApproveInput{Approver: "bob", Decision: "maybe"}); !errors.Is(err, domain.ErrInvalidDecision) {
+		t.Fatalf("expected ErrInvalidDecision, got %v", err)
+	}
+	updated, err := changeSvc.Approve(ctx, change.ID, ApproveInput{Approver: "bob", Decision: "approve", Comment: "LGTM"})
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if updated.Change.ApprovalStatus() != domain.ApprovalApproved || updated.Change.RiskLevel != domain.RiskHigh {
+		t.Fatalf("unexpected change details: %+v", updated.Change)
+	}
+
+	started, err := stateSvc.Update(ctx, change.ID, UpdateStateInput{Status: &inProgress})
+	if err != nil {
+		t.Fatalf("expected transition after approval, got %v", err)
+	}
+	if started.Status != domain.StatusInProgress {
+		t.Fatalf("unexpected status: %s", started.Status)
+	}
+
+	task, _ := stateSvc.Create(ctx, CreateStateInput{ProjectID: "proj-1", Type: "task", Priority: "P2", Title: "t", Description: "d"})
+	if _, err := changeSvc.Approve(ctx, task.ID, ApproveInput{Approver: "alice", Decision: "approve"}); !errors.Is(err, domain.ErrNotChange) {
+		t.Fatalf("expected ErrNotChange, got %v", err)
+	}
+	if _, err := stateSvc.Update(ctx, task.ID, UpdateStateInput{Status: &inProgress}); err != nil {
+		t.Fatalf("tasks should not require approval: %v", err)
+	}
+}
+
+type fakeReleaseRepo struct {
+	releases []*domain.Release
+}
+
+func (f *fakeReleaseRepo) Create(ctx context.Context, release *domain.Release) error {
+	for _, r := range f.releases {
+		if r.ProjectID == release.ProjectID && r.Version == release.Version {
+			return domain.ErrAlreadyExists
+		}
+	}
+	f.releases = append(f.releases, release)
+	return nil
+}
+
+func (f *fakeReleaseRepo) Get(ctx context.Context, projectID string, version string) (*domain.Release, error) {
+	for _, r := range f.releases {
+		if r.ProjectID == projectID && r.Version == version {
+			return r, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeReleaseRepo) List(ctx context.Context, projectID string) ([]*domain.Release, error) {
+	var out []*domain.Release
+	for _, r := range f.releases {
+		if r.ProjectID == projectID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+var _ repository.ReleaseRepository = (*fakeReleaseRepo)(nil)
+
+func TestReleaseServiceCreate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeStateRepo()
+	now := time.Now()
+	repo.states["STA-CHANGE-001"] = &domain.State{
+		ID: "STA-CHANGE-001", ProjectID: "proj-1", Type: domain.StateTypeChange, Status: domain.StatusResolved,
+		Title: "Upgrade DB", Resolution: "upgraded to v16", Change: &domain.ChangeDetails{RiskLevel: domain.RiskHigh},
+		CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-TASK-002"] = &domain.State{
+		ID: "STA-TASK-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusResolved,
+		Title: "Add login page", CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-TASK-005"] = &domain.State{
+		ID: "STA-TASK-005", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusArchived,
+		Title: "Remove legacy API", Resolution: "removed", CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-TASK-003"] = &domain.State{
+		ID: "STA-TASK-003", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Title: "WIP", CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-ISSUE-004"] = &domain.State{
+		ID: "STA-ISSUE-004", ProjectID: "proj-1", Type: domain.StateTypeIssue, Status: domain.StatusResolved,
+		Title: "Bug", CreatedAt: now, UpdatedAt: now,
+	}
+	svc := NewReleaseService(&fakeReleaseRepo{}, repo)
+
+	if _, err := svc.Create(ctx, CreateReleaseInput{ProjectID: "proj-1", Version: "0.9.0", ItemIDs: []string{"STA-TASK-003"}}); err == nil {
+		t.Fatal("expected error for unresolved item")
+	}
+	if _, err := svc.Create(ctx, CreateReleaseInput{ProjectID: "proj-1", Version: "0.9.0", ItemIDs: []string{"STA-ISSUE-004"}}); err == nil {
+		t.Fatal("expected error for non-releasable type")
+	}
+
+	release, err := svc.Create(ctx, CreateReleaseInput{ProjectID: "proj-1", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// 解決後にアーカイブしたタスクも自動選択の対象
+	if len(release.ItemIDs) != 3 || !slices.Contains(release.ItemIDs, "STA-TASK-005") {
+		t.Fatalf("expected resolved change, task and archived task, got %v", release.ItemIDs)
+	}
+	for _, want := range []string{"# Release 1.0.0", "## Changes", "**Upgrade DB** (STA-CHANGE-001, risk: high) — upgraded to v16", "## Tasks", "Add login page"} {
+		if !strings.Contains(release.Notes, want) {
+			t.Fatalf("release notes missing %q:\n%s", want, release.Notes)
+		}
+	}
+
+	// 既にリリース済みのものは自動選択の対象外
+	if _, err := svc.Create(ctx, CreateReleaseInput{ProjectID: "proj-1", Version: "1.0.1"}); err == nil {
+		t.Fatal("expected error when nothing is left to release")
+	}
+
+	summaries, err := svc.ListSummary(ctx, "proj-1")
+	if err != nil || len(summaries) != 1 || summaries[0].ItemCount != 3 {
+		t.Fatalf("unexpected summaries: %+v, err=%v", summaries, err)
+	}
+}
@@ -35,21 +35,54 @@ func NewContextService(
 }
 
 // ContextSearchResult はコンテキスト横断検索の結果。
+// Total はページングの対象となる全ヒット件数で、Stocks/States はそのうち現在のページの分。
 type ContextSearchResult struct {
-	Stocks []ContextSearchItem `json:"stocks"`
-	States []ContextSearchItem `json:"states"`
-	Total  int                 `json:"total"`
+	Stocks     []ContextSearchItem `json:"stocks"`
+	States     []ContextSearchItem `json:"states"`
+	Total      int                 `json:"total"`
+	NextCursor string              `json:"next_cursor,omitempty"` // 次のページのカーソル（最後のページでは省略）
 }
 
 // ContextSearchItem は検索結果の各アイテム（Stock/State共通のサマリ）。
 type ContextSearchItem struct {
-	ID       string  `json:"id"`
-	Type     string  `json:"type"` // "stock" or "state"
-	Title    string  `json:"title"`
-	Category string  `json:"category"` // Stock: category, State: state_type
-	Priority string  `json:"priority"`
-	Status   string  `json:"status,omitempty"` // Stateのみ
-	Score    float32 `json:"score,omitempty"`  // 類似度スコア
+	ID            string   `json:"id"`
+	Type          string   `json:"type"` // "stock" or "state"
+	Title         string   `json:"title"`
+	Summary       string   `json:"summary,omitempty"` // Stockのみ
+	Category      string   `json:"category"`          // Stock: category, State: state_type
+	Priority      string   `json:"priority"`
+	Status        string   `json:"status,omitempty"` // Stateのみ
+	Tags          []string `json:"tags,omitempty"`
+	SuggestedTags []string `json:"suggested_tags,omitempty"` // Stockのみ
+	Score         float32  `json:"score,omitempty"`          // 類似度スコア
+	Snippet       string   `json:"snippet,omitempty"`        // 全文検索の一致箇所（フォールバック検索のStateのみ）
+}
+
+func stockContextItem(stock *domain.Stock, score float32) ContextSearchItem {
+	return ContextSearchItem{
+		ID:            stock.ID,
+		Type:          "stock",
+		Title:         stock.Title,
+		Summary:       stock.Summary,
+		Category:      string(stock.Category),
+		Priority:      stock.Priority.String(),
+		Tags:          stock.Tags,
+		SuggestedTags: stock.SuggestedTags,
+		Score:         score,
+	}
+}
+
+func stateContextItem(state *domain.State, score float32) ContextSearchItem {
+	return ContextSearchItem{
+		ID:       state.ID,
+		Type:     "state",
+		Title:    state.Title,
+		Category: string(state.Type),
+		Priority: state.Priority.String(),
+		Status:   string(state.Status),
+		Tags:     state.Tags,
+		Score:    score,
+	}
 }
 
 type scoredContextItem struct {
@@ -61,24 +94,69 @@ type scoredContextItem struct {
 // Search はStock/Stateを横断してRAGセマンティック検索を行い、
 // サマリビューで結果を返却する。
 func (s *ContextService) Search(ctx context.Context, query string, projectID string, limit int) (*ContextSearchResult, error) {
-	if limit <= 0 {
-		limit = 10
+	return s.SearchPage(ctx, query, projectID, PageRequest{Limit: limit})
+}
+
+// SearchPage はStock/Stateを横断して検索し、関連度順のヒット（最大 maxSearchHits 件）をページ単位で返却する。
+func (s *ContextService) SearchPage(ctx context.Context, query string, projectID string, page PageRequest) (*ContextSearchResult, error) {
+	key := cursorKey("context-search", projectID, query)
+	offset, err := decodeCursor(page.Cursor, key)
+	if err != nil {
+		return nil, err
 	}
+	limit := pageLimit(page.Limit, defaultSearchLimit)
 
-	if s.vectorRepo == nil {
-		return s.fallbackSearch(ctx, query, projectID, limit)
+	var candidates []scoredContextItem
+	if s.vectorRepo != nil {
+		candidates, err = s.vectorCandidates(ctx, query, projectID)
+		if err != nil {
+			slog.Warn("vector context search failed, fallback to keyword search", "error", err)
+		}
+	}
+	if s.vectorRepo == nil || err != nil {
+		candidates, err = s.fallbackCandidates(ctx, query, projectID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	searchLimit := limit * 4
-	if searchLimit < limit {
-		searchLimit = limit
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].weighted != candidates[j].weighted {
+			return candidates[i].weighted > candidates[j].weighted
+		}
+		if !candidates[i].updatedAt.Equal(candidates[j].updatedAt) {
+			return candidates[i].updatedAt.After(candidates[j].updatedAt)
+		}
+		return candidates[i].item.ID < candidates[j].item.ID
+	})
+	if len(candidates) > maxSearchHits {
+		candidates = candidates[:maxSearchHits]
+	}
+
+	start, end := pageBounds(offset, limit, len(candidates))
+	result := &ContextSearchResult{
+		Stocks:     make([]ContextSearchItem, 0, end-start),
+		States:     make([]ContextSearchItem, 0, end-start),
+		Total:      len(candidates),
+		NextCursor: nextCursor(offset, end-start, len(candidates), key),
+	}
+	for _, c := range candidates[start:end] {
+		if c.item.Type == "stock" {
+			result.Stocks = append(result.Stocks, c.item)
+		} else if c.item.Type == "state" {
+			result.States = append(result.States, c.item)
+		}
 	}
-	searchResults, err := s.vectorRepo.Search(ctx, query, searchLimit, map[string]string{
+	return result, nil
+}
+
+// vectorCandidates はベクトル検索のヒットを優先度で重み付けした候補を返す。
+func (s *ContextService) vectorCandidates(ctx context.Context, query string, projectID string) ([]scoredContextItem, error) {
+	searchResults, err := s.vectorRepo.Search(ctx, query, maxSearchHits, map[string]string{
 		"project_id": projectID,
 	})
 	if err != nil {
-		slog.Warn("vector context search failed, fallback to keyword search", "error", err)
-		return s.fallbackSearch(ctx, query, projectID, limit)
+		return nil, err
 	}
 
 	candidates := make([]scoredContextItem, 0, len(searchResults))
@@ -86,8 +164,8 @@ func (s *ContextService) Search(ctx context.Context, query string, projectID str
 		docType := sr.Metadata["type"]
 		switch docType {
 		case "stock":
-			stock, err := s.stockRepo.Get(ctx, sr.ID)
-			if err != nil {
+			stock, err := s.stockRepo.Get(ctx, sr.Metadata["project_id"], vectorItemID(sr))
+			if err != nil || stock.IsDeleted() || stock.IsAlias() {
 				continue
 			}
 			if projectID != "" && stock.ProjectID != projectID {
@@ -95,14 +173,7 @@ func (s *ContextService) Search(ctx context.Context, query string, projectID str
 			}
 			weighted := sr.Similarity * priorityWeight(stock.Priority)
 			candidates = append(candidates, scoredContextItem{
-				item: ContextSearchItem{
-					ID:       stock.ID,
-					Type:     "stock",
-					Title:    stock.Title,
-					Category: string(stock.Category),
-					Priority: stock.Priority.String(),
-					Score:    weighted,
-				},
+				item:      stockContextItem(stock, weighted),
 				weighted:  weighted,
 				updatedAt: stock.UpdatedAt,
 			})
@@ -120,50 +191,19 @@ func (s *ContextService) Search(ctx context.Context, query string, projectID str
 			}
 			weighted := sr.Similarity * priorityWeight(state.Priority)
 			candidates = append(candidates, scoredContextItem{
-				item: ContextSearchItem{
-					ID:       state.ID,
-					Type:     "state",
-					Title:    state.Title,
-					Category: string(state.Type),
-					Priority: state.Priority.String(),
-					Status:   string(state.Status),
-					Score:    weighted,
-				},
+				item:      stateContextItem(state, weighted),
 				weighted:  weighted,
 				updatedAt: state.UpdatedAt,
 			})
 		}
 	}
 
-	sort.Slice(candidates, func(i, j int) bool {
-		if candidates[i].weighted != candidates[j].weighted {
-			return candidates[i].weighted > candidates[j].weighted
-		}
-		return candidates[i].updatedAt.After(candidates[j].updatedAt)
-	})
-
-	if len(candidates) > limit {
-		candidates = candidates[:limit]
-	}
-
-	result := &ContextSearchResult{
-		Stocks: make([]ContextSearchItem, 0, len(candidates)),
-		States: make([]ContextSearchItem, 0, len(candidates)),
-	}
-	for _, c := range candidates {
-		if c.item.Type == "stock" {
-			result.Stocks = append(result.Stocks, c.item)
-		} else if c.item.Type == "state" {
-			result.States = append(result.States, c.item)
-		}
-	}
-	result.Total = len(result.Stocks) + len(result.States)
-	return result, nil
+	return candidates, nil
 }
 
-// fallbackSearch はベクトルDBなしの場合のフォールバック。
-// タイトル・本文・タグの部分一致で検索し、優先度と更新日時で並び替える。
-func (s *ContextService) fallbackSearch(ctx context.Context, query string, projectID string, limit int) (*ContextSearchResult, error) {
+// fallbackCandidates はベクトルDBなしの場合のフォールバック。
+// Stockはタイトル・本文・タグの部分一致、Stateは全文検索で検索し、優先度で重み付けした候補を返す。
+func (s *ContextService) fallbackCandidates(ctx context.Context, query string, projectID string) ([]scoredContextItem, error) {
 	candidates := make([]scoredContextItem, 0)
 
 	if s.stockRepo != nil {
@@ -177,14 +217,7 @@ func (s *ContextService) fallbackSearch(ctx context.Context, query string, proje
 			}
 			score := priorityWeight(stock.Priority)
 			candidates = append(candidates, scoredContextItem{
-				item: ContextSearchItem{
-					ID:       stock.ID,
-					Type:     "stock",
-					Title:    stock.Title,
-					Category: string(stock.Category),
-					Priority: stock.Priority.String(),
-					Score:    score,
-				},
+				item:      stockContextItem(stock, score),
 				weighted:  score,
 				updatedAt: stock.UpdatedAt,
 			})
@@ -192,59 +225,21 @@ func (s *ContextService) fallbackSearch(ctx context.Context, query string, proje
 	}
 
 	if s.stateRepo != nil {
-		states, err := s.stateRepo.List(ctx, projectID, nil)
+		hits, err := s.stateRepo.Search(ctx, projectID, query, &repository.StateListOptions{Limit: maxSearchHits})
 		if err != nil {
-			return nil, fmt.Errorf("failed to list states for fallback search: %w", err)
+			return nil, fmt.Errorf("failed to search states for fallback search: %w", err)
 		}
-		for _, state := range states {
-			if state.Status == domain.StatusArchived {
-				continue
-			}
-			if !matchesQuery(query, state.Title, state.Description, joinTags(state.Tags)) {
-				continue
-			}
-			score := priorityWeight(state.Priority)
+		for _, hit := range hits {
+			score := priorityWeight(hit.State.Priority)
+			item := stateContextItem(hit.State, score)
+			item.Snippet = hit.Snippet
 			candidates = append(candidates, scoredContextItem{
-				item: ContextSearchItem{
-					ID:       state.ID,
-					Type:     "state",
-					Title:    state.Title,
-					Category: string(state.Type),
-					Priority: state.Priority.String(),
-					Status:   string(state.Status),
-					Score:    score,
-				},
+				item:      item,
 				weighted:  score,
-				updatedAt: state.UpdatedAt,
+				updatedAt: hit.State.UpdatedAt,
 			})
 		}
 	}
 
-	sort.Slice(candidates, func(i, j int) bool {
-		if candidates[i].weighted != candidates[j].weighted {
-			return candidates[i].weighted > candidates[j].weighted
-		}
-		return candidates[i].updatedAt.After(candidates[j].updatedAt)
-	})
-
-	if limit <= 0 {
-		limit = 10
-	}
-	if len(candidates) > limit {
-		candidates = candidates[:limit]
-	}
-
-	result := &ContextSearchResult{
-		Stocks: make([]ContextSearchItem, 0, len(candidates)),
-		States: make([]ContextSearchItem, 0, len(candidates)),
-	}
-	for _, c := range candidates {
-		if c.item.Type == "stock" {
-			result.Stocks = append(result.Stocks, c.item)
-		} else if c.item.Type == "state" {
-			result.States = append(result.States, c.item)
-		}
-	}
-	result.Total = len(result.Stocks) + len(result.States)
-	return result, nil
+	return candidates, nil
 }
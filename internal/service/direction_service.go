@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// GoalTag はプロダクトゴール・想定課題・提供価値を記述したStockに付与するタグ。
+const GoalTag = "goal"
+
+const (
+	// defaultDirectionWindow は分析対象とする最近の作業の期間。
+	defaultDirectionWindow = 90 * 24 * time.Hour
+	// vectorAlignmentThreshold はベクトル類似度でゴールに沿っているとみなす下限。
+	vectorAlignmentThreshold = 0.5
+	// lexicalAlignmentThreshold は文字bigram類似度でゴールに沿っているとみなす下限。
+	lexicalAlignmentThreshold = 0.15
+	// driftRatioDrop は直近期間の整合率がそれ以前の平均からこれ以上下がった場合にドリフトとみなす幅。
+	driftRatioDrop = 0.2
+)
+
+// DirectionService はプロジェクトのゴールと実際の作業（State・最近のStock）を比較し、
+// プロダクトの進行方向の妥当性をチェックする。
+type DirectionService struct {
+	stockRepo  repository.StockRepository
+	stateRepo  repository.StateRepository
+	vectorRepo repository.VectorRepository
+	generator  TextGenerator
+}
+
+// NewDirectionService は新しいDirectionServiceを生成する。
+func NewDirectionService(
+	stockRepo repository.StockRepository,
+	stateRepo repository.StateRepository,
+	vectorRepo repository.VectorRepository,
+) *DirectionService {
+	return &DirectionService{
+		stockRepo:  stockRepo,
+		stateRepo:  stateRepo,
+		vectorRepo: vectorRepo,
+	}
+}
+
+// SetTextGenerator は総評の生成に用いるLLMを設定する。
+func (s *DirectionService) SetTextGenerator(generator TextGenerator) {
+	s.generator = generator
+}
+
+// DirectionInput は方向性チェックの入力パラメータ。
+type DirectionInput struct {
+	ProjectID string
+	Since     time.Duration // 分析対象とする作業の期間（デフォルト: 90日）
+	Threshold float32       // 整合とみなす類似度の下限（0 の場合は手法ごとの既定値）
+}
+
+// DirectionReport はプロダクトの進行方向の妥当性チェック結果。
+type DirectionReport struct {
+	ProjectID   string          `json:"project_id"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Method      string          `json:"method"` // "vector" | "lexical"
+	Threshold   float32         `json:"threshold"`
+	Goals       []GoalCoverage  `json:"goals"`
+	Unaligned   []WorkAlignment `json:"unaligned"`            // どのゴールにも沿っていない進行中の作業
+	IdleGoals   []string        `json:"idle_goals"`           // 進行中の作業が1件もないゴールのID
+	Drift       []DriftPoint    `json:"drift"`                // 期間ごとの整合率の推移
+	Drifting    bool            `json:"drifting"`             // 直近期間の整合率が大きく下がっているか
+	Assessment  string          `json:"assessment,omitempty"` // LLMによる総評（LLM設定時のみ）
+	Notes       []string        `json:"notes,omitempty"`
+}
+
+// GoalCoverage はゴールごとの作業の対応状況。
+type GoalCoverage struct {
+	Goal       domain.StockSummary `json:"goal"`
+	ActiveWork int                 `json:"active_work"` // このゴールに最も沿っている進行中の作業数
+	Items      []WorkAlignment     `json:"items"`
+}
+
+// WorkAlignment は作業アイテムとゴールの対応。
+type WorkAlignment struct {
+	ID         string  `json:"id"`
+	Kind       string  `json:"kind"` // "state" | "stock"
+	Title      string  `json:"title"`
+	GoalID     string  `json:"goal_id,omitempty"` // 最も類似するゴール
+	Similarity float32 `json:"similarity"`
+}
+
+// DriftPoint は期間ごとのゴールとの整合状況。
+type DriftPoint struct {
+	Period        string  `json:"period"` // 例: "2025-03"
+	Total         int     `json:"total"`
+	Aligned       int     `json:"aligned"`
+	AlignedRatio  float32 `json:"aligned_ratio"`
+	AvgSimilarity float32 `json:"avg_similarity"`
+}
+
+// directionItem は分析対象の作業アイテム。
+type directionItem struct {
+	id        string
+	kind      string
+	title     string
+	text      string
+	active    bool // 進行中の作業か（未解決のState、または期間内に更新されたStock）
+	createdAt time.Time
+	scores    map[string]float32 // ゴールIDごとの類似度
+}
+
+func (item *directionItem) best() (string, float32) {
+	var (
+		bestID    string
+		bestScore float32
+	)
+	for goalID, score := range item.scores {
+		if bestID == "" || score > bestScore || (score == bestScore && goalID < bestID) {
+			bestID, bestScore = goalID, score
+		}
+	}
+	return bestID, bestScore
+}
+
+// Check はゴールに沿っていない作業、作業のないゴール、整合率の推移を報告する。
+// ゴールは goal タグの付いたStock、なければP0のStockを用いる。
+// 類似度はベクトルインデックスで計算し、利用できない場合は文字bigram類似度で代替する。
+func (s *DirectionService) Check(ctx context.Context, input DirectionInput) (*DirectionReport, error) {
+	if input.ProjectID == "" {
+		return nil, fmt.Errorf("project_id is required")
+	}
+	if input.Since <= 0 {
+		input.Since = defaultDirectionWindow
+	}
+	now := time.Now()
+
+	goals, items, err := s.collect(ctx, input.ProjectID, now.Add(-input.Since))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DirectionReport{
+		ProjectID:   input.ProjectID,
+		GeneratedAt: now,
+		Goals:       []GoalCoverage{},
+		Unaligned:   []WorkAlignment{},
+		IdleGoals:   []string{},
+		Drift:       []DriftPoint{},
+	}
+	if len(goals) == 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("ゴールが見つかりません。プロダクトゴール・想定課題・提供価値を記述したStockに %q タグを付けるか、P0で登録してください。", GoalTag))
+		return report, nil
+	}
+
+	report.Method = "vector"
+	if !s.scoreWithVectors(ctx, input.ProjectID, goals, items) {
+		report.Method = "lexical"
+		scoreLexically(goals, items)
+	}
+	report.Threshold = input.Threshold
+	if report.Threshold <= 0 {
+		report.Threshold = vectorAlignmentThreshold
+		if report.Method == "lexical" {
+			report.Threshold = lexicalAlignmentThreshold
+		}
+	}
+
+	coverage := make(map[string]*GoalCoverage, len(goals))
+	for _, goal := range goals {
+		report.Goals = append(report.Goals, GoalCoverage{Goal: goal.ToSummary(), Items: []WorkAlignment{}})
+	}
+	for i := range report.Goals {
+		coverage[report.Goals[i].Goal.ID] = &report.Goals[i]
+	}
+
+	for _, item := range items {
+		goalID, score := item.best()
+		alignment := WorkAlignment{ID: item.id, Kind: item.kind, Title: item.title, GoalID: goalID, Similarity: score}
+		if score < report.Threshold {
+			if item.active {
+				report.Unaligned = append(report.Unaligned, alignment)
+			}
+			continue
+		}
+		goal := coverage[goalID]
+		goal.Items = append(goal.Items, alignment)
+		if item.active {
+			goal.ActiveWork++
+		}
+	}
+	for _, goal := range report.Goals {
+		if goal.ActiveWork == 0 {
+			report.IdleGoals = append(report.IdleGoals, goal.Goal.ID)
+		}
+	}
+	sort.SliceStable(report.Unaligned, func(i, j int) bool {
+		return report.Unaligned[i].Similarity < report.Unaligned[j].Similarity
+	})
+
+	report.Drift, report.Drifting = computeDrift(items, report.Threshold)
+	report.Assessment = s.assess(ctx, report)
+	return report, nil
+}
+
+// assessmentMaxChars は総評の最大文字数。
+const assessmentMaxChars = 400
+
+// assess はLLMでチェック結果の総評を生成する。LLMが未設定、または生成に失敗した場合は空文字列を返す。
+func (s *DirectionService) assess(ctx context.Context, report *DirectionReport) string {
+	generator := generatorFor(ctx, s.generator)
+	if generator == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Product direction check result.\n\nGoals:\n")
+	for _, goal := range report.Goals {
+		fmt.Fprintf(&b, "- %s (active work: %d)\n", goal.Goal.Title, goal.ActiveWork)
+	}
+	b.WriteString("\nWork not aligned with any goal:\n")
+	for _, item := range report.Unaligned {
+		fmt.Fprintf(&b, "- [%s] %s\n", item.Kind, item.Title)
+	}
+	fmt.Fprintf(&b, "\nGoals without active work: %d\nDrifting: %t\n", len(report.IdleGoals), report.Drifting)
+
+	assessment, err := generator.Summarize(ctx, b.String(), assessmentMaxChars)
+	if err != nil {
+		warnGenerationError("failed to generate direction assessment", err)
+		return ""
+	}
+	return assessment
+}
+
+// collect はゴールと分析対象の作業アイテムを収集する。
+func (s *DirectionService) collect(ctx context.Context, projectID string, since time.Time) ([]*domain.Stock, []*directionItem, error) {
+	stocks, err := s.stockRepo.List(ctx, projectID, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+	var goals, p0 []*domain.Stock
+	for _, stock := range stocks {
+		if containsString(stock.Tags, GoalTag) {
+			goals = append(goals, stock)
+		}
+		if stock.Priority == domain.PriorityP0 {
+			p0 = append(p0, stock)
+		}
+	}
+	if len(goals) == 0 {
+		goals = p0
+	}
+	sort.Slice(goals, func(i, j int) bool { return goals[i].ID < goals[j].ID })
+
+	isGoal := make(map[string]bool, len(goals))
+	for _, goal := range goals {
+		isGoal[goal.ID] = true
+	}
+
+	var items []*directionItem
+	for _, stock := range stocks {
+		if isGoal[stock.ID] || stock.UpdatedAt.Before(since) {
+			continue
+		}
+		items = append(items, &directionItem{
+			id: stock.ID, kind: "stock", title: stock.Title, text: stock.Title + "\n" + stock.Content,
+			active: true, createdAt: stock.UpdatedAt, scores: map[string]float32{},
+		})
+	}
+
+	// アーカイブ済みはベクトルインデックスから除外されているため対象外とする
+	states, err := s.stateRepo.List(ctx, projectID, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list states: %w", err)
+	}
+	for _, state := range states {
+		if !state.IsOpen() && state.CreatedAt.Before(since) {
+			continue
+		}
+		items = append(items, &directionItem{
+			id: state.ID, kind: "state", title: state.Title, text: state.Title + "\n" + state.Description,
+			active: state.IsOpen(), createdAt: state.CreatedAt, scores: map[string]float32{},
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].id < items[j].id })
+	return goals, items, nil
+}
+
+// scoreWithVectors はゴールをクエリとしてベクトル検索し、各作業アイテムとの類似度を記録する。
+// ベクトルインデックスが利用できない場合は false を返す。
+func (s *DirectionService) scoreWithVectors(ctx context.Context, projectID string, goals []*domain.Stock, items []*directionItem) bool {
+	if s.vectorRepo == nil {
+		return false
+	}
+	byID := make(map[string]*directionItem, len(items))
+	counts := map[string]int{}
+	for _, item := range items {
+		byID[item.id] = item
+		counts[item.kind]++
+	}
+
+	for _, goal := range goals {
+		for kind, count := range counts {
+			filters := map[string]string{"type": kind, "project_id": projectID}
+			// ゴール自身やアーカイブ済みも含まれ得るため多めに取得する
+			results, err := s.vectorRepo.Search(ctx, goal.Title+"\n"+goal.Content, count+len(goals), filters)
+			if err != nil {
+				slog.Warn("vector direction check failed, fallback to text similarity", "error", err)
+				return false
+			}
+			for _, result := range results {
+				if item, ok := byID[vectorItemID(result)]; ok {
+					item.scores[goal.ID] = result.Similarity
+				}
+			}
+		}
+	}
+	return true
+}
+
+// scoreLexically は文字bigram類似度で各作業アイテムとゴールの類似度を計算する。
+func scoreLexically(goals []*domain.Stock, items []*directionItem) {
+	for _, item := range items {
+		for _, goal := range goals {
+			item.scores[goal.ID] = bigramSimilarity(goal.Title+"\n"+goal.Content, item.text)
+		}
+	}
+}
+
+// computeDrift は作業アイテムを作成月ごとに集計し、直近の月の整合率が下がっているかを判定する。
+func computeDrift(items []*directionItem, threshold float32) ([]DriftPoint, bool) {
+	buckets := make(map[string]*DriftPoint)
+	sums := make(map[string]float32)
+	for _, item := range items {
+		period := item.createdAt.Format("2006-01")
+		point, ok := buckets[period]
+		if !ok {
+			point = &DriftPoint{Period: period}
+			buckets[period] = point
+		}
+		_, score := item.best()
+		point.Total++
+		sums[period] += score
+		if score >= threshold {
+			point.Aligned++
+		}
+	}
+
+	drift := make([]DriftPoint, 0, len(buckets))
+	for period, point := range buckets {
+		point.AlignedRatio = float32(point.Aligned) / float32(point.Total)
+		point.AvgSimilarity = sums[period] / float32(point.Total)
+		drift = append(drift, *point)
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Period < drift[j].Period })
+
+	if len(drift) < 2 {
+		return drift, false
+	}
+	var previous float32
+	for _, point := range drift[:len(drift)-1] {
+		previous += point.AlignedRatio
+	}
+	previous /= float32(len(drift) - 1)
+	return drift, drift[len(drift)-1].AlignedRatio < previous-driftRatioDrop
+}
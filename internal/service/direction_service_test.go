@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/llm"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func seedDirectionFixtures(t *testing.T, stockRepo repository.StockRepository, stateRepo *fakeStateRepo) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	for _, stock := range []*domain.Stock{
+		{ID: "STK-REQUIREMENT-001", Priority: domain.PriorityP0, Title: "オフライン同期", Content: "モバイルアプリのオフライン同期で現場作業を止めない", Tags: []string{GoalTag}},
+		{ID: "STK-REQUIREMENT-002", Priority: domain.PriorityP0, Title: "請求書の自動発行", Content: "月次の請求書発行を自動化する", Tags: []string{GoalTag}},
+		{ID: "STK-DESIGN-003", Priority: domain.PriorityP2, Title: "同期キュー設計", Content: "オフライン同期のキューと競合解決"},
+	} {
+		stock.ProjectID = "proj-1"
+		stock.Category = domain.CategoryRequirement
+		stock.CreatedAt = now
+		stock.UpdatedAt = now
+		if err := stockRepo.Create(ctx, stock); err != nil {
+			t.Fatalf("create stock: %v", err)
+		}
+	}
+	lastMonth := now.AddDate(0, -1, 0)
+	stateRepo.states["STA-TASK-001"] = &domain.State{
+		ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusInProgress,
+		Title: "オフライン同期の競合解決", Description: "モバイルのオフライン同期", CreatedAt: lastMonth, UpdatedAt: now,
+	}
+	stateRepo.states["STA-TASK-002"] = &domain.State{
+		ID: "STA-TASK-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Title: "社内ブログのテーマ変更", Description: "デザインを刷新", CreatedAt: now, UpdatedAt: now,
+	}
+}
+
+func TestDirectionServiceCheckLexical(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := newFakeStateRepo()
+	seedDirectionFixtures(t, stockRepo, stateRepo)
+	svc := NewDirectionService(stockRepo, stateRepo, &fakeVectorRepo{searchErr: errors.New("vector unavailable")})
+
+	report, err := svc.Check(context.Background(), DirectionInput{ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if report.Method != "lexical" || report.Threshold != lexicalAlignmentThreshold {
+		t.Fatalf("expected lexical fallback, got %s (%v)", report.Method, report.Threshold)
+	}
+	if len(report.Goals) != 2 {
+		t.Fatalf("expected tagged goals, got %+v", report.Goals)
+	}
+	if len(report.Unaligned) != 1 || report.Unaligned[0].ID != "STA-TASK-002" {
+		t.Fatalf("expected blog task to be unaligned, got %+v", report.Unaligned)
+	}
+	if len(report.IdleGoals) != 1 || report.IdleGoals[0] != "STK-REQUIREMENT-002" {
+		t.Fatalf("expected invoicing goal to be idle, got %v", report.IdleGoals)
+	}
+	if report.Goals[0].ActiveWork != 2 {
+		t.Fatalf("expected sync goal to cover task and design stock, got %+v", report.Goals[0])
+	}
+	if len(report.Drift) != 2 || !report.Drifting {
+		t.Fatalf("expected drift in the latest month, got %+v (drifting=%v)", report.Drift, report.Drifting)
+	}
+	if report.Assessment != "" {
+		t.Fatalf("expected no assessment without llm, got %q", report.Assessment)
+	}
+}
+
+func TestDirectionServiceCheckWithLLMAssessment(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := newFakeStateRepo()
+	seedDirectionFixtures(t, stockRepo, stateRepo)
+	svc := NewDirectionService(stockRepo, stateRepo, nil)
+	client := llm.NewClient(llm.NewStubProvider())
+	svc.SetTextGenerator(client)
+
+	report, err := svc.Check(context.Background(), DirectionInput{ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if report.Assessment == "" {
+		t.Fatal("expected llm assessment")
+	}
+	if calls := client.Calls(); len(calls) != 1 || calls[0].Purpose != "summarize" {
+		t.Fatalf("expected one summarize call, got %+v", calls)
+	}
+}
+
+func TestDirectionServiceCheckVector(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := newFakeStateRepo()
+	seedDirectionFixtures(t, stockRepo, stateRepo)
+	vectorRepo := &fakeVectorRepo{results: []repository.SearchResult{
+		{ID: "STA-TASK-001", Similarity: 0.8},
+		{ID: "STA-TASK-002", Similarity: 0.6},
+		{ID: "STK-DESIGN-003", Similarity: 0.3},
+	}}
+	svc := NewDirectionService(stockRepo, stateRepo, vectorRepo)
+
+	report, err := svc.Check(context.Background(), DirectionInput{ProjectID: "proj-1", Threshold: 0.7})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if report.Method != "vector" {
+		t.Fatalf("expected vector method, got %s", report.Method)
+	}
+	if len(report.Unaligned) != 2 || report.Unaligned[0].ID != "STK-DESIGN-003" {
+		t.Fatalf("unexpected unaligned items: %+v", report.Unaligned)
+	}
+}
+
+func TestDirectionServiceCheckWithoutGoals(t *testing.T) {
+	svc := NewDirectionService(repository.NewFileStockRepository(t.TempDir()), newFakeStateRepo(), nil)
+	report, err := svc.Check(context.Background(), DirectionInput{ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(report.Notes) == 0 || len(report.Goals) != 0 {
+		t.Fatalf("expected note about missing goals, got %+v", report)
+	}
+}
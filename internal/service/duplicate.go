@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+const (
+	// duplicateVectorThreshold はベクトル類似度で重複の可能性ありとみなす下限。
+	duplicateVectorThreshold = 0.9
+	// duplicateTitleThreshold はタイトルの文字bigram類似度で重複の可能性ありとみなす下限。
+	duplicateTitleThreshold = 0.7
+	// maxDuplicateCandidates は報告する重複候補の最大件数。
+	maxDuplicateCandidates = 5
+)
+
+// DuplicatePolicy は作成時に重複の可能性がある既存アイテムが見つかった場合の扱い。
+type DuplicatePolicy string
+
+const (
+	DuplicateAllow  DuplicatePolicy = "allow"  // 作成し、重複候補を警告として返す（デフォルト）
+	DuplicateReject DuplicatePolicy = "reject" // 作成せずエラーを返す
+	DuplicateMerge  DuplicatePolicy = "merge"  // 最も類似する既存アイテムにタグ・参照・本文を統合する
+)
+
+// ParseDuplicatePolicy は文字列からDuplicatePolicyを解析する。空の場合は allow とみなす。
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(s) {
+	case "", DuplicateAllow:
+		return DuplicateAllow, nil
+	case DuplicateReject, DuplicateMerge:
+		return DuplicatePolicy(s), nil
+	default:
+		return "", domain.ErrInvalidDuplicatePolicy
+	}
+}
+
+// DuplicateCandidate は重複の可能性がある既存アイテム。
+type DuplicateCandidate struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Similarity float32 `json:"similarity"`
+	Method     string  `json:"method"` // "vector" | "title"
+}
+
+// duplicateError は重複候補を含む ErrDuplicate を返す。
+func duplicateError(candidates []DuplicateCandidate) error {
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, fmt.Sprintf("%s (%.2f)", c.ID, c.Similarity))
+	}
+	return fmt.Errorf("%w: %s", domain.ErrDuplicate, strings.Join(ids, ", "))
+}
+
+// duplicateTarget は重複判定の対象となる既存アイテム。
+type duplicateTarget struct {
+	id    string
+	title string
+}
+
+// findDuplicates は既存アイテム targets から重複候補を探す。
+// ベクトル検索が利用できる場合は類似度のしきい値で判定し、利用できない場合はタイトルの文字bigram類似度で判定する。
+// ベクトル検索の結果のうち targets に含まれないもの（別プロジェクト・アーカイブ済み等）は無視する。
+func findDuplicates(
+	ctx context.Context,
+	vectorRepo repository.VectorRepository,
+	kind string,
+	projectID string,
+	title string,
+	text string,
+	targets []duplicateTarget,
+) []DuplicateCandidate {
+	if len(targets) == 0 {
+		return nil
+	}
+	titles := make(map[string]string, len(targets))
+	for _, target := range targets {
+		titles[target.id] = target.title
+	}
+
+	var candidates []DuplicateCandidate
+	if vectorRepo != nil {
+		results, err := vectorRepo.Search(ctx, title+"\n"+text, maxDuplicateCandidates, map[string]string{
+			"type":       kind,
+			"project_id": projectID,
+		})
+		if err == nil {
+			for _, result := range results {
+				id := vectorItemID(result)
+				existing, ok := titles[id]
+				if !ok || result.Similarity < duplicateVectorThreshold {
+					continue
+				}
+				candidates = append(candidates, DuplicateCandidate{ID: id, Title: existing, Similarity: result.Similarity, Method: "vector"})
+			}
+			return sortDuplicates(candidates)
+		}
+		slog.Warn("vector duplicate check failed, fallback to title similarity", "error", err)
+	}
+
+	for _, target := range targets {
+		similarity := bigramSimilarity(title, target.title)
+		if similarity >= duplicateTitleThreshold {
+			candidates = append(candidates, DuplicateCandidate{ID: target.id, Title: target.title, Similarity: similarity, Method: "title"})
+		}
+	}
+	return sortDuplicates(candidates)
+}
+
+// sortDuplicates は重複候補を類似度の高い順に並べ、上限件数に切り詰める。
+func sortDuplicates(candidates []DuplicateCandidate) []DuplicateCandidate {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Similarity != candidates[j].Similarity {
+			return candidates[i].Similarity > candidates[j].Similarity
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+	return candidates
+}
+
+// mergeStrings は a に b のうち未登録の要素を順に追加した新しいスライスを返す。
+func mergeStrings(a []string, b []string) []string {
+	merged := append([]string(nil), a...)
+	for _, v := range b {
+		if !containsString(merged, v) {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// mergeText は既存の本文に新しい本文を区切り線付きで追記する。既に含まれている場合は既存の本文を返す。
+func mergeText(existing string, addition string) string {
+	addition = strings.TrimSpace(addition)
+	if addition == "" || strings.Contains(existing, addition) {
+		return existing
+	}
+	if strings.TrimSpace(existing) == "" {
+		return addition
+	}
+	return strings.TrimRight(existing, "\n") + "\n\n---\n\n" + addition
+}
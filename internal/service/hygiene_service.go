@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+// HygieneService は一定期間更新のない未解決Stateを検出し、タグ付け・優先度引き上げと
+// 衛生レポートの生成を行う。
+type HygieneService struct {
+	states   *StateService
+	policies domain.StalePolicies
+	options  HygieneOptions
+}
+
+// HygieneOptions は放置State検出時の自動対応を指定する。
+type HygieneOptions struct {
+	TagStale bool          // stale タグを付与する
+	Escalate bool          // 優先度を1段階引き上げる（検出ごとに1回）
+	Interval time.Duration // 定期実行間隔（0 以下で定期実行しない）
+}
+
+// NewHygieneService は新しいHygieneServiceを生成する。
+func NewHygieneService(states *StateService, policies domain.StalePolicies, options HygieneOptions) *HygieneService {
+	return &HygieneService{
+		states:   states,
+		policies: policies,
+		options:  options,
+	}
+}
+
+// HygieneRunInput は放置State検出の実行パラメータ。
+type HygieneRunInput struct {
+	ProjectID string // 空の場合は全プロジェクトを対象にする
+	DryRun    bool   // true の場合は検出のみ行い、タグ付け・優先度引き上げをしない
+}
+
+// StaleState は放置と判定されたStateと実施した対応。
+type StaleState struct {
+	State            domain.StateSummary `json:"state"`
+	IdleFor          string              `json:"idle_for"`                    // 最終更新からの経過時間
+	Threshold        string              `json:"threshold"`                   // 適用されたしきい値
+	Tagged           bool                `json:"tagged,omitempty"`            // 今回 stale タグを付与したか
+	Escalated        bool                `json:"escalated,omitempty"`         // 今回優先度を引き上げたか
+	PreviousPriority string              `json:"previous_priority,omitempty"` // 引き上げ前の優先度
+}
+
+// HygieneReport はState衛生レポート。
+type HygieneReport struct {
+	ProjectID   string         `json:"project_id,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	DryRun      bool           `json:"dry_run,omitempty"`
+	OpenCount   int            `json:"open_count"`    // 未解決State数
+	StaleCount  int            `json:"stale_count"`   // 放置State数
+	Unassigned  int            `json:"unassigned"`    // 担当者未設定の未解決State数
+	StaleByType map[string]int `json:"stale_by_type"` // 種別ごとの放置State数
+	Stale       []StaleState   `json:"stale"`         // 放置State（経過時間の長い順）
+}
+
+// Run は放置Stateを検出し、設定に応じてタグ付け・優先度引き上げを行った上でレポートを返す。
+// 自動対応は UpdatedAt を変更しない（活動として扱わない）。
+// 優先度の引き上げは stale タグが付いていない、すなわち新たに検出されたStateに対してのみ行う。
+func (s *HygieneService) Run(ctx context.Context, input HygieneRunInput) (*HygieneReport, error) {
+	states, err := s.states.stateRepo.List(ctx, input.ProjectID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	now := time.Now()
+	report := &HygieneReport{
+		ProjectID:   input.ProjectID,
+		GeneratedAt: now,
+		DryRun:      input.DryRun,
+		StaleByType: map[string]int{},
+		Stale:       []StaleState{},
+	}
+	// 優先度を引き上げる場合は再検出時の二重引き上げを防ぐため必ずタグを付ける
+	mark := s.options.TagStale || s.options.Escalate
+
+	for _, state := range states {
+		if !state.IsOpen() {
+			continue
+		}
+		report.OpenCount++
+		if state.Assignee == "" {
+			report.Unassigned++
+		}
+
+		policy, ok := s.policies.Match(state)
+		if !ok || !policy.IsStale(state, now) {
+			continue
+		}
+
+		stale := StaleState{
+			IdleFor:   now.Sub(state.UpdatedAt).Truncate(time.Minute).String(),
+			Threshold: policy.After.String(),
+		}
+		if !input.DryRun && !state.HasTag(domain.StaleTag) {
+			if s.options.Escalate && state.Priority != domain.PriorityP0 {
+				stale.PreviousPriority = state.Priority.String()
+				state.Priority = state.Priority.Raise()
+				stale.Escalated = true
+			}
+			if mark {
+				state.Tags = append(state.Tags, domain.StaleTag)
+				stale.Tagged = true
+			}
+			if stale.Tagged || stale.Escalated {
+				if err := s.states.stateRepo.Update(ctx, state); err != nil {
+					return nil, fmt.Errorf("failed to mark stale state %s: %w", state.ID, err)
+				}
+				s.states.indexState(ctx, state)
+			}
+		}
+
+		stale.State = s.states.summarize(state, now)
+		report.Stale = append(report.Stale, stale)
+		report.StaleByType[string(state.Type)]++
+	}
+
+	sort.SliceStable(report.Stale, func(i, j int) bool {
+		return report.Stale[i].State.UpdatedAt.Before(report.Stale[j].State.UpdatedAt)
+	})
+	report.StaleCount = len(report.Stale)
+	return report, nil
+}
+
+// Start は設定された間隔で全プロジェクトを対象に放置State検出を実行する。
+// 開始時に一度実行し、間隔より短い稼働でも検出されるようにする。
+// ctx がキャンセルされるまでブロックする。間隔が未設定の場合は何もしない。
+func (s *HygieneService) Start(ctx context.Context) {
+	if s.options.Interval <= 0 {
+		return
+	}
+	s.runScheduled(ctx)
+
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runScheduled(ctx)
+		}
+	}
+}
+
+// runScheduled は Start から全プロジェクトを対象に一度実行し、結果をログに出力する。
+func (s *HygieneService) runScheduled(ctx context.Context) {
+	report, err := s.Run(ctx, HygieneRunInput{})
+	if err != nil {
+		slog.Warn("scheduled state hygiene run failed", "error", err)
+		return
+	}
+	slog.Info("state hygiene run completed", "open", report.OpenCount, "stale", report.StaleCount)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+func TestHygieneServiceRun(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeStateRepo()
+	now := time.Now()
+	idle := now.Add(-10 * 24 * time.Hour)
+	repo.states["STA-TASK-001"] = &domain.State{
+		ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "forgotten", CreatedAt: idle, UpdatedAt: idle,
+	}
+	repo.states["STA-TASK-002"] = &domain.State{
+		ID: "STA-TASK-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusInProgress,
+		Priority: domain.PriorityP2, Title: "active", Assignee: "alice", CreatedAt: idle, UpdatedAt: now,
+	}
+	repo.states["STA-ISSUE-003"] = &domain.State{
+		ID: "STA-ISSUE-003", ProjectID: "proj-1", Type: domain.StateTypeIssue, Status: domain.StatusResolved,
+		Priority: domain.PriorityP2, Title: "done", CreatedAt: idle, UpdatedAt: idle,
+	}
+
+	states := NewStateService(repo, nil)
+	svc := NewHygieneService(states, domain.StalePolicies{{After: 7 * 24 * time.Hour}}, HygieneOptions{TagStale: true, Escalate: true})
+
+	report, err := svc.Run(ctx, HygieneRunInput{ProjectID: "proj-1", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.OpenCount != 2 || report.StaleCount != 1 || report.Unassigned != 1 || report.StaleByType["task"] != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got, _ := repo.Get(ctx, "STA-TASK-001"); got.HasTag(domain.StaleTag) || got.Priority != domain.PriorityP2 {
+		t.Fatalf("dry run should not modify state: %+v", got)
+	}
+
+	report, err = svc.Run(ctx, HygieneRunInput{ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stale := report.Stale[0]
+	if !stale.Tagged || !stale.Escalated || stale.PreviousPriority != "P2" || stale.State.Priority != domain.PriorityP1 {
+		t.Fatalf("unexpected stale entry: %+v", stale)
+	}
+	got, _ := repo.Get(ctx, "STA-TASK-001")
+	if !got.HasTag(domain.StaleTag) || got.Priority != domain.PriorityP1 || !got.UpdatedAt.Equal(idle) {
+		t.Fatalf("unexpected marked state: %+v", got)
+	}
+
+	// 再検出時は二重に引き上げない
+	report, _ = svc.Run(ctx, HygieneRunInput{})
+	if report.StaleCount != 1 || report.Stale[0].Escalated {
+		t.Fatalf("expected no re-escalation, got %+v", report.Stale)
+	}
+	if got, _ := repo.Get(ctx, "STA-TASK-001"); got.Priority != domain.PriorityP1 {
+		t.Fatalf("expected priority to stay P1, got %s", got.Priority)
+	}
+
+	// 更新されると stale タグは外れる
+	desc := "picked up again"
+	updated, err := states.Update(ctx, "STA-TASK-001", UpdateStateInput{Description: &desc})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.HasTag(domain.StaleTag) {
+		t.Fatalf("expected stale tag to be cleared on update, got %v", updated.Tags)
+	}
+}
+
+func TestHygieneServiceStartRunsImmediately(t *testing.T) {
+	repo := newFakeStateRepo()
+	idle := time.Now().Add(-10 * 24 * time.Hour)
+	for _, state := range []*domain.State{
+		{ID: "STA-INCIDENT-001", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusOpen,
+			Priority: domain.PriorityP2, Title: "slow api", CreatedAt: idle, UpdatedAt: idle},
+		{ID: "STA-CHANGE-002", ProjectID: "proj-1", Type: domain.StateTypeChange, Status: domain.StatusOpen,
+			Priority: domain.PriorityP2, Title: "upgrade db", CreatedAt: idle, UpdatedAt: idle},
+	} {
+		repo.states[state.ID] = state
+	}
+	svc := NewHygieneService(NewStateService(repo, nil), domain.StalePolicies{{After: 7 * 24 * time.Hour}}, HygieneOptions{Interval: time.Hour, TagStale: true})
+
+	// 間隔を待たずに開始時に一度実行する
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.Start(ctx)
+	for id := range repo.states {
+		if got, _ := repo.Get(context.Background(), id); !got.HasTag(domain.StaleTag) {
+			t.Fatalf("expected %s to be tagged stale on start, got %v", id, got.Tags)
+		}
+	}
+
+	// 種別ごとのサービスによる更新でも stale タグは外れる
+	incident, err := NewIncidentService(repo, nil).AddTimelineEntry(context.Background(), "STA-INCIDENT-001", time.Now(), "investigating")
+	if err != nil || incident.HasTag(domain.StaleTag) {
+		t.Fatalf("expected timeline entry to clear stale tag, got %+v (%v)", incident, err)
+	}
+	risk := "low"
+	change, err := NewChangeService(repo).Update(context.Background(), "STA-CHANGE-002", UpdateChangeInput{RiskLevel: &risk})
+	if err != nil || change.HasTag(domain.StaleTag) {
+		t.Fatalf("expected change update to clear stale tag, got %+v (%v)", change, err)
+	}
+}
+
+func TestHygieneOptionsFromConfig(t *testing.T) {
+	cfg := config.HygieneConfig{
+		Interval: "12h",
+		Escalate: true,
+		Thresholds: []config.StaleThresholdConfig{
+			{Priority: "P0", After: "72h"},
+			{Type: "bogus", After: "24h"},
+			{After: "soon"},
+		},
+	}
+	policies := stalePoliciesFromConfig(cfg)
+	if len(policies) != 1 || policies[0].After != 72*time.Hour {
+		t.Fatalf("unexpected policies: %+v", policies)
+	}
+	options := hygieneOptionsFromConfig(cfg)
+	if options.Interval != 12*time.Hour || !options.Escalate || options.TagStale {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+	if hygieneOptionsFromConfig(config.HygieneConfig{Interval: "0"}).Interval != 0 {
+		t.Fatal("expected interval 0 to disable scheduled runs")
+	}
+}
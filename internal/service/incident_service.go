@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// IncidentService はインシデント（StateTypeIncident）固有の情報管理と
+// ポストモーテム生成を提供する。
+type IncidentService struct {
+	stateRepo    repository.StateRepository
+	stockService *StockService
+}
+
+// NewIncidentService は新しいIncidentServiceを生成する。
+func NewIncidentService(stateRepo repository.StateRepository, stockService *StockService) *IncidentService {
+	return &IncidentService{
+		stateRepo:    stateRepo,
+		stockService: stockService,
+	}
+}
+
+// UpdateIncidentInput はインシデント固有情報の更新パラメータ。nil の項目は変更しない。
+type UpdateIncidentInput struct {
+	Severity    *string
+	Impact      *string
+	DetectedAt  *time.Time
+	MitigatedAt *time.Time
+	ResolvedAt  *time.Time
+}
+
+// Update はインシデントの深刻度・影響・各種タイムスタンプを更新する。
+func (s *IncidentService) Update(ctx context.Context, id string, input UpdateIncidentInput) (*domain.State, error) {
+	state, err := s.getIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := state.Incident
+	if input.Severity != nil {
+		sev, err := domain.ParseSeverity(*input.Severity)
+		if err != nil {
+			return nil, err
+		}
+		details.Severity = sev
+	}
+	if input.Impact != nil {
+		details.Impact = *input.Impact
+	}
+	if input.DetectedAt != nil {
+		details.DetectedAt = input.DetectedAt
+	}
+	if input.MitigatedAt != nil {
+		details.MitigatedAt = input.MitigatedAt
+	}
+	if input.ResolvedAt != nil {
+		details.ResolvedAt = input.ResolvedAt
+	}
+
+	state.Touch(time.Now())
+	if err := s.stateRepo.Update(ctx, state); err != nil {
+		return nil, fmt.Errorf("failed to update incident: %w", err)
+	}
+	return state, nil
+}
+
+// AddTimelineEntry はインシデントのタイムラインにエントリを追加する。
+// at がゼロ値の場合は現在時刻を使用する。
+func (s *IncidentService) AddTimelineEntry(ctx context.Context, id string, at time.Time, note string) (*domain.State, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, fmt.Errorf("timeline note is required")
+	}
+
+	state, err := s.getIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	state.Incident.AddTimelineEntry(domain.TimelineEntry{At: at, Note: note})
+
+	state.Touch(now)
+	if err := s.stateRepo.Update(ctx, state); err != nil {
+		return nil, fmt.Errorf("failed to add timeline entry: %w", err)
+	}
+	return state, nil
+}
+
+// PostmortemInput はポストモーテム生成時の入力パラメータ。
+type PostmortemInput struct {
+	SaveCategory string // Stockとして保存する場合のカテゴリ（postmortem/test/management）。空なら保存しない
+	Priority     string // 保存時の優先度（デフォルト: P2）
+}
+
+// PostmortemResult はポストモーテム生成の結果。
+type PostmortemResult struct {
+	Markdown string               `json:"markdown"`
+	Stock    *domain.StockSummary `json:"stock,omitempty"` // 保存した場合のみ
+}
+
+// postmortemCategories はポストモーテムの保存先として許可するカテゴリ。
+var postmortemCategories = map[domain.StockCategory]bool{
+	domain.CategoryPostmortem: true,
+	domain.CategoryTest:       true,
+	domain.CategoryManagement: true,
+}
+
+// Postmortem はインシデントのタイムライン・説明・解決内容からMarkdownのポストモーテムを生成する。
+// SaveCategory が指定された場合はStockとして保存し、StateのReferencesに紐づける。
+func (s *IncidentService) Postmortem(ctx context.Context, id string, input PostmortemInput) (*PostmortemResult, error) {
+	state, err := s.getIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PostmortemResult{Markdown: RenderPostmortem(state)}
+	if input.SaveCategory == "" {
+		return result, nil
+	}
+
+	category := domain.StockCategory(input.SaveCategory)
+	if !postmortemCategories[category] {
+		return nil, fmt.Errorf("%w: postmortem can be saved as postmortem, test or management", domain.ErrInvalidCategory)
+	}
+	priority := input.Priority
+	if priority == "" {
+		priority = domain.PriorityP2.String()
+	}
+
+	stock, err := s.stockService.Create(ctx, CreateStockInput{
+		ProjectID:  state.ProjectID,
+		Category:   string(category),
+		Priority:   priority,
+		Title:      "Postmortem: " + state.Title,
+		Content:    result.Markdown,
+		Tags:       []string{"postmortem", "incident"},
+		References: []string{state.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.References = append(state.References, stock.ID)
+	state.Touch(time.Now())
+	if err := s.stateRepo.Update(ctx, state); err != nil {
+		// 紐づけに失敗したポストモーテムは、インシデントから辿れないため残さない
+		if discardErr := s.stockService.discard(ctx, stock); discardErr != nil {
+			return nil, fmt.Errorf("failed to link postmortem to incident: %w (and failed to remove stock %s: %v)", err, stock.ID, discardErr)
+		}
+		return nil, fmt.Errorf("failed to link postmortem to incident: %w", err)
+	}
+
+	summary := stock.ToSummary()
+	result.Stock = &summary
+	return result, nil
+}
+
+func (s *IncidentService) getIncident(ctx context.Context, id string) (*domain.State, error) {
+	state, err := s.stateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if state.Type != domain.StateTypeIncident {
+		return nil, domain.ErrNotIncident
+	}
+	if state.Incident == nil {
+		state.Incident = &domain.IncidentDetails{}
+	}
+	return state, nil
+}
+
+// RenderPostmortem はインシデントStateからMarkdown形式のポストモーテムを組み立てる。
+func RenderPostmortem(state *domain.State) string {
+	details := state.Incident
+	if details == nil {
+		details = &domain.IncidentDetails{}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Postmortem: %s\n\n", state.Title)
+	fmt.Fprintf(&b, "- Incident: %s\n", state.ID)
+	if details.Severity != "" {
+		fmt.Fprintf(&b, "- Severity: %s\n", details.Severity)
+	}
+	fmt.Fprintf(&b, "- Priority: %s\n", state.Priority)
+	fmt.Fprintf(&b, "- Status: %s\n", state.Status)
+	if state.Assignee != "" {
+		fmt.Fprintf(&b, "- Assignee: %s\n", state.Assignee)
+	}
+
+	b.WriteString("\n## Summary\n\n")
+	writeSection(&b, state.Description)
+
+	b.WriteString("## Impact\n\n")
+	writeSection(&b, details.Impact)
+
+	b.WriteString("## Key Timestamps\n\n")
+	b.WriteString("| Event | Time |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Detected | %s |\n", formatOptionalTime(details.DetectedAt))
+	fmt.Fprintf(&b, "| Mitigated | %s |\n", formatOptionalTime(details.MitigatedAt))
+	fmt.Fprintf(&b, "| Resolved | %s |\n", formatOptionalTime(details.ResolvedAt))
+	if d, ok := details.TimeToMitigate(); ok {
+		fmt.Fprintf(&b, "\nTime to mitigate: %s\n", d)
+	}
+	if d, ok := details.TimeToResolve(); ok {
+		fmt.Fprintf(&b, "\nTime to resolve: %s\n", d)
+	}
+
+	b.WriteString("\n## Timeline\n\n")
+	if len(details.Timeline) == 0 {
+		b.WriteString("_No timeline entries recorded._\n\n")
+	} else {
+		for _, entry := range details.Timeline {
+			fmt.Fprintf(&b, "- **%s** %s\n", entry.At.Format(time.RFC3339), entry.Note)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Resolution\n\n")
+	writeSection(&b, state.Resolution)
+
+	if len(state.References) > 0 {
+		b.WriteString("## References\n\n")
+		for _, ref := range state.References {
+			fmt.Fprintf(&b, "- %s\n", ref)
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeSection(b *strings.Builder, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		b.WriteString("_Not recorded._\n\n")
+		return
+	}
+	b.WriteString(text)
+	b.WriteString("\n\n")
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func newTestIncidentService(t *testing.T) (*IncidentService, *fakeStateRepo, repository.StockRepository) {
+	t.Helper()
+	stateRepo := newFakeStateRepo()
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	return NewIncidentService(stateRepo, NewStockService(stockRepo, nil)), stateRepo, stockRepo
+}
+
+// failingUpdateStateRepo はStateの更新に失敗するStateリポジトリ。
+type failingUpdateStateRepo struct {
+	*fakeStateRepo
+}
+
+func (f *failingUpdateStateRepo) Update(ctx context.Context, state *domain.State) error {
+	return errors.New("database is locked")
+}
+
+func TestIncidentServicePostmortemRollsBackStock(t *testing.T) {
+	ctx := context.Background()
+	stateRepo := &failingUpdateStateRepo{fakeStateRepo: newFakeStateRepo()}
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewIncidentService(stateRepo, NewStockService(stockRepo, nil))
+	stateRepo.states["STA-INCIDENT-001"] = &domain.State{ID: "STA-INCIDENT-001", ProjectID: "proj-1", Type: domain.StateTypeIncident,
+		Status: domain.StatusResolved, Priority: domain.PriorityP1, Title: "API outage"}
+
+	if _, err := svc.Postmortem(ctx, "STA-INCIDENT-001", PostmortemInput{SaveCategory: "postmortem"}); err == nil {
+		t.Fatal("expected error when the incident cannot be updated")
+	}
+	// インシデントに紐づけられなかったポストモーテムは残さない
+	stocks, err := stockRepo.List(ctx, "proj-1", &repository.StockListOptions{IncludeDeleted: true})
+	if err != nil || len(stocks) != 0 {
+		t.Fatalf("expected orphaned postmortem to be removed, got %d stocks (%v)", len(stocks), err)
+	}
+}
+
+func TestIncidentServiceTimelineAndPostmortem(t *testing.T) {
+	ctx := context.Background()
+	svc, stateRepo, stockRepo := newTestIncidentService(t)
+
+	detected := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	stateRepo.states["STA-INCIDENT-001"] = &domain.State{
+		ID:          "STA-INCIDENT-001",
+		ProjectID:   "proj-1",
+		Type:        domain.StateTypeIncident,
+		Status:      domain.StatusResolved,
+		Priority:    domain.PriorityP0,
+		Title:       "API outage",
+		Description: "All API requests returned 500.",
+		Resolution:  "Rolled back the faulty deploy.",
+		CreatedAt:   detected,
+		UpdatedAt:   detected,
+	}
+
+	severity := "SEV1"
+	impact := "All customers"
+	mitigated := detected.Add(30 * time.Minute)
+	resolved := detected.Add(2 * time.Hour)
+	if _, err := svc.Update(ctx, "STA-INCIDENT-001", UpdateIncidentInput{
+		Severity:    &severity,
+		Impact:      &impact,
+		DetectedAt:  &detected,
+		MitigatedAt: &mitigated,
+		ResolvedAt:  &resolved,
+	}); err != nil {
+		t.Fatalf("update incident: %v", err)
+	}
+
+	// 時系列順でない追記でもタイムラインは時刻順に並ぶ
+	if _, err := svc.AddTimelineEntry(ctx, "STA-INCIDENT-001", detected.Add(20*time.Minute), "Rollback started"); err != nil {
+		t.Fatalf("add timeline entry: %v", err)
+	}
+	state, err := svc.AddTimelineEntry(ctx, "STA-INCIDENT-001", detected.Add(5*time.Minute), "On-call paged")
+	if err != nil {
+		t.Fatalf("add timeline entry: %v", err)
+	}
+	if len(state.Incident.Timeline) != 2 || state.Incident.Timeline[0].Note != "On-call paged" {
+		t.Fatalf("expected chronological timeline, got %+v", state.Incident.Timeline)
+	}
+
+	result, err := svc.Postmortem(ctx, "STA-INCIDENT-001", PostmortemInput{SaveCategory: "postmortem"})
+	if err != nil {
+		t.Fatalf("postmortem: %v", err)
+	}
+	for _, want := range []string{"# Postmortem: API outage", "Severity: SEV1", "All customers", "Time to resolve: 2h0m0s", "Rollback started", "Rolled back the faulty deploy."} {
+		if !strings.Contains(result.Markdown, want) {
+			t.Errorf("expected postmortem to contain %q\n%s", want, result.Markdown)
+		}
+	}
+	if strings.Index(result.Markdown, "On-call paged") > strings.Index(result.Markdown, "Rollback started") {
+		t.Errorf("expected timeline entries in chronological order")
+	}
+
+	if result.Stock == nil || result.Stock.Category != domain.CategoryPostmortem {
+		t.Fatalf("expected postmortem stock to be saved, got %+v", result.Stock)
+	}
+	saved, err := stockRepo.Get(ctx, "", result.Stock.ID)
+	if err != nil {
+		t.Fatalf("get saved postmortem: %v", err)
+	}
+	if saved.Content != result.Markdown || len(saved.References) != 1 || saved.References[0] != "STA-INCIDENT-001" {
+		t.Fatalf("unexpected saved postmortem: %+v", saved)
+	}
+	linked, _ := stateRepo.Get(ctx, "STA-INCIDENT-001")
+	if !containsID(linked.References, result.Stock.ID) {
+		t.Fatalf("expected incident to reference postmortem stock, got %v", linked.References)
+	}
+}
+
+func TestIncidentServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, stateRepo, _ := newTestIncidentService(t)
+	now := time.Now()
+	stateRepo.states["STA-TASK-001"] = &domain.State{
+		ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Title: "task", CreatedAt: now, UpdatedAt: now,
+	}
+	stateRepo.states["STA-INCIDENT-002"] = &domain.State{
+		ID: "STA-INCIDENT-002", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusOpen,
+		Title: "incident", CreatedAt: now, UpdatedAt: now,
+	}
+
+	if _, err := svc.AddTimelineEntry(ctx, "STA-TASK-001", now, "note"); err != domain.ErrNotIncident {
+		t.Fatalf("expected ErrNotIncident, got %v", err)
+	}
+	bad := "SEV9"
+	if _, err := svc.Update(ctx, "STA-INCIDENT-002", UpdateIncidentInput{Severity: &bad}); err != domain.ErrInvalidSeverity {
+		t.Fatalf("expected ErrInvalidSeverity, got %v", err)
+	}
+	if _, err := svc.Postmortem(ctx, "STA-INCIDENT-002", PostmortemInput{SaveCategory: "design"}); !errors.Is(err, domain.ErrInvalidCategory) {
+		t.Fatalf("expected ErrInvalidCategory for design, got %v", err)
+	}
+
+	result, err := svc.Postmortem(ctx, "STA-INCIDENT-002", PostmortemInput{})
+	if err != nil {
+		t.Fatalf("postmortem without save: %v", err)
+	}
+	if result.Stock != nil || !strings.Contains(result.Markdown, "_No timeline entries recorded._") {
+		t.Fatalf("unexpected unsaved postmortem: %+v", result)
+	}
+}
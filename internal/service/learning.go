@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+const (
+	// learningMaxChars はLLMで下書きする学びの最大文字数。
+	learningMaxChars = 2000
+	// learningInstruction は学びの下書きをLLMに依頼する際の指示。
+	learningInstruction = "Draft reusable lessons learned from this finished work item as Markdown " +
+		"with the sections \"## Background\", \"## What we did\" and \"## Learnings\" (a bullet list of reusable insights)."
+)
+
+// learningCategories は学びの転記先として許可するカテゴリ。
+var learningCategories = map[domain.StockCategory]bool{
+	domain.CategoryDesign:       true,
+	domain.CategoryRules:        true,
+	domain.CategoryManagement:   true,
+	domain.CategoryArchitecture: true,
+	domain.CategoryTest:         true,
+	domain.CategoryPostmortem:   true,
+}
+
+// draftLearnings はアーカイブするStateから学びの下書きを生成する。
+// LLMが利用できる場合はLLMで下書きし、利用できない・失敗した場合は記録からテンプレートを組み立てる。
+func (s *StateService) draftLearnings(ctx context.Context, state *domain.State) string {
+	if generator := generatorFor(ctx, s.generator); generator != nil {
+		draft, err := generator.Draft(ctx, learningInstruction, renderStateRecord(state), learningMaxChars)
+		if err == nil {
+			return draft
+		}
+		warnGenerationError("failed to draft learnings with llm, fallback to template", err, "id", state.ID)
+	}
+
+	var b strings.Builder
+	b.WriteString("## Background\n\n")
+	writeSection(&b, state.Description)
+	b.WriteString("## What we did\n\n")
+	writeSection(&b, state.Resolution)
+	b.WriteString("## Learnings\n\n")
+	b.WriteString("<!-- 次回以降に再利用できる知見を追記してください -->\n")
+	return b.String()
+}
+
+// saveLearnings は学びをStockとして保存し、StateのReferencesに紐づける。
+func (s *StateService) saveLearnings(ctx context.Context, state *domain.State, input ArchiveInput) (*domain.Stock, error) {
+	if s.stockService == nil {
+		return nil, fmt.Errorf("stock service is not configured")
+	}
+	content := strings.TrimSpace(input.StockSummary)
+	if content == "" {
+		content = s.draftLearnings(ctx, state)
+	}
+	priority := input.Priority
+	if priority == "" {
+		priority = domain.PriorityP2.String()
+	}
+
+	stock, err := s.stockService.Create(ctx, CreateStockInput{
+		ProjectID:  state.ProjectID,
+		Category:   input.SaveCategory,
+		Priority:   priority,
+		Title:      "Learnings: " + state.Title,
+		Content:    content,
+		Tags:       []string{"learnings", string(state.Type)},
+		References: []string{state.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	state.References = append(state.References, stock.ID)
+	return stock, nil
+}
+
+// renderStateRecord はStateの記録をLLMへの入力用のMarkdownにまとめる。
+func renderStateRecord(state *domain.State) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n- Type: %s\n- Priority: %s\n", state.Title, state.Type, state.Priority)
+	if len(state.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(state.Tags, ", "))
+	}
+	b.WriteString("\n## Description\n\n")
+	writeSection(&b, state.Description)
+	b.WriteString("## Resolution\n\n")
+	writeSection(&b, state.Resolution)
+	if state.Incident != nil && len(state.Incident.Timeline) > 0 {
+		b.WriteString("## Timeline\n\n")
+		for _, entry := range state.Incident.Timeline {
+			fmt.Fprintf(&b, "- %s %s\n", entry.At.Format(time.RFC3339), entry.Note)
+		}
+		b.WriteString("\n")
+	}
+	if state.Problem != nil && state.Problem.RootCause != "" {
+		b.WriteString("## Root Cause\n\n")
+		writeSection(&b, state.Problem.RootCause)
+	}
+	return b.String()
+}
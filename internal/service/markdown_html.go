@@ -0,0 +1,365 @@
+package service
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// markdownHTML はStock本文（Markdown）を静的サイト用のHTMLに変換する。
+// 見出し・段落・リスト（入れ子・チェックボックス）・引用・コードブロック・表・水平線と、
+// インラインのコード・強調・リンクに対応する。生のHTMLはエスケープし、javascript: 等のリンクは無効にする。
+// linkID は本文中の管理番号（STK-/STA-）に対するリンク先を返す（リンクしない場合は空文字）。
+type markdownHTML struct {
+	linkID func(id string) string
+}
+
+var (
+	mdHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem   = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])(\s+|$)(.*)$`)
+	mdRule       = regexp.MustCompile(`^ {0,3}((-\s*){3,}|(\*\s*){3,}|(_\s*){3,})$`)
+	mdTableDelim = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdTask       = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	mdIDPattern  = regexp.MustCompile(`^(?:STK|STA)-[A-Za-z]+-\d+`)
+)
+
+// render はMarkdownをHTMLに変換する。
+func (m *markdownHTML) render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var b strings.Builder
+	m.blocks(&b, strings.Split(src, "\n"), false)
+	return b.String()
+}
+
+// blocks はブロック要素を変換する。tight の場合（空行を含まないリスト項目）は段落を <p> で囲まない。
+func (m *markdownHTML) blocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			i = m.codeBlock(b, lines, i)
+		case mdHeading.MatchString(trimmed):
+			match := mdHeading.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(match[1])))
+			b.WriteString("<h" + level + ">" + m.inline(match[2]) + "</h" + level + ">\n")
+			i++
+		case mdRule.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			b.WriteString("<blockquote>\n")
+			m.blocks(b, quoted, false)
+			b.WriteString("</blockquote>\n")
+		case strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelim.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "|"):
+			i = m.table(b, lines, i)
+		case mdListItem.MatchString(line):
+			i = m.list(b, lines, i)
+		default:
+			var para []string
+			for ; i < len(lines) && !m.startsBlock(lines, i); i++ {
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			text := m.inline(strings.Join(para, "\n"))
+			if tight {
+				b.WriteString(text + "\n")
+			} else {
+				b.WriteString("<p>" + text + "</p>\n")
+			}
+		}
+	}
+}
+
+// startsBlock は lines[i] が段落を終える行（空行・他のブロック要素の開始）かどうかを返す。
+func (m *markdownHTML) startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" ||
+		strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") ||
+		mdHeading.MatchString(trimmed) || mdRule.MatchString(line) ||
+		strings.HasPrefix(trimmed, ">") || mdListItem.MatchString(line)
+}
+
+func (m *markdownHTML) codeBlock(b *strings.Builder, lines []string, start int) int {
+	open := strings.TrimSpace(lines[start])
+	fence := open[:3]
+	lang := strings.Fields(strings.TrimLeft(open, fence[:1]) + " ")
+	b.WriteString("<pre><code")
+	if len(lang) > 0 {
+		b.WriteString(` class="language-` + html.EscapeString(lang[0]) + `"`)
+	}
+	b.WriteString(">")
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			i++
+			break
+		}
+		b.WriteString(html.EscapeString(lines[i]) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+func (m *markdownHTML) table(b *strings.Builder, lines []string, start int) int {
+	cells := func(line string) []string {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+		parts := strings.Split(line, "|")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts
+	}
+	var aligns []string
+	for _, delim := range cells(lines[start+1]) {
+		switch {
+		case strings.HasPrefix(delim, ":") && strings.HasSuffix(delim, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(delim, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(delim, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	row := func(tag string, values []string) {
+		b.WriteString("<tr>")
+		for i := range aligns {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			b.WriteString("<" + tag)
+			if aligns[i] != "" {
+				b.WriteString(` style="text-align:` + aligns[i] + `"`)
+			}
+			b.WriteString(">" + m.inline(value) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	row("th", cells(lines[start]))
+	b.WriteString("</thead>\n<tbody>\n")
+	i := start + 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row("td", cells(lines[i]))
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// list はリストを変換する。項目の継続行（インデントされた行）は項目内のブロックとして再帰的に変換する。
+func (m *markdownHTML) list(b *strings.Builder, lines []string, start int) int {
+	first := mdListItem.FindStringSubmatch(lines[start])
+	indent := len(first[1])
+	ordered := !strings.ContainsAny(first[2][:1], "-*+")
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag + ">\n")
+
+	i := start
+	for i < len(lines) {
+		match := mdListItem.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) != indent || ordered == strings.ContainsAny(match[2][:1], "-*+") {
+			break
+		}
+		offset := len(match[1]) + len(match[2]) + len(match[3])
+		item := []string{match[4]}
+		tight := true
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// 空行の後にインデントされた行が続く場合のみ項目が続く
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= offset {
+					item = append(item, "")
+					tight = false
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= offset || (leadingSpaces(line) > indent && mdListItem.MatchString(line)) {
+				item = append(item, line[min(leadingSpaces(line), offset):])
+				continue
+			}
+			if mdListItem.MatchString(line) || m.startsBlock(lines, i) {
+				break
+			}
+			item = append(item, strings.TrimSpace(line)) // 段落の遅延継続行
+		}
+
+		b.WriteString("<li>")
+		if task := mdTask.FindStringSubmatch(item[0]); task != nil {
+			checked := ""
+			if task[1] != " " {
+				checked = " checked"
+			}
+			b.WriteString(`<input type="checkbox" disabled` + checked + `> `)
+			item[0] = item[0][len(task[0]):]
+		}
+		m.blocks(b, item, tight)
+		b.WriteString("</li>\n")
+
+		// 項目間の空行
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && mdListItem.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// inline はインライン要素を変換する。
+func (m *markdownHTML) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!|<>", rune(rest[1])):
+			b.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(rest[1:1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				b.WriteString("<strong>" + m.inline(rest[2:2+end]) + "</strong>")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*':
+			if end := strings.Index(rest[1:], "*"); end > 0 && rest[1] != ' ' {
+				b.WriteString("<em>" + m.inline(rest[1:1+end]) + "</em>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				b.WriteString("<del>" + m.inline(rest[2:2+end]) + "</del>")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '[':
+			if text, url, n, ok := parseLink(rest); ok {
+				b.WriteString(`<a href="` + html.EscapeString(safeURL(url)) + `">` + m.inline(text) + "</a>")
+				i += n
+				continue
+			}
+		case strings.HasPrefix(rest, "https://") || strings.HasPrefix(rest, "http://"):
+			end := strings.IndexAny(rest, " \n<>\"')")
+			if end < 0 {
+				end = len(rest)
+			}
+			url := strings.TrimRight(rest[:end], ".,;:")
+			b.WriteString(`<a href="` + html.EscapeString(url) + `">` + html.EscapeString(url) + "</a>")
+			i += len(url)
+			continue
+		case rest[0] == '\n':
+			b.WriteString("\n")
+			i++
+			continue
+		case rest[0] == 'S' && (i == 0 || !isWordByte(s[i-1])):
+			if id := mdIDPattern.FindString(rest); id != "" && (len(rest) == len(id) || !isWordByte(rest[len(id)])) {
+				if href := m.resolveID(id); href != "" {
+					b.WriteString(`<a class="ref" href="` + html.EscapeString(href) + `">` + id + "</a>")
+				} else {
+					b.WriteString(id)
+				}
+				i += len(id)
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+	return b.String()
+}
+
+func (m *markdownHTML) resolveID(id string) string {
+	if m.linkID == nil {
+		return ""
+	}
+	return m.linkID(id)
+}
+
+// parseLink は s の先頭の [text](url) を解析し、消費したバイト数を返す。
+func parseLink(s string) (text, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if i+1 >= len(s) || s[i+1] != '(' {
+					return "", "", 0, false
+				}
+				end := closingParen(s[i+2:])
+				if end < 0 {
+					return "", "", 0, false
+				}
+				url = strings.TrimSpace(s[i+2 : i+2+end])
+				if sp := strings.IndexAny(url, " \t"); sp >= 0 {
+					url = url[:sp] // [text](url "title") の title は使わない
+				}
+				return s[1:i], url, i + 3 + end, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// closingParen は s 中の、対応する閉じ括弧の位置を返す（URL内の括弧の入れ子を許す）。
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// safeURL は javascript: などのスキームのリンクを無効にする。相対パス・http(s)・mailto のみ許可する。
+func safeURL(url string) string {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	if colon := strings.IndexByte(lower, ':'); colon >= 0 && !strings.ContainsAny(lower[:colon], "/?#") {
+		switch lower[:colon] {
+		case "http", "https", "mailto":
+		default:
+			return "#"
+		}
+	}
+	return url
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
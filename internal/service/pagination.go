@@ -0,0 +1,84 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+)
+
+const (
+	// defaultListLimit は一覧取得の1ページあたりの既定件数。
+	defaultListLimit = 50
+	// defaultSearchLimit は検索の1ページあたりの既定件数。
+	defaultSearchLimit = 10
+	// maxPageLimit は1ページあたりの最大件数。
+	maxPageLimit = 200
+	// maxSearchHits は検索でページングの対象とするヒット件数の上限。
+	maxSearchHits = 200
+)
+
+// PageRequest はページングの指定。Cursor は前のページの NextCursor、初回は空。
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// pageCursor はカーソルの内容。利用者には不透明な文字列として渡す。
+type pageCursor struct {
+	Offset int    `json:"o"`
+	Key    string `json:"k"` // 一覧・検索条件の指紋。条件の異なるカーソルの再利用を検出する
+}
+
+// cursorKey は一覧・検索条件からカーソルの指紋を生成する。
+func cursorKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// encodeCursor は次のページの開始位置をカーソル文字列に変換する。
+func encodeCursor(offset int, key string) string {
+	data, _ := json.Marshal(pageCursor{Offset: offset, Key: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor はカーソル文字列から開始位置を取り出す。空の場合は先頭を表す。
+func decodeCursor(cursor string, key string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, domain.ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 || c.Key != key {
+		return 0, domain.ErrInvalidCursor
+	}
+	return c.Offset, nil
+}
+
+// pageLimit は1ページあたりの件数を既定値・上限で補正する。
+func pageLimit(limit int, defaultLimit int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	return min(limit, maxPageLimit)
+}
+
+// nextCursor は offset から limit 件を返した後に続きがあれば次のカーソルを返す。
+func nextCursor(offset int, returned int, total int, key string) string {
+	if next := offset + returned; returned > 0 && next < total {
+		return encodeCursor(next, key)
+	}
+	return ""
+}
+
+// pageBounds は全件のうち offset から limit 件の範囲を返す。
+func pageBounds(offset int, limit int, total int) (int, int) {
+	start := min(offset, total)
+	return start, min(start+limit, total)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// ProblemService はITILの問題管理（再発インシデントの根本原因単位での集約）を提供する。
+type ProblemService struct {
+	stateRepo  repository.StateRepository
+	vectorRepo repository.VectorRepository
+}
+
+// NewProblemService は新しいProblemServiceを生成する。
+func NewProblemService(stateRepo repository.StateRepository, vectorRepo repository.VectorRepository) *ProblemService {
+	return &ProblemService{
+		stateRepo:  stateRepo,
+		vectorRepo: vectorRepo,
+	}
+}
+
+// UpdateProblemInput は問題固有情報の更新パラメータ。nil の項目は変更しない。
+type UpdateProblemInput struct {
+	RootCause  *string
+	Workaround *string
+	KnownError *bool
+}
+
+// Update は根本原因・回避策・既知のエラー登録状況を更新する。
+func (s *ProblemService) Update(ctx context.Context, id string, input UpdateProblemInput) (*domain.State, error) {
+	problem, err := s.getProblem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.RootCause != nil {
+		problem.Problem.RootCause = *input.RootCause
+	}
+	if input.Workaround != nil {
+		problem.Problem.Workaround = *input.Workaround
+	}
+	if input.KnownError != nil {
+		problem.Problem.KnownError = *input.KnownError
+	}
+
+	problem.Touch(time.Now())
+	if err := s.stateRepo.Update(ctx, problem); err != nil {
+		return nil, fmt.Errorf("failed to update problem: %w", err)
+	}
+	return problem, nil
+}
+
+// LinkIncidents はインシデントを問題に紐づけ、インシデント側のReferencesにも問題IDを追加する。
+// 別プロジェクトのものやインシデント以外のStateは紐づけない。
+func (s *ProblemService) LinkIncidents(ctx context.Context, problemID string, incidentIDs []string) (*domain.State, error) {
+	problem, err := s.getProblem(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, incidentID := range incidentIDs {
+		incident, err := s.stateRepo.Get(ctx, incidentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get incident %s: %w", incidentID, err)
+		}
+		if incident.Type != domain.StateTypeIncident {
+			return nil, fmt.Errorf("%s: %w", incidentID, domain.ErrNotIncident)
+		}
+		if incident.ProjectID != problem.ProjectID {
+			return nil, fmt.Errorf("incident %s belongs to another project", incidentID)
+		}
+		if !problem.Problem.LinkIncident(incidentID) {
+			continue
+		}
+		if !containsString(incident.References, problem.ID) {
+			incident.References = append(incident.References, problem.ID)
+			incident.Touch(now)
+			if err := s.stateRepo.Update(ctx, incident); err != nil {
+				return nil, fmt.Errorf("failed to link incident %s: %w", incidentID, err)
+			}
+		}
+	}
+
+	problem.Touch(now)
+	if err := s.stateRepo.Update(ctx, problem); err != nil {
+		return nil, fmt.Errorf("failed to update problem: %w", err)
+	}
+	return problem, nil
+}
+
+// UnlinkIncident はインシデントと問題の紐づけを解除する。
+func (s *ProblemService) UnlinkIncident(ctx context.Context, problemID string, incidentID string) (*domain.State, error) {
+	problem, err := s.getProblem(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+	if !problem.Problem.UnlinkIncident(incidentID) {
+		return nil, domain.ErrNotFound
+	}
+
+	now := time.Now()
+	if incident, err := s.stateRepo.Get(ctx, incidentID); err == nil {
+		incident.References = removeString(incident.References, problem.ID)
+		incident.Touch(now)
+		if err := s.stateRepo.Update(ctx, incident); err != nil {
+			return nil, fmt.Errorf("failed to unlink incident %s: %w", incidentID, err)
+		}
+	}
+
+	problem.Touch(now)
+	if err := s.stateRepo.Update(ctx, problem); err != nil {
+		return nil, fmt.Errorf("failed to update problem: %w", err)
+	}
+	return problem, nil
+}
+
+// IncidentSuggestion は問題に紐づける候補となるインシデント。
+type IncidentSuggestion struct {
+	Incident   domain.StateSummary `json:"incident"`
+	Similarity float32             `json:"similarity"`
+}
+
+// SuggestIncidents は問題のタイトル・説明に類似する未紐づけのインシデントを候補として返す。
+// ベクトルインデックスが利用できない場合は文字bigramの類似度で代替する。
+func (s *ProblemService) SuggestIncidents(ctx context.Context, problemID string, limit int) ([]IncidentSuggestion, error) {
+	problem, err := s.getProblem(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := problem.Title + "\n" + problem.Description
+	if problem.Problem.RootCause != "" {
+		query += "\n" + problem.Problem.RootCause
+	}
+
+	if s.vectorRepo != nil {
+		filters := map[string]string{
+			"type":       "state",
+			"project_id": problem.ProjectID,
+			"state_type": string(domain.StateTypeIncident),
+		}
+		// 紐づけ済みのものを除外しても limit 件を確保できるよう多めに取得する
+		results, err := s.vectorRepo.Search(ctx, query, limit+len(problem.Problem.IncidentIDs), filters)
+		if err == nil {
+			suggestions := make([]IncidentSuggestion, 0, len(results))
+			for _, result := range results {
+				if problem.Problem.HasIncident(result.ID) {
+					continue
+				}
+				incident, err := s.stateRepo.Get(ctx, result.ID)
+				if err != nil || incident.Type != domain.StateTypeIncident || incident.ProjectID != problem.ProjectID {
+					continue
+				}
+				suggestions = append(suggestions, IncidentSuggestion{Incident: incident.ToSummary(), Similarity: result.Similarity})
+			}
+			return truncateSuggestions(suggestions, limit), nil
+		}
+		slog.Warn("vector incident suggestion failed, fallback to text similarity", "error", err)
+	}
+
+	incidentType := domain.StateTypeIncident
+	incidents, err := s.stateRepo.List(ctx, problem.ProjectID, &repository.StateListOptions{Type: &incidentType, IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	suggestions := make([]IncidentSuggestion, 0, len(incidents))
+	for _, incident := range incidents {
+		if problem.Problem.HasIncident(incident.ID) {
+			continue
+		}
+		score := bigramSimilarity(query, incident.Title+"\n"+incident.Description)
+		if score <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, IncidentSuggestion{Incident: incident.ToSummary(), Similarity: score})
+	}
+	return truncateSuggestions(suggestions, limit), nil
+}
+
+func truncateSuggestions(suggestions []IncidentSuggestion, limit int) []IncidentSuggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Similarity > suggestions[j].Similarity
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// ProblemReport は問題ごとの紐づきインシデント件数の推移。
+type ProblemReport struct {
+	Problem  domain.StateSummary `json:"problem"`
+	Total    int                 `json:"total"`
+	Periods  []PeriodCount       `json:"periods"`
+	LastSeen *time.Time          `json:"last_seen,omitempty"` // 最も新しいインシデントの発生日時
+}
+
+// PeriodCount は期間ごとの件数。
+type PeriodCount struct {
+	Period string `json:"period"` // 例: "2025-03"（月）, "2025-W09"（週）
+	Count  int    `json:"count"`
+}
+
+// Report はプロジェクト内の問題ごとに、紐づくインシデントの発生件数を期間別に集計する。
+// period は "month"（デフォルト）または "week"。インシデントの発生日時には CreatedAt を用いる。
+func (s *ProblemService) Report(ctx context.Context, projectID string, period string) ([]ProblemReport, error) {
+	bucket, err := periodBucketFunc(period)
+	if err != nil {
+		return nil, err
+	}
+
+	problemType := domain.StateTypeProblem
+	problems, err := s.stateRepo.List(ctx, projectID, &repository.StateListOptions{Type: &problemType, IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list problems: %w", err)
+	}
+
+	reports := make([]ProblemReport, 0, len(problems))
+	for _, problem := range problems {
+		report := ProblemReport{Problem: problem.ToSummary(), Periods: []PeriodCount{}}
+		if problem.Problem != nil {
+			counts := make(map[string]int)
+			for _, incidentID := range problem.Problem.IncidentIDs {
+				incident, err := s.stateRepo.Get(ctx, incidentID)
+				if err != nil {
+					slog.Warn("linked incident not found", "problem_id", problem.ID, "incident_id", incidentID, "error", err)
+					continue
+				}
+				counts[bucket(incident.CreatedAt)]++
+				report.Total++
+				if report.LastSeen == nil || incident.CreatedAt.After(*report.LastSeen) {
+					seen := incident.CreatedAt
+					report.LastSeen = &seen
+				}
+			}
+			for p, c := range counts {
+				report.Periods = append(report.Periods, PeriodCount{Period: p, Count: c})
+			}
+			sort.Slice(report.Periods, func(i, j int) bool {
+				return report.Periods[i].Period < report.Periods[j].Period
+			})
+		}
+		reports = append(reports, report)
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Total > reports[j].Total
+	})
+	return reports, nil
+}
+
+func periodBucketFunc(period string) (func(time.Time) string, error) {
+	switch period {
+	case "", "month":
+		return func(t time.Time) string { return t.Format("2006-01") }, nil
+	case "week":
+		return func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid period: %s (must be month or week)", period)
+	}
+}
+
+func (s *ProblemService) getProblem(ctx context.Context, id string) (*domain.State, error) {
+	state, err := s.stateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if state.Type != domain.StateTypeProblem {
+		return nil, domain.ErrNotProblem
+	}
+	if state.Problem == nil {
+		state.Problem = &domain.ProblemDetails{}
+	}
+	return state, nil
+}
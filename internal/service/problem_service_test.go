@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+func seedProblemFixtures(repo *fakeStateRepo) {
+	jan := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+	repo.states["STA-PROBLEM-001"] = &domain.State{
+		ID: "STA-PROBLEM-001", ProjectID: "proj-1", Type: domain.StateTypeProblem, Status: domain.StatusOpen,
+		Priority: domain.PriorityP1, Title: "DB connection pool exhaustion", Description: "connection pool exhausted under load",
+		CreatedAt: feb, UpdatedAt: feb,
+	}
+	repo.states["STA-INCIDENT-001"] = &domain.State{
+		ID: "STA-INCIDENT-001", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusResolved,
+		Priority: domain.PriorityP0, Title: "API timeout", Description: "connection pool exhausted", CreatedAt: jan, UpdatedAt: jan,
+	}
+	repo.states["STA-INCIDENT-002"] = &domain.State{
+		ID: "STA-INCIDENT-002", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusOpen,
+		Priority: domain.PriorityP0, Title: "Checkout errors", Description: "DB connection pool exhausted again", CreatedAt: feb, UpdatedAt: feb,
+	}
+	repo.states["STA-INCIDENT-003"] = &domain.State{
+		ID: "STA-INCIDENT-003", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "Typo on landing page", Description: "wrong wording", CreatedAt: feb, UpdatedAt: feb,
+	}
+	repo.states["STA-TASK-004"] = &domain.State{
+		ID: "STA-TASK-004", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Title: "task", CreatedAt: feb, UpdatedAt: feb,
+	}
+}
+
+func TestProblemServiceLinkAndReport(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeStateRepo()
+	seedProblemFixtures(repo)
+	svc := NewProblemService(repo, nil)
+
+	workaround := "restart the API pods"
+	known := true
+	if _, err := svc.Update(ctx, "STA-PROBLEM-001", UpdateProblemInput{Workaround: &workaround, KnownError: &known}); err != nil {
+		t.Fatalf("update problem: %v", err)
+	}
+
+	problem, err := svc.LinkIncidents(ctx, "STA-PROBLEM-001", []string{"STA-INCIDENT-001", "STA-INCIDENT-002", "STA-INCIDENT-001"})
+	if err != nil {
+		t.Fatalf("link incidents: %v", err)
+	}
+	if len(problem.Problem.IncidentIDs) != 2 || !problem.Problem.KnownError || problem.Problem.Workaround != workaround {
+		t.Fatalf("unexpected problem details: %+v", problem.Problem)
+	}
+	incident, _ := repo.Get(ctx, "STA-INCIDENT-001")
+	if !containsID(incident.References, "STA-PROBLEM-001") {
+		t.Fatalf("expected incident to reference problem, got %v", incident.References)
+	}
+
+	if _, err := svc.LinkIncidents(ctx, "STA-PROBLEM-001", []string{"STA-TASK-004"}); err == nil {
+		t.Fatalf("expected error when linking non-incident")
+	}
+
+	reports, err := svc.Report(ctx, "proj-1", "month")
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Total != 2 {
+		t.Fatalf("unexpected report: %+v", reports)
+	}
+	if len(reports[0].Periods) != 2 || reports[0].Periods[0].Period != "2025-01" || reports[0].Periods[1].Count != 1 {
+		t.Fatalf("unexpected periods: %+v", reports[0].Periods)
+	}
+	if _, err := svc.Report(ctx, "proj-1", "year"); err == nil {
+		t.Fatalf("expected error for invalid period")
+	}
+
+	problem, err = svc.UnlinkIncident(ctx, "STA-PROBLEM-001", "STA-INCIDENT-001")
+	if err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	if len(problem.Problem.IncidentIDs) != 1 {
+		t.Fatalf("expected 1 linked incident after unlink, got %v", problem.Problem.IncidentIDs)
+	}
+	incident, _ = repo.Get(ctx, "STA-INCIDENT-001")
+	if containsID(incident.References, "STA-PROBLEM-001") {
+		t.Fatalf("expected problem reference removed from incident")
+	}
+	if _, err := svc.UnlinkIncident(ctx, "STA-PROBLEM-001", "STA-INCIDENT-001"); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound on double unlink, got %v", err)
+	}
+}
+
+func TestProblemServiceSuggestIncidents(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeStateRepo()
+	seedProblemFixtures(repo)
+
+	// ベクトル検索: 紐づけ済み・インシデント以外は除外される
+	vector := &fakeVectorRepo{results: []repository.SearchResult{
+		{ID: "STA-INCIDENT-002", Similarity: 0.9},
+		{ID: "STA-TASK-004", Similarity: 0.8},
+		{ID: "STA-INCIDENT-001", Similarity: 0.7},
+	}}
+	svc := NewProblemService(repo, vector)
+	if _, err := svc.LinkIncidents(ctx, "STA-PROBLEM-001", []string{"STA-INCIDENT-001"}); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	suggestions, err := svc.SuggestIncidents(ctx, "STA-PROBLEM-001", 5)
+	if err != nil {
+		t.Fatalf("suggest with vector: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Incident.ID != "STA-INCIDENT-002" {
+		t.Fatalf("unexpected vector suggestions: %+v", suggestions)
+	}
+
+	// ベクトルDBなし: bigram類似度で代替し、類似度順に並ぶ
+	fallback := NewProblemService(repo, nil)
+	suggestions, err = fallback.SuggestIncidents(ctx, "STA-PROBLEM-001", 5)
+	if err != nil {
+		t.Fatalf("suggest fallback: %v", err)
+	}
+	if len(suggestions) == 0 || suggestions[0].Incident.ID != "STA-INCIDENT-002" {
+		t.Fatalf("unexpected fallback suggestions: %+v", suggestions)
+	}
+
+	if _, err := fallback.SuggestIncidents(ctx, "STA-INCIDENT-002", 5); err != domain.ErrNotProblem {
+		t.Fatalf("expected ErrNotProblem, got %v", err)
+	}
+}
+
+func TestBigramSimilarity(t *testing.T) {
+	if got := bigramSimilarity("connection pool", "connection pool"); got != 1 {
+		t.Fatalf("expected identical strings to score 1, got %v", got)
+	}
+	if got := bigramSimilarity("abc", "xyz"); got != 0 {
+		t.Fatalf("expected disjoint strings to score 0, got %v", got)
+	}
+	if bigramSimilarity("接続プール枯渇", "接続プールが枯渇") <= bigramSimilarity("接続プール枯渇", "画面の誤字") {
+		t.Fatalf("expected similar Japanese text to score higher")
+	}
+}
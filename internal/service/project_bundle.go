@@ -0,0 +1,711 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// バンドル（プロジェクトのエクスポートファイル）の形式
+const (
+	bundleFormat   = "pim-project-bundle"
+	bundleVersion  = 1
+	bundleManifest = "manifest.json"
+	bundleReleases = "releases.json"
+	bundleLinks    = "links.json"
+
+	// maxBundleSize は読み込むバンドルの展開後の合計サイズの上限。
+	maxBundleSize = 256 << 20
+)
+
+// ErrImportConflict は取り込むStock・State・Releaseの管理番号が取り込み先に既に存在することを表す。
+var ErrImportConflict = errors.New("bundle ids already exist in the destination: use --on-conflict skip or renumber")
+
+// BundleManifest はバンドルの内容を記述するマニフェスト（manifest.json）。
+// Version はバンドル形式のバージョンで、新しい形式のバンドルは取り込まない。
+type BundleManifest struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	PIMVersion string            `json:"pim_version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Project    domain.Project    `json:"project"`
+	Counts     BundleCounts      `json:"counts"`
+	Files      map[string]string `json:"files"` // バンドル内のパス → SHA-256
+}
+
+// BundleCounts はバンドルに含まれる件数。
+type BundleCounts struct {
+	Stocks   int `json:"stocks"`
+	States   int `json:"states"`
+	Releases int `json:"releases"`
+	Links    int `json:"links"`
+}
+
+// BundleLink はStock・State・Release間の参照（links.json）。
+type BundleLink struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"` // reference, redirect, problem_incident, release_item
+}
+
+// ExportProject はプロジェクトのStock（削除済み・再分類のエイリアスを含む）、State（アーカイブ済みを含む）、
+// Release、それらの間の参照をマニフェスト付きの tar.gz として w に書き出す。ベクトルインデックスは含めない。
+func (s *Services) ExportProject(ctx context.Context, projectID string, w io.Writer) (*BundleManifest, error) {
+	if projectID == "" {
+		return nil, domain.ErrInvalidProjectID
+	}
+	// 再分類のエイリアスも、旧管理番号での参照を解決するために含める
+	stocks, err := s.Stock.stockRepo.List(ctx, projectID, &repository.StockListOptions{IncludeDeleted: true, IncludeAliases: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+	states, err := s.State.stateRepo.List(ctx, projectID, &repository.StateListOptions{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+	releases, err := s.Release.releaseRepo.List(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+	if len(stocks) == 0 && len(states) == 0 && len(releases) == 0 {
+		return nil, fmt.Errorf("project %s has no stocks, states or releases: %w", projectID, domain.ErrNotFound)
+	}
+	slices.SortFunc(stocks, func(a, b *domain.Stock) int { return strings.Compare(a.ID, b.ID) })
+	slices.SortFunc(states, func(a, b *domain.State) int { return strings.Compare(a.ID, b.ID) })
+	links := collectBundleLinks(stocks, states, releases)
+
+	files := map[string][]byte{}
+	var order []string
+	add := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		files[name] = data
+		order = append(order, name)
+		return nil
+	}
+	project := domain.Project{ID: projectID}
+	for _, stock := range stocks {
+		project.CreatedAt, project.UpdatedAt = widenPeriod(project.CreatedAt, project.UpdatedAt, stock.CreatedAt, stock.UpdatedAt)
+		if err := add(path.Join("stocks", string(stock.Category), stock.ID+".json"), stock); err != nil {
+			return nil, err
+		}
+	}
+	for _, state := range states {
+		project.CreatedAt, project.UpdatedAt = widenPeriod(project.CreatedAt, project.UpdatedAt, state.CreatedAt, state.UpdatedAt)
+		if err := add(path.Join("states", state.ID+".json"), state); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(bundleReleases, releases); err != nil {
+		return nil, err
+	}
+	if err := add(bundleLinks, links); err != nil {
+		return nil, err
+	}
+
+	manifest := &BundleManifest{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		PIMVersion: config.Version,
+		ExportedAt: time.Now().UTC(),
+		Project:    project,
+		Counts:     BundleCounts{Stocks: len(stocks), States: len(states), Releases: len(releases), Links: len(links)},
+		Files:      map[string]string{},
+	}
+	for name, data := range files {
+		manifest.Files[name] = sha256Hex(data)
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	// マニフェストを先頭に置き、中身を読まずに形式を確認できるようにする
+	for _, name := range append([]string{bundleManifest}, order...) {
+		data := manifestData
+		if name != bundleManifest {
+			data = files[name]
+		}
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.ExportedAt, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// collectBundleLinks はStock・State・Release間の参照を列挙する。
+func collectBundleLinks(stocks []*domain.Stock, states []*domain.State, releases []*domain.Release) []BundleLink {
+	links := []BundleLink{}
+	for _, stock := range stocks {
+		for _, ref := range stock.References {
+			links = append(links, BundleLink{From: stock.ID, To: ref, Kind: "reference"})
+		}
+		if stock.IsAlias() {
+			links = append(links, BundleLink{From: stock.ID, To: stock.RedirectTo, Kind: "redirect"})
+		}
+	}
+	for _, state := range states {
+		for _, ref := range state.References {
+			links = append(links, BundleLink{From: state.ID, To: ref, Kind: "reference"})
+		}
+		if state.Problem != nil {
+			for _, id := range state.Problem.IncidentIDs {
+				links = append(links, BundleLink{From: state.ID, To: id, Kind: "problem_incident"})
+			}
+		}
+	}
+	for _, release := range releases {
+		for _, id := range release.ItemIDs {
+			links = append(links, BundleLink{From: release.Version, To: id, Kind: "release_item"})
+		}
+	}
+	return links
+}
+
+// ImportConflictPolicy は取り込み先に同じ管理番号（Releaseはバージョン）が存在する場合の扱い。
+type ImportConflictPolicy string
+
+const (
+	ImportConflictFail     ImportConflictPolicy = "fail"     // 取り込まずにエラーを返す（デフォルト）
+	ImportConflictSkip     ImportConflictPolicy = "skip"     // 既存のものを残し、バンドルの項目は取り込まない
+	ImportConflictRenumber ImportConflictPolicy = "renumber" // 新しい管理番号を採番し、参照も書き換える
+)
+
+// ParseImportConflictPolicy は文字列を ImportConflictPolicy に変換する。空文字は fail とする。
+func ParseImportConflictPolicy(s string) (ImportConflictPolicy, error) {
+	switch policy := ImportConflictPolicy(s); policy {
+	case "":
+		return ImportConflictFail, nil
+	case ImportConflictFail, ImportConflictSkip, ImportConflictRenumber:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid on-conflict policy %q: must be fail, skip, or renumber", s)
+	}
+}
+
+// ImportOptions はバンドルの取り込みの指定。
+type ImportOptions struct {
+	ProjectID  string // 取り込み先のプロジェクトID（空の場合はバンドルのプロジェクトID）
+	OnConflict ImportConflictPolicy
+	DryRun     bool // 書き込まずに取り込み内容だけを返す
+}
+
+// ImportItem は取り込むStock・State・Releaseの1件。
+type ImportItem struct {
+	Kind   string `json:"kind"`   // stock, state, release
+	OldID  string `json:"old_id"` // バンドル内の管理番号（Releaseはバージョン）
+	NewID  string `json:"new_id"` // 取り込み先の管理番号（skip の場合は空）
+	Action string `json:"action"` // create, renumber, skip
+}
+
+// ImportReport はバンドルの取り込み結果（dry-run の場合は取り込み予定）。
+type ImportReport struct {
+	Manifest  *BundleManifest
+	ProjectID string
+	DryRun    bool
+	Items     []ImportItem
+	Conflicts []string // 取り込み先に既に存在する管理番号
+	Warnings  []string // バンドル外・取り込み先にもない参照先など
+	// Written は書き込んだ件数。失敗して取り消した場合は、取り消せずに残った件数になる。
+	Written BundleCounts
+}
+
+// Count は action の件数を返す。
+func (r *ImportReport) Count(action string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// projectBundle は読み込んだバンドルの内容。
+type projectBundle struct {
+	manifest *BundleManifest
+	stocks   []*domain.Stock
+	states   []*domain.State
+	releases []*domain.Release
+	links    []BundleLink
+}
+
+// ImportProject は ExportProject で書き出したバンドルを取り込む。
+// 取り込み先に同じ管理番号がある場合は opts.OnConflict に従い、renumber では新しい管理番号を採番して
+// Stock・State・Release間の参照（references・再分類の転送先・問題に紐づくインシデント・リリースの項目）を書き換える。
+// 本文中に書かれた管理番号は書き換えない。取り込んだStock・Stateはベクトルインデックスに登録し直す。
+func (s *Services) ImportProject(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	bundle, err := readProjectBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	projectID := opts.ProjectID
+	if projectID == "" {
+		projectID = bundle.manifest.Project.ID
+	}
+	if projectID == "" || strings.ContainsAny(projectID, `/\`) || projectID == "." || projectID == ".." {
+		return nil, domain.ErrInvalidProjectID
+	}
+	if opts.OnConflict == "" {
+		opts.OnConflict = ImportConflictFail
+	}
+
+	report := &ImportReport{Manifest: bundle.manifest, ProjectID: projectID, DryRun: opts.DryRun}
+	plan := &importPlan{services: s, projectID: projectID, policy: opts.OnConflict, report: report,
+		ids: map[string]string{}, reserved: map[string]bool{}}
+	if err := plan.assign(ctx, bundle); err != nil {
+		return nil, err
+	}
+	if len(report.Conflicts) > 0 && opts.OnConflict == ImportConflictFail {
+		return report, fmt.Errorf("%w: %s", ErrImportConflict, strings.Join(report.Conflicts, ", "))
+	}
+	plan.checkLinks(ctx, bundle)
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := plan.write(ctx, bundle); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// importPlan は取り込み先の管理番号の割り当てと書き込みを行う。
+type importPlan struct {
+	services  *Services
+	projectID string
+	policy    ImportConflictPolicy
+	report    *ImportReport
+
+	ids      map[string]string // バンドル内の管理番号 → 取り込み先の管理番号（skip は含まない）
+	skipped  map[string]bool   // 取り込まない管理番号（Releaseは "release " + バージョン）
+	reserved map[string]bool   // バンドル内の管理番号と、割り当て済みの管理番号（採番で使わない）
+}
+
+// assign は取り込み先の管理番号を割り当てる。
+func (p *importPlan) assign(ctx context.Context, bundle *projectBundle) error {
+	p.skipped = map[string]bool{}
+	for _, stock := range bundle.stocks {
+		p.reserved[stock.ID] = true
+	}
+	for _, state := range bundle.states {
+		p.reserved[state.ID] = true
+	}
+	for _, stock := range bundle.stocks {
+		_, err := p.services.Stock.stockRepo.Get(ctx, p.projectID, stock.ID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("failed to check stock %s: %w", stock.ID, err)
+		}
+		if err := p.resolve(ctx, "stock", stock.ID, err == nil, func(ctx context.Context) (string, error) {
+			return p.nextStockID(ctx, stock.Category)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, state := range bundle.states {
+		_, err := p.services.State.stateRepo.Get(ctx, state.ID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("failed to check state %s: %w", state.ID, err)
+		}
+		if err := p.resolve(ctx, "state", state.ID, err == nil, func(ctx context.Context) (string, error) {
+			return p.nextStateID(ctx, state.Type)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, release := range bundle.releases {
+		_, err := p.services.Release.releaseRepo.Get(ctx, p.projectID, release.Version)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("failed to check release %s: %w", release.Version, err)
+		}
+		item := ImportItem{Kind: "release", OldID: release.Version, NewID: release.Version, Action: "create"}
+		if err == nil {
+			// バージョンは採番し直せないため、renumber でも既存のReleaseを残す
+			p.report.Conflicts = append(p.report.Conflicts, "release "+release.Version)
+			item.NewID, item.Action = "", "skip"
+			p.skipped["release "+release.Version] = true
+		}
+		p.report.Items = append(p.report.Items, item)
+	}
+	return nil
+}
+
+// resolve は1件の管理番号の割り当てを決める。
+func (p *importPlan) resolve(ctx context.Context, kind, id string, exists bool, next func(context.Context) (string, error)) error {
+	item := ImportItem{Kind: kind, OldID: id, NewID: id, Action: "create"}
+	if exists {
+		p.report.Conflicts = append(p.report.Conflicts, id)
+		switch p.policy {
+		case ImportConflictSkip:
+			item.NewID, item.Action = "", "skip"
+			p.skipped[id] = true
+		case ImportConflictRenumber:
+			newID, err := next(ctx)
+			if err != nil {
+				return err
+			}
+			item.NewID, item.Action = newID, "renumber"
+		}
+	}
+	if item.NewID != "" {
+		p.ids[id] = item.NewID
+		p.reserved[item.NewID] = true
+	}
+	p.report.Items = append(p.report.Items, item)
+	return nil
+}
+
+// nextStockID は取り込み先のプロジェクト・カテゴリで未使用の管理番号を返す。
+func (p *importPlan) nextStockID(ctx context.Context, category domain.StockCategory) (string, error) {
+	stocks, err := p.services.Stock.stockRepo.List(ctx, p.projectID, &repository.StockListOptions{Category: &category, IncludeDeleted: true, IncludeAliases: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to list stocks: %w", err)
+	}
+	seq := 0
+	for _, stock := range stocks {
+		seq = max(seq, stockSequence(stock.ID, category))
+	}
+	for id := range p.reserved {
+		seq = max(seq, stockSequence(id, category))
+	}
+	return generateStockID(category, seq+1), nil
+}
+
+// nextStateID は全プロジェクトで未使用のStateの管理番号を返す。
+func (p *importPlan) nextStateID(ctx context.Context, stateType domain.StateType) (string, error) {
+	states, err := p.services.State.stateRepo.List(ctx, "", &repository.StateListOptions{Type: &stateType, IncludeArchived: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to list states: %w", err)
+	}
+	seq := 0
+	for _, state := range states {
+		seq = max(seq, stateSequence(state.ID, stateType))
+	}
+	for id := range p.reserved {
+		seq = max(seq, stateSequence(id, stateType))
+	}
+	return fmt.Sprintf("STA-%s-%03d", stateType, seq+1), nil
+}
+
+// checkLinks はバンドル外を指す参照のうち、取り込み先にも存在しないものを警告する。
+// skip で取り込まなかった項目への参照は、書き換えずに取り込み先の既存の項目を指すことになるため、参照ごとに警告する。
+func (p *importPlan) checkLinks(ctx context.Context, bundle *projectBundle) {
+	inBundle := map[string]bool{}
+	for _, item := range p.report.Items {
+		if item.Kind != "release" {
+			inBundle[item.OldID] = true
+		}
+	}
+	for _, link := range bundle.links {
+		from := link.From
+		if link.Kind == "release_item" {
+			from = "release " + link.From
+		}
+		if p.skipped[from] {
+			continue // 参照元を取り込まない
+		}
+		if p.skipped[link.To] {
+			p.report.Warnings = append(p.report.Warnings, fmt.Sprintf("%s %s -> %s: target was skipped; the reference now points to the existing %s in the destination", link.Kind, link.From, link.To, link.To))
+			continue
+		}
+		if inBundle[link.To] {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(link.To, "STA-") {
+			_, err = p.services.State.stateRepo.Get(ctx, link.To)
+		} else {
+			_, err = p.services.Stock.stockRepo.Get(ctx, p.projectID, link.To)
+		}
+		if err != nil {
+			p.report.Warnings = append(p.report.Warnings, fmt.Sprintf("%s %s -> %s: target is not in the bundle or the destination", link.Kind, link.From, link.To))
+		}
+	}
+}
+
+// write は割り当てた管理番号で参照を書き換えて保存し、ベクトルインデックスに登録する。
+// StockはStockリポジトリのロックを保持したまま作成し、StateとReleaseは1つのトランザクションで作成する。
+// 途中で失敗した場合は作成済みのStockを削除して取り込みを取り消す。書き込んだ件数は report.Written に記録する。
+func (p *importPlan) write(ctx context.Context, bundle *projectBundle) error {
+	stocks, states, releases := p.remap(bundle)
+	return p.services.Stock.withStockLock(ctx, func(ctx context.Context) error {
+		var created []*domain.Stock
+		for _, stock := range stocks {
+			if err := p.services.Stock.stockRepo.Create(ctx, stock); err != nil {
+				p.rollbackStocks(ctx, created)
+				return fmt.Errorf("failed to import stock %s: %w", stock.ID, err)
+			}
+			created = append(created, stock)
+		}
+		p.report.Written.Stocks = len(created)
+
+		stateCount, releaseCount, err := p.createStatesAndReleases(ctx, states, releases)
+		p.report.Written.States, p.report.Written.Releases = stateCount, releaseCount
+		if err != nil {
+			p.rollbackStocks(ctx, created)
+			return err
+		}
+
+		if history := p.services.Stock.history; history != nil {
+			paths := make([]string, 0, len(created))
+			for _, stock := range created {
+				paths = append(paths, repository.StockFilePath(stock))
+			}
+			commit := repository.StockCommit{ProjectID: p.projectID, Action: "import", Actor: actorFrom(ctx), Paths: paths}
+			if err := history.Record(ctx, commit); err != nil {
+				// 履歴の記録の失敗は取り込み自体を妨げない
+				p.report.Warnings = append(p.report.Warnings, fmt.Sprintf("failed to record stock history: %v", err))
+			}
+		}
+		for _, stock := range created {
+			if !stock.IsDeleted() && !stock.IsAlias() {
+				p.services.Stock.indexStock(ctx, stock)
+			}
+		}
+		for _, state := range states {
+			if state.Status != domain.StatusArchived {
+				p.services.State.indexState(ctx, state)
+			}
+		}
+		return nil
+	})
+}
+
+// remap は取り込むStock・State・Releaseを、割り当てた管理番号と取り込み先のプロジェクトに書き換えて返す。
+func (p *importPlan) remap(bundle *projectBundle) ([]*domain.Stock, []*domain.State, []*domain.Release) {
+	var stocks []*domain.Stock
+	for _, stock := range bundle.stocks {
+		if p.skipped[stock.ID] {
+			continue
+		}
+		stock.ID = p.ids[stock.ID]
+		stock.ProjectID = p.projectID
+		stock.References = p.remapIDs(stock.References)
+		if stock.RedirectTo != "" {
+			stock.RedirectTo = p.remapID(stock.RedirectTo)
+		}
+		stocks = append(stocks, stock)
+	}
+
+	var states []*domain.State
+	for _, state := range bundle.states {
+		if p.skipped[state.ID] {
+			continue
+		}
+		state.ID = p.ids[state.ID]
+		state.ProjectID = p.projectID
+		state.References = p.remapIDs(state.References)
+		if state.Problem != nil {
+			state.Problem.IncidentIDs = p.remapIDs(state.Problem.IncidentIDs)
+		}
+		states = append(states, state)
+	}
+
+	var releases []*domain.Release
+	for _, release := range bundle.releases {
+		if p.skipped["release "+release.Version] {
+			continue
+		}
+		release.ProjectID = p.projectID
+		release.ItemIDs = p.remapIDs(release.ItemIDs)
+		releases = append(releases, release)
+	}
+	return stocks, states, releases
+}
+
+// createStatesAndReleases はStateとReleaseを作成し、作成した件数を返す。
+// Stateリポジトリが repository.StateBatchCreator を実装している場合は1つのトランザクションで作成し、失敗した場合は何も作成しない。
+func (p *importPlan) createStatesAndReleases(ctx context.Context, states []*domain.State, releases []*domain.Release) (int, int, error) {
+	if batch, ok := p.services.State.stateRepo.(repository.StateBatchCreator); ok {
+		if err := batch.CreateBatch(ctx, states, releases); err != nil {
+			return 0, 0, fmt.Errorf("failed to import states and releases: %w", err)
+		}
+		return len(states), len(releases), nil
+	}
+	for i, state := range states {
+		if err := p.services.State.stateRepo.Create(ctx, state); err != nil {
+			return i, 0, fmt.Errorf("failed to import state %s: %w", state.ID, err)
+		}
+	}
+	for i, release := range releases {
+		if err := p.services.Release.releaseRepo.Create(ctx, release); err != nil {
+			return len(states), i, fmt.Errorf("failed to import release %s: %w", release.Version, err)
+		}
+	}
+	return len(states), len(releases), nil
+}
+
+// rollbackStocks は取り込みに失敗した場合に、作成したStockを削除する。削除できなかったStockは警告し、書き込んだ件数に残す。
+func (p *importPlan) rollbackStocks(ctx context.Context, created []*domain.Stock) {
+	remaining := 0
+	for _, stock := range created {
+		if err := p.services.Stock.stockRepo.Delete(ctx, stock.ProjectID, stock.ID); err != nil {
+			p.report.Warnings = append(p.report.Warnings, fmt.Sprintf("failed to roll back imported stock %s: %v", stock.ID, err))
+			remaining++
+		}
+	}
+	p.report.Written.Stocks = remaining
+}
+
+func (p *importPlan) remapID(id string) string {
+	if newID, ok := p.ids[id]; ok {
+		return newID
+	}
+	return id
+}
+
+func (p *importPlan) remapIDs(ids []string) []string {
+	if ids == nil {
+		return nil
+	}
+	remapped := make([]string, len(ids))
+	for i, id := range ids {
+		remapped[i] = p.remapID(id)
+	}
+	return remapped
+}
+
+// readProjectBundle はバンドルを読み込み、マニフェストの形式・バージョンとファイルのチェックサムを検証する。
+func readProjectBundle(r io.Reader) (*projectBundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(io.LimitReader(gz, maxBundleSize))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || strings.HasPrefix(name, "..") {
+			return nil, fmt.Errorf("invalid bundle: unsafe path %q", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		files[name] = data
+	}
+
+	data, ok := files[bundleManifest]
+	if !ok {
+		return nil, fmt.Errorf("invalid bundle: %s is missing", bundleManifest)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if manifest.Format != bundleFormat {
+		return nil, fmt.Errorf("invalid bundle: unknown format %q", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (this pim supports up to %d)", manifest.Version, bundleVersion)
+	}
+	for name, sum := range manifest.Files {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle: %s is missing", name)
+		}
+		if sha256Hex(data) != sum {
+			return nil, fmt.Errorf("invalid bundle: checksum mismatch for %s", name)
+		}
+	}
+
+	bundle := &projectBundle{manifest: &manifest}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		data := files[name]
+		var err error
+		switch {
+		case strings.HasPrefix(name, "stocks/"):
+			var stock domain.Stock
+			if err = json.Unmarshal(data, &stock); err == nil {
+				bundle.stocks = append(bundle.stocks, &stock)
+			}
+		case strings.HasPrefix(name, "states/"):
+			var state domain.State
+			if err = json.Unmarshal(data, &state); err == nil {
+				bundle.states = append(bundle.states, &state)
+			}
+		case name == bundleReleases:
+			err = json.Unmarshal(data, &bundle.releases)
+		case name == bundleLinks:
+			err = json.Unmarshal(data, &bundle.links)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: failed to parse %s: %w", name, err)
+		}
+	}
+	return bundle, nil
+}
+
+// stateSequence はStateの管理番号の連番部分を返す。種別の形式に一致しない場合は 0 を返す。
+func stateSequence(id string, stateType domain.StateType) int {
+	rest, ok := strings.CutPrefix(id, "STA-"+string(stateType)+"-")
+	if !ok {
+		return 0
+	}
+	seq, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// widenPeriod は期間 [from, to] を [created, updated] を含むように広げる。
+func widenPeriod(from, to, created, updated time.Time) (time.Time, time.Time) {
+	if from.IsZero() || created.Before(from) {
+		from = created
+	}
+	if updated.After(to) {
+		to = updated
+	}
+	return from, to
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
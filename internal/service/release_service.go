@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// ReleaseService は解決済みの変更・タスクを束ねたリリースの作成とリリースノート生成を提供する。
+type ReleaseService struct {
+	releaseRepo repository.ReleaseRepository
+	stateRepo   repository.StateRepository
+}
+
+// NewReleaseService は新しいReleaseServiceを生成する。
+func NewReleaseService(releaseRepo repository.ReleaseRepository, stateRepo repository.StateRepository) *ReleaseService {
+	return &ReleaseService{
+		releaseRepo: releaseRepo,
+		stateRepo:   stateRepo,
+	}
+}
+
+// CreateReleaseInput はRelease作成時の入力パラメータ。
+type CreateReleaseInput struct {
+	ProjectID string
+	Version   string
+	Title     string
+	ItemIDs   []string // 空の場合は未リリースの解決済み変更・タスクをすべて含める
+}
+
+// Create はReleaseを作成し、含まれる変更・タスクからリリースノートを生成する。
+func (s *ReleaseService) Create(ctx context.Context, input CreateReleaseInput) (*domain.Release, error) {
+	if s.releaseRepo == nil {
+		return nil, fmt.Errorf("release repository is not configured")
+	}
+	if strings.TrimSpace(input.ProjectID) == "" || strings.TrimSpace(input.Version) == "" {
+		return nil, fmt.Errorf("project_id and version are required")
+	}
+
+	var (
+		items []*domain.State
+		err   error
+	)
+	if len(input.ItemIDs) == 0 {
+		items, err = s.unreleasedItems(ctx, input.ProjectID)
+	} else {
+		items, err = s.loadItems(ctx, input.ProjectID, input.ItemIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no resolved changes or tasks to release")
+	}
+
+	release := &domain.Release{
+		ProjectID: input.ProjectID,
+		Version:   input.Version,
+		Title:     input.Title,
+		CreatedAt: time.Now(),
+	}
+	for _, item := range items {
+		release.ItemIDs = append(release.ItemIDs, item.ID)
+	}
+	release.Notes = RenderReleaseNotes(release, items)
+
+	if err := s.releaseRepo.Create(ctx, release); err != nil {
+		return nil, fmt.Errorf("failed to create release: %w", err)
+	}
+	return release, nil
+}
+
+// Get はプロジェクトIDとバージョンでReleaseを取得する。
+func (s *ReleaseService) Get(ctx context.Context, projectID string, version string) (*domain.Release, error) {
+	if s.releaseRepo == nil {
+		return nil, fmt.Errorf("release repository is not configured")
+	}
+	return s.releaseRepo.Get(ctx, projectID, version)
+}
+
+// ListSummary はプロジェクト内のReleaseをサマリビューで一覧取得する。
+func (s *ReleaseService) ListSummary(ctx context.Context, projectID string) ([]domain.ReleaseSummary, error) {
+	if s.releaseRepo == nil {
+		return nil, fmt.Errorf("release repository is not configured")
+	}
+	releases, err := s.releaseRepo.List(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]domain.ReleaseSummary, 0, len(releases))
+	for _, release := range releases {
+		summaries = append(summaries, release.ToSummary())
+	}
+	return summaries, nil
+}
+
+func isReleasableType(t domain.StateType) bool {
+	return t == domain.StateTypeChange || t == domain.StateTypeTask
+}
+
+// loadItems は指定されたStateを取得し、リリース可能（解決済みの変更・タスク）かを検証する。
+func (s *ReleaseService) loadItems(ctx context.Context, projectID string, ids []string) ([]*domain.State, error) {
+	items := make([]*domain.State, 0, len(ids))
+	for _, id := range ids {
+		state, err := s.stateRepo.Get(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state %s: %w", id, err)
+		}
+		if state.ProjectID != projectID {
+			return nil, fmt.Errorf("state %s belongs to another project", id)
+		}
+		if !isReleasableType(state.Type) {
+			return nil, fmt.Errorf("state %s is a %s; only changes and tasks can be released", id, state.Type)
+		}
+		if state.Status != domain.StatusResolved && state.Status != domain.StatusArchived {
+			return nil, fmt.Errorf("state %s is not resolved (status: %s)", id, state.Status)
+		}
+		items = append(items, state)
+	}
+	return items, nil
+}
+
+// unreleasedItems は既存のReleaseに含まれていない解決済み（アーカイブ済みを含む）の変更・タスクを返す。
+func (s *ReleaseService) unreleasedItems(ctx context.Context, projectID string) ([]*domain.State, error) {
+	releases, err := s.releaseRepo.List(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[string]bool)
+	for _, release := range releases {
+		for _, id := range release.ItemIDs {
+			released[id] = true
+		}
+	}
+
+	// 解決後にアーカイブしたものも含める（item_ids で指定する場合と同じく、アーカイブ済みは解決済みとして扱う）
+	states, err := s.stateRepo.List(ctx, projectID, &repository.StateListOptions{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resolved states: %w", err)
+	}
+	var items []*domain.State
+	for _, state := range states {
+		if state.Status != domain.StatusResolved && state.Status != domain.StatusArchived {
+			continue
+		}
+		if isReleasableType(state.Type) && !released[state.ID] {
+			items = append(items, state)
+		}
+	}
+	return items, nil
+}
+
+// RenderReleaseNotes は変更・タスクを種別ごとにまとめたMarkdownのリリースノートを組み立てる。
+func RenderReleaseNotes(release *domain.Release, items []*domain.State) string {
+	var b strings.Builder
+	title := release.Title
+	if title == "" {
+		title = "Release " + release.Version
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Version: %s\n", release.Version)
+	fmt.Fprintf(&b, "- Date: %s\n", release.CreatedAt.Format("2006-01-02"))
+
+	sections := []struct {
+		heading   string
+		stateType domain.StateType
+	}{
+		{"Changes", domain.StateTypeChange},
+		{"Tasks", domain.StateTypeTask},
+	}
+	for _, section := range sections {
+		var lines []string
+		for _, item := range items {
+			if item.Type != section.stateType {
+				continue
+			}
+			line := fmt.Sprintf("- **%s** (%s", item.Title, item.ID)
+			if item.Change != nil && item.Change.RiskLevel != "" {
+				line += ", risk: " + string(item.Change.RiskLevel)
+			}
+			line += ")"
+			if resolution := strings.TrimSpace(item.Resolution); resolution != "" {
+				line += " — " + strings.ReplaceAll(resolution, "\n", " ")
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", section.heading, strings.Join(lines, "\n"))
+	}
+
+	return b.String()
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
 )
 
 func matchesQuery(query string, fields ...string) bool {
@@ -19,6 +20,21 @@ func matchesQuery(query string, fields ...string) bool {
 	return false
 }
 
+// stockVectorID はStockのベクトルインデックス上のドキュメントIDを返す。
+// 管理番号はプロジェクト内でのみ一意のため、プロジェクトIDを前置する（例: "proj-1/STK-DESIGN-001"）。
+func stockVectorID(projectID string, id string) string {
+	return projectID + "/" + id
+}
+
+// vectorItemID はベクトル検索結果のドキュメントIDから管理番号を返す。
+// Stateのドキュメントや旧形式（管理番号のみ）のStockのドキュメントはそのまま返す。
+func vectorItemID(result repository.SearchResult) string {
+	if i := strings.LastIndex(result.ID, "/"); i >= 0 {
+		return result.ID[i+1:]
+	}
+	return result.ID
+}
+
 func joinTags(tags []string) string {
 	return strings.Join(tags, " ")
 }
@@ -37,3 +53,55 @@ func priorityWeight(priority domain.Priority) float32 {
 		return 1.00
 	}
 }
+
+// bigramSimilarity は2つの文字列の文字bigramによるDice係数（0.0 ~ 1.0）を返す。
+// 分かち書きのない日本語でも動作するよう、空白区切りではなく文字単位で比較する。
+func bigramSimilarity(a, b string) float32 {
+	ga := charBigrams(a)
+	gb := charBigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+	shared := 0
+	for g, ca := range ga {
+		if cb, ok := gb[g]; ok {
+			shared += min(ca, cb)
+		}
+	}
+	total := 0
+	for _, c := range ga {
+		total += c
+	}
+	for _, c := range gb {
+		total += c
+	}
+	return float32(2*shared) / float32(total)
+}
+
+func charBigrams(s string) map[string]int {
+	runes := []rune(strings.Join(strings.Fields(strings.ToLower(s)), " "))
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, target string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != target {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -1,12 +1,20 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/llm"
 	"github.com/haconeco/project-information-manager/internal/repository"
 )
 
@@ -42,7 +50,7 @@ func TestStockServiceCreate(t *testing.T) {
 	}
 
 	// 取得テスト
-	got, err := svc.Get(context.Background(), stock.ID)
+	got, err := svc.Get(context.Background(), "", stock.ID)
 	if err != nil {
 		t.Fatalf("failed to get stock: %v", err)
 	}
@@ -51,6 +59,55 @@ func TestStockServiceCreate(t *testing.T) {
 	}
 }
 
+func TestStockServiceProjectScopedIDs(t *testing.T) {
+	ctx := context.Background()
+	svc := NewStockService(repository.NewFileStockRepository(t.TempDir()), nil)
+
+	create := func(projectID, title string) *domain.Stock {
+		t.Helper()
+		stock, err := svc.Create(ctx, CreateStockInput{ProjectID: projectID, Category: "design", Priority: "P2", Title: title, Content: title})
+		if err != nil {
+			t.Fatalf("create %s: %v", title, err)
+		}
+		return stock
+	}
+
+	// 連番はプロジェクト・カテゴリごとに振られる
+	a1 := create("proj-a", "認証方式")
+	b1 := create("proj-b", "課金フロー")
+	a2 := create("proj-a", "画面遷移")
+	if a1.ID != "STK-DESIGN-001" || b1.ID != "STK-DESIGN-001" || a2.ID != "STK-DESIGN-002" {
+		t.Fatalf("unexpected ids: %s, %s, %s", a1.ID, b1.ID, a2.ID)
+	}
+
+	if got, err := svc.Get(ctx, "proj-b", "STK-DESIGN-001"); err != nil || got.Title != "課金フロー" {
+		t.Fatalf("expected project-scoped get, got %+v (%v)", got, err)
+	}
+	if _, err := svc.Get(ctx, "", "STK-DESIGN-001"); err != domain.ErrAmbiguousID {
+		t.Fatalf("expected ErrAmbiguousID without project, got %v", err)
+	}
+	if got, err := svc.Get(ctx, "", "STK-DESIGN-002"); err != nil || got.ProjectID != "proj-a" {
+		t.Fatalf("expected unique id resolved without project, got %+v (%v)", got, err)
+	}
+
+	// 再分類しても転送先は同じプロジェクト内で解決する
+	category := "rules"
+	moved, err := svc.Update(ctx, "proj-b", "STK-DESIGN-001", UpdateStockInput{Category: &category})
+	if err != nil || moved.ID != "STK-RULES-001" {
+		t.Fatalf("unexpected recategorized stock: %+v (%v)", moved, err)
+	}
+	if got, err := svc.Get(ctx, "proj-b", "STK-DESIGN-001"); err != nil || got.ID != "STK-RULES-001" || got.ProjectID != "proj-b" {
+		t.Fatalf("expected alias resolved within project, got %+v (%v)", got, err)
+	}
+	if got, err := svc.Get(ctx, "proj-a", "STK-DESIGN-001"); err != nil || got.Title != "認証方式" {
+		t.Fatalf("expected other project unaffected, got %+v (%v)", got, err)
+	}
+	// エイリアスの管理番号は再利用しない
+	if b2 := create("proj-b", "請求書"); b2.ID != "STK-DESIGN-002" {
+		t.Fatalf("expected alias id skipped, got %s", b2.ID)
+	}
+}
+
 func TestStockServiceCreateInvalidCategory(t *testing.T) {
 	stockRepo := repository.NewFileStockRepository(t.TempDir())
 	svc := NewStockService(stockRepo, nil)
@@ -89,7 +146,7 @@ func TestStockServiceUpdate(t *testing.T) {
 	// 更新
 	newContent := "更新された内容"
 	newPriority := "P0"
-	updated, err := svc.Update(context.Background(), stock.ID, UpdateStockInput{
+	updated, err := svc.Update(context.Background(), "", stock.ID, UpdateStockInput{
 		Content:  &newContent,
 		Priority: &newPriority,
 	})
@@ -105,6 +162,185 @@ func TestStockServiceUpdate(t *testing.T) {
 	}
 }
 
+func TestStockServiceUpdateExpectedUpdatedAt(t *testing.T) {
+	ctx := context.Background()
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+
+	stock, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "rules", Priority: "P2", Title: "開発ルール", Content: "初期内容"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	readAt := stock.UpdatedAt
+
+	// エージェントAが読み込んだ時点の updated_at を指定して更新する
+	contentA := "エージェントAの変更"
+	if _, err := svc.Update(ctx, "proj-1", stock.ID, UpdateStockInput{Content: &contentA, ExpectedUpdatedAt: &readAt}); err != nil {
+		t.Fatalf("update with expected_updated_at: %v", err)
+	}
+
+	// 同じ時点に読み込んだエージェントBの更新は競合として拒否する
+	contentB := "エージェントBの変更"
+	if _, err := svc.Update(ctx, "proj-1", stock.ID, UpdateStockInput{Content: &contentB, ExpectedUpdatedAt: &readAt}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	current, err := svc.Get(ctx, "proj-1", stock.ID)
+	if err != nil || current.Content != contentA {
+		t.Fatalf("expected agent A's content kept, got %+v (%v)", current, err)
+	}
+
+	// updated_at は完全一致で比較する。秒単位に丸めた値は一致しない
+	rounded := current.UpdatedAt.Truncate(time.Second).Add(-time.Nanosecond)
+	if _, err := svc.Update(ctx, "proj-1", stock.ID, UpdateStockInput{Content: &contentB, ExpectedUpdatedAt: &rounded}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for a rounded expected_updated_at, got %v", err)
+	}
+
+	// 削除・復元も更新日時を進めるため、削除前に読み込んだ更新は削除を上書きできない
+	readAt = current.UpdatedAt
+	if _, err := svc.Delete(ctx, "proj-1", stock.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	current.Content = contentB
+	if err := stockRepo.UpdateIfUnchanged(ctx, current, readAt); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict after delete, got %v", err)
+	}
+	deleted, err := stockRepo.Get(ctx, "proj-1", stock.ID)
+	if err != nil || !deleted.IsDeleted() || !deleted.UpdatedAt.After(readAt) {
+		t.Fatalf("expected delete to bump updated_at, got %+v (%v)", deleted, err)
+	}
+	restored, err := svc.Restore(ctx, "proj-1", stock.ID)
+	if err != nil || !restored.UpdatedAt.After(deleted.UpdatedAt) {
+		t.Fatalf("expected restore to bump updated_at, got %+v (%v)", restored, err)
+	}
+}
+
+func TestStockServiceRecategorizeLeavesAlias(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	vector := &fakeVectorRepo{existing: map[string]bool{}}
+	svc := NewStockService(stockRepo, vector)
+	ctx := context.Background()
+
+	stock, err := svc.Create(ctx, CreateStockInput{
+		ProjectID: "proj-1",
+		Category:  "design",
+		Priority:  "P2",
+		Title:     "デプロイ手順",
+		Content:   "手順を記述する。",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	title := "リリース手順"
+	category := "management"
+	updated, err := svc.Update(ctx, "", stock.ID, UpdateStockInput{
+		Title:      &title,
+		Category:   &category,
+		Tags:       []string{"release"},
+		References: []string{"STA-TASK-001"},
+	})
+	if err != nil {
+		t.Fatalf("recategorize: %v", err)
+	}
+	if updated.ID == stock.ID || !strings.HasPrefix(updated.ID, "STK-MANAGEMENT-") {
+		t.Fatalf("expected new management ID, got %s", updated.ID)
+	}
+	if updated.Title != title || updated.Category != domain.CategoryManagement || !updated.CreatedAt.Equal(stock.CreatedAt) {
+		t.Fatalf("unexpected recategorized stock: %+v", updated)
+	}
+	if updated.Tags[0] != "release" || updated.References[0] != "STA-TASK-001" {
+		t.Fatalf("expected tags/references updated, got %v %v", updated.Tags, updated.References)
+	}
+
+	// 旧管理番号は転送される
+	resolved, err := svc.Get(ctx, "", stock.ID)
+	if err != nil {
+		t.Fatalf("get by old id: %v", err)
+	}
+	if resolved.ID != updated.ID {
+		t.Fatalf("expected redirect to %s, got %s", updated.ID, resolved.ID)
+	}
+	stocks, _ := svc.List(ctx, "proj-1", nil)
+	if len(stocks) != 1 || stocks[0].ID != updated.ID {
+		t.Fatalf("expected alias excluded from list, got %d stocks", len(stocks))
+	}
+	if vector.existing[stockVectorID("proj-1", stock.ID)] || !vector.existing[stockVectorID("proj-1", updated.ID)] {
+		t.Fatalf("expected vector index moved to new ID, got %v", vector.existing)
+	}
+
+	// 旧管理番号での更新も転送先に反映される
+	content := "手順を更新した。"
+	if _, err := svc.Update(ctx, "", stock.ID, UpdateStockInput{Content: &content}); err != nil {
+		t.Fatalf("update via alias: %v", err)
+	}
+	if got, _ := svc.Get(ctx, "", updated.ID); got.Content != content {
+		t.Fatalf("expected content updated via alias, got %q", got.Content)
+	}
+
+	invalid := "unknown"
+	if _, err := svc.Update(ctx, "", updated.ID, UpdateStockInput{Category: &invalid}); err != domain.ErrInvalidCategory {
+		t.Fatalf("expected ErrInvalidCategory, got %v", err)
+	}
+}
+
+func TestStockServiceDeleteRestorePurge(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	vector := &fakeVectorRepo{existing: map[string]bool{}}
+	svc := NewStockService(stockRepo, vector)
+	svc.SetDeletedRetention(24 * time.Hour)
+	ctx := context.Background()
+
+	stock, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "rules", Priority: "P1", Title: "命名規則", Content: "camelCase"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := svc.Delete(ctx, "", stock.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.Get(ctx, "", stock.ID); err != domain.ErrDeleted {
+		t.Fatalf("expected ErrDeleted, got %v", err)
+	}
+	if _, err := svc.Delete(ctx, "", stock.ID); err != domain.ErrDeleted {
+		t.Fatalf("expected ErrDeleted on second delete, got %v", err)
+	}
+	if stocks, _ := svc.List(ctx, "proj-1", nil); len(stocks) != 0 {
+		t.Fatalf("expected deleted stock excluded from list, got %d", len(stocks))
+	}
+	if stocks, _ := svc.List(ctx, "proj-1", &repository.StockListOptions{IncludeDeleted: true}); len(stocks) != 1 {
+		t.Fatalf("expected deleted stock with include_deleted, got %d", len(stocks))
+	}
+	if vector.existing[stockVectorID("proj-1", stock.ID)] {
+		t.Fatalf("expected vector removed on delete")
+	}
+
+	restored, err := svc.Restore(ctx, "", stock.ID)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.IsDeleted() || !vector.existing[stockVectorID("proj-1", stock.ID)] {
+		t.Fatalf("expected restored and reindexed stock, got %+v", restored)
+	}
+	if _, err := svc.Restore(ctx, "", stock.ID); err != domain.ErrNotDeleted {
+		t.Fatalf("expected ErrNotDeleted, got %v", err)
+	}
+
+	if _, err := svc.Delete(ctx, "", stock.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	purged, err := svc.PurgeDeleted(ctx, time.Now())
+	if err != nil || len(purged) != 0 {
+		t.Fatalf("expected nothing purged within retention, got %v %v", purged, err)
+	}
+	purged, err = svc.PurgeDeleted(ctx, time.Now().Add(25*time.Hour))
+	if err != nil || len(purged) != 1 || purged[0] != stock.ID {
+		t.Fatalf("expected %s purged, got %v %v", stock.ID, purged, err)
+	}
+	if _, err := stockRepo.Get(ctx, "", stock.ID); err != domain.ErrNotFound {
+		t.Fatalf("expected purged stock removed, got %v", err)
+	}
+}
+
 func TestContextServiceSearch(t *testing.T) {
 	tmpDir := t.TempDir()
 	stockRepo := repository.NewFileStockRepository(tmpDir + "/stocks")
@@ -150,6 +386,10 @@ func TestContextServiceSearch(t *testing.T) {
 	if result.Stocks[0].Priority != "P0" {
 		t.Errorf("expected priority P0, got %s", result.Stocks[0].Priority)
 	}
+
+	if result.Stocks[0].Summary != stock.Summary || len(result.Stocks[0].Tags) != 2 {
+		t.Errorf("expected summary and tags in result, got %+v", result.Stocks[0])
+	}
 }
 
 func TestStockServiceListSummary(t *testing.T) {
@@ -280,8 +520,8 @@ func TestContextServiceSearchWeightedRanking(t *testing.T) {
 	if err != nil {
 		t.Fatalf("search error: %v", err)
 	}
-	if result.Total != 1 {
-		t.Fatalf("expected total 1, got %d", result.Total)
+	if result.Total != 2 || result.NextCursor == "" {
+		t.Fatalf("expected total 2 with next cursor, got %+v", result)
 	}
 	if len(result.Stocks) != 1 {
 		t.Fatalf("expected weighted top result to be stock, got %+v", result)
@@ -347,24 +587,25 @@ func TestServicesBootstrapVectorIndex(t *testing.T) {
 
 	vector := &fakeVectorRepo{
 		existing: map[string]bool{
-			"STK-DESIGN-001": true,
-			"STA-TASK-002":   true,
+			"proj-1/STK-DESIGN-001": true,
+			"STK-DESIGN-002":        true, // 管理番号のみのドキュメントIDで登録された旧形式
+			"STA-TASK-002":          true,
 		},
 	}
 	repos := &repository.Repositories{Stock: stockRepo, State: stateRepo, Vector: vector}
-	services := NewServices(repos)
+	services := NewServices(repos, nil)
 
 	if err := services.BootstrapVectorIndex(context.Background()); err != nil {
 		t.Fatalf("bootstrap error: %v", err)
 	}
 
-	if !containsID(vector.upserts, "STK-DESIGN-002") {
-		t.Fatalf("expected missing stock to be upserted, got %v", vector.upserts)
+	if !containsID(vector.upserts, "proj-1/STK-DESIGN-002") || !containsID(vector.deletes, "STK-DESIGN-002") {
+		t.Fatalf("expected legacy stock vector to be replaced, got upserts %v deletes %v", vector.upserts, vector.deletes)
 	}
 	if !containsID(vector.upserts, "STA-TASK-001") {
 		t.Fatalf("expected active state to be upserted, got %v", vector.upserts)
 	}
-	if containsID(vector.upserts, "STK-DESIGN-001") {
+	if containsID(vector.upserts, "proj-1/STK-DESIGN-001") {
 		t.Fatalf("did not expect existing stock to be upserted")
 	}
 	if !containsID(vector.deletes, "STA-TASK-002") {
@@ -403,3 +644,794 @@ func TestStockSummary(t *testing.T) {
 	// Summary にはContentが含まれないことを型レベルで保証
 	// (StockSummary構造体にContentフィールドがない)
 }
+
+func TestStockServiceGeneratesSummaryAndSuggestedTags(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+	ctx := context.Background()
+
+	stock, err := svc.Create(ctx, CreateStockInput{
+		ProjectID: "test-project",
+		Category:  "design",
+		Priority:  "P1",
+		Title:     "Session cache design",
+		Content: "# Redis session cache\n\nSessions are stored in Redis with a 30 minute TTL. " +
+			"Cache misses fall back to PostgreSQL.\n\n```go\nclient := redis.NewClient()\n```\n\n" +
+			"Redis cluster failover keeps sessions alive.",
+		Tags: []string{"design"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	expected := "Redis session cache: Sessions are stored in Redis with a 30 minute TTL. Cache misses fall back to PostgreSQL."
+	if stock.Summary != expected {
+		t.Fatalf("unexpected summary: %q", stock.Summary)
+	}
+	if len(stock.SuggestedTags) == 0 || stock.SuggestedTags[0] != "cache" || !containsString(stock.SuggestedTags, "redis") {
+		t.Fatalf("unexpected suggested tags: %v", stock.SuggestedTags)
+	}
+
+	stored, err := svc.Get(ctx, "", stock.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Summary != stock.Summary || stored.ToSummary().Summary != stock.Summary {
+		t.Fatalf("expected summary to be persisted, got %q", stored.Summary)
+	}
+
+	// 候補を採用したタグは候補から外れる
+	updated, err := svc.Update(ctx, "", stock.ID, UpdateStockInput{Tags: []string{"design", "redis"}})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if containsString(updated.SuggestedTags, "redis") || updated.Summary != expected {
+		t.Fatalf("expected adopted tag to be dropped from suggestions, got %v (%q)", updated.SuggestedTags, updated.Summary)
+	}
+
+	content := "認証基盤はOAuth2で実装する。トークンは30日で失効する。"
+	updated, err = svc.Update(ctx, "", stock.ID, UpdateStockInput{Content: &content})
+	if err != nil {
+		t.Fatalf("update content: %v", err)
+	}
+	if updated.Summary != content {
+		t.Fatalf("expected summary to be regenerated, got %q", updated.Summary)
+	}
+}
+
+func TestStockServiceGeneratesSummaryWithLLM(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+	client := llm.NewClient(llm.NewStubProvider())
+	svc.SetTextGenerator(client)
+
+	stock, err := svc.Create(context.Background(), CreateStockInput{
+		ProjectID: "test-project",
+		Category:  "architecture",
+		Priority:  "P2",
+		Title:     "Event sourcing",
+		Content:   "Orders are stored as events. Projections rebuild order views from events.",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if stock.Summary != "Event sourcing" {
+		t.Fatalf("expected stub summary, got %q", stock.Summary)
+	}
+	if len(stock.SuggestedTags) == 0 || stock.SuggestedTags[0] != "events" {
+		t.Fatalf("expected stub keywords, got %v", stock.SuggestedTags)
+	}
+	calls := client.Calls()
+	if len(calls) != 2 || calls[0].Purpose != "summarize" || calls[1].Purpose != "keywords" {
+		t.Fatalf("unexpected llm calls: %+v", calls)
+	}
+}
+
+func TestExtractiveSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		content  string
+		maxChars int
+		want     string
+	}{
+		{name: "heading same as title", title: "API設計", content: "# API設計\n\nREST APIの設計方針を記述する。詳細は別紙。", maxChars: 120, want: "REST APIの設計方針を記述する。詳細は別紙。"},
+		{name: "list and table", title: "Rules", content: "| a | b |\n|---|---|\n- Use gofmt.\n- Keep v1.2 compatible.", maxChars: 120, want: "Use gofmt. Keep v1.2 compatible."},
+		{name: "empty content", title: "Only title", content: "", maxChars: 120, want: "Only title"},
+		{name: "long", title: "Long", content: strings.Repeat("あ", 200), maxChars: 20, want: strings.Repeat("あ", 19) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractiveSummary(tt.title, tt.content, tt.maxChars); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractKeywords(t *testing.T) {
+	keywords := extractKeywords("決済基盤の冗長化", "決済基盤はマルチリージョンで冗長化する。マルチリージョン構成のフェイルオーバーはDNSで行う。DNS切替は手動。", 5)
+	for _, want := range []string{"決済基盤", "マルチリージョン", "dns"} {
+		if !containsString(keywords, want) {
+			t.Fatalf("expected %q in %v", want, keywords)
+		}
+	}
+	if keywords[0] != "決済基盤" {
+		t.Fatalf("expected title term first, got %v", keywords)
+	}
+}
+
+func TestStockServiceCreateDuplicatePolicies(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+	ctx := context.Background()
+
+	original, err := svc.Create(ctx, CreateStockInput{
+		ProjectID:  "proj-1",
+		Category:   "design",
+		Priority:   "P2",
+		Title:      "認証API設計",
+		Content:    "JWTで認証する。",
+		Tags:       []string{"auth"},
+		References: []string{"STK-REQ-001"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	input := CreateStockInput{
+		ProjectID:  "proj-1",
+		Category:   "design",
+		Priority:   "P1",
+		Title:      "認証API設計方針",
+		Content:    "リフレッシュトークンは7日で失効する。",
+		Tags:       []string{"auth", "token"},
+		References: []string{"STK-REQ-002"},
+	}
+
+	// allow: 作成し、重複候補を返す
+	result, err := svc.CreateWithDuplicateCheck(ctx, input)
+	if err != nil {
+		t.Fatalf("create allow: %v", err)
+	}
+	if result.Merged || result.Stock.ID == original.ID {
+		t.Fatalf("expected new stock, got %+v", result)
+	}
+	if len(result.Duplicates) != 1 || result.Duplicates[0].ID != original.ID || result.Duplicates[0].Method != "title" {
+		t.Fatalf("expected title duplicate of %s, got %+v", original.ID, result.Duplicates)
+	}
+	if err := stockRepo.Delete(ctx, "", result.Stock.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// reject: 作成しない
+	input.OnDuplicate = "reject"
+	if _, err := svc.CreateWithDuplicateCheck(ctx, input); !errors.Is(err, domain.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+
+	// merge: 既存Stockに統合する
+	input.OnDuplicate = "merge"
+	result, err = svc.CreateWithDuplicateCheck(ctx, input)
+	if err != nil {
+		t.Fatalf("create merge: %v", err)
+	}
+	merged := result.Stock
+	if !result.Merged || merged.ID != original.ID {
+		t.Fatalf("expected merge into %s, got %+v", original.ID, result)
+	}
+	if merged.Priority != domain.PriorityP1 {
+		t.Fatalf("expected priority P1, got %s", merged.Priority.String())
+	}
+	if strings.Join(merged.Tags, ",") != "auth,token" || strings.Join(merged.References, ",") != "STK-REQ-001,STK-REQ-002" {
+		t.Fatalf("unexpected merged tags/refs: %v %v", merged.Tags, merged.References)
+	}
+	if !strings.Contains(merged.Content, "JWTで認証する。") || !strings.Contains(merged.Content, "リフレッシュトークン") {
+		t.Fatalf("expected merged content, got %q", merged.Content)
+	}
+	stocks, _ := stockRepo.List(ctx, "proj-1", nil)
+	if len(stocks) != 1 {
+		t.Fatalf("expected 1 stock after merge, got %d", len(stocks))
+	}
+
+	input.OnDuplicate = "skip"
+	if _, err := svc.CreateWithDuplicateCheck(ctx, input); err != domain.ErrInvalidDuplicatePolicy {
+		t.Fatalf("expected ErrInvalidDuplicatePolicy, got %v", err)
+	}
+}
+
+func TestStockServiceListSummaryPage(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+	ctx := context.Background()
+
+	for _, title := range []string{"設計A", "設計B", "設計C", "設計D", "設計E"} {
+		if _, err := svc.Create(ctx, CreateStockInput{
+			ProjectID: "proj-1", Category: "design", Priority: "P2", Title: title, Content: "本文",
+		}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	var ids []string
+	page := PageRequest{Limit: 2}
+	for i := 0; ; i++ {
+		result, err := svc.ListSummaryPage(ctx, "proj-1", nil, page)
+		if err != nil {
+			t.Fatalf("list page %d: %v", i, err)
+		}
+		if result.Total != 5 {
+			t.Fatalf("expected total 5, got %d", result.Total)
+		}
+		for _, s := range result.Stocks {
+			ids = append(ids, s.ID)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	all, err := svc.ListSummary(ctx, "proj-1", nil)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ids) != 5 || len(all) != 5 {
+		t.Fatalf("expected 5 stocks across pages, got %v", ids)
+	}
+	for i := range all {
+		if ids[i] != all[i].ID {
+			t.Fatalf("unstable order at %d: %s != %s", i, ids[i], all[i].ID)
+		}
+	}
+
+	// 条件の異なるカーソルは拒否する
+	first, err := svc.ListSummaryPage(ctx, "proj-1", nil, PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("list first page: %v", err)
+	}
+	if _, err := svc.ListSummaryPage(ctx, "proj-2", nil, PageRequest{Cursor: first.NextCursor, Limit: 2}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for other project, got %v", err)
+	}
+	if _, err := svc.SearchSummaryPage(ctx, "設計", "proj-1", TagFilter{}, PageRequest{Cursor: first.NextCursor}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for search, got %v", err)
+	}
+	if _, err := svc.ListSummaryPage(ctx, "proj-1", nil, PageRequest{Cursor: "!!"}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for malformed cursor, got %v", err)
+	}
+
+	search, err := svc.SearchSummaryPage(ctx, "設計", "proj-1", TagFilter{}, PageRequest{Limit: 3})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if search.Total != 5 || len(search.Stocks) != 3 || search.NextCursor == "" {
+		t.Fatalf("unexpected search page: total=%d len=%d next=%q", search.Total, len(search.Stocks), search.NextCursor)
+	}
+	search, err = svc.SearchSummaryPage(ctx, "設計", "proj-1", TagFilter{}, PageRequest{Cursor: search.NextCursor, Limit: 3})
+	if err != nil || len(search.Stocks) != 2 || search.NextCursor != "" {
+		t.Fatalf("unexpected last search page: %+v (%v)", search, err)
+	}
+}
+
+func TestStockServiceTagFilterAndRename(t *testing.T) {
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	svc := NewStockService(stockRepo, nil)
+	ctx := context.Background()
+
+	for _, in := range []struct {
+		title string
+		tags  []string
+	}{
+		{"API設計", []string{" api ", "backend", "api"}},
+		{"DB設計", []string{"db", "be"}},
+		{"画面設計", []string{"frontend"}},
+	} {
+		if _, err := svc.Create(ctx, CreateStockInput{
+			ProjectID: "proj-1", Category: "design", Priority: "P2", Title: in.title, Content: "設計メモ", Tags: in.tags,
+		}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	all, err := svc.ListSummary(ctx, "proj-1", &repository.StockListOptions{Tags: []string{"api", "backend"}, TagMatch: domain.TagMatchAll})
+	if err != nil || len(all) != 1 || all[0].Title != "API設計" || strings.Join(all[0].Tags, ",") != "api,backend" {
+		t.Fatalf("unexpected all-tag list: %+v (%v)", all, err)
+	}
+
+	page, err := svc.SearchSummaryPage(ctx, "設計", "proj-1", TagFilter{Tags: []string{"db", "frontend"}}, PageRequest{})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("expected 2 tagged search hits, got %+v (%v)", page, err)
+	}
+
+	result, err := svc.RenameTags(ctx, "proj-1", []string{"backend", "be"}, "server")
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if len(result.Updated) != 2 {
+		t.Fatalf("expected 2 updated stocks, got %v", result.Updated)
+	}
+	counts, err := svc.TagCounts(ctx, "proj-1", nil)
+	if err != nil {
+		t.Fatalf("tag counts: %v", err)
+	}
+	if counts[0] != (domain.TagCount{Tag: "server", Count: 2}) {
+		t.Fatalf("unexpected tag counts after merge: %+v", counts)
+	}
+	for _, c := range counts {
+		if c.Tag == "backend" || c.Tag == "be" {
+			t.Fatalf("expected merged tag %s to be gone, got %+v", c.Tag, counts)
+		}
+	}
+
+	if _, err := svc.RenameTags(ctx, "proj-1", nil, "server"); err == nil {
+		t.Fatal("expected error without source tags")
+	}
+}
+
+// listHookStockRepo は一覧の取得直後に afterList を呼び出すStockリポジトリ。一覧と書き込みの間の更新を再現する。
+type listHookStockRepo struct {
+	*repository.FileStockRepository
+	afterList func()
+}
+
+func (r *listHookStockRepo) List(ctx context.Context, projectID string, opts *repository.StockListOptions) ([]*domain.Stock, error) {
+	stocks, err := r.FileStockRepository.List(ctx, projectID, opts)
+	if err == nil && r.afterList != nil {
+		r.afterList()
+		r.afterList = nil
+	}
+	return stocks, err
+}
+
+func TestStockServiceRenameTagsKeepsConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	stockRepo := &listHookStockRepo{FileStockRepository: repository.NewFileStockRepository(t.TempDir())}
+	svc := NewStockService(stockRepo, nil)
+	stock, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "API設計", Content: "初期内容", Tags: []string{"be"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	content := "一覧の取得後の変更"
+	stockRepo.afterList = func() {
+		if _, err := svc.Update(ctx, "proj-1", stock.ID, UpdateStockInput{Content: &content}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	result, err := svc.RenameTags(ctx, "proj-1", []string{"be"}, "server")
+	if err != nil || len(result.Updated) != 1 {
+		t.Fatalf("unexpected rename result: %+v (%v)", result, err)
+	}
+	got, err := svc.Get(ctx, "proj-1", stock.ID)
+	if err != nil || got.Content != content || strings.Join(got.Tags, ",") != "server" {
+		t.Fatalf("expected both the update and the rename to be kept, got %+v (%v)", got, err)
+	}
+}
+
+func TestStockWatcherPicksUpExternalEdits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	files := repository.NewFileStockRepository(dir)
+	vector := &fakeVectorRepo{existing: map[string]bool{}}
+	svc := NewStockService(files, vector)
+
+	stock, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "認証方式", Content: "JWTを使う"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	watcher := NewStockWatcher(files, svc, StockWatchNotify, time.Second)
+	changes := make(chan []repository.StockChange, 10)
+	watcher.OnChange(func(c []repository.StockChange) { changes <- c })
+	go watcher.Start(ctx)
+
+	waitChange := func(want repository.StockChangeOp) repository.StockChange {
+		t.Helper()
+		select {
+		case c := <-changes:
+			if len(c) != 1 || c[0].Op != want {
+				t.Fatalf("expected one %s change, got %+v", want, c)
+			}
+			return c[0]
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s change", want)
+		}
+		return repository.StockChange{}
+	}
+
+	// 監視の開始を待つため、開始直後の取り込みが終わるまで少し待ってから編集する
+	time.Sleep(200 * time.Millisecond)
+
+	path := filepath.Join(dir, "proj-1", "design", stock.ID+".json")
+	edited := *stock
+	edited.Content = "OAuth 2.0 に変更"
+	data, _ := json.MarshalIndent(&edited, "", "  ")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("edit file: %v", err)
+	}
+	change := waitChange(repository.StockChanged)
+	if change.Stock == nil || change.Stock.Content != edited.Content {
+		t.Fatalf("expected edited stock, got %+v", change.Stock)
+	}
+	if got, err := svc.Get(ctx, "proj-1", stock.ID); err != nil || got.Content != edited.Content {
+		t.Fatalf("expected edit visible through service, got %+v (%v)", got, err)
+	}
+
+	// 壊れたファイルは診断情報として報告し、一覧から黙って消さない
+	if err := os.WriteFile(filepath.Join(dir, "proj-1", "design", "STK-DESIGN-002.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken file: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(watcher.Diagnostics("proj-1")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for diagnostic")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	waitChange(repository.StockDeleted)
+	if vector.existing[stockVectorID("proj-1", stock.ID)] {
+		t.Fatal("expected deleted stock removed from vector index")
+	}
+}
+
+func TestStockServiceGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := WithActor(context.Background(), "claude-code")
+	dir := t.TempDir()
+	files := repository.NewFileStockRepository(dir)
+	history, err := repository.NewGitStockHistory(ctx, dir)
+	if err != nil {
+		t.Fatalf("init history: %v", err)
+	}
+	services := NewServices(&repository.Repositories{Stock: files, StockFiles: files, StockHistory: history}, nil)
+	svc := services.Stock
+
+	if _, err := NewStockService(files, nil).History(ctx, "proj-1", "STK-DESIGN-001", 0); !errors.Is(err, domain.ErrHistoryDisabled) {
+		t.Fatalf("expected ErrHistoryDisabled without history, got %v", err)
+	}
+
+	stock, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "認証方式", Content: "JWTを使う"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	content := "OAuth 2.0 を使う"
+	if _, err := svc.Update(ctx, "proj-1", stock.ID, UpdateStockInput{Content: &content}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := svc.Delete(ctx, "proj-1", stock.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	revisions, err := svc.History(ctx, "", stock.ID, 0)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var actions []string
+	for _, r := range revisions {
+		actions = append(actions, r.Action)
+		if r.Author != "claude-code" {
+			t.Errorf("expected actor claude-code, got %q", r.Author)
+		}
+	}
+	if strings.Join(actions, ",") != "delete,update,create" {
+		t.Fatalf("expected delete,update,create, got %v", actions)
+	}
+
+	// 再分類は旧管理番号（エイリアス）と新管理番号のファイルを1つのコミットに記録する
+	moved, err := svc.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "命名規約", Content: "snake_case"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	rules := "rules"
+	recategorized, err := svc.Update(ctx, "proj-1", moved.ID, UpdateStockInput{Category: &rules})
+	if err != nil {
+		t.Fatalf("recategorize: %v", err)
+	}
+	oldRevisions, err := svc.History(ctx, "proj-1", moved.ID, 1)
+	if err != nil {
+		t.Fatalf("history of old id: %v", err)
+	}
+	newRevisions, err := svc.History(ctx, "proj-1", recategorized.ID, 1)
+	if err != nil {
+		t.Fatalf("history of new id: %v", err)
+	}
+	if len(oldRevisions) != 1 || len(newRevisions) != 1 || oldRevisions[0].Commit != newRevisions[0].Commit || newRevisions[0].Action != "recategorize" {
+		t.Fatalf("expected one recategorize commit for both files, got %+v / %+v", oldRevisions, newRevisions)
+	}
+
+	// 手作業の編集は sync でコミットし、索引へ取り込む（上流がなければ pull しない）
+	path := filepath.Join(dir, "proj-1", "design", stock.ID+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), "OAuth 2.0", "SAML", 1)), 0o644); err != nil {
+		t.Fatalf("edit file: %v", err)
+	}
+	report, err := services.SyncStocks(ctx)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if !report.Committed || report.Upstream != "" || len(report.Reconciled.Changes) != 1 || len(report.Conflicts) != 0 {
+		t.Fatalf("expected hand edit committed and reconciled, got %+v", report)
+	}
+	if revisions, err := svc.History(ctx, "proj-1", stock.ID, 1); err != nil || len(revisions) != 1 || revisions[0].Action != "sync" {
+		t.Fatalf("expected sync commit at the top of history, got %+v (%v)", revisions, err)
+	}
+}
+
+func TestProjectExportImport(t *testing.T) {
+	ctx := context.Background()
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := newFakeStateRepo()
+	releaseRepo := &fakeReleaseRepo{}
+	vector := &fakeVectorRepo{existing: map[string]bool{}}
+	services := NewServices(&repository.Repositories{Stock: stockRepo, State: stateRepo, Release: releaseRepo, Vector: vector}, nil)
+
+	design, err := services.Stock.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P1", Title: "認証方式", Content: "JWT", References: []string{"STA-incident-001"}})
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	moved, err := services.Stock.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "命名規約", Content: "snake_case"})
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	rules := "rules"
+	if moved, err = services.Stock.Update(ctx, "proj-1", moved.ID, UpdateStockInput{Category: &rules}); err != nil {
+		t.Fatalf("recategorize: %v", err)
+	}
+	now := time.Now()
+	for _, state := range []*domain.State{
+		{ID: "STA-incident-001", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusResolved, Title: "障害", References: []string{design.ID},
+			Incident: &domain.IncidentDetails{Timeline: []domain.TimelineEntry{{At: now, Note: "検知"}}}, CreatedAt: now, UpdatedAt: now},
+		{ID: "STA-problem-002", ProjectID: "proj-1", Type: domain.StateTypeProblem, Status: domain.StatusOpen, Title: "根本原因",
+			Problem: &domain.ProblemDetails{IncidentIDs: []string{"STA-incident-001"}}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := stateRepo.Create(ctx, state); err != nil {
+			t.Fatalf("create state: %v", err)
+		}
+	}
+	if err := releaseRepo.Create(ctx, &domain.Release{ProjectID: "proj-1", Version: "v1.0.0", ItemIDs: []string{"STA-incident-001"}, CreatedAt: now}); err != nil {
+		t.Fatalf("create release: %v", err)
+	}
+
+	var bundle bytes.Buffer
+	manifest, err := services.ExportProject(ctx, "proj-1", &bundle)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	// 再分類のエイリアスも含める
+	if manifest.Counts != (BundleCounts{Stocks: 3, States: 2, Releases: 1, Links: 5}) {
+		t.Fatalf("unexpected counts: %+v", manifest.Counts)
+	}
+	if manifest.Format != bundleFormat || manifest.Version != bundleVersion || manifest.Project.ID != "proj-1" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	importBundle := func(opts ImportOptions) (*ImportReport, error) {
+		return services.ImportProject(ctx, bytes.NewReader(bundle.Bytes()), opts)
+	}
+
+	// Stateの管理番号は全プロジェクトで一意のため、別プロジェクトへの取り込みでも競合する
+	if _, err := importBundle(ImportOptions{ProjectID: "proj-2"}); !errors.Is(err, ErrImportConflict) {
+		t.Fatalf("expected ErrImportConflict, got %v", err)
+	}
+	report, err := importBundle(ImportOptions{ProjectID: "proj-2", OnConflict: ImportConflictRenumber, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Count("create") != 4 || report.Count("renumber") != 2 {
+		t.Fatalf("unexpected dry run plan: %+v", report.Items)
+	}
+	if stocks, _ := stockRepo.List(ctx, "proj-2", &repository.StockListOptions{IncludeDeleted: true}); len(stocks) != 0 {
+		t.Fatalf("expected dry run to write nothing, got %d stocks", len(stocks))
+	}
+
+	upserts := len(vector.upserts)
+	if _, err := importBundle(ImportOptions{ProjectID: "proj-2", OnConflict: ImportConflictRenumber}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	incident, err := stateRepo.Get(ctx, "STA-incident-002")
+	if err != nil || incident.ProjectID != "proj-2" || len(incident.Incident.Timeline) != 1 || incident.References[0] != design.ID {
+		t.Fatalf("expected renumbered incident with history, got %+v (%v)", incident, err)
+	}
+	problem, err := stateRepo.Get(ctx, "STA-problem-003")
+	if err != nil || problem.Problem.IncidentIDs[0] != "STA-incident-002" {
+		t.Fatalf("expected problem link remapped, got %+v (%v)", problem, err)
+	}
+	imported, err := stockRepo.Get(ctx, "proj-2", design.ID)
+	if err != nil || imported.References[0] != "STA-incident-002" {
+		t.Fatalf("expected stock reference remapped, got %+v (%v)", imported, err)
+	}
+	if alias, err := services.Stock.Get(ctx, "proj-2", "STK-DESIGN-002"); err != nil || alias.ID != moved.ID {
+		t.Fatalf("expected alias to resolve in new project, got %+v (%v)", alias, err)
+	}
+	release, err := releaseRepo.Get(ctx, "proj-2", "v1.0.0")
+	if err != nil || release.ItemIDs[0] != "STA-incident-002" {
+		t.Fatalf("expected release items remapped, got %+v (%v)", release, err)
+	}
+	// ベクトルは取り込み時に作り直す（エイリアス・アーカイブ済みは除く）
+	if got := len(vector.upserts) - upserts; got != 4 {
+		t.Fatalf("expected 4 vector upserts, got %d", got)
+	}
+
+	// 同じプロジェクトへの skip は既存のものを残す
+	report, err = importBundle(ImportOptions{OnConflict: ImportConflictSkip})
+	if err != nil {
+		t.Fatalf("import skip: %v", err)
+	}
+	if report.Count("skip") != 6 || report.Count("create") != 0 {
+		t.Fatalf("expected everything skipped, got %+v", report.Items)
+	}
+
+	// skip したStateへの参照は取り込み先の既存のStateを指すため、参照ごとに警告する
+	report, err = importBundle(ImportOptions{ProjectID: "proj-3", OnConflict: ImportConflictSkip, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run skip: %v", err)
+	}
+	for _, want := range []string{"reference " + design.ID + " -> STA-incident-001: target was skipped", "release_item v1.0.0 -> STA-incident-001: target was skipped"} {
+		if !slices.ContainsFunc(report.Warnings, func(w string) bool { return strings.HasPrefix(w, want) }) {
+			t.Fatalf("expected warning %q, got %v", want, report.Warnings)
+		}
+	}
+
+	// StateとReleaseの書き込みに失敗した場合は、作成済みのStockも削除して何も取り込まない
+	failingStocks := repository.NewFileStockRepository(t.TempDir())
+	failing := NewServices(&repository.Repositories{Stock: failingStocks, State: &failingBatchStateRepo{newFakeStateRepo()}, Release: &fakeReleaseRepo{}}, nil)
+	report, err = failing.ImportProject(ctx, bytes.NewReader(bundle.Bytes()), ImportOptions{})
+	if err == nil {
+		t.Fatal("expected import to fail")
+	}
+	if stocks, _ := failingStocks.List(ctx, "proj-1", &repository.StockListOptions{IncludeDeleted: true, IncludeAliases: true}); len(stocks) != 0 {
+		t.Fatalf("expected imported stocks to be rolled back, got %d", len(stocks))
+	}
+	if report.Written != (BundleCounts{}) {
+		t.Fatalf("expected nothing written, got %+v", report.Written)
+	}
+
+	// 改ざんされたバンドルは取り込まない
+	tampered := bytes.Clone(bundle.Bytes())
+	tampered[len(tampered)/2] ^= 0xff
+	if _, err := services.ImportProject(ctx, bytes.NewReader(tampered), ImportOptions{ProjectID: "proj-3"}); err == nil {
+		t.Fatal("expected error for corrupted bundle")
+	}
+}
+
+// failingBatchStateRepo はStateとReleaseの一括作成に失敗するStateリポジトリ。
+type failingBatchStateRepo struct {
+	*fakeStateRepo
+}
+
+func (f *failingBatchStateRepo) CreateBatch(ctx context.Context, states []*domain.State, releases []*domain.Release) error {
+	return errors.New("disk I/O error")
+}
+
+func TestSiteBuild(t *testing.T) {
+	ctx := context.Background()
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := newFakeStateRepo()
+	services := NewServices(&repository.Repositories{Stock: stockRepo, State: stateRepo, Release: &fakeReleaseRepo{}}, nil)
+
+	requirement, err := services.Stock.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "requirement", Priority: "P0", Title: "ログイン", Content: "利用者はログインできる"})
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	overview, err := services.Stock.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P1", Title: "認証方式", Content: "## 方針\n\n| 項目 | 内容 |\n|---|---|\n| 方式 | JWT |\n", References: []string{requirement.ID}})
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	detail, err := services.Stock.Create(ctx, CreateStockInput{ProjectID: "proj-1", Category: "design", Priority: "P2", Title: "JWT署名",
+		Content:    "- 鍵は `KMS` で管理\n  - ローテーションは90日\n\n<script>alert(1)</script> [危険](javascript:alert(1)) 障害 STA-incident-001 を参照",
+		References: []string{overview.ID, "STK-DESIGN-999"}})
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	due := now.Add(-24 * time.Hour)
+	for _, state := range []*domain.State{
+		{ID: "STA-incident-001", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusOpen, Priority: domain.PriorityP0,
+			Title: "署名検証の失敗", References: []string{detail.ID}, DueAt: &due, CreatedAt: now.Add(-48 * time.Hour), UpdatedAt: now.Add(-48 * time.Hour)},
+		{ID: "STA-task-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusResolved, Priority: domain.PriorityP2,
+			Title: "鍵の登録", CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := stateRepo.Create(ctx, state); err != nil {
+			t.Fatalf("create state: %v", err)
+		}
+	}
+
+	files, err := services.Site.Build(ctx, SiteBuildInput{ProjectID: "proj-1", Now: now})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	pages := map[string]string{}
+	for _, file := range files {
+		pages[file.Path] = file.Content
+	}
+	for _, path := range []string{"index.html", "search.html", "search-index.js", "assets/style.css", "assets/search.js", ".pim-site",
+		"proj-1/index.html", "proj-1/dashboard.html", "proj-1/stocks/" + detail.ID + ".html", "proj-1/states/STA-incident-001.html"} {
+		if _, ok := pages[path]; !ok {
+			t.Fatalf("expected %s in site, got %d files", path, len(files))
+		}
+	}
+
+	page := pages["proj-1/stocks/"+detail.ID+".html"]
+	for _, want := range []string{
+		`<base href="../../">`,
+		`<a href="proj-1/stocks/` + overview.ID + `.html">認証方式</a>`,  // パンくずの親
+		`<a class="ref" href="proj-1/states/STA-incident-001.html">`, // 本文中の管理番号
+		`<span class="id unresolved"`,                                // サイトにない参照
+		"<li>鍵は <code>KMS</code> で管理",
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected %q in stock page:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script>alert") || strings.Contains(page, `href="javascript:`) {
+		t.Fatalf("expected unsafe markdown to be neutralized:\n%s", page)
+	}
+	// 同じカテゴリのStockを参照しているStockは、その下の階層になる
+	if page := pages["proj-1/stocks/"+overview.ID+".html"]; !strings.Contains(page, "<h2>詳細化</h2>") || !strings.Contains(page, "<table>") {
+		t.Fatalf("expected children and rendered table on overview page:\n%s", page)
+	}
+	// 被参照は Stock の References・Stateの References の両方から集める
+	if page := pages["proj-1/stocks/"+requirement.ID+".html"]; !strings.Contains(page, "<h2>被参照</h2>") || !strings.Contains(page, overview.ID) {
+		t.Fatalf("expected backlink from design on requirement page:\n%s", page)
+	}
+	if page := pages["proj-1/stocks/"+detail.ID+".html"]; !strings.Contains(page, `href="proj-1/states/STA-incident-001.html"><span class="id">STA-incident-001</span>`) {
+		t.Fatalf("expected backlink from state on stock page:\n%s", page)
+	}
+	dashboard := pages["proj-1/dashboard.html"]
+	if !strings.Contains(dashboard, "期限超過") || !strings.Contains(dashboard, `<tr class="overdue">`) || !strings.Contains(dashboard, "STA-task-002") {
+		t.Fatalf("unexpected dashboard:\n%s", dashboard)
+	}
+	if !strings.Contains(pages["search-index.js"], `"t":"JWT署名"`) {
+		t.Fatalf("expected stock in search index: %s", pages["search-index.js"])
+	}
+
+	// 出力先の置き換え。pim が生成していない空でないディレクトリは上書きしない
+	out := filepath.Join(t.TempDir(), "site")
+	if err := WriteSite(out, files); err != nil {
+		t.Fatalf("write site: %v", err)
+	}
+	if err := WriteSite(out, files[:len(files)-1]); err != nil {
+		t.Fatalf("rewrite site: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(files[len(files)-1].Path))); !os.IsNotExist(err) {
+		t.Fatalf("expected stale page to be removed, got %v", err)
+	}
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSite(other, files); err == nil {
+		t.Fatal("expected error for a directory not generated by pim")
+	}
+
+	// パスとして安全でないプロジェクトIDのStateはページにしない。WriteSite も --out の外には書かない
+	if err := stateRepo.Create(ctx, &domain.State{ID: "STA-task-003", ProjectID: "../../x", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "外", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("create state: %v", err)
+	}
+	all, err := services.Site.Build(ctx, SiteBuildInput{Now: now})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	for _, file := range all {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			t.Fatalf("expected no page outside the site, got %s", file.Path)
+		}
+	}
+	escaped := append(slices.Clone(files), GeneratedFile{Path: "../escape.html", Content: "x"})
+	if err := WriteSite(out, escaped); err == nil {
+		t.Fatal("expected error for a path outside --out")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(out), "escape.html")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written outside --out, got %v", err)
+	}
+}
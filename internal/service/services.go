@@ -3,32 +3,208 @@ package service
 import (
 	"context"
 	"log/slog"
+	"time"
 
+	"github.com/haconeco/project-information-manager/internal/config"
 	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/llm"
 	"github.com/haconeco/project-information-manager/internal/repository"
 )
 
 // Services は全サービスを束ねる構造体。
 type Services struct {
-	Stock   *StockService
-	State   *StateService
-	Context *ContextService
+	Stock       *StockService
+	State       *StateService
+	Incident    *IncidentService
+	Problem     *ProblemService
+	Change      *ChangeService
+	Release     *ReleaseService
+	Hygiene     *HygieneService
+	Context     *ContextService
+	Direction   *DirectionService
+	AgentConfig *AgentConfigService
+	Site        *SiteService
+
+	// StockWatcher はStockファイルの外部編集を取り込む。stock.store が sqlite の場合は nil。
+	StockWatcher *StockWatcher
+
+	// LLM は要約・分類に用いるLLMクライアント。未設定の場合は nil。
+	LLM *llm.Client
 
 	vectorRepo repository.VectorRepository
+	backups    *repository.BackupManager
 }
 
-// NewServices は設定に基づいて全サービスを初期化する。
-func NewServices(repos *repository.Repositories) *Services {
+// NewServices は設定に基づいて全サービスを初期化する。cfg が nil の場合は既定値で動作する。
+func NewServices(repos *repository.Repositories, cfg *config.Config) *Services {
+	var llmClient *llm.Client
+	if cfg != nil {
+		llmClient = llmClientFromConfig(cfg.LLM)
+	}
+
 	stockService := NewStockService(repos.Stock, repos.Vector)
+	if cfg != nil {
+		stockService.SetDeletedRetention(deletedRetentionFromConfig(cfg.Stock))
+	}
+	if repos.StockHistory != nil {
+		stockService.SetHistory(repos.StockHistory)
+	}
 	stateService := NewStateService(repos.State, repos.Vector)
+	stateService.SetStockService(stockService)
+	if cfg != nil {
+		stateService.SetSLAPolicies(slaPoliciesFromConfig(cfg.SLA))
+	}
+	incidentService := NewIncidentService(repos.State, stockService)
+	problemService := NewProblemService(repos.State, repos.Vector)
+	changeService := NewChangeService(repos.State)
+	releaseService := NewReleaseService(repos.Release, repos.State)
+	hygieneService := NewHygieneService(stateService, nil, HygieneOptions{})
+	if cfg != nil {
+		hygieneService = NewHygieneService(stateService, stalePoliciesFromConfig(cfg.Hygiene), hygieneOptionsFromConfig(cfg.Hygiene))
+	}
 	contextService := NewContextService(repos.Stock, repos.State, repos.Vector)
+	directionService := NewDirectionService(repos.Stock, repos.State, repos.Vector)
+	if llmClient != nil {
+		stockService.SetTextGenerator(llmClient)
+		stateService.SetTextGenerator(llmClient)
+		directionService.SetTextGenerator(llmClient)
+	}
+
+	var stockWatcher *StockWatcher
+	if repos.StockFiles != nil {
+		mode, interval := StockWatchNotify, 10*time.Second
+		if cfg != nil {
+			mode, interval = stockWatchFromConfig(cfg.Stock)
+		}
+		stockWatcher = NewStockWatcher(repos.StockFiles, stockService, mode, interval)
+	}
 
 	return &Services{
-		Stock:      stockService,
-		State:      stateService,
-		Context:    contextService,
-		vectorRepo: repos.Vector,
+		Stock:        stockService,
+		State:        stateService,
+		Incident:     incidentService,
+		Problem:      problemService,
+		Change:       changeService,
+		Release:      releaseService,
+		Hygiene:      hygieneService,
+		Context:      contextService,
+		Direction:    directionService,
+		AgentConfig:  NewAgentConfigService(repos.Stock),
+		Site:         NewSiteService(repos.Stock, stateService),
+		StockWatcher: stockWatcher,
+		LLM:          llmClient,
+		vectorRepo:   repos.Vector,
+		backups:      repos.Backups,
+	}
+}
+
+// slaPoliciesFromConfig は設定ファイルのSLAポリシーをドメインモデルに変換する。
+// 解釈できないエントリは警告を出力して読み飛ばす。
+func slaPoliciesFromConfig(cfg config.SLAConfig) domain.SLAPolicies {
+	policies := make(domain.SLAPolicies, 0, len(cfg.Policies))
+	for _, pc := range cfg.Policies {
+		within, err := time.ParseDuration(pc.ResolveWithin)
+		if err != nil || within <= 0 {
+			slog.Warn("ignoring SLA policy with invalid resolve_within", "type", pc.Type, "priority", pc.Priority, "resolve_within", pc.ResolveWithin)
+			continue
+		}
+		stateType, priority, err := parsePolicyScope(pc.Type, pc.Priority)
+		if err != nil {
+			slog.Warn("ignoring SLA policy with invalid scope", "type", pc.Type, "priority", pc.Priority, "error", err)
+			continue
+		}
+		policies = append(policies, domain.SLAPolicy{Type: stateType, Priority: priority, ResolveWithin: within})
 	}
+	return policies
+}
+
+// stalePoliciesFromConfig は設定ファイルの放置判定しきい値をドメインモデルに変換する。
+// 解釈できないエントリは警告を出力して読み飛ばす。
+func stalePoliciesFromConfig(cfg config.HygieneConfig) domain.StalePolicies {
+	policies := make(domain.StalePolicies, 0, len(cfg.Thresholds))
+	for _, tc := range cfg.Thresholds {
+		after, err := time.ParseDuration(tc.After)
+		if err != nil || after <= 0 {
+			slog.Warn("ignoring stale threshold with invalid after", "type", tc.Type, "priority", tc.Priority, "after", tc.After)
+			continue
+		}
+		stateType, priority, err := parsePolicyScope(tc.Type, tc.Priority)
+		if err != nil {
+			slog.Warn("ignoring stale threshold with invalid scope", "type", tc.Type, "priority", tc.Priority, "error", err)
+			continue
+		}
+		policies = append(policies, domain.StalePolicy{Type: stateType, Priority: priority, After: after})
+	}
+	return policies
+}
+
+// stockWatchFromConfig はStockファイルの監視方法と走査間隔を設定から組み立てる。
+// 解釈できない値は警告を出力して既定値（notify, 10s）を使う。
+func stockWatchFromConfig(cfg config.StockConfig) (StockWatchMode, time.Duration) {
+	mode := StockWatchNotify
+	switch StockWatchMode(cfg.Watch) {
+	case "", StockWatchNotify:
+	case StockWatchPoll, StockWatchOff:
+		mode = StockWatchMode(cfg.Watch)
+	default:
+		slog.Warn("ignoring invalid stock watch mode; using notify", "watch", cfg.Watch)
+	}
+	interval := 10 * time.Second
+	if cfg.PollInterval != "" {
+		d, err := time.ParseDuration(cfg.PollInterval)
+		if err != nil || d <= 0 {
+			slog.Warn("ignoring invalid stock poll interval", "poll_interval", cfg.PollInterval)
+		} else {
+			interval = d
+		}
+	}
+	return mode, interval
+}
+
+// hygieneOptionsFromConfig は放置State検出ジョブの自動対応・実行間隔を設定から組み立てる。
+func hygieneOptionsFromConfig(cfg config.HygieneConfig) HygieneOptions {
+	options := HygieneOptions{TagStale: cfg.TagStale, Escalate: cfg.Escalate}
+	if cfg.Interval != "" && cfg.Interval != "0" {
+		interval, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			slog.Warn("ignoring invalid hygiene interval; scheduled runs disabled", "interval", cfg.Interval)
+		} else {
+			options.Interval = interval
+		}
+	}
+	return options
+}
+
+// deletedRetentionFromConfig は削除済みStockの保持期間を設定から解析する。解釈できない場合は既定値を用いる。
+func deletedRetentionFromConfig(cfg config.StockConfig) time.Duration {
+	if cfg.DeletedRetention == "" {
+		return defaultDeletedRetention
+	}
+	retention, err := time.ParseDuration(cfg.DeletedRetention)
+	if err != nil || retention < 0 {
+		slog.Warn("ignoring invalid stock deleted_retention; using default", "deleted_retention", cfg.DeletedRetention)
+		return defaultDeletedRetention
+	}
+	return retention
+}
+
+// parsePolicyScope はポリシーの適用範囲（種別・優先度）を解析する。空文字列は全体に適用する。
+func parsePolicyScope(typ string, priority string) (domain.StateType, *domain.Priority, error) {
+	var stateType domain.StateType
+	if typ != "" {
+		stateType = domain.StateType(typ)
+		if !isValidStateType(stateType) {
+			return "", nil, domain.ErrInvalidType
+		}
+	}
+	if priority == "" {
+		return stateType, nil, nil
+	}
+	p, err := domain.ParsePriority(priority)
+	if err != nil {
+		return "", nil, err
+	}
+	return stateType, &p, nil
 }
 
 // BootstrapVectorIndex は既存データのうち未インデックス分だけをベクトルDBへ補完する。
@@ -42,7 +218,13 @@ func (s *Services) BootstrapVectorIndex(ctx context.Context) error {
 		return err
 	}
 	for _, stock := range stocks {
-		exists, err := s.vectorRepo.Exists(ctx, stock.ID)
+		// 管理番号のみのドキュメントIDで登録された旧形式のドキュメントは削除して登録し直す
+		if legacy, err := s.vectorRepo.Exists(ctx, stock.ID); err == nil && legacy {
+			if err := s.vectorRepo.Delete(ctx, stock.ID); err != nil {
+				slog.Warn("failed to delete legacy stock vector", "stock_id", stock.ID, "error", err)
+			}
+		}
+		exists, err := s.vectorRepo.Exists(ctx, stockVectorID(stock.ProjectID, stock.ID))
 		if err != nil {
 			slog.Warn("failed to check stock vector existence", "stock_id", stock.ID, "error", err)
 			continue
@@ -57,7 +239,7 @@ func (s *Services) BootstrapVectorIndex(ctx context.Context) error {
 			"category":   string(stock.Category),
 			"priority":   stock.Priority.String(),
 		}
-		if err := s.vectorRepo.Upsert(ctx, stock.ID, stock.Title+"\n"+stock.Content, metadata); err != nil {
+		if err := s.vectorRepo.Upsert(ctx, stockVectorID(stock.ProjectID, stock.ID), stock.Title+"\n"+stock.Content, metadata); err != nil {
 			slog.Warn("failed to upsert stock vector", "stock_id", stock.ID, "error", err)
 		}
 	}
@@ -0,0 +1,119 @@
+// pim site build が出力する静的サイトの検索。search-index.js の window.PIM_SEARCH_INDEX をブラウザ内で検索する。
+(function () {
+  "use strict";
+
+  // 現在のページへのナビゲーションのリンクを強調する
+  var here = location.pathname.replace(/\\/g, "/");
+  document.querySelectorAll(".sidebar a").forEach(function (a) {
+    if (a.pathname === here) {
+      a.setAttribute("aria-current", "page");
+    }
+  });
+
+  var results = document.getElementById("search-results");
+  if (!results) {
+    return;
+  }
+  var input = document.getElementById("search-query");
+  var status = document.getElementById("search-status");
+  var index = window.PIM_SEARCH_INDEX || [];
+
+  function escapeHTML(s) {
+    return s.replace(/[&<>"']/g, function (c) {
+      return { "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" }[c];
+    });
+  }
+
+  // 語の出現位置を元のテキスト上で求めてから、区間ごとにエスケープして <mark> で囲む
+  function highlight(text, terms) {
+    var lower = text.toLowerCase();
+    var marked = [];
+    if (lower.length === text.length) {
+      terms.forEach(function (term) {
+        for (var i = lower.indexOf(term); term && i >= 0; i = lower.indexOf(term, i + 1)) {
+          for (var j = i; j < i + term.length; j++) {
+            marked[j] = true;
+          }
+        }
+      });
+    }
+    var html = "";
+    var start = 0;
+    for (var k = 1; k <= text.length; k++) {
+      if (k === text.length || !marked[k] !== !marked[start]) {
+        var segment = escapeHTML(text.slice(start, k));
+        html += marked[start] ? "<mark>" + segment + "</mark>" : segment;
+        start = k;
+      }
+    }
+    return html;
+  }
+
+  function snippet(text, terms) {
+    var lower = text.toLowerCase();
+    var pos = -1;
+    terms.forEach(function (term) {
+      var i = lower.indexOf(term);
+      if (i >= 0 && (pos < 0 || i < pos)) {
+        pos = i;
+      }
+    });
+    var start = Math.max(0, pos - 60);
+    var s = text.slice(start, start + 200);
+    return (start > 0 ? "…" : "") + s + (start + 200 < text.length ? "…" : "");
+  }
+
+  // 全ての語を含む項目を、タイトル・管理番号・タグに含まれるものを優先して並べる
+  function search(query) {
+    var terms = query.toLowerCase().split(/\s+/).filter(Boolean);
+    if (terms.length === 0) {
+      return { terms: terms, hits: [] };
+    }
+    var hits = [];
+    index.forEach(function (entry) {
+      var head = (entry.i + " " + entry.t + " " + (entry.g || []).join(" ")).toLowerCase();
+      var body = entry.x.toLowerCase();
+      var score = 0;
+      for (var i = 0; i < terms.length; i++) {
+        if (head.indexOf(terms[i]) >= 0) {
+          score += 10;
+        } else if (body.indexOf(terms[i]) >= 0) {
+          score += 1;
+        } else {
+          return;
+        }
+      }
+      hits.push({ entry: entry, score: score });
+    });
+    hits.sort(function (a, b) { return b.score - a.score || a.entry.i.localeCompare(b.entry.i); });
+    return { terms: terms, hits: hits };
+  }
+
+  function render(query) {
+    var result = search(query);
+    results.innerHTML = "";
+    if (result.terms.length === 0) {
+      status.textContent = index.length + " 件のStock・Stateを検索できます";
+      return;
+    }
+    status.textContent = "「" + query + "」の検索結果: " + result.hits.length + " 件";
+    result.hits.slice(0, 100).forEach(function (hit) {
+      var e = hit.entry;
+      var li = document.createElement("li");
+      li.innerHTML =
+        '<a href="' + escapeHTML(e.u) + '"><span class="id">' + escapeHTML(e.i) + "</span> " + highlight(e.t, result.terms) + "</a>" +
+        ' <span class="meta">' + escapeHTML(e.p + " / " + e.k) + "</span>" +
+        '<div class="snippet">' + highlight(snippet(e.x, result.terms), result.terms) + "</div>";
+      results.appendChild(li);
+    });
+  }
+
+  var query = new URLSearchParams(location.search).get("q") || "";
+  input.value = query;
+  document.querySelectorAll(".search-form input").forEach(function (el) { el.value = query; });
+  render(query);
+  input.addEventListener("input", function () {
+    render(input.value);
+    history.replaceState(null, "", "search.html" + (input.value ? "?q=" + encodeURIComponent(input.value) : ""));
+  });
+})();
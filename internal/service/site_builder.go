@@ -0,0 +1,764 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+//go:embed site/*
+var siteAssets embed.FS
+
+// siteMarker は静的サイトの出力先に置くファイル。出力先の置き換え時に、pim が生成したディレクトリであることを確認する。
+const siteMarker = ".pim-site"
+
+// siteCategoryLabels はカテゴリの表示名。
+var siteCategoryLabels = map[domain.StockCategory]string{
+	domain.CategoryDesign:       "設計",
+	domain.CategoryRules:        "開発ルール",
+	domain.CategoryManagement:   "管理方針",
+	domain.CategoryArchitecture: "方式設計",
+	domain.CategoryRequirement:  "要件",
+	domain.CategoryTest:         "テスト",
+	domain.CategoryPostmortem:   "ポストモーテム",
+}
+
+// siteStatuses はダッシュボードに表示するStateのステータス（表示順）。
+var siteStatuses = []domain.StateStatus{domain.StatusOpen, domain.StatusInProgress, domain.StatusResolved, domain.StatusArchived}
+
+// siteTypes はダッシュボードに表示するStateの種別（表示順）。
+var siteTypes = []domain.StateType{domain.StateTypeTask, domain.StateTypeIssue, domain.StateTypeIncident, domain.StateTypeProblem, domain.StateTypeChange}
+
+// SiteService はプロジェクトのStockを、エージェントを使わない人向けの静的HTMLサイトとして出力する。
+// カテゴリ・階層によるナビゲーション、References と被参照（バックリンク）のリンク、Stateのダッシュボード、
+// ブラウザ内で動く検索（検索インデックスをJavaScriptとして同梱）を含み、サーバーなしで file:// からも閲覧できる。
+type SiteService struct {
+	stockRepo repository.StockRepository
+	states    *StateService
+}
+
+// NewSiteService は新しいSiteServiceを生成する。
+func NewSiteService(stockRepo repository.StockRepository, states *StateService) *SiteService {
+	return &SiteService{stockRepo: stockRepo, states: states}
+}
+
+// SiteBuildInput は静的サイト生成の入力パラメータ。
+type SiteBuildInput struct {
+	ProjectID string    // 対象プロジェクト（空の場合はStockのある全プロジェクト）
+	Title     string    // サイトのタイトル（デフォルト: Project Information Manager）
+	Now       time.Time // ダッシュボードの期限判定・生成日時（ゼロ値の場合は現在時刻）
+}
+
+// siteProjectData はサイトに出力する1プロジェクト分のStock・State。
+type siteProjectData struct {
+	id       string
+	stocks   []*domain.Stock // 削除済み・エイリアスを除く
+	aliases  map[string]string
+	states   []*domain.State // アーカイブ済みを含む
+	parent   map[string]string
+	children map[string][]*domain.Stock
+}
+
+// siteBuild は生成中のサイト全体の状態。管理番号の解決とバックリンクの収集に使う。
+type siteBuild struct {
+	input     SiteBuildInput
+	projects  []*siteProjectData
+	stocks    map[string]map[string]*domain.Stock // project → id → Stock
+	states    map[string]*domain.State
+	backlinks map[string][]siteRef // ページのパス → 参照元
+	files     []GeneratedFile
+	tmpl      *template.Template
+	index     []siteSearchEntry
+}
+
+// siteLink はページへのリンク。Href はサイトのルートからの相対パス。
+type siteLink struct {
+	Href  string
+	Label string
+}
+
+// siteRef は参照・被参照の1件。Href が空の場合は解決できなかった管理番号。
+type siteRef struct {
+	ID    string
+	Title string
+	Href  string
+	Kind  string // "stock" | "state"
+}
+
+// siteNode はナビゲーションのStockの階層。
+type siteNode struct {
+	ID       string
+	Title    string
+	Href     string
+	Children []*siteNode
+}
+
+type siteNavCategory struct {
+	Label    string
+	Category domain.StockCategory
+	Count    int
+	Nodes    []*siteNode
+}
+
+type siteNav struct {
+	ProjectID  string
+	Href       string
+	Dashboard  string
+	Categories []siteNavCategory
+}
+
+// sitePage はページテンプレートに渡すデータ。
+type sitePage struct {
+	SiteTitle   string
+	Title       string
+	Root        string // <base href> に設定するルートへの相対パス
+	Kind        string
+	Projects    []siteLink
+	Nav         *siteNav
+	Breadcrumbs []siteLink
+	Body        any
+	Generated   string
+}
+
+type siteStockBody struct {
+	Stock      *domain.Stock
+	Category   string
+	Content    template.HTML
+	Children   []siteRef
+	References []siteRef
+	Backlinks  []siteRef
+}
+
+type siteStateBody struct {
+	State       *domain.State
+	Description template.HTML
+	Resolution  template.HTML
+	Due         string
+	References  []siteRef
+	Backlinks   []siteRef
+}
+
+type siteStateRow struct {
+	ID       string
+	Href     string
+	Type     domain.StateType
+	Status   domain.StateStatus
+	Priority domain.Priority
+	Title    string
+	Assignee string
+	Due      string
+	Overdue  bool
+	Updated  string
+}
+
+type siteCountRow struct {
+	Type   domain.StateType
+	Counts []int
+	Total  int
+}
+
+type siteDashboardBody struct {
+	Statuses []domain.StateStatus
+	Rows     []siteCountRow
+	Totals   []int
+	Overdue  []siteStateRow
+	Open     []siteStateRow
+	Resolved []siteStateRow
+}
+
+type siteProjectBody struct {
+	Categories []siteProjectCategory
+	OpenStates int
+	Overdue    int
+}
+
+type siteProjectCategory struct {
+	Category domain.StockCategory
+	Label    string
+	Stocks   []siteStockRow
+}
+
+type siteStockRow struct {
+	ID       string
+	Href     string
+	Title    string
+	Priority domain.Priority
+	Summary  string
+	Updated  string
+}
+
+type siteIndexBody struct {
+	Projects []siteIndexProject
+}
+
+type siteIndexProject struct {
+	ID         string
+	Href       string
+	Stocks     int
+	OpenStates int
+}
+
+// siteSearchEntry は検索インデックスの1件。ファイルサイズを抑えるためキーを短くする。
+type siteSearchEntry struct {
+	URL     string   `json:"u"`
+	ID      string   `json:"i"`
+	Title   string   `json:"t"`
+	Project string   `json:"p"`
+	Kind    string   `json:"k"`
+	Tags    []string `json:"g,omitempty"`
+	Text    string   `json:"x"`
+}
+
+// Build はStock・Stateから静的サイトのファイル一式を生成する。結果はパス順に並ぶ。
+func (s *SiteService) Build(ctx context.Context, input SiteBuildInput) ([]GeneratedFile, error) {
+	if input.Title == "" {
+		input.Title = "Project Information Manager"
+	}
+	if input.Now.IsZero() {
+		input.Now = time.Now()
+	}
+	tmpl, err := template.New("site").Funcs(template.FuncMap{
+		"categoryLabel": func(c domain.StockCategory) string { return categoryLabel(c) },
+		"date":          func(t time.Time) string { return t.Local().Format("2006-01-02") },
+	}).ParseFS(siteAssets, "site/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse site templates: %w", err)
+	}
+	b := &siteBuild{
+		input:     input,
+		stocks:    map[string]map[string]*domain.Stock{},
+		states:    map[string]*domain.State{},
+		backlinks: map[string][]siteRef{},
+		tmpl:      tmpl,
+	}
+	if err := s.load(ctx, b); err != nil {
+		return nil, err
+	}
+	if len(b.projects) == 0 {
+		if input.ProjectID != "" {
+			return nil, fmt.Errorf("project %s has no stocks or states: %w", input.ProjectID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("no stocks or states to publish: %w", domain.ErrNotFound)
+	}
+
+	b.collectBacklinks()
+	for _, project := range b.projects {
+		if err := b.renderProject(s, project); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.renderIndex(); err != nil {
+		return nil, err
+	}
+	if err := b.renderSearch(); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"style.css", "search.js"} {
+		data, err := siteAssets.ReadFile("site/" + name)
+		if err != nil {
+			return nil, err
+		}
+		b.files = append(b.files, GeneratedFile{Path: "assets/" + name, Content: string(data)})
+	}
+	b.files = append(b.files, GeneratedFile{Path: siteMarker, Content: "pim " + config.Version + "\n"})
+
+	sort.Slice(b.files, func(i, j int) bool { return b.files[i].Path < b.files[j].Path })
+	return b.files, nil
+}
+
+// load は対象プロジェクトのStock（エイリアスを含む）とState（アーカイブ済みを含む）を読み込む。
+func (s *SiteService) load(ctx context.Context, b *siteBuild) error {
+	stocks, err := s.stockRepo.List(ctx, b.input.ProjectID, &repository.StockListOptions{IncludeAliases: true})
+	if err != nil {
+		return fmt.Errorf("failed to list stocks: %w", err)
+	}
+	states, err := s.states.List(ctx, b.input.ProjectID, &repository.StateListOptions{IncludeArchived: true})
+	if err != nil {
+		return fmt.Errorf("failed to list states: %w", err)
+	}
+
+	projects := map[string]*siteProjectData{}
+	project := func(id string) *siteProjectData {
+		p, ok := projects[id]
+		if !ok {
+			p = &siteProjectData{id: id, aliases: map[string]string{}}
+			projects[id] = p
+			b.stocks[id] = map[string]*domain.Stock{}
+		}
+		return p
+	}
+	for _, stock := range stocks {
+		// プロジェクトIDと管理番号はページのパスになるため、--out の外を指すものは含めない
+		if !repository.ValidPathElement(stock.ProjectID) || !repository.ValidPathElement(stock.ID) {
+			slog.Warn("site: skipping stock with an unsafe path", "project_id", stock.ProjectID, "stock_id", stock.ID)
+			continue
+		}
+		p := project(stock.ProjectID)
+		if stock.IsAlias() {
+			p.aliases[stock.ID] = stock.RedirectTo
+			continue
+		}
+		p.stocks = append(p.stocks, stock)
+		b.stocks[stock.ProjectID][stock.ID] = stock
+	}
+	for _, state := range states {
+		if !repository.ValidPathElement(state.ProjectID) || !repository.ValidPathElement(state.ID) {
+			slog.Warn("site: skipping state with an unsafe path", "project_id", state.ProjectID, "state_id", state.ID)
+			continue
+		}
+		p := project(state.ProjectID)
+		p.states = append(p.states, state)
+		b.states[state.ID] = state
+	}
+	for _, p := range projects {
+		b.projects = append(b.projects, p)
+		p.buildHierarchy(b)
+	}
+	sort.Slice(b.projects, func(i, j int) bool { return b.projects[i].id < b.projects[j].id })
+	return nil
+}
+
+// buildHierarchy はStockの階層を決める。同じカテゴリのStockを References で参照している場合、
+// 最初に参照しているものを親とする（例: 基本設計 → 概要設計）。循環する参照は親にしない。
+func (p *siteProjectData) buildHierarchy(b *siteBuild) {
+	p.parent = map[string]string{}
+	p.children = map[string][]*domain.Stock{}
+	for _, stock := range p.stocks {
+		for _, ref := range stock.References {
+			target := b.resolveStock(p.id, ref)
+			if target == nil || target.ProjectID != p.id || target.Category != stock.Category || target.ID == stock.ID {
+				continue
+			}
+			if p.isAncestor(stock.ID, target.ID) {
+				continue
+			}
+			p.parent[stock.ID] = target.ID
+			p.children[target.ID] = append(p.children[target.ID], stock)
+			break
+		}
+	}
+}
+
+// isAncestor は ancestor が id 自身またはその祖先かどうかを返す。
+func (p *siteProjectData) isAncestor(ancestor, id string) bool {
+	for seen := 0; id != "" && seen <= len(p.stocks); seen++ {
+		if id == ancestor {
+			return true
+		}
+		id = p.parent[id]
+	}
+	return false
+}
+
+// resolveStock は projectID から見た管理番号のStockを返す。再分類前の管理番号は転送先に解決し、
+// プロジェクト内にない場合は、他のプロジェクトで一意に決まるものを返す。
+func (b *siteBuild) resolveStock(projectID, id string) *domain.Stock {
+	for _, p := range b.projects {
+		if p.id != projectID {
+			continue
+		}
+		for hops := 0; hops < 8; hops++ {
+			to, ok := p.aliases[id]
+			if !ok {
+				break
+			}
+			id = to
+		}
+	}
+	if stock, ok := b.stocks[projectID][id]; ok {
+		return stock
+	}
+	var found *domain.Stock
+	for _, stocks := range b.stocks {
+		if stock, ok := stocks[id]; ok {
+			if found != nil {
+				return nil
+			}
+			found = stock
+		}
+	}
+	return found
+}
+
+// resolve は管理番号をリンクに解決する。
+func (b *siteBuild) resolve(projectID, id string) siteRef {
+	if stock := b.resolveStock(projectID, id); stock != nil {
+		return siteRef{ID: stock.ID, Title: stock.Title, Href: stockPagePath(stock), Kind: "stock"}
+	}
+	if state, ok := b.states[id]; ok {
+		return siteRef{ID: state.ID, Title: state.Title, Href: statePagePath(state), Kind: "state"}
+	}
+	return siteRef{ID: id}
+}
+
+// siteIDPattern は本文中の管理番号。本文で言及しているStock・Stateもバックリンクに含める。
+var siteIDPattern = regexp.MustCompile(`\b(?:STK|STA)-[A-Za-z]+-\d+\b`)
+
+// collectBacklinks は References と本文中の管理番号から、参照先ページごとの参照元を集める。
+func (b *siteBuild) collectBacklinks() {
+	add := func(source siteRef, projectID string, ids []string) {
+		seen := map[string]bool{}
+		for _, id := range ids {
+			target := b.resolve(projectID, id)
+			if target.Href == "" || target.Href == source.Href || seen[target.Href] {
+				continue
+			}
+			seen[target.Href] = true
+			b.backlinks[target.Href] = append(b.backlinks[target.Href], source)
+		}
+	}
+	for _, p := range b.projects {
+		for _, stock := range p.stocks {
+			ids := append(slices.Clone(stock.References), siteIDPattern.FindAllString(stock.Content, -1)...)
+			add(siteRef{ID: stock.ID, Title: stock.Title, Href: stockPagePath(stock), Kind: "stock"}, p.id, ids)
+		}
+		for _, state := range p.states {
+			ids := append(slices.Clone(state.References), siteIDPattern.FindAllString(state.Description+"\n"+state.Resolution, -1)...)
+			add(siteRef{ID: state.ID, Title: state.Title, Href: statePagePath(state), Kind: "state"}, p.id, ids)
+		}
+	}
+	for href := range b.backlinks {
+		sort.Slice(b.backlinks[href], func(i, j int) bool {
+			refs := b.backlinks[href]
+			if refs[i].Kind != refs[j].Kind {
+				return refs[i].Kind < refs[j].Kind
+			}
+			return refs[i].ID < refs[j].ID
+		})
+	}
+}
+
+func (b *siteBuild) renderProject(s *SiteService, p *siteProjectData) error {
+	nav := b.nav(p)
+	md := &markdownHTML{linkID: func(id string) string { return b.resolve(p.id, id).Href }}
+	projectCrumb := siteLink{Href: projectPagePath(p.id), Label: p.id}
+
+	body := &siteProjectBody{}
+	for _, category := range domain.ValidStockCategories() {
+		var rows []siteStockRow
+		for _, stock := range p.stocks {
+			if stock.Category == category {
+				rows = append(rows, siteStockRow{
+					ID: stock.ID, Href: stockPagePath(stock), Title: stock.Title, Priority: stock.Priority,
+					Summary: stock.Summary, Updated: stock.UpdatedAt.Local().Format("2006-01-02"),
+				})
+			}
+		}
+		if len(rows) > 0 {
+			body.Categories = append(body.Categories, siteProjectCategory{Category: category, Label: categoryLabel(category), Stocks: rows})
+		}
+	}
+	dashboard := b.dashboard(s, p)
+	body.OpenStates = len(dashboard.Open)
+	body.Overdue = len(dashboard.Overdue)
+	if err := b.page(projectPagePath(p.id), sitePage{Title: p.id, Kind: "project", Nav: nav, Body: body}); err != nil {
+		return err
+	}
+	if err := b.page(dashboardPagePath(p.id), sitePage{
+		Title: p.id + " ダッシュボード", Kind: "dashboard", Nav: nav, Body: dashboard,
+		Breadcrumbs: []siteLink{projectCrumb},
+		Generated:   b.input.Now.Local().Format("2006-01-02 15:04"),
+	}); err != nil {
+		return err
+	}
+
+	for _, stock := range p.stocks {
+		href := stockPagePath(stock)
+		crumbs := []siteLink{projectCrumb, {Href: projectPagePath(p.id) + "#" + string(stock.Category), Label: categoryLabel(stock.Category)}}
+		var ancestors []siteLink
+		for id := p.parent[stock.ID]; id != ""; id = p.parent[id] {
+			parent := b.stocks[p.id][id]
+			ancestors = append([]siteLink{{Href: stockPagePath(parent), Label: parent.Title}}, ancestors...)
+		}
+		body := &siteStockBody{
+			Stock:     stock,
+			Category:  categoryLabel(stock.Category),
+			Content:   template.HTML(md.render(stock.Content)),
+			Backlinks: b.backlinks[href],
+		}
+		for _, child := range p.children[stock.ID] {
+			body.Children = append(body.Children, siteRef{ID: child.ID, Title: child.Title, Href: stockPagePath(child), Kind: "stock"})
+		}
+		for _, ref := range stock.References {
+			body.References = append(body.References, b.resolve(p.id, ref))
+		}
+		if err := b.page(href, sitePage{
+			Title: stock.ID + " " + stock.Title, Kind: "stock", Nav: nav, Body: body,
+			Breadcrumbs: append(crumbs, ancestors...),
+		}); err != nil {
+			return err
+		}
+		b.index = append(b.index, siteSearchEntry{
+			URL: href, ID: stock.ID, Title: stock.Title, Project: p.id, Kind: categoryLabel(stock.Category),
+			Tags: stock.Tags, Text: searchText(stock.Summary + "\n" + stock.Content),
+		})
+	}
+
+	for _, state := range p.states {
+		href := statePagePath(state)
+		body := &siteStateBody{
+			State:       state,
+			Description: template.HTML(md.render(state.Description)),
+			Resolution:  template.HTML(md.render(state.Resolution)),
+			Backlinks:   b.backlinks[href],
+		}
+		if state.DueAt != nil {
+			body.Due = state.DueAt.Local().Format("2006-01-02 15:04")
+		}
+		for _, ref := range state.References {
+			body.References = append(body.References, b.resolve(p.id, ref))
+		}
+		if err := b.page(href, sitePage{
+			Title: state.ID + " " + state.Title, Kind: "state", Nav: nav, Body: body,
+			Breadcrumbs: []siteLink{projectCrumb, {Href: dashboardPagePath(p.id), Label: "ダッシュボード"}},
+		}); err != nil {
+			return err
+		}
+		b.index = append(b.index, siteSearchEntry{
+			URL: href, ID: state.ID, Title: state.Title, Project: p.id, Kind: string(state.Type) + " / " + string(state.Status),
+			Tags: state.Tags, Text: searchText(state.Description + "\n" + state.Resolution),
+		})
+	}
+	return nil
+}
+
+// nav はプロジェクトのナビゲーション（カテゴリ → Stockの階層）を生成する。
+func (b *siteBuild) nav(p *siteProjectData) *siteNav {
+	var node func(stock *domain.Stock) *siteNode
+	node = func(stock *domain.Stock) *siteNode {
+		n := &siteNode{ID: stock.ID, Title: stock.Title, Href: stockPagePath(stock)}
+		for _, child := range p.children[stock.ID] {
+			n.Children = append(n.Children, node(child))
+		}
+		return n
+	}
+	nav := &siteNav{ProjectID: p.id, Href: projectPagePath(p.id), Dashboard: dashboardPagePath(p.id)}
+	for _, category := range domain.ValidStockCategories() {
+		c := siteNavCategory{Label: categoryLabel(category), Category: category}
+		for _, stock := range p.stocks {
+			if stock.Category != category {
+				continue
+			}
+			c.Count++
+			if _, ok := p.parent[stock.ID]; !ok {
+				c.Nodes = append(c.Nodes, node(stock))
+			}
+		}
+		if c.Count > 0 {
+			nav.Categories = append(nav.Categories, c)
+		}
+	}
+	return nav
+}
+
+// dashboard はプロジェクトのStateの集計（種別 × ステータス）、期限超過・未解決・最近解決したStateを生成する。
+func (b *siteBuild) dashboard(s *SiteService, p *siteProjectData) *siteDashboardBody {
+	now := b.input.Now
+	body := &siteDashboardBody{Statuses: siteStatuses, Totals: make([]int, len(siteStatuses)+1)}
+	for _, stateType := range siteTypes {
+		row := siteCountRow{Type: stateType, Counts: make([]int, len(siteStatuses))}
+		for _, state := range p.states {
+			if state.Type != stateType {
+				continue
+			}
+			if i := slices.Index(siteStatuses, state.Status); i >= 0 {
+				row.Counts[i]++
+				body.Totals[i]++
+			}
+			row.Total++
+			body.Totals[len(siteStatuses)]++
+		}
+		if row.Total > 0 {
+			body.Rows = append(body.Rows, row)
+		}
+	}
+
+	var resolved []*domain.State
+	for _, state := range p.states {
+		if !state.IsOpen() {
+			if state.Status == domain.StatusResolved || state.Status == domain.StatusArchived {
+				resolved = append(resolved, state)
+			}
+			continue
+		}
+		row := siteStateRow{
+			ID: state.ID, Href: statePagePath(state), Type: state.Type, Status: state.Status, Priority: state.Priority,
+			Title: state.Title, Assignee: state.Assignee, Updated: state.UpdatedAt.Local().Format("2006-01-02"),
+		}
+		if state.DueAt != nil {
+			row.Due = state.DueAt.Local().Format("2006-01-02")
+		}
+		summary := s.states.summarize(state, now)
+		row.Overdue = state.IsOverdue(now) || (summary.SLA != nil && summary.SLA.Breached)
+		if row.Overdue {
+			body.Overdue = append(body.Overdue, row)
+		}
+		body.Open = append(body.Open, row)
+	}
+	sort.SliceStable(body.Open, func(i, j int) bool {
+		if body.Open[i].Priority != body.Open[j].Priority {
+			return body.Open[i].Priority < body.Open[j].Priority
+		}
+		return body.Open[i].ID < body.Open[j].ID
+	})
+	sort.SliceStable(body.Overdue, func(i, j int) bool { return body.Overdue[i].Priority < body.Overdue[j].Priority })
+
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].UpdatedAt.After(resolved[j].UpdatedAt) })
+	for _, state := range resolved[:min(len(resolved), 10)] {
+		body.Resolved = append(body.Resolved, siteStateRow{
+			ID: state.ID, Href: statePagePath(state), Type: state.Type, Status: state.Status, Priority: state.Priority,
+			Title: state.Title, Assignee: state.Assignee, Updated: state.UpdatedAt.Local().Format("2006-01-02"),
+		})
+	}
+	return body
+}
+
+func (b *siteBuild) renderIndex() error {
+	body := &siteIndexBody{}
+	for _, p := range b.projects {
+		open := 0
+		for _, state := range p.states {
+			if state.IsOpen() {
+				open++
+			}
+		}
+		body.Projects = append(body.Projects, siteIndexProject{ID: p.id, Href: projectPagePath(p.id), Stocks: len(p.stocks), OpenStates: open})
+	}
+	return b.page("index.html", sitePage{Title: "プロジェクト", Kind: "index", Body: body})
+}
+
+// renderSearch は検索ページと検索インデックスを出力する。インデックスは file:// でも読み込めるよう
+// JSONではなくスクリプトとして出力する。
+func (b *siteBuild) renderSearch() error {
+	sort.Slice(b.index, func(i, j int) bool { return b.index[i].URL < b.index[j].URL })
+	data, err := json.Marshal(b.index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	b.files = append(b.files, GeneratedFile{Path: "search-index.js", Content: "window.PIM_SEARCH_INDEX = " + string(data) + ";\n"})
+	return b.page("search.html", sitePage{Title: "検索", Kind: "search"})
+}
+
+// page はテンプレートからページを出力する。
+func (b *siteBuild) page(pagePath string, page sitePage) error {
+	page.SiteTitle = b.input.Title
+	page.Root = strings.Repeat("../", strings.Count(pagePath, "/"))
+	if page.Root == "" {
+		page.Root = "./"
+	}
+	for _, p := range b.projects {
+		page.Projects = append(page.Projects, siteLink{Href: projectPagePath(p.id), Label: p.id})
+	}
+	var buf bytes.Buffer
+	if err := b.tmpl.ExecuteTemplate(&buf, "page.html.tmpl", page); err != nil {
+		return fmt.Errorf("failed to render %s: %w", pagePath, err)
+	}
+	b.files = append(b.files, GeneratedFile{Path: pagePath, Content: buf.String()})
+	return nil
+}
+
+func projectPagePath(projectID string) string {
+	return projectID + "/index.html"
+}
+
+func dashboardPagePath(projectID string) string {
+	return projectID + "/dashboard.html"
+}
+
+func stockPagePath(stock *domain.Stock) string {
+	return path.Join(stock.ProjectID, "stocks", stock.ID+".html")
+}
+
+func statePagePath(state *domain.State) string {
+	return path.Join(state.ProjectID, "states", state.ID+".html")
+}
+
+func categoryLabel(category domain.StockCategory) string {
+	if label, ok := siteCategoryLabels[category]; ok {
+		return label
+	}
+	return string(category)
+}
+
+// searchText は検索インデックスに入れる本文。Markdownの記号を除き、空白を詰める。
+func searchText(markdown string) string {
+	text := strings.Map(func(r rune) rune {
+		if strings.ContainsRune("#*_`>|[]()~", r) {
+			return ' '
+		}
+		return r
+	}, markdown)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// WriteSite は生成したサイトを outDir に出力する。別の場所に書き出してから置き換えるため、
+// 前回の出力に含まれていた（削除されたStockの）ページは残らない。outDir が pim の生成したサイト
+// （.pim-site を含む）でも空でもない場合は、誤って上書きしないようエラーにする。
+func WriteSite(outDir string, files []GeneratedFile) error {
+	outDir = filepath.Clean(outDir)
+	entries, err := os.ReadDir(outDir)
+	exists := err == nil
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", outDir, err)
+	case len(entries) > 0:
+		if _, err := os.Stat(filepath.Join(outDir, siteMarker)); err != nil {
+			return fmt.Errorf("%s is not empty and was not generated by pim site build; choose another --out", outDir)
+		}
+	}
+
+	parent := filepath.Dir(outDir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(outDir)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	for _, file := range files {
+		rel := filepath.FromSlash(file.Path)
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("refusing to write %s outside %s", file.Path, outDir)
+		}
+		target := filepath.Join(staging, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+		}
+		if err := os.WriteFile(target, []byte(file.Content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return err
+	}
+
+	if exists {
+		old := staging + ".old"
+		if err := os.Rename(outDir, old); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", outDir, err)
+		}
+		defer os.RemoveAll(old)
+	}
+	if err := os.Rename(staging, outDir); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", outDir, err)
+	}
+	return nil
+}
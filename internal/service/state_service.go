@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 	"time"
 
@@ -13,8 +14,12 @@ import (
 
 // StateService はStateのビジネスロジックを提供する。
 type StateService struct {
-	stateRepo  repository.StateRepository
-	vectorRepo repository.VectorRepository
+	stateRepo   repository.StateRepository
+	vectorRepo  repository.VectorRepository
+	slaPolicies domain.SLAPolicies
+	generator   TextGenerator
+	// stockService はアーカイブ時に学びをStockとして転記するために使用する
+	stockService *StockService
 }
 
 // NewStateService は新しいStateServiceを生成する。
@@ -25,6 +30,21 @@ func NewStateService(stateRepo repository.StateRepository, vectorRepo repository
 	}
 }
 
+// SetSLAPolicies はサマリ表示・期限超過判定に用いるSLAポリシーを設定する。
+func (s *StateService) SetSLAPolicies(policies domain.SLAPolicies) {
+	s.slaPolicies = policies
+}
+
+// SetTextGenerator は種別の自動分類に用いるLLMを設定する。
+func (s *StateService) SetTextGenerator(generator TextGenerator) {
+	s.generator = generator
+}
+
+// SetStockService はアーカイブ時の学びの転記先を設定する。
+func (s *StateService) SetStockService(stockService *StockService) {
+	s.stockService = stockService
+}
+
 // CreateStateInput はState作成時の入力パラメータ。
 type CreateStateInput struct {
 	ProjectID   string
@@ -34,13 +54,37 @@ type CreateStateInput struct {
 	Description string
 	Tags        []string
 	References  []string
+	Assignee    string
+	DueAt       *time.Time
+	OnDuplicate string // 重複の可能性がある場合の扱い: allow（デフォルト）, reject, merge
+}
+
+// CreateStateResult はState作成の結果。
+type CreateStateResult struct {
+	State      *domain.State        // 作成した（merge時は統合先の）State
+	Merged     bool                 // 既存のStateに統合した場合 true
+	Duplicates []DuplicateCandidate // 重複の可能性がある未解決の既存State
 }
 
-// Create は新しいStateを作成する。
+// Create は新しいStateを作成する。重複候補が必要な場合は CreateWithDuplicateCheck を使う。
 func (s *StateService) Create(ctx context.Context, input CreateStateInput) (*domain.State, error) {
-	stateType := domain.StateType(input.Type)
-	if !isValidStateType(stateType) {
-		return nil, domain.ErrInvalidType
+	result, err := s.CreateWithDuplicateCheck(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return result.State, nil
+}
+
+// CreateWithDuplicateCheck は同一プロジェクト内の未解決のStateと重複していないかを確認したうえでStateを作成する。
+// 重複候補がある場合、OnDuplicate に従って作成・拒否・既存Stateへの統合を行う。
+func (s *StateService) CreateWithDuplicateCheck(ctx context.Context, input CreateStateInput) (*CreateStateResult, error) {
+	// プロジェクトIDはStockディレクトリやサイトのページのパスになる
+	if !repository.ValidPathElement(input.ProjectID) {
+		return nil, domain.ErrInvalidProjectID
+	}
+	policy, err := ParseDuplicatePolicy(input.OnDuplicate)
+	if err != nil {
+		return nil, err
 	}
 
 	priority, err := domain.ParsePriority(input.Priority)
@@ -48,6 +92,28 @@ func (s *StateService) Create(ctx context.Context, input CreateStateInput) (*dom
 		return nil, err
 	}
 
+	stateType := domain.StateType(input.Type)
+	if stateType == "" {
+		stateType = s.classifyType(ctx, input.Title, input.Description)
+	}
+	if !isValidStateType(stateType) {
+		return nil, domain.ErrInvalidType
+	}
+
+	duplicates := s.findDuplicates(ctx, input)
+	if len(duplicates) > 0 {
+		switch policy {
+		case DuplicateReject:
+			return nil, duplicateError(duplicates)
+		case DuplicateMerge:
+			state, err := s.mergeInto(ctx, duplicates[0].ID, input, priority)
+			if err != nil {
+				return nil, err
+			}
+			return &CreateStateResult{State: state, Merged: true, Duplicates: duplicates}, nil
+		}
+	}
+
 	id := generateStateID(stateType)
 
 	now := time.Now()
@@ -59,8 +125,10 @@ func (s *StateService) Create(ctx context.Context, input CreateStateInput) (*dom
 		Priority:    priority,
 		Title:       input.Title,
 		Description: input.Description,
-		Tags:        input.Tags,
+		Tags:        domain.NormalizeTags(input.Tags),
 		References:  input.References,
+		Assignee:    input.Assignee,
+		DueAt:       input.DueAt,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -70,18 +138,62 @@ func (s *StateService) Create(ctx context.Context, input CreateStateInput) (*dom
 	}
 
 	// ベクトルインデックスに追加
-	if s.vectorRepo != nil {
-		metadata := map[string]string{
-			"type":       "state",
-			"project_id": state.ProjectID,
-			"state_type": string(state.Type),
-			"status":     string(state.Status),
-			"priority":   state.Priority.String(),
+	s.indexState(ctx, state)
+
+	return &CreateStateResult{State: state, Duplicates: duplicates}, nil
+}
+
+// findDuplicates は同一プロジェクト内で作成しようとしているStateと重複の可能性がある未解決のStateを返す。
+// 重複確認に失敗しても作成は妨げない。
+func (s *StateService) findDuplicates(ctx context.Context, input CreateStateInput) []DuplicateCandidate {
+	states, err := s.stateRepo.List(ctx, input.ProjectID, nil)
+	if err != nil {
+		slog.Warn("failed to list states for duplicate check", "error", err)
+		return nil
+	}
+	targets := make([]duplicateTarget, 0, len(states))
+	for _, state := range states {
+		if state.IsOpen() {
+			targets = append(targets, duplicateTarget{id: state.ID, title: state.Title})
 		}
-		_ = s.vectorRepo.Upsert(ctx, state.ID, state.Title+"\n"+state.Description, metadata)
 	}
+	return findDuplicates(ctx, s.vectorRepo, "state", input.ProjectID, input.Title, input.Description, targets)
+}
 
-	return state, nil
+// mergeInto は作成しようとしたStateのタグ・参照・説明を既存のStateに統合する。
+// 優先度は高い方に揃える。
+func (s *StateService) mergeInto(ctx context.Context, id string, input CreateStateInput, priority domain.Priority) (*domain.State, error) {
+	existing, err := s.stateRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	description := mergeText(existing.Description, input.Description)
+	merged := min(existing.Priority, priority).String()
+	return s.Update(ctx, id, UpdateStateInput{
+		Description: &description,
+		Priority:    &merged,
+		Tags:        mergeStrings(existing.Tags, input.Tags),
+		References:  mergeStrings(existing.References, input.References),
+	})
+}
+
+// classifyType はLLMでタイトル・説明からStateの種別を推定する。
+// LLMが未設定、または分類に失敗した場合は空文字列を返す。
+func (s *StateService) classifyType(ctx context.Context, title string, description string) domain.StateType {
+	generator := generatorFor(ctx, s.generator)
+	if generator == nil {
+		return ""
+	}
+	labels := []string{
+		string(domain.StateTypeTask), string(domain.StateTypeIssue), string(domain.StateTypeIncident),
+		string(domain.StateTypeChange), string(domain.StateTypeProblem),
+	}
+	label, err := generator.Classify(ctx, title+"\n"+description, labels)
+	if err != nil {
+		warnGenerationError("failed to classify state type", err)
+		return ""
+	}
+	return domain.StateType(label)
 }
 
 // Get は管理番号でStateを取得する。
@@ -97,6 +209,9 @@ type UpdateStateInput struct {
 	Priority    *string
 	Tags        []string
 	References  []string
+	Assignee    *string // 空文字の場合は担当者を外す
+	DueAt       *time.Time
+	ClearDueAt  bool // 期日を削除する（DueAt より優先）
 }
 
 // Update はStateを更新する。
@@ -111,7 +226,14 @@ func (s *StateService) Update(ctx context.Context, id string, input UpdateStateI
 	}
 
 	if input.Status != nil {
-		state.Status = domain.StateStatus(*input.Status)
+		status := domain.StateStatus(*input.Status)
+		// 変更は承認が揃うまで open から先へ進められない（作業開始・解決のいずれも）
+		if state.Status == domain.StatusOpen && status != domain.StatusOpen {
+			if err := checkChangeApproval(state); err != nil {
+				return nil, err
+			}
+		}
+		state.Status = status
 	}
 	if input.Description != nil {
 		state.Description = *input.Description
@@ -127,11 +249,22 @@ func (s *StateService) Update(ctx context.Context, id string, input UpdateStateI
 		state.Priority = p
 	}
 	if input.Tags != nil {
-		state.Tags = input.Tags
+		state.Tags = domain.NormalizeTags(input.Tags)
+	} else if state.HasTag(domain.StaleTag) {
+		// 更新されたStateは放置状態ではなくなる
+		state.Tags = removeString(state.Tags, domain.StaleTag)
 	}
 	if input.References != nil {
 		state.References = input.References
 	}
+	if input.Assignee != nil {
+		state.Assignee = *input.Assignee
+	}
+	if input.ClearDueAt {
+		state.DueAt = nil
+	} else if input.DueAt != nil {
+		state.DueAt = input.DueAt
+	}
 
 	state.UpdatedAt = time.Now()
 
@@ -139,32 +272,49 @@ func (s *StateService) Update(ctx context.Context, id string, input UpdateStateI
 		return nil, fmt.Errorf("failed to update state: %w", err)
 	}
 
-	if s.vectorRepo != nil {
-		if state.Status == domain.StatusArchived {
+	if state.Status == domain.StatusArchived {
+		if s.vectorRepo != nil {
 			_ = s.vectorRepo.Delete(ctx, state.ID)
-		} else {
-			metadata := map[string]string{
-				"type":       "state",
-				"project_id": state.ProjectID,
-				"state_type": string(state.Type),
-				"status":     string(state.Status),
-				"priority":   state.Priority.String(),
-			}
-			_ = s.vectorRepo.Upsert(ctx, state.ID, state.Title+"\n"+state.Description, metadata)
 		}
+	} else {
+		s.indexState(ctx, state)
 	}
 
 	return state, nil
 }
 
+// indexState はStateをベクトルインデックスに登録・更新する。
+func (s *StateService) indexState(ctx context.Context, state *domain.State) {
+	if s.vectorRepo == nil {
+		return
+	}
+	metadata := map[string]string{
+		"type":       "state",
+		"project_id": state.ProjectID,
+		"state_type": string(state.Type),
+		"status":     string(state.Status),
+		"priority":   state.Priority.String(),
+	}
+	_ = s.vectorRepo.Upsert(ctx, state.ID, state.Title+"\n"+state.Description, metadata)
+}
+
 // ArchiveInput はStateアーカイブ時の入力パラメータ。
 type ArchiveInput struct {
-	Resolution   string
-	StockSummary string // Stockに転記する要約（空の場合は転記しない）
+	Resolution     string
+	StockSummary   string // Stockに転記する学び（空の場合は DraftLearnings が true のときのみ下書きを生成して転記）
+	DraftLearnings bool   // 学びを下書きしてStockに転記する（LLMが利用できない場合はテンプレート）
+	SaveCategory   string // 転記先のカテゴリ（デフォルト: management）
+	Priority       string // 転記時の優先度（デフォルト: P2）
+}
+
+// ArchiveResult はアーカイブの結果。
+type ArchiveResult struct {
+	State    *domain.State        `json:"state"`
+	Learning *domain.StockSummary `json:"learning,omitempty"` // 学びを転記した場合のみ
 }
 
-// Archive はStateをアーカイブする。
-func (s *StateService) Archive(ctx context.Context, id string, input ArchiveInput) (*domain.State, error) {
+// Archive はStateをアーカイブする。学びの転記が指定された場合はStockとして保存し、StateのReferencesに紐づける。
+func (s *StateService) Archive(ctx context.Context, id string, input ArchiveInput) (*ArchiveResult, error) {
 	state, err := s.stateRepo.Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -174,10 +324,36 @@ func (s *StateService) Archive(ctx context.Context, id string, input ArchiveInpu
 		return nil, domain.ErrArchived
 	}
 
+	saveLearnings := input.StockSummary != "" || input.DraftLearnings
+	if saveLearnings {
+		if input.SaveCategory == "" {
+			input.SaveCategory = string(domain.CategoryManagement)
+		}
+		if !learningCategories[domain.StockCategory(input.SaveCategory)] {
+			return nil, fmt.Errorf("%w: learnings cannot be saved as %s", domain.ErrInvalidCategory, input.SaveCategory)
+		}
+	}
+
 	now := time.Now()
 	state.Archive(input.Resolution, now)
 
+	result := &ArchiveResult{State: state}
+	var learning *domain.Stock
+	if saveLearnings {
+		if learning, err = s.saveLearnings(ctx, state, input); err != nil {
+			return nil, fmt.Errorf("failed to save learnings: %w", err)
+		}
+		summary := learning.ToSummary()
+		result.Learning = &summary
+	}
+
 	if err := s.stateRepo.Update(ctx, state); err != nil {
+		// アーカイブできなかった場合、転記した学びは紐づけ先がないため残さない
+		if learning != nil {
+			if discardErr := s.stockService.discard(ctx, learning); discardErr != nil {
+				return nil, fmt.Errorf("failed to archive state: %w (and failed to remove stock %s: %v)", err, learning.ID, discardErr)
+			}
+		}
 		return nil, fmt.Errorf("failed to archive state: %w", err)
 	}
 
@@ -186,7 +362,7 @@ func (s *StateService) Archive(ctx context.Context, id string, input ArchiveInpu
 		_ = s.vectorRepo.Delete(ctx, state.ID)
 	}
 
-	return state, nil
+	return result, nil
 }
 
 // List はプロジェクト内のStateを一覧取得する。
@@ -200,13 +376,130 @@ func (s *StateService) ListSummary(ctx context.Context, projectID string, opts *
 	if err != nil {
 		return nil, err
 	}
-	summaries := make([]domain.StateSummary, 0, len(states))
+	return s.toSummaries(states, time.Now()), nil
+}
+
+// StateSummaryPage はStateのサマリビューの1ページ。
+type StateSummaryPage struct {
+	States     []domain.StateSummary
+	Total      int    // 条件に一致する全件数
+	NextCursor string // 次のページのカーソル（最後のページでは空）
+}
+
+// ListSummaryPage はプロジェクト内のStateをサマリビューでページ単位に一覧取得する。
+func (s *StateService) ListSummaryPage(ctx context.Context, projectID string, opts *repository.StateListOptions, page PageRequest) (*StateSummaryPage, error) {
+	if opts == nil {
+		opts = &repository.StateListOptions{}
+	}
+	key := cursorKey("state-list", projectID, stateListKey(opts))
+	offset, err := decodeCursor(page.Cursor, key)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageLimit(page.Limit, defaultListLimit)
+
+	total, err := s.stateRepo.Count(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
+	paged := *opts
+	paged.Offset = offset
+	paged.Limit = limit
+	summaries, err := s.ListSummary(ctx, projectID, &paged)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateSummaryPage{
+		States:     summaries,
+		Total:      total,
+		NextCursor: nextCursor(offset, len(summaries), total, key),
+	}, nil
+}
+
+// stateListKey は一覧取得条件をカーソルの指紋用の文字列にする。
+func stateListKey(opts *repository.StateListOptions) string {
+	var stateType, status, priority, assignee string
+	if opts.Type != nil {
+		stateType = string(*opts.Type)
+	}
+	if opts.Status != nil {
+		status = string(*opts.Status)
+	}
+	if opts.Priority != nil {
+		priority = opts.Priority.String()
+	}
+	if opts.Assignee != nil {
+		assignee = *opts.Assignee
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%t|%s", stateType, status, priority, assignee, opts.IncludeArchived, tagFilterKey(opts.Tags, opts.TagMatch))
+}
+
+// Overdue は期日またはSLAの解決期限を過ぎた未解決のStateを、期限の古い順に返す。
+func (s *StateService) Overdue(ctx context.Context, projectID string) ([]domain.StateSummary, error) {
+	states, err := s.stateRepo.List(ctx, projectID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	type overdueState struct {
+		summary  domain.StateSummary
+		deadline time.Time
+	}
+	var overdue []overdueState
 	for _, state := range states {
-		summaries = append(summaries, state.ToSummary())
+		if !state.IsOpen() {
+			continue
+		}
+		summary := s.summarize(state, now)
+		var deadline time.Time
+		if state.IsOverdue(now) {
+			deadline = *state.DueAt
+		}
+		if summary.SLA != nil && summary.SLA.Breached {
+			if deadline.IsZero() || summary.SLA.Deadline.Before(deadline) {
+				deadline = summary.SLA.Deadline
+			}
+		}
+		if deadline.IsZero() {
+			continue
+		}
+		overdue = append(overdue, overdueState{summary: summary, deadline: deadline})
+	}
+
+	sort.Slice(overdue, func(i, j int) bool {
+		if !overdue[i].deadline.Equal(overdue[j].deadline) {
+			return overdue[i].deadline.Before(overdue[j].deadline)
+		}
+		return overdue[i].summary.Priority < overdue[j].summary.Priority
+	})
+
+	summaries := make([]domain.StateSummary, 0, len(overdue))
+	for _, o := range overdue {
+		summaries = append(summaries, o.summary)
 	}
 	return summaries, nil
 }
 
+// summarize はStateのサマリビューを生成し、適用されるSLAの達成状況を付与する。
+func (s *StateService) summarize(state *domain.State, now time.Time) domain.StateSummary {
+	summary := state.ToSummary()
+	if policy, ok := s.slaPolicies.Match(state); ok {
+		status := policy.Evaluate(state, now)
+		summary.SLA = &status
+	}
+	return summary
+}
+
+func (s *StateService) toSummaries(states []*domain.State, now time.Time) []domain.StateSummary {
+	summaries := make([]domain.StateSummary, 0, len(states))
+	for _, state := range states {
+		summaries = append(summaries, s.summarize(state, now))
+	}
+	return summaries
+}
+
 // Search はセマンティック検索でStateを検索する。
 func (s *StateService) Search(ctx context.Context, query string, limit int, projectID string) ([]*domain.State, error) {
 	if s.vectorRepo != nil {
@@ -242,49 +535,54 @@ func (s *StateService) SearchSummary(ctx context.Context, query string, limit in
 	if err != nil {
 		return nil, err
 	}
-	summaries := make([]domain.StateSummary, 0, len(states))
-	for _, state := range states {
-		summaries = append(summaries, state.ToSummary())
-	}
-	return summaries, nil
+	return s.toSummaries(states, time.Now()), nil
 }
 
-func (s *StateService) fallbackSearch(ctx context.Context, query string, limit int, projectID string) ([]*domain.State, error) {
-	states, err := s.stateRepo.List(ctx, projectID, nil)
+// SearchSummaryPage はセマンティック検索でStateをサマリビューでページ単位に検索する。
+// 関連度順のヒット（最大 maxSearchHits 件）をタグで絞り込んでページングする。
+func (s *StateService) SearchSummaryPage(ctx context.Context, query string, projectID string, filter TagFilter, page PageRequest) (*StateSummaryPage, error) {
+	key := cursorKey("state-search", projectID, query, tagFilterKey(filter.Tags, filter.Match))
+	offset, err := decodeCursor(page.Cursor, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list states for fallback search: %w", err)
+		return nil, err
 	}
+	limit := pageLimit(page.Limit, defaultSearchLimit)
 
-	matched := make([]*domain.State, 0, len(states))
-	for _, state := range states {
-		if state.Status == domain.StatusArchived {
-			continue
-		}
-		if matchesQuery(query, state.Title, state.Description, joinTags(state.Tags)) {
-			matched = append(matched, state)
-		}
+	states, err := s.Search(ctx, query, maxSearchHits, projectID)
+	if err != nil {
+		return nil, err
 	}
+	states = slices.DeleteFunc(states, func(state *domain.State) bool { return !filter.matches(state.Tags) })
+	start, end := pageBounds(offset, limit, len(states))
+	summaries := s.toSummaries(states[start:end], time.Now())
+
+	return &StateSummaryPage{
+		States:     summaries,
+		Total:      len(states),
+		NextCursor: nextCursor(offset, len(summaries), len(states), key),
+	}, nil
+}
 
-	sort.Slice(matched, func(i, j int) bool {
-		if matched[i].Priority != matched[j].Priority {
-			return matched[i].Priority < matched[j].Priority
-		}
-		return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
-	})
-
+// fallbackSearch はベクトル検索が使えない場合にリポジトリの全文検索でStateを検索する。
+func (s *StateService) fallbackSearch(ctx context.Context, query string, limit int, projectID string) ([]*domain.State, error) {
 	if limit <= 0 {
 		limit = 10
 	}
-	if len(matched) > limit {
-		matched = matched[:limit]
+	hits, err := s.stateRepo.Search(ctx, projectID, query, &repository.StateListOptions{Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search states: %w", err)
 	}
 
-	return matched, nil
+	states := make([]*domain.State, 0, len(hits))
+	for _, hit := range hits {
+		states = append(states, hit.State)
+	}
+	return states, nil
 }
 
 func isValidStateType(t domain.StateType) bool {
 	switch t {
-	case domain.StateTypeTask, domain.StateTypeIssue, domain.StateTypeIncident, domain.StateTypeChange:
+	case domain.StateTypeTask, domain.StateTypeIssue, domain.StateTypeIncident, domain.StateTypeChange, domain.StateTypeProblem:
 		return true
 	default:
 		return false
@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/haconeco/project-information-manager/internal/config"
 	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/llm"
 	"github.com/haconeco/project-information-manager/internal/repository"
 )
 
@@ -58,6 +62,12 @@ func (f *fakeStateRepo) List(ctx context.Context, projectID string, opts *reposi
 			if opts.Priority != nil && state.Priority != *opts.Priority {
 				continue
 			}
+			if opts.Assignee != nil && state.Assignee != *opts.Assignee {
+				continue
+			}
+			if !domain.MatchTags(state.Tags, opts.Tags, opts.TagMatch) {
+				continue
+			}
 			if !opts.IncludeArchived && state.Status == domain.StatusArchived {
 				continue
 			}
@@ -66,9 +76,73 @@ func (f *fakeStateRepo) List(ctx context.Context, projectID string, opts *reposi
 		}
 		out = append(out, cloneState(state))
 	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority < out[j].Priority
+		}
+		if !out[i].UpdatedAt.Equal(out[j].UpdatedAt) {
+			return out[i].UpdatedAt.After(out[j].UpdatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if opts != nil {
+		out = out[min(opts.Offset, len(out)):]
+		if opts.Limit > 0 && opts.Limit < len(out) {
+			out = out[:opts.Limit]
+		}
+	}
 	return out, nil
 }
 
+func (f *fakeStateRepo) Count(ctx context.Context, projectID string, opts *repository.StateListOptions) (int, error) {
+	var unpaged *repository.StateListOptions
+	if opts != nil {
+		o := *opts
+		o.Limit, o.Offset = 0, 0
+		unpaged = &o
+	}
+	states, err := f.List(ctx, projectID, unpaged)
+	return len(states), err
+}
+
+func (f *fakeStateRepo) TagCounts(ctx context.Context, projectID string, opts *repository.StateListOptions) ([]domain.TagCount, error) {
+	states, err := f.List(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
+	tagLists := make([][]string, 0, len(states))
+	for _, state := range states {
+		tagLists = append(tagLists, state.Tags)
+	}
+	return domain.CountTags(tagLists), nil
+}
+
+func (f *fakeStateRepo) Search(ctx context.Context, projectID string, query string, opts *repository.StateListOptions) ([]repository.StateSearchHit, error) {
+	var unpaged *repository.StateListOptions
+	if opts != nil {
+		o := *opts
+		o.Limit, o.Offset = 0, 0
+		unpaged = &o
+	}
+	states, err := f.List(ctx, projectID, unpaged)
+	if err != nil {
+		return nil, err
+	}
+	var hits []repository.StateSearchHit
+	for _, state := range states {
+		if matchesQuery(query, state.Title, state.Description, state.Resolution, joinTags(state.Tags)) {
+			hits = append(hits, repository.StateSearchHit{State: state})
+		}
+	}
+	if opts != nil {
+		hits = hits[min(opts.Offset, len(hits)):]
+		if opts.Limit > 0 && opts.Limit < len(hits) {
+			hits = hits[:opts.Limit]
+		}
+	}
+	return hits, nil
+}
+
 type fakeVectorRepo struct {
 	results   []repository.SearchResult
 	searchErr error
@@ -152,6 +226,22 @@ func cloneState(s *domain.State) *domain.State {
 	if s.References != nil {
 		copy.References = append([]string(nil), s.References...)
 	}
+	if s.Incident != nil {
+		incident := *s.Incident
+		incident.Timeline = append([]domain.TimelineEntry(nil), s.Incident.Timeline...)
+		copy.Incident = &incident
+	}
+	if s.Problem != nil {
+		problem := *s.Problem
+		problem.IncidentIDs = append([]string(nil), s.Problem.IncidentIDs...)
+		copy.Problem = &problem
+	}
+	if s.Change != nil {
+		change := *s.Change
+		change.Approvers = append([]string(nil), s.Change.Approvers...)
+		change.Approvals = append([]domain.Approval(nil), s.Change.Approvals...)
+		copy.Change = &change
+	}
 	return &copy
 }
 
@@ -159,10 +249,15 @@ func TestStateServiceCreateUpdateArchive(t *testing.T) {
 	repo := newFakeStateRepo()
 	svc := NewStateService(repo, nil)
 
-	_, err := svc.Create(context.Background(), CreateStateInput{Type: "invalid", Priority: "P1"})
+	_, err := svc.Create(context.Background(), CreateStateInput{ProjectID: "proj-1", Type: "invalid", Priority: "P1"})
 	if err != domain.ErrInvalidType {
 		t.Fatalf("expected ErrInvalidType, got %v", err)
 	}
+	for _, projectID := range []string{"", "..", "../../x"} {
+		if _, err := svc.Create(context.Background(), CreateStateInput{ProjectID: projectID, Type: "task", Priority: "P1"}); err != domain.ErrInvalidProjectID {
+			t.Fatalf("project %q: expected ErrInvalidProjectID, got %v", projectID, err)
+		}
+	}
 
 	created, err := svc.Create(context.Background(), CreateStateInput{
 		ProjectID:   "proj-1",
@@ -201,13 +296,17 @@ func TestStateServiceCreateUpdateArchive(t *testing.T) {
 		t.Fatalf("expected priority P0, got %s", updated.Priority.String())
 	}
 
-	archived, err := svc.Archive(context.Background(), created.ID, ArchiveInput{Resolution: "done"})
+	result, err := svc.Archive(context.Background(), created.ID, ArchiveInput{Resolution: "done"})
 	if err != nil {
 		t.Fatalf("archive: %v", err)
 	}
+	archived := result.State
 	if archived.Status != domain.StatusArchived || archived.ArchivedAt == nil {
 		t.Fatalf("expected archived state, got %v", archived.Status)
 	}
+	if result.Learning != nil {
+		t.Fatalf("expected no learnings without request, got %+v", result.Learning)
+	}
 }
 
 func TestStateServiceUpdateArchived(t *testing.T) {
@@ -284,6 +383,210 @@ func TestStateServiceListSummary(t *testing.T) {
 	}
 }
 
+func TestStateServiceOverdue(t *testing.T) {
+	repo := newFakeStateRepo()
+	now := time.Now()
+	pastDue := now.Add(-time.Hour)
+	futureDue := now.Add(time.Hour)
+	repo.states["STA-TASK-001"] = &domain.State{
+		ID: "STA-TASK-001", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "past due", DueAt: &pastDue, CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-TASK-002"] = &domain.State{
+		ID: "STA-TASK-002", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "not yet due", DueAt: &futureDue, CreatedAt: now, UpdatedAt: now,
+	}
+	repo.states["STA-INCIDENT-003"] = &domain.State{
+		ID: "STA-INCIDENT-003", ProjectID: "proj-1", Type: domain.StateTypeIncident, Status: domain.StatusInProgress,
+		Priority: domain.PriorityP0, Title: "sla breached", CreatedAt: now.Add(-5 * time.Hour), UpdatedAt: now,
+	}
+	repo.states["STA-TASK-004"] = &domain.State{
+		ID: "STA-TASK-004", ProjectID: "proj-1", Type: domain.StateTypeTask, Status: domain.StatusResolved,
+		Priority: domain.PriorityP2, Title: "resolved", DueAt: &pastDue, CreatedAt: now, UpdatedAt: now,
+	}
+
+	svc := NewStateService(repo, nil)
+	p0 := domain.PriorityP0
+	svc.SetSLAPolicies(domain.SLAPolicies{{Type: domain.StateTypeIncident, Priority: &p0, ResolveWithin: 4 * time.Hour}})
+
+	overdue, err := svc.Overdue(context.Background(), "proj-1")
+	if err != nil {
+		t.Fatalf("overdue: %v", err)
+	}
+	if len(overdue) != 2 {
+		t.Fatalf("expected 2 overdue states, got %d", len(overdue))
+	}
+	// SLA期限（作成+4h = 1時間前より前）の方が古いため先頭になる
+	if overdue[0].ID != "STA-INCIDENT-003" || overdue[1].ID != "STA-TASK-001" {
+		t.Fatalf("unexpected overdue order: %s, %s", overdue[0].ID, overdue[1].ID)
+	}
+	if overdue[0].SLA == nil || !overdue[0].SLA.Breached {
+		t.Fatalf("expected SLA breach info on incident summary")
+	}
+
+	summaries, err := svc.ListSummary(context.Background(), "proj-1", nil)
+	if err != nil {
+		t.Fatalf("list summary: %v", err)
+	}
+	for _, summary := range summaries {
+		if summary.Type == domain.StateTypeTask && summary.SLA != nil {
+			t.Fatalf("expected no SLA on task without policy: %s", summary.ID)
+		}
+	}
+}
+
+func TestSLAPoliciesFromConfig(t *testing.T) {
+	policies := slaPoliciesFromConfig(config.SLAConfig{Policies: []config.SLAPolicyConfig{
+		{Type: "incident", Priority: "P0", ResolveWithin: "4h"},
+		{Type: "task", ResolveWithin: "72h"},
+		{Type: "invalid", ResolveWithin: "1h"},
+		{Priority: "P9", ResolveWithin: "1h"},
+		{ResolveWithin: "soon"},
+	}})
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 valid policies, got %d", len(policies))
+	}
+	if policies[0].Priority == nil || *policies[0].Priority != domain.PriorityP0 || policies[0].ResolveWithin != 4*time.Hour {
+		t.Fatalf("unexpected first policy: %+v", policies[0])
+	}
+	if policies[1].Priority != nil || policies[1].Type != domain.StateTypeTask {
+		t.Fatalf("unexpected second policy: %+v", policies[1])
+	}
+}
+
 func ptrString(v string) *string {
 	return &v
 }
+
+func TestStateServiceCreateClassifiesTypeWithLLM(t *testing.T) {
+	ctx := context.Background()
+	svc := NewStateService(newFakeStateRepo(), nil)
+
+	// LLM未設定の場合は種別の省略を許容しない
+	if _, err := svc.Create(ctx, CreateStateInput{ProjectID: "proj-1", Priority: "P1", Title: "Production incident"}); !errors.Is(err, domain.ErrInvalidType) {
+		t.Fatalf("expected ErrInvalidType without llm, got %v", err)
+	}
+
+	client := llm.NewClient(llm.NewStubProvider())
+	svc.SetTextGenerator(client)
+	state, err := svc.Create(ctx, CreateStateInput{
+		ProjectID:   "proj-1",
+		Priority:    "P1",
+		Title:       "Production incident",
+		Description: "API returns 500 since the incident started",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if state.Type != domain.StateTypeIncident {
+		t.Fatalf("expected incident, got %s", state.Type)
+	}
+	if usage := client.Usage(); usage.Calls != 1 || usage.InputTokens == 0 {
+		t.Fatalf("expected one tracked call, got %+v", usage)
+	}
+
+	// 明示された種別は分類しない
+	if _, err := svc.Create(ctx, CreateStateInput{ProjectID: "proj-1", Type: "task", Priority: "P2", Title: "Write docs"}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if usage := client.Usage(); usage.Calls != 1 {
+		t.Fatalf("expected explicit type to skip llm, got %+v", usage)
+	}
+}
+
+func TestStateServiceArchiveSavesLearnings(t *testing.T) {
+	ctx := context.Background()
+	stockService := NewStockService(repository.NewFileStockRepository(t.TempDir()), nil)
+	svc := NewStateService(newFakeStateRepo(), nil)
+	svc.SetStockService(stockService)
+
+	created, err := svc.Create(ctx, CreateStateInput{ProjectID: "proj-1", Type: "issue", Priority: "P2", Title: "Flaky test"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Archive(ctx, created.ID, ArchiveInput{StockSummary: "x", SaveCategory: "requirement"}); !errors.Is(err, domain.ErrInvalidCategory) {
+		t.Fatalf("expected invalid category, got %v", err)
+	}
+
+	result, err := svc.Archive(ctx, created.ID, ArchiveInput{Resolution: "Fixed clock", StockSummary: "Freeze time in tests."})
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if result.Learning == nil || result.Learning.Category != domain.CategoryManagement || result.Learning.Priority != domain.PriorityP2 {
+		t.Fatalf("expected learnings saved to management, got %+v", result.Learning)
+	}
+	stock, err := stockService.Get(ctx, "", result.Learning.ID)
+	if err != nil {
+		t.Fatalf("get learnings: %v", err)
+	}
+	if stock.Content != "Freeze time in tests." || !containsString(stock.References, created.ID) {
+		t.Fatalf("unexpected learnings stock: %+v", stock)
+	}
+	if !containsString(result.State.References, stock.ID) {
+		t.Fatalf("expected state to reference learnings, got %v", result.State.References)
+	}
+}
+
+func TestStateServiceArchiveRollsBackLearnings(t *testing.T) {
+	ctx := context.Background()
+	stockRepo := repository.NewFileStockRepository(t.TempDir())
+	stateRepo := &failingUpdateStateRepo{fakeStateRepo: newFakeStateRepo()}
+	svc := NewStateService(stateRepo, nil)
+	svc.SetStockService(NewStockService(stockRepo, nil))
+	stateRepo.states["STA-ISSUE-001"] = &domain.State{ID: "STA-ISSUE-001", ProjectID: "proj-1", Type: domain.StateTypeIssue,
+		Status: domain.StatusResolved, Priority: domain.PriorityP2, Title: "Flaky test"}
+
+	if _, err := svc.Archive(ctx, "STA-ISSUE-001", ArchiveInput{StockSummary: "Freeze time in tests."}); err == nil {
+		t.Fatal("expected error when the state cannot be archived")
+	}
+	// アーカイブできなかったStateの学びは残さない
+	stocks, err := stockRepo.List(ctx, "proj-1", &repository.StockListOptions{IncludeDeleted: true})
+	if err != nil || len(stocks) != 0 {
+		t.Fatalf("expected orphaned learnings to be removed, got %d stocks (%v)", len(stocks), err)
+	}
+}
+
+func TestStateServiceCreateDetectsDuplicateWithVector(t *testing.T) {
+	repo := newFakeStateRepo()
+	now := time.Now()
+	repo.states["STA-ISSUE-001"] = &domain.State{
+		ID: "STA-ISSUE-001", ProjectID: "proj-1", Type: domain.StateTypeIssue, Status: domain.StatusOpen,
+		Priority: domain.PriorityP2, Title: "ログイン不可", CreatedAt: now, UpdatedAt: now,
+	}
+	archivedAt := now
+	repo.states["STA-ISSUE-002"] = &domain.State{
+		ID: "STA-ISSUE-002", ProjectID: "proj-1", Type: domain.StateTypeIssue, Status: domain.StatusArchived,
+		Priority: domain.PriorityP2, Title: "ログイン不可", CreatedAt: now, UpdatedAt: now, ArchivedAt: &archivedAt,
+	}
+	vector := &fakeVectorRepo{results: []repository.SearchResult{
+		{ID: "STA-ISSUE-002", Similarity: 0.98},
+		{ID: "STA-ISSUE-001", Similarity: 0.93},
+		{ID: "STA-TASK-009", Similarity: 0.95},
+	}}
+	svc := NewStateService(repo, vector)
+
+	result, err := svc.CreateWithDuplicateCheck(context.Background(), CreateStateInput{
+		ProjectID:   "proj-1",
+		Type:        "issue",
+		Priority:    "P1",
+		Title:       "サインインできない",
+		Description: "SSO経由でサインインできない",
+		Tags:        []string{"sso"},
+		OnDuplicate: "merge",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !result.Merged || result.State.ID != "STA-ISSUE-001" {
+		t.Fatalf("expected merge into open STA-ISSUE-001, got %+v", result)
+	}
+	if len(result.Duplicates) != 1 || result.Duplicates[0].Method != "vector" {
+		t.Fatalf("expected only open vector duplicate, got %+v", result.Duplicates)
+	}
+	if result.State.Priority != domain.PriorityP1 || !containsString(result.State.Tags, "sso") {
+		t.Fatalf("unexpected merged state: %+v", result.State)
+	}
+	if !strings.Contains(result.State.Description, "SSO経由") {
+		t.Fatalf("expected merged description, got %q", result.State.Description)
+	}
+}
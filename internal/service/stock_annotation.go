@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/llm"
+)
+
+const (
+	// stockSummaryMaxChars はStockの1行サマリの最大文字数。
+	stockSummaryMaxChars = 120
+	// maxSuggestedTags はタグ候補の最大件数。
+	maxSuggestedTags = 5
+)
+
+// keywordStopWords はキーワード抽出で除外する英語の機能語・汎用語。
+var keywordStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "that": true, "this": true,
+	"are": true, "was": true, "were": true, "will": true, "should": true, "must": true, "can": true,
+	"not": true, "all": true, "any": true, "into": true, "when": true, "then": true, "than": true,
+	"each": true, "per": true, "via": true, "use": true, "uses": true, "used": true, "using": true,
+	"has": true, "have": true, "been": true, "its": true, "our": true, "your": true, "their": true,
+	"todo": true, "http": true, "https": true, "www": true, "com": true,
+}
+
+// annotateStock はStockの1行サマリとタグ候補を生成する。
+// LLM（サーバー側の設定、またはMCPクライアントのサンプリング）が利用できる場合はLLMを用い、
+// 利用できない・失敗した場合は本文からの抽出で代替する。
+func (s *StockService) annotateStock(ctx context.Context, stock *domain.Stock) {
+	text := stock.Title + "\n" + stock.Content
+	generator := generatorFor(ctx, s.generator)
+
+	var summary string
+	if generator != nil {
+		generated, err := generator.Summarize(ctx, text, stockSummaryMaxChars)
+		if err != nil {
+			warnGenerationError("failed to summarize stock with llm, fallback to extraction", err, "id", stock.ID)
+		}
+		summary = generated
+	}
+	if summary == "" {
+		summary = extractiveSummary(stock.Title, stock.Content, stockSummaryMaxChars)
+	}
+	stock.Summary = summary
+
+	// 既存タグと重複した分を除いても上限まで埋まるよう多めに抽出する
+	limit := maxSuggestedTags + len(stock.Tags)
+	var keywords []string
+	if generator != nil {
+		generated, err := generator.ExtractKeywords(ctx, text, limit)
+		if err != nil {
+			warnGenerationError("failed to extract stock keywords with llm, fallback to extraction", err, "id", stock.ID)
+		}
+		keywords = generated
+	}
+	if len(keywords) == 0 {
+		keywords = extractKeywords(stock.Title, stock.Content, limit)
+	}
+	stock.SuggestedTags = suggestTags(keywords, stock.Tags)
+}
+
+// suggestTags はキーワードのうち既存タグに含まれないものを最大 maxSuggestedTags 件返す。
+func suggestTags(keywords []string, tags []string) []string {
+	existing := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		existing[strings.ToLower(tag)] = true
+	}
+	suggested := make([]string, 0, maxSuggestedTags)
+	for _, keyword := range keywords {
+		if existing[strings.ToLower(keyword)] {
+			continue
+		}
+		existing[strings.ToLower(keyword)] = true
+		suggested = append(suggested, keyword)
+		if len(suggested) == maxSuggestedTags {
+			break
+		}
+	}
+	if len(suggested) == 0 {
+		return nil
+	}
+	return suggested
+}
+
+// extractiveSummary は本文の最初の見出しと冒頭の文から1行サマリを組み立てる。
+// 見出しがタイトルと同じ場合は省略し、コードブロック・表・箇条書きの記号は読み飛ばす。
+func extractiveSummary(title string, content string, maxChars int) string {
+	var heading string
+	var sentences []string
+	inCode := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || line == "" || strings.HasPrefix(line, "|") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if heading == "" && len(sentences) == 0 {
+				heading = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			}
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, ">-*+ "))
+		sentences = append(sentences, splitSentences(line)...)
+		if utf8.RuneCountInString(joinSentences(sentences)) >= maxChars {
+			break
+		}
+	}
+
+	var parts []string
+	if heading != "" && !strings.EqualFold(heading, strings.TrimSpace(title)) {
+		parts = append(parts, heading+":")
+	}
+	for _, sentence := range sentences {
+		candidate := joinSentences(append(parts, sentence))
+		if len(parts) > 0 && utf8.RuneCountInString(candidate) > maxChars {
+			break
+		}
+		parts = append(parts, sentence)
+	}
+	summary := joinSentences(parts)
+	if summary == "" {
+		summary = strings.TrimSpace(title)
+	}
+	return llm.TruncateRunes(summary, maxChars)
+}
+
+// joinSentences は文を連結する。全角の文末記号で終わる文の後には空白を入れない。
+func joinSentences(sentences []string) string {
+	var b strings.Builder
+	for i, sentence := range sentences {
+		if i > 0 {
+			last, _ := utf8.DecodeLastRuneInString(sentences[i-1])
+			if last < utf8.RuneSelf {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(sentence)
+	}
+	return b.String()
+}
+
+// splitSentences は行を文末記号（。．.!?！？）で文に分割する。
+func splitSentences(line string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(line)
+	for i, r := range runes {
+		isEnd := r == '。' || r == '．' || r == '!' || r == '?' || r == '！' || r == '？'
+		// 英文のピリオドは後続が空白・行末の場合のみ文末とみなす（バージョン番号等を分割しない）
+		if r == '.' && (i == len(runes)-1 || unicode.IsSpace(runes[i+1])) {
+			isEnd = true
+		}
+		if isEnd {
+			if sentence := strings.TrimSpace(string(runes[start : i+1])); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// extractKeywords はタイトルと本文から頻出語を最大 limit 件抽出する。
+// 英数字の語（3文字以上）、カタカナ語（3文字以上）、漢字の連続（2文字以上）を候補とし、
+// タイトルに含まれる語を優先する。
+func extractKeywords(title string, content string, limit int) []string {
+	type candidate struct {
+		score int
+		first int
+	}
+	candidates := make(map[string]*candidate)
+	position := 0
+	add := func(text string, weight int) {
+		for _, term := range keywordTerms(text) {
+			c, ok := candidates[term]
+			if !ok {
+				c = &candidate{first: position}
+				candidates[term] = c
+			}
+			c.score += weight
+			position++
+		}
+	}
+	add(title, 3)
+	add(stripCodeBlocks(content), 1)
+
+	terms := make([]string, 0, len(candidates))
+	for term, c := range candidates {
+		// 1回しか現れない本文中の語はノイズとして扱う
+		if c.score < 2 {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		ci, cj := candidates[terms[i]], candidates[terms[j]]
+		if ci.score != cj.score {
+			return ci.score > cj.score
+		}
+		return ci.first < cj.first
+	})
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms
+}
+
+// keywordTerms はテキストを文字種の連続で区切り、キーワード候補を返す。
+func keywordTerms(text string) []string {
+	var terms []string
+	var current []rune
+	var kind rune
+	flush := func() {
+		term := strings.ToLower(string(current))
+		n := len(current)
+		current = current[:0]
+		switch kind {
+		case 'a':
+			term = strings.Trim(term, "-_")
+			n = len(term)
+			if n >= 3 && !keywordStopWords[term] && strings.IndexFunc(term, unicode.IsLetter) >= 0 {
+				terms = append(terms, term)
+			}
+		case 'k':
+			if n >= 3 {
+				terms = append(terms, term)
+			}
+		case 'h':
+			if n >= 2 {
+				terms = append(terms, term)
+			}
+		}
+	}
+	for _, r := range text {
+		k := termKind(r)
+		if k != kind && len(current) > 0 {
+			flush()
+		}
+		kind = k
+		if k != 0 {
+			current = append(current, r)
+		}
+	}
+	if len(current) > 0 {
+		flush()
+	}
+	return terms
+}
+
+// termKind は文字種を返す（'a': 英数字・ハイフン, 'k': カタカナ, 'h': 漢字, 0: 区切り）。
+func termKind(r rune) rune {
+	switch {
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'):
+		return 'a'
+	case unicode.Is(unicode.Katakana, r) || r == 'ー':
+		return 'k'
+	case unicode.Is(unicode.Han, r):
+		return 'h'
+	default:
+		return 0
+	}
+}
+
+func stripCodeBlocks(content string) string {
+	var b strings.Builder
+	inCode := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
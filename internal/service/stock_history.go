@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// Stockの変更履歴に記録する操作
+const (
+	stockActionCreate       = "create"
+	stockActionUpdate       = "update"
+	stockActionRecategorize = "recategorize"
+	stockActionDelete       = "delete"
+	stockActionRestore      = "restore"
+	stockActionPurge        = "purge"
+	stockActionRenameTags   = "rename-tags"
+)
+
+type actorKey struct{}
+
+// WithActor は操作しているエージェント・ユーザーの名前をコンテキストに設定する。
+// Stockの変更履歴に操作者として記録する。
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom はコンテキストに設定された操作者を返す。未設定の場合は空文字を返す。
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// SetHistory はStockの変更を記録する履歴（git）を設定する。未設定の場合は記録しない。
+func (s *StockService) SetHistory(history repository.StockHistory) {
+	s.history = history
+}
+
+// withStockLock はStockの書き込みと変更履歴の記録を、Stockリポジトリの書き込みのロックを保持したまま行う。
+// 記録までの間に他の書き込みが入ると、別の変更がこのコミットに混ざったり記録から漏れたりするため。
+// ロックを提供しないリポジトリ（stock.store=sqlite）ではそのまま fn を実行する。
+func (s *StockService) withStockLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	if files, ok := s.stockRepo.(repository.StockFileSource); ok {
+		return files.WithLock(ctx, fn)
+	}
+	return fn(ctx)
+}
+
+// recordChange はStockの変更を履歴に記録する。記録の失敗は変更自体を妨げないため警告にとどめる。
+// stock のファイルに加えて、others のファイル（再分類前の管理番号など）も同じコミットに含める。
+// withStockLock の中で呼び出す。
+func (s *StockService) recordChange(ctx context.Context, stock *domain.Stock, action string, others ...*domain.Stock) {
+	if s.history == nil {
+		return
+	}
+	paths := []string{repository.StockFilePath(stock)}
+	for _, other := range others {
+		paths = append(paths, repository.StockFilePath(other))
+	}
+	commit := repository.StockCommit{ProjectID: stock.ProjectID, StockID: stock.ID, Action: action, Actor: actorFrom(ctx), Paths: paths}
+	if err := s.history.Record(ctx, commit); err != nil {
+		slog.Warn("failed to record stock history", "project_id", stock.ProjectID, "id", stock.ID, "action", action, "error", err)
+	}
+}
+
+// History はStockの変更履歴を新しい順に返す。再分類前の管理番号は転送先に解決せず、その管理番号の履歴を返す。
+// 完全削除されたStockも projectID を指定すれば履歴を参照できる。履歴が無効な場合は domain.ErrHistoryDisabled を返す。
+func (s *StockService) History(ctx context.Context, projectID string, id string, limit int) ([]repository.StockRevision, error) {
+	if s.history == nil {
+		return nil, domain.ErrHistoryDisabled
+	}
+	stock, err := s.stockRepo.Get(ctx, projectID, id)
+	switch {
+	case err == nil:
+		projectID = stock.ProjectID
+	case errors.Is(err, domain.ErrNotFound) && projectID != "":
+		// 完全削除済み。ファイルはないが履歴は残っている
+	default:
+		return nil, err
+	}
+	revisions, err := s.history.History(ctx, projectID, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stock history: %w", err)
+	}
+	return revisions, nil
+}
+
+// StockSyncReport は SyncStocks の結果。
+type StockSyncReport struct {
+	*repository.StockSyncResult
+	Reconciled *repository.StockReconcileResult // 取り込んだStockファイルの変更と診断情報
+}
+
+// SyncStocks はStockディレクトリの未記録の変更を記録して上流の変更を取り込み（git pull）、
+// 取り込んだファイルを索引とベクトルインデックスへ反映する。競合した場合は取り込みを取り消し、競合したファイルを返す。
+func (s *Services) SyncStocks(ctx context.Context) (*StockSyncReport, error) {
+	if s.Stock.history == nil || s.StockWatcher == nil {
+		return nil, domain.ErrHistoryDisabled
+	}
+	// git add・commit・pull と取り込みの間に pim の書き込みが入らないよう、Stockのロックを保持したまま行う
+	report := &StockSyncReport{}
+	err := s.Stock.withStockLock(ctx, func(ctx context.Context) error {
+		result, err := s.Stock.history.Sync(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to sync stocks: %w", err)
+		}
+		report.StockSyncResult = result
+		reconciled, err := s.StockWatcher.Reconcile(ctx, "")
+		if err != nil {
+			return fmt.Errorf("failed to reconcile stock files: %w", err)
+		}
+		report.Reconciled = reconciled
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
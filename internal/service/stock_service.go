@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,29 +19,61 @@ import (
 type StockService struct {
 	stockRepo  repository.StockRepository
 	vectorRepo repository.VectorRepository
+	generator  TextGenerator
+	history    repository.StockHistory // Stockの変更履歴（stock.git が無効の場合は nil）
+
+	// deletedRetention は削除済みStockを完全削除するまでの保持期間。
+	deletedRetention time.Duration
 }
 
+// defaultDeletedRetention は削除済みStockの既定の保持期間（30日）。
+const defaultDeletedRetention = 30 * 24 * time.Hour
+
 // NewStockService は新しいStockServiceを生成する。
 func NewStockService(stockRepo repository.StockRepository, vectorRepo repository.VectorRepository) *StockService {
 	return &StockService{
-		stockRepo:  stockRepo,
-		vectorRepo: vectorRepo,
+		stockRepo:        stockRepo,
+		vectorRepo:       vectorRepo,
+		deletedRetention: defaultDeletedRetention,
 	}
 }
 
-// CreateInput はStock作成時の入力パラメータ。
+// SetTextGenerator はサマリ・タグ候補の生成に用いるLLMを設定する。
+func (s *StockService) SetTextGenerator(generator TextGenerator) {
+	s.generator = generator
+}
+
+// CreateStockInput はStock作成時の入力パラメータ。
 type CreateStockInput struct {
-	ProjectID  string
-	Category   string
-	Priority   string
-	Title      string
-	Content    string
-	Tags       []string
-	References []string
+	ProjectID   string
+	Category    string
+	Priority    string
+	Title       string
+	Content     string
+	Tags        []string
+	References  []string
+	OnDuplicate string // 重複の可能性がある場合の扱い: allow（デフォルト）, reject, merge
+}
+
+// CreateStockResult はStock作成の結果。
+type CreateStockResult struct {
+	Stock      *domain.Stock        // 作成した（merge時は統合先の）Stock
+	Merged     bool                 // 既存のStockに統合した場合 true
+	Duplicates []DuplicateCandidate // 重複の可能性がある既存のStock
 }
 
-// Create は新しいStockを作成する。
+// Create は新しいStockを作成する。重複候補が必要な場合は CreateWithDuplicateCheck を使う。
 func (s *StockService) Create(ctx context.Context, input CreateStockInput) (*domain.Stock, error) {
+	result, err := s.CreateWithDuplicateCheck(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return result.Stock, nil
+}
+
+// CreateWithDuplicateCheck は同一プロジェクト内の重複候補を確認したうえでStockを作成する。
+// 重複候補がある場合、OnDuplicate に従って作成・拒否・既存Stockへの統合を行う。
+func (s *StockService) CreateWithDuplicateCheck(ctx context.Context, input CreateStockInput) (*CreateStockResult, error) {
 	// バリデーション
 	category := domain.StockCategory(input.Category)
 	if !isValidCategory(category) {
@@ -50,24 +85,47 @@ func (s *StockService) Create(ctx context.Context, input CreateStockInput) (*dom
 		return nil, err
 	}
 
-	// ID生成
-	id := generateStockID(category)
+	policy, err := ParseDuplicatePolicy(input.OnDuplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := s.findDuplicates(ctx, input)
+	if len(duplicates) > 0 {
+		switch policy {
+		case DuplicateReject:
+			return nil, duplicateError(duplicates)
+		case DuplicateMerge:
+			stock, err := s.mergeInto(ctx, input.ProjectID, duplicates[0].ID, input, priority)
+			if err != nil {
+				return nil, err
+			}
+			return &CreateStockResult{Stock: stock, Merged: true, Duplicates: duplicates}, nil
+		}
+	}
 
 	now := time.Now()
 	stock := &domain.Stock{
-		ID:         id,
 		ProjectID:  input.ProjectID,
 		Category:   category,
 		Priority:   priority,
 		Title:      input.Title,
 		Content:    input.Content,
-		Tags:       input.Tags,
+		Tags:       domain.NormalizeTags(input.Tags),
 		References: input.References,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 	}
+	s.annotateStock(ctx, stock)
 
-	if err := s.stockRepo.Create(ctx, stock); err != nil {
+	err = s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.createWithNewID(ctx, stock); err != nil {
+			return err
+		}
+		s.recordChange(ctx, stock, stockActionCreate)
+		return nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create stock: %w", err)
 	}
 
@@ -79,36 +137,122 @@ func (s *StockService) Create(ctx context.Context, input CreateStockInput) (*dom
 			"category":   string(stock.Category),
 			"priority":   stock.Priority.String(),
 		}
-		if err := s.vectorRepo.Upsert(ctx, stock.ID, stock.Title+"\n"+stock.Content, metadata); err != nil {
+		if err := s.vectorRepo.Upsert(ctx, stockVectorID(stock.ProjectID, stock.ID), stock.Title+"\n"+stock.Content, metadata); err != nil {
 			// ベクトルインデックスのエラーは致命的ではない
 			fmt.Printf("warning: failed to index stock in vector DB: %v\n", err)
 		}
 	}
 
+	return &CreateStockResult{Stock: stock, Duplicates: duplicates}, nil
+}
+
+// findDuplicates は同一プロジェクト内で作成しようとしているStockと重複の可能性があるStockを返す。
+// 重複確認に失敗しても作成は妨げない。
+func (s *StockService) findDuplicates(ctx context.Context, input CreateStockInput) []DuplicateCandidate {
+	stocks, err := s.stockRepo.List(ctx, input.ProjectID, nil)
+	if err != nil {
+		slog.Warn("failed to list stocks for duplicate check", "error", err)
+		return nil
+	}
+	targets := make([]duplicateTarget, 0, len(stocks))
+	for _, stock := range stocks {
+		targets = append(targets, duplicateTarget{id: stock.ID, title: stock.Title})
+	}
+	return findDuplicates(ctx, s.vectorRepo, "stock", input.ProjectID, input.Title, input.Content, targets)
+}
+
+// mergeInto は作成しようとしたStockのタグ・参照・本文を既存のStockに統合する。
+// 優先度は高い方に揃える。
+func (s *StockService) mergeInto(ctx context.Context, projectID string, id string, input CreateStockInput, priority domain.Priority) (*domain.Stock, error) {
+	existing, err := s.stockRepo.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	content := mergeText(existing.Content, input.Content)
+	merged := min(existing.Priority, priority).String()
+	return s.Update(ctx, projectID, id, UpdateStockInput{
+		Content:    &content,
+		Priority:   &merged,
+		Tags:       mergeStrings(existing.Tags, input.Tags),
+		References: mergeStrings(existing.References, input.References),
+	})
+}
+
+// Get はプロジェクトと管理番号でStockを取得する。再分類前の管理番号は転送先のStockに解決する。
+// projectID が空の場合は全プロジェクトから探し、複数のプロジェクトに存在する場合は domain.ErrAmbiguousID を返す。
+// 削除済みのStockは domain.ErrDeleted を返す（restore で復元可能）。
+func (s *StockService) Get(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	stock, err := s.resolve(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if stock.IsDeleted() {
+		return nil, domain.ErrDeleted
+	}
 	return stock, nil
 }
 
-// Get は管理番号でStockを取得する。
-func (s *StockService) Get(ctx context.Context, id string) (*domain.Stock, error) {
-	return s.stockRepo.Get(ctx, id)
+// maxStockRedirects は再分類エイリアスをたどる最大回数。
+const maxStockRedirects = 10
+
+// resolve は再分類エイリアスをたどってStockを取得する。削除済みのStockもそのまま返す。
+// 転送先は転送元と同じプロジェクトから探す。
+func (s *StockService) resolve(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	for range maxStockRedirects {
+		stock, err := s.stockRepo.Get(ctx, projectID, id)
+		if err != nil {
+			return nil, err
+		}
+		if !stock.IsAlias() {
+			return stock, nil
+		}
+		projectID, id = stock.ProjectID, stock.RedirectTo
+	}
+	return nil, domain.ErrRedirectLoop
 }
 
 // UpdateStockInput はStock更新時の入力パラメータ。
+// Category を変更した場合は新しい管理番号を採番し、旧管理番号は転送用のエイリアスとして残す。
+// ExpectedUpdatedAt を指定した場合、保存されているStockの更新日時と一致しなければ domain.ErrConflict を返す
+// （読み込んでから更新するまでの間に、他のエージェントが更新していないことを確認する）。
 type UpdateStockInput struct {
-	Content    *string
-	Priority   *string
-	Tags       []string
-	References []string
+	Title             *string
+	Category          *string
+	Content           *string
+	Priority          *string
+	Tags              []string
+	References        []string
+	ExpectedUpdatedAt *time.Time
 }
 
-// Update はStockを更新する。
-func (s *StockService) Update(ctx context.Context, id string, input UpdateStockInput) (*domain.Stock, error) {
-	stock, err := s.stockRepo.Get(ctx, id)
+// Update はStockを更新する。再分類した場合、返却するStockの ID は新しい管理番号になる。
+func (s *StockService) Update(ctx context.Context, projectID string, id string, input UpdateStockInput) (*domain.Stock, error) {
+	stock, err := s.Get(ctx, projectID, id)
 	if err != nil {
 		return nil, err
 	}
-
+	if input.ExpectedUpdatedAt != nil && !stock.UpdatedAt.Equal(*input.ExpectedUpdatedAt) {
+		return nil, domain.ErrConflict
+	}
+	// 読み込んだ時点の更新日時。保存時に変わっていれば他の更新と競合したとみなす
+	readUpdatedAt := stock.UpdatedAt
+
+	// サマリはタイトル・本文の変更時（および未生成の既存Stock）に再生成する
+	regenerate := stock.Summary == ""
+	if input.Title != nil {
+		regenerate = regenerate || stock.Title != *input.Title
+		stock.Title = *input.Title
+	}
+	oldCategory := stock.Category
+	if input.Category != nil {
+		category := domain.StockCategory(*input.Category)
+		if !isValidCategory(category) {
+			return nil, domain.ErrInvalidCategory
+		}
+		stock.Category = category
+	}
 	if input.Content != nil {
+		regenerate = regenerate || stock.Content != *input.Content
 		stock.Content = *input.Content
 	}
 	if input.Priority != nil {
@@ -119,32 +263,232 @@ func (s *StockService) Update(ctx context.Context, id string, input UpdateStockI
 		stock.Priority = p
 	}
 	if input.Tags != nil {
-		stock.Tags = input.Tags
+		stock.Tags = domain.NormalizeTags(input.Tags)
 	}
 	if input.References != nil {
 		stock.References = input.References
 	}
 
+	if regenerate {
+		s.annotateStock(ctx, stock)
+	} else {
+		stock.SuggestedTags = suggestTags(stock.SuggestedTags, stock.Tags)
+	}
 	stock.UpdatedAt = time.Now()
 
-	if err := s.stockRepo.Update(ctx, stock); err != nil {
+	if stock.Category != oldCategory {
+		return s.recategorize(ctx, stock, oldCategory, readUpdatedAt)
+	}
+
+	err = s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.stockRepo.UpdateIfUnchanged(ctx, stock, readUpdatedAt); err != nil {
+			return err
+		}
+		s.recordChange(ctx, stock, stockActionUpdate)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update stock: %w", err)
 	}
 
 	// ベクトルインデックスを更新
+	s.indexStock(ctx, stock)
+
+	return stock, nil
+}
+
+// recategorize は新しいカテゴリの管理番号でStockを保存し直し、旧管理番号を転送用のエイリアスに置き換える。
+// 既存のStock・Stateからの参照は旧管理番号のままでもエイリアス経由で解決できる。
+// 旧管理番号のStockが readUpdatedAt の後に更新されていた場合は、作成したStockを取り消して domain.ErrConflict を返す。
+func (s *StockService) recategorize(ctx context.Context, stock *domain.Stock, oldCategory domain.StockCategory, readUpdatedAt time.Time) (*domain.Stock, error) {
+	oldID := stock.ID
+	alias := &domain.Stock{
+		ID:        oldID,
+		ProjectID: stock.ProjectID,
+		Category:  oldCategory,
+		Priority:  stock.Priority,
+		Title:     stock.Title,
+		CreatedAt: stock.CreatedAt,
+		UpdatedAt: stock.UpdatedAt,
+	}
+
+	err := s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.createWithNewID(ctx, stock); err != nil {
+			return fmt.Errorf("failed to create recategorized stock: %w", err)
+		}
+		alias.RedirectTo = stock.ID
+		if err := s.stockRepo.UpdateIfUnchanged(ctx, alias, readUpdatedAt); err != nil {
+			if delErr := s.stockRepo.Delete(ctx, stock.ProjectID, stock.ID); delErr != nil {
+				slog.Warn("failed to roll back recategorized stock", "id", stock.ID, "error", delErr)
+			}
+			if errors.Is(err, domain.ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("failed to replace stock with redirect alias: %w", err)
+		}
+		s.recordChange(ctx, stock, stockActionRecategorize, alias)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	if s.vectorRepo != nil {
-		metadata := map[string]string{
-			"type":       "stock",
-			"project_id": stock.ProjectID,
-			"category":   string(stock.Category),
-			"priority":   stock.Priority.String(),
+		_ = s.vectorRepo.Delete(ctx, stockVectorID(stock.ProjectID, oldID))
+	}
+	s.indexStock(ctx, stock)
+
+	return stock, nil
+}
+
+// Delete はStockを削除済みにする（ソフトデリート）。
+// 削除済みのStockは一覧・検索から除外され、保持期間内であれば Restore で復元できる。
+func (s *StockService) Delete(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	stock, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新日時も進め、削除前に読み込んだ更新が削除を上書きしないようにする
+	readUpdatedAt := stock.UpdatedAt
+	now := time.Now()
+	stock.DeletedAt = &now
+	stock.UpdatedAt = now
+	err = s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.stockRepo.UpdateIfUnchanged(ctx, stock, readUpdatedAt); err != nil {
+			return err
+		}
+		s.recordChange(ctx, stock, stockActionDelete)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete stock: %w", err)
+	}
+
+	if s.vectorRepo != nil {
+		_ = s.vectorRepo.Delete(ctx, stockVectorID(stock.ProjectID, stock.ID))
+	}
+
+	return stock, nil
+}
+
+// Restore は削除済みのStockを復元する。
+func (s *StockService) Restore(ctx context.Context, projectID string, id string) (*domain.Stock, error) {
+	stock, err := s.resolve(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !stock.IsDeleted() {
+		return nil, domain.ErrNotDeleted
+	}
+
+	readUpdatedAt := stock.UpdatedAt
+	stock.DeletedAt = nil
+	stock.UpdatedAt = time.Now()
+	err = s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.stockRepo.UpdateIfUnchanged(ctx, stock, readUpdatedAt); err != nil {
+			return err
+		}
+		s.recordChange(ctx, stock, stockActionRestore)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, err
 		}
-		_ = s.vectorRepo.Upsert(ctx, stock.ID, stock.Title+"\n"+stock.Content, metadata)
+		return nil, fmt.Errorf("failed to restore stock: %w", err)
 	}
 
+	s.indexStock(ctx, stock)
+
 	return stock, nil
 }
 
+// SetDeletedRetention は削除済みStockを完全削除するまでの保持期間を設定する。
+func (s *StockService) SetDeletedRetention(retention time.Duration) {
+	s.deletedRetention = retention
+}
+
+// PurgeDeleted は保持期間を過ぎた削除済みStockを完全に削除し、削除した管理番号を返す。
+func (s *StockService) PurgeDeleted(ctx context.Context, now time.Time) ([]string, error) {
+	stocks, err := s.stockRepo.List(ctx, "", &repository.StockListOptions{IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-s.deletedRetention)
+	purged := []string{}
+	for _, stock := range stocks {
+		if !stock.IsDeleted() || stock.DeletedAt.After(cutoff) {
+			continue
+		}
+		err := s.withStockLock(ctx, func(ctx context.Context) error {
+			if err := s.stockRepo.Delete(ctx, stock.ProjectID, stock.ID); err != nil {
+				return err
+			}
+			s.recordChange(ctx, stock, stockActionPurge)
+			return nil
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge stock %s: %w", stock.ID, err)
+		}
+		purged = append(purged, stock.ID)
+	}
+	return purged, nil
+}
+
+// discard は作成直後のStockを完全に削除する。Stockを作成した後の処理（Stateへの紐づけなど）に失敗した場合に、
+// 紐づけ先のないStockを残さないために使う。
+func (s *StockService) discard(ctx context.Context, stock *domain.Stock) error {
+	err := s.withStockLock(ctx, func(ctx context.Context) error {
+		if err := s.stockRepo.Delete(ctx, stock.ProjectID, stock.ID); err != nil {
+			return err
+		}
+		s.recordChange(ctx, stock, stockActionPurge)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if s.vectorRepo != nil {
+		_ = s.vectorRepo.Delete(ctx, stockVectorID(stock.ProjectID, stock.ID))
+	}
+	return nil
+}
+
+// indexStock はStockをベクトルインデックスに登録する。ベクトルインデックスのエラーは致命的ではない。
+func (s *StockService) indexStock(ctx context.Context, stock *domain.Stock) {
+	if s.vectorRepo == nil {
+		return
+	}
+	metadata := map[string]string{
+		"type":       "stock",
+		"project_id": stock.ProjectID,
+		"category":   string(stock.Category),
+		"priority":   stock.Priority.String(),
+	}
+	_ = s.vectorRepo.Upsert(ctx, stockVectorID(stock.ProjectID, stock.ID), stock.Title+"\n"+stock.Content, metadata)
+}
+
+// reindexStock はファイルの外部編集で変更されたStockをベクトルインデックスへ反映する。
+// 削除・削除済み・エイリアスになったStockはベクトルインデックスから取り除く。
+func (s *StockService) reindexStock(ctx context.Context, change repository.StockChange) {
+	if s.vectorRepo == nil {
+		return
+	}
+	if change.Stock == nil || change.Stock.IsDeleted() || change.Stock.IsAlias() {
+		_ = s.vectorRepo.Delete(ctx, stockVectorID(change.ProjectID, change.ID))
+		return
+	}
+	s.indexStock(ctx, change.Stock)
+}
+
 // List はプロジェクト内のStockを一覧取得する。
 func (s *StockService) List(ctx context.Context, projectID string, opts *repository.StockListOptions) ([]*domain.Stock, error) {
 	return s.stockRepo.List(ctx, projectID, opts)
@@ -163,6 +507,84 @@ func (s *StockService) ListSummary(ctx context.Context, projectID string, opts *
 	return summaries, nil
 }
 
+// StockSummaryPage はStockのサマリビューの1ページ。
+type StockSummaryPage struct {
+	Stocks     []domain.StockSummary
+	Total      int    // 条件に一致する全件数
+	NextCursor string // 次のページのカーソル（最後のページでは空）
+}
+
+// ListSummaryPage はプロジェクト内のStockをサマリビューでページ単位に一覧取得する。
+func (s *StockService) ListSummaryPage(ctx context.Context, projectID string, opts *repository.StockListOptions, page PageRequest) (*StockSummaryPage, error) {
+	if opts == nil {
+		opts = &repository.StockListOptions{}
+	}
+	key := cursorKey("stock-list", projectID, stockListKey(opts))
+	offset, err := decodeCursor(page.Cursor, key)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageLimit(page.Limit, defaultListLimit)
+
+	total, err := s.stockRepo.Count(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
+	paged := *opts
+	paged.Offset = offset
+	paged.Limit = limit
+	summaries, err := s.ListSummary(ctx, projectID, &paged)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StockSummaryPage{
+		Stocks:     summaries,
+		Total:      total,
+		NextCursor: nextCursor(offset, len(summaries), total, key),
+	}, nil
+}
+
+// stockListKey は一覧取得条件をカーソルの指紋用の文字列にする。
+func stockListKey(opts *repository.StockListOptions) string {
+	var category, priority string
+	if opts.Category != nil {
+		category = string(*opts.Category)
+	}
+	if opts.Priority != nil {
+		priority = opts.Priority.String()
+	}
+	return fmt.Sprintf("%s|%s|%t|%s", category, priority, opts.IncludeDeleted, tagFilterKey(opts.Tags, opts.TagMatch))
+}
+
+// SearchSummaryPage はセマンティック検索でStockをサマリビューでページ単位に検索する。
+// 関連度順のヒット（最大 maxSearchHits 件）をタグで絞り込んでページングする。
+func (s *StockService) SearchSummaryPage(ctx context.Context, query string, projectID string, filter TagFilter, page PageRequest) (*StockSummaryPage, error) {
+	key := cursorKey("stock-search", projectID, query, tagFilterKey(filter.Tags, filter.Match))
+	offset, err := decodeCursor(page.Cursor, key)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageLimit(page.Limit, defaultSearchLimit)
+
+	stocks, err := s.Search(ctx, query, maxSearchHits, projectID)
+	if err != nil {
+		return nil, err
+	}
+	stocks = slices.DeleteFunc(stocks, func(stock *domain.Stock) bool { return !filter.matches(stock.Tags) })
+	start, end := pageBounds(offset, limit, len(stocks))
+	summaries := make([]domain.StockSummary, 0, end-start)
+	for _, stock := range stocks[start:end] {
+		summaries = append(summaries, stock.ToSummary())
+	}
+
+	return &StockSummaryPage{
+		Stocks:     summaries,
+		Total:      len(stocks),
+		NextCursor: nextCursor(offset, len(summaries), len(stocks), key),
+	}, nil
+}
+
 // Search はセマンティック検索でStockを検索する。
 func (s *StockService) Search(ctx context.Context, query string, limit int, projectID string) ([]*domain.Stock, error) {
 	if s.vectorRepo != nil {
@@ -174,8 +596,8 @@ func (s *StockService) Search(ctx context.Context, query string, limit int, proj
 		if err == nil {
 			var stocks []*domain.Stock
 			for _, result := range results {
-				stock, err := s.stockRepo.Get(ctx, result.ID)
-				if err != nil {
+				stock, err := s.stockRepo.Get(ctx, result.Metadata["project_id"], vectorItemID(result))
+				if err != nil || stock.IsDeleted() || stock.IsAlias() {
 					continue
 				}
 				stocks = append(stocks, stock)
@@ -218,7 +640,10 @@ func (s *StockService) fallbackSearch(ctx context.Context, query string, limit i
 		if matched[i].Priority != matched[j].Priority {
 			return matched[i].Priority < matched[j].Priority
 		}
-		return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
+		if !matched[i].UpdatedAt.Equal(matched[j].UpdatedAt) {
+			return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
+		}
+		return matched[i].ID < matched[j].ID
 	})
 
 	if limit <= 0 {
@@ -240,11 +665,46 @@ func isValidCategory(c domain.StockCategory) bool {
 	return false
 }
 
-// stockIDCounter はStock IDの連番カウンターとして使用する（簡易実装）。
-var stockIDCounter int
+// maxStockIDAttempts は管理番号の採番で既存の管理番号と衝突した場合に再試行する最大回数。
+const maxStockIDAttempts = 100
 
-func generateStockID(category domain.StockCategory) string {
-	stockIDCounter++
+// createWithNewID はプロジェクト内で未使用の管理番号を採番してStockを保存する。
+// 連番はプロジェクト・カテゴリごとに、既存の管理番号の最大値の次から振る。
+func (s *StockService) createWithNewID(ctx context.Context, stock *domain.Stock) error {
+	stocks, err := s.stockRepo.List(ctx, stock.ProjectID, &repository.StockListOptions{Category: &stock.Category, IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	seq := 0
+	for _, existing := range stocks {
+		seq = max(seq, stockSequence(existing.ID, stock.Category))
+	}
+
+	// 一覧に含まれないエイリアスの管理番号と衝突した場合は次の番号で再試行する
+	for range maxStockIDAttempts {
+		seq++
+		stock.ID = generateStockID(stock.Category, seq)
+		if err := s.stockRepo.Create(ctx, stock); !errors.Is(err, domain.ErrAlreadyExists) {
+			return err
+		}
+	}
+	return domain.ErrAlreadyExists
+}
+
+func generateStockID(category domain.StockCategory, seq int) string {
 	prefix := strings.ToUpper(string(category))
-	return fmt.Sprintf("STK-%s-%03d", prefix, stockIDCounter)
+	return fmt.Sprintf("STK-%s-%03d", prefix, seq)
+}
+
+// stockSequence は管理番号の連番部分を返す。カテゴリの形式に一致しない場合は 0 を返す。
+func stockSequence(id string, category domain.StockCategory) int {
+	rest, ok := strings.CutPrefix(id, "STK-"+strings.ToUpper(string(category))+"-")
+	if !ok {
+		return 0
+	}
+	seq, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0
+	}
+	return seq
 }
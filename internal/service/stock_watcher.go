@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// StockWatchMode はStockファイルの外部編集の監視方法。
+type StockWatchMode string
+
+const (
+	StockWatchNotify StockWatchMode = "notify" // OSのファイル変更通知（使えない場合はポーリング）
+	StockWatchPoll   StockWatchMode = "poll"   // 一定間隔でファイルを走査する
+	StockWatchOff    StockWatchMode = "off"
+)
+
+// stockWatchDebounce は変更通知をまとめて取り込むまでの待ち時間。
+// git pull などで多数のファイルが続けて変更された場合に、まとめて1回取り込む。
+const stockWatchDebounce = 300 * time.Millisecond
+
+// StockWatcher は手作業や git pull によるStockファイルの追加・変更・削除を検出し、
+// 索引（index.json・SQLiteのミラー）とベクトルインデックスへ反映する。
+// 取り込めなかったファイルは診断情報として保持し、変更は OnChange で登録した関数へ通知する。
+type StockWatcher struct {
+	files    repository.StockFileSource
+	stocks   *StockService
+	mode     StockWatchMode
+	interval time.Duration
+
+	mu          sync.Mutex
+	diagnostics map[string][]repository.StockFileDiagnostic // プロジェクトID → 診断情報
+	listeners   []func([]repository.StockChange)
+}
+
+// NewStockWatcher は新しいStockWatcherを生成する。
+func NewStockWatcher(files repository.StockFileSource, stocks *StockService, mode StockWatchMode, interval time.Duration) *StockWatcher {
+	return &StockWatcher{
+		files:       files,
+		stocks:      stocks,
+		mode:        mode,
+		interval:    interval,
+		diagnostics: map[string][]repository.StockFileDiagnostic{},
+	}
+}
+
+// OnChange は外部編集でStockが変更された場合に呼び出す関数を登録する。
+func (w *StockWatcher) OnChange(fn func([]repository.StockChange)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Diagnostics は取り込めなかったStockファイルの診断情報を返す。projectID が空の場合は全プロジェクト分を返す。
+func (w *StockWatcher) Diagnostics(projectID string) []repository.StockFileDiagnostic {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	diagnostics := []repository.StockFileDiagnostic{}
+	for project, list := range w.diagnostics {
+		if projectID == "" || project == projectID {
+			diagnostics = append(diagnostics, list...)
+		}
+	}
+	sort.Slice(diagnostics, func(i, j int) bool { return diagnostics[i].Path < diagnostics[j].Path })
+	return diagnostics
+}
+
+// Reconcile はプロジェクトのStockファイルを取り込み、変更をベクトルインデックスへ反映して通知する。
+// projectID が空の場合は全プロジェクトを対象にする。
+func (w *StockWatcher) Reconcile(ctx context.Context, projectID string) (*repository.StockReconcileResult, error) {
+	result, err := w.files.Reconcile(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range result.Changes {
+		w.stocks.reindexStock(ctx, change)
+		slog.Info("stock file changed outside pim", "op", change.Op, "project_id", change.ProjectID, "id", change.ID)
+	}
+
+	w.mu.Lock()
+	if projectID == "" {
+		w.diagnostics = map[string][]repository.StockFileDiagnostic{}
+	} else {
+		delete(w.diagnostics, projectID)
+	}
+	for _, d := range result.Diagnostics {
+		w.diagnostics[d.ProjectID] = append(w.diagnostics[d.ProjectID], d)
+		slog.Warn("invalid stock file", "path", d.Path, "error", d.Message)
+	}
+	listeners := append([]func([]repository.StockChange){}, w.listeners...)
+	w.mu.Unlock()
+
+	if len(result.Changes) > 0 {
+		for _, fn := range listeners {
+			fn(result.Changes)
+		}
+	}
+	return result, nil
+}
+
+// Start は監視を開始し、ctx が終了するまでブロックする。
+// 開始時に全プロジェクトを一度取り込み、停止中に行われた編集も反映する。
+func (w *StockWatcher) Start(ctx context.Context) {
+	if w.mode == StockWatchOff {
+		return
+	}
+	if _, err := w.Reconcile(ctx, ""); err != nil {
+		slog.Warn("failed to reconcile stock files", "error", err)
+	}
+
+	if w.mode == StockWatchNotify {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			defer watcher.Close()
+			w.watch(ctx, watcher)
+			return
+		}
+		slog.Warn("file change notifications are unavailable; falling back to polling", "error", err, "interval", w.interval)
+	}
+	w.poll(ctx)
+}
+
+// poll は一定間隔で全プロジェクトを取り込む。
+func (w *StockWatcher) poll(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.Reconcile(ctx, ""); err != nil {
+				slog.Warn("failed to reconcile stock files", "error", err)
+			}
+		}
+	}
+}
+
+// watch はファイル変更通知を受け取り、変更のあったプロジェクトを少し待ってからまとめて取り込む。
+// fsnotify はサブディレクトリを再帰的に監視しないため、ディレクトリごとに監視を追加する。
+func (w *StockWatcher) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	base := w.files.Dir()
+	w.addWatches(watcher, base)
+
+	pending := map[string]bool{} // 取り込み待ちのプロジェクトID（空文字は全プロジェクト）
+	timer := time.NewTimer(stockWatchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// 新しいプロジェクト・カテゴリのディレクトリ。作成前に書かれたファイルは取り込みで拾う
+					w.addWatches(watcher, event.Name)
+				}
+			}
+			projectID, ok := stockEventProject(base, event.Name)
+			if !ok {
+				continue
+			}
+			pending[projectID] = true
+			timer.Reset(stockWatchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// 通知の取りこぼし（キューのあふれ等）に備え、全プロジェクトを取り込み直す
+			slog.Warn("stock file watcher error; reconciling all projects", "error", err)
+			pending[""] = true
+			timer.Reset(stockWatchDebounce)
+		case <-timer.C:
+			projects := pending
+			pending = map[string]bool{}
+			if projects[""] {
+				projects = map[string]bool{"": true}
+			}
+			for projectID := range projects {
+				if _, err := w.Reconcile(ctx, projectID); err != nil {
+					slog.Warn("failed to reconcile stock files", "project_id", projectID, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// addWatches は dir 以下のディレクトリをすべて監視対象に追加する。
+func (w *StockWatcher) addWatches(watcher *fsnotify.Watcher, dir string) {
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir // .git 等
+		}
+		if err := watcher.Add(path); err != nil {
+			slog.Warn("failed to watch stock directory", "path", path, "error", err)
+		}
+		return nil
+	})
+}
+
+// stockEventProject は変更通知のパスから取り込み対象のプロジェクトIDを返す。
+// 索引・ロックファイル・書き込み途中の一時ファイル・隠しディレクトリ（.git 等）の変更は対象外とする。
+func stockEventProject(base, path string) (string, bool) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, ".") {
+		return "", false
+	}
+	name := filepath.Base(rel)
+	if strings.HasPrefix(name, ".") || name == "index.json" {
+		return "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) == 1 {
+		// プロジェクトのディレクトリ自体の作成・削除
+		return parts[0], true
+	}
+	if len(parts) == 3 && !strings.HasSuffix(name, ".json") {
+		return "", false
+	}
+	return parts[0], true
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/haconeco/project-information-manager/internal/domain"
+	"github.com/haconeco/project-information-manager/internal/repository"
+)
+
+// TagFilter は検索結果をタグで絞り込む条件。Tags が空の場合は絞り込まない。
+type TagFilter struct {
+	Tags  []string
+	Match domain.TagMatch
+}
+
+func (f TagFilter) matches(tags []string) bool {
+	return domain.MatchTags(tags, domain.NormalizeTags(f.Tags), f.Match)
+}
+
+// tagFilterKey はタグの絞り込み条件をカーソルの指紋用の文字列にする。
+func tagFilterKey(tags []string, match domain.TagMatch) string {
+	tags = domain.NormalizeTags(tags)
+	if len(tags) == 0 {
+		return ""
+	}
+	sorted := slices.Sorted(slices.Values(tags))
+	if match != domain.TagMatchAll {
+		match = domain.TagMatchAny
+	}
+	return string(match) + ":" + strings.Join(sorted, ",")
+}
+
+// TagRenameResult はタグの名前変更・統合の結果。
+type TagRenameResult struct {
+	From    []string `json:"from"`
+	To      string   `json:"to"`
+	Updated []string `json:"updated"` // タグを書き換えた管理番号
+}
+
+// renameTagsInput はタグの名前変更・統合の入力を検証して正規化する。
+func renameTagsInput(from []string, to string) ([]string, string, error) {
+	from = domain.NormalizeTags(from)
+	to = strings.TrimSpace(to)
+	if len(from) == 0 {
+		return nil, "", fmt.Errorf("source tags are required")
+	}
+	if to == "" {
+		return nil, "", fmt.Errorf("new tag is required")
+	}
+	return from, to, nil
+}
+
+// TagCounts はプロジェクト内のStockのタグごとの件数を、件数の降順で返す。
+func (s *StockService) TagCounts(ctx context.Context, projectID string, opts *repository.StockListOptions) ([]domain.TagCount, error) {
+	return s.stockRepo.TagCounts(ctx, projectID, opts)
+}
+
+// RenameTags はプロジェクト内のStock（削除済みを含む）のタグ from を to に置き換える。
+// from に複数のタグを指定すると、それらを to に統合する。
+// タグの整理は内容の変更ではないため、更新日時は変更しない。
+func (s *StockService) RenameTags(ctx context.Context, projectID string, from []string, to string) (*TagRenameResult, error) {
+	from, to, err := renameTagsInput(from, to)
+	if err != nil {
+		return nil, err
+	}
+	stocks, err := s.stockRepo.List(ctx, projectID, &repository.StockListOptions{Tags: from, IncludeDeleted: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+
+	result := &TagRenameResult{From: from, To: to, Updated: []string{}}
+	for _, listed := range stocks {
+		changed := false
+		// 一覧の取得後に更新されていてもその内容を上書きしないよう、ロックを保持したまま読み直してから書き込む
+		err := s.withStockLock(ctx, func(ctx context.Context) error {
+			stock, err := s.stockRepo.Get(ctx, listed.ProjectID, listed.ID)
+			if err != nil {
+				return err
+			}
+			var tags []string
+			if tags, changed = domain.RenameTags(stock.Tags, from, to); !changed {
+				return nil
+			}
+			stock.Tags = tags
+			stock.SuggestedTags = suggestTags(stock.SuggestedTags, stock.Tags)
+			if err := s.stockRepo.UpdateIfUnchanged(ctx, stock, stock.UpdatedAt); err != nil {
+				return err
+			}
+			s.recordChange(ctx, stock, stockActionRenameTags)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename tags of %s: %w", listed.ID, err)
+		}
+		if changed {
+			result.Updated = append(result.Updated, listed.ID)
+		}
+	}
+	return result, nil
+}
+
+// TagCounts はプロジェクト内のStateのタグごとの件数を、件数の降順で返す。
+func (s *StateService) TagCounts(ctx context.Context, projectID string, opts *repository.StateListOptions) ([]domain.TagCount, error) {
+	return s.stateRepo.TagCounts(ctx, projectID, opts)
+}
+
+// RenameTags はプロジェクト内のState（アーカイブ済みを含む）のタグ from を to に置き換える。
+// from に複数のタグを指定すると、それらを to に統合する。
+// タグの整理は作業の進捗ではないため、更新日時は変更しない（放置判定に影響させない）。
+func (s *StateService) RenameTags(ctx context.Context, projectID string, from []string, to string) (*TagRenameResult, error) {
+	from, to, err := renameTagsInput(from, to)
+	if err != nil {
+		return nil, err
+	}
+	states, err := s.stateRepo.List(ctx, projectID, &repository.StateListOptions{Tags: from, IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	result := &TagRenameResult{From: from, To: to, Updated: []string{}}
+	for _, state := range states {
+		tags, changed := domain.RenameTags(state.Tags, from, to)
+		if !changed {
+			continue
+		}
+		state.Tags = tags
+		if err := s.stateRepo.Update(ctx, state); err != nil {
+			return nil, fmt.Errorf("failed to rename tags of %s: %w", state.ID, err)
+		}
+		result.Updated = append(result.Updated, state.ID)
+	}
+	return result, nil
+}
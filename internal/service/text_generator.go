@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/haconeco/project-information-manager/internal/config"
+	"github.com/haconeco/project-information-manager/internal/llm"
+)
+
+// TextGenerator はサービス層がLLMに依頼する処理（要約・分類・キーワード抽出・下書き）のインターフェース。
+// *llm.Client が実装する。未設定（nil）の場合、サービスはLLMを呼び出さない。
+type TextGenerator interface {
+	// Summarize はテキストを maxChars 文字以内に要約する。
+	Summarize(ctx context.Context, text string, maxChars int) (string, error)
+	// Classify はテキストを labels のいずれかに分類する。
+	Classify(ctx context.Context, text string, labels []string) (string, error)
+	// ExtractKeywords はテキストから検索用のキーワードを最大 max 件抽出する。
+	ExtractKeywords(ctx context.Context, text string, max int) ([]string, error)
+	// Draft は instruction に従ってテキストから maxChars 文字以内の文書を下書きする。
+	Draft(ctx context.Context, instruction string, text string, maxChars int) (string, error)
+}
+
+type textGeneratorKey struct{}
+
+// WithTextGenerator は呼び出し元が提供するLLM（MCPクライアントのサンプリング等）をコンテキストに設定する。
+// サーバー側でLLMが設定されている場合はそちらを優先する。
+func WithTextGenerator(ctx context.Context, generator TextGenerator) context.Context {
+	return context.WithValue(ctx, textGeneratorKey{}, generator)
+}
+
+// generatorFor は利用するLLMを返す。サーバー側の設定がなければコンテキストのLLMを用い、どちらもなければ nil を返す。
+func generatorFor(ctx context.Context, configured TextGenerator) TextGenerator {
+	if configured != nil {
+		return configured
+	}
+	if generator, ok := ctx.Value(textGeneratorKey{}).(TextGenerator); ok {
+		return generator
+	}
+	return nil
+}
+
+// warnGenerationError はLLMの呼び出し失敗を警告する。
+// プロバイダーがリクエストに応答できない場合（llm.ErrUnavailable）は想定内の代替動作のため出力しない。
+func warnGenerationError(msg string, err error, args ...any) {
+	if errors.Is(err, llm.ErrUnavailable) {
+		return
+	}
+	slog.Warn(msg, append(args, "error", err)...)
+}
+
+// llmClientFromConfig は設定からLLMクライアントを生成する。未設定・不正な設定の場合は nil を返す。
+func llmClientFromConfig(cfg config.LLMConfig) *llm.Client {
+	client, err := llm.New(cfg)
+	if err != nil {
+		if !errors.Is(err, llm.ErrNotConfigured) {
+			slog.Warn("llm gateway disabled", "provider", cfg.Provider, "error", err)
+		}
+		return nil
+	}
+	slog.Info("llm gateway enabled", "provider", client.Provider().Name(), "model", cfg.Model)
+	return client
+}